@@ -0,0 +1,408 @@
+package insideout
+
+import (
+	"math"
+
+	"github.com/golang/geo/s2"
+)
+
+// wrapLng returns the longitude delta d in the range [-180, 180].
+func wrapLng(d float64) float64 {
+	for d > 180 {
+		d -= 360
+	}
+
+	for d < -180 {
+		d += 360
+	}
+
+	return d
+}
+
+// CrossesAntimeridian reports whether a lng lat ring has consecutive vertices more than 180° apart,
+// meaning the ring is crossing the antimeridian.
+func CrossesAntimeridian(c []float64) bool {
+	for i := 2; i+1 < len(c); i += 2 {
+		if math.Abs(c[i]-c[i-2]) > 180 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UnwrapLongitudes returns a copy of the lng lat ring with continuous longitudes:
+// consecutive vertices are never more than 180° apart, so rings crossing the antimeridian
+// can be processed in a plane.
+// It also returns the longitude offset between the last and the first vertex once unwrapped,
+// 0 for regular rings, ±360 for rings going around a pole.
+func UnwrapLongitudes(c []float64) ([]float64, float64) {
+	u := make([]float64, len(c))
+	if len(c) < 2 {
+		return u, 0
+	}
+
+	copy(u, c)
+
+	for i := 2; i+1 < len(c); i += 2 {
+		u[i] = u[i-2] + wrapLng(c[i]-c[i-2])
+	}
+
+	last := len(c) - 2
+	offset := u[last] + wrapLng(c[0]-c[last]) - u[0]
+
+	if math.Abs(offset) < 180 {
+		offset = 0
+	}
+
+	return u, offset
+}
+
+// CutAntimeridian cuts a polygon crossing the antimeridian as described in RFC 7946 3.1.9,
+// rings are closed lng lat rings, the first one is the outer ring counter clockwise,
+// the next ones are clockwise holes.
+// It returns the resulting polygons as lists of closed rings with longitudes in [-180, 180].
+func CutAntimeridian(rings [][]float64) [][][]float64 {
+	if len(rings) == 0 {
+		return nil
+	}
+
+	outer, offset := UnwrapLongitudes(rings[0])
+	if offset != 0 {
+		return [][][]float64{cutPolarRing(rings)}
+	}
+
+	// move the outer ring so its western most vertex is in [-180, 180)
+	minx, maxx := math.Inf(1), math.Inf(-1)
+
+	for i := 0; i < len(outer); i += 2 {
+		minx = math.Min(minx, outer[i])
+		maxx = math.Max(maxx, outer[i])
+	}
+
+	shift := -360 * math.Floor((minx+180)/360)
+	minx += shift
+	maxx += shift
+
+	pr := make([][][2]float64, len(rings))
+	pr[0] = toPoints(outer, shift)
+
+	for i := 1; i < len(rings); i++ {
+		hole, _ := UnwrapLongitudes(rings[i])
+		// holes are in the same longitude range as the outer ring
+		hshift := shift - 360*math.Floor((hole[0]+shift-minx)/360)
+		pr[i] = toPoints(hole, hshift)
+	}
+
+	if maxx <= 180 {
+		return [][][]float64{fromPoints(pr, 0)}
+	}
+
+	var res [][][]float64
+
+	for _, p := range splitPolygon(pr, 180) {
+		res = append(res, fromPoints(p, 0))
+	}
+
+	// the east side is mirrored around the antimeridian, split then mirrored back
+	mirrored := make([][][2]float64, len(pr))
+	for i, r := range pr {
+		mirrored[i] = mirrorRing(r, 180)
+	}
+
+	for _, p := range splitPolygon(mirrored, 180) {
+		for i, r := range p {
+			p[i] = mirrorRing(r, 180)
+		}
+
+		res = append(res, fromPoints(p, -360))
+	}
+
+	return res
+}
+
+// cutPolarRing cuts a polygon going around a pole at the antimeridian,
+// closing it through the pole as found in RFC 7946 datasets.
+func cutPolarRing(rings [][]float64) [][]float64 {
+	c := rings[0]
+	n := len(c) / 2
+
+	if c[0] == c[len(c)-2] && c[1] == c[len(c)-1] {
+		n--
+	}
+
+	// find the edge crossing the antimeridian
+	cross := -1
+
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		if math.Abs(c[j*2]-c[i*2]) > 180 {
+			cross = i
+
+			break
+		}
+	}
+
+	res := make([][]float64, len(rings))
+	for i := 1; i < len(rings); i++ {
+		res[i] = rings[i]
+	}
+
+	if cross < 0 {
+		res[0] = c
+
+		return res
+	}
+
+	ai, bi := cross, (cross+1)%n
+	alng, alat := c[ai*2], c[ai*2+1]
+	blng, blat := c[bi*2], c[bi*2+1]
+
+	// a and b on each side of the antimeridian
+	aside := math.Copysign(180, alng)
+	bside := math.Copysign(180, blng)
+	da := math.Abs(aside - alng)
+	db := math.Abs(bside - blng)
+
+	lat := alat
+	if da+db > 0 {
+		lat = alat + (blat-alat)*da/(da+db)
+	}
+
+	poleLat := -90.0
+
+	loop := LoopFromCoordinates(c)
+	if loop != nil && loop.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(90, 0))) {
+		poleLat = 90
+	}
+
+	pts := make([][2]float64, 0, n+4)
+	pts = append(pts, [2]float64{bside, lat})
+
+	for k := 0; k < n; k++ {
+		i := (bi + k) % n
+		pts = append(pts, [2]float64{c[i*2], c[i*2+1]})
+	}
+
+	pts = append(pts, [2]float64{aside, lat}, [2]float64{aside, poleLat}, [2]float64{bside, poleLat})
+	res[0] = fromPoints([][][2]float64{dedupPoints(pts)}, 0)[0]
+
+	return res
+}
+
+// crossingChain is a part of a ring on the kept side of a split line.
+type crossingChain struct {
+	points [][2]float64
+	used   bool
+	entryY float64
+	exitY  float64
+}
+
+// splitPolygon returns the parts of the polygon on the west side of the vertical line x,
+// rings are not closed, outer ring counter clockwise, holes clockwise.
+func splitPolygon(rings [][][2]float64, x float64) [][][][2]float64 {
+	var (
+		chains      []*crossingChain
+		polygons    [][][][2]float64
+		insideHoles [][][2]float64
+	)
+
+	for ri, r := range rings {
+		in := 0
+
+		for _, p := range r {
+			if p[0] <= x {
+				in++
+			}
+		}
+
+		switch {
+		case in == len(r):
+			if ri == 0 {
+				polygons = append(polygons, [][][2]float64{r})
+			} else {
+				insideHoles = append(insideHoles, r)
+			}
+		case in == 0:
+			if ri == 0 {
+				return nil
+			}
+		default:
+			chains = append(chains, ringChains(r, x)...)
+		}
+	}
+
+	for _, ch := range chains {
+		if ch.used {
+			continue
+		}
+
+		var ring [][2]float64
+
+		cur := ch
+
+		for cur != nil && !cur.used {
+			cur.used = true
+			ring = append(ring, cur.points...)
+
+			// the kept part is on the west side, following its boundary counter clockwise
+			// means going north along the line to the next chain
+			var next *crossingChain
+
+			for _, cand := range chains {
+				if cand.entryY < cur.exitY || (cand.used && cand != ch) {
+					continue
+				}
+
+				if next == nil || cand.entryY < next.entryY {
+					next = cand
+				}
+			}
+
+			cur = next
+		}
+
+		polygons = append(polygons, [][][2]float64{dedupPoints(ring)})
+	}
+
+	// assign holes to the polygons containing them
+	for _, h := range insideHoles {
+		for i, p := range polygons {
+			if planarContains(p[0], h[0]) {
+				polygons[i] = append(polygons[i], h)
+
+				break
+			}
+		}
+	}
+
+	return polygons
+}
+
+// ringChains returns the parts of the ring on the west side of the vertical line x.
+func ringChains(r [][2]float64, x float64) []*crossingChain {
+	n := len(r)
+
+	// start on a vertex outside
+	start := 0
+
+	for i, p := range r {
+		if p[0] > x {
+			start = i
+
+			break
+		}
+	}
+
+	var (
+		chains []*crossingChain
+		cur    *crossingChain
+	)
+
+	for k := 1; k <= n; k++ {
+		a := r[(start+k-1)%n]
+		b := r[(start+k)%n]
+		ain, bin := a[0] <= x, b[0] <= x
+
+		switch {
+		case !ain && bin:
+			e := intersectX(a, b, x)
+			cur = &crossingChain{entryY: e[1], points: [][2]float64{e, b}}
+		case ain && bin:
+			cur.points = append(cur.points, b)
+		case ain && !bin:
+			e := intersectX(a, b, x)
+			cur.points = append(cur.points, e)
+			cur.exitY = e[1]
+			chains = append(chains, cur)
+			cur = nil
+		}
+	}
+
+	return chains
+}
+
+// intersectX returns the point of segment ab on the vertical line x.
+func intersectX(a, b [2]float64, x float64) [2]float64 {
+	if a[0] == b[0] {
+		return [2]float64{x, a[1]}
+	}
+
+	t := (x - a[0]) / (b[0] - a[0])
+
+	return [2]float64{x, a[1] + t*(b[1]-a[1])}
+}
+
+// planarContains reports whether the ring contains the point in lng lat space.
+func planarContains(r [][2]float64, p [2]float64) bool {
+	inside := false
+
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		if (r[i][1] > p[1]) != (r[j][1] > p[1]) &&
+			p[0] < (r[j][0]-r[i][0])*(p[1]-r[i][1])/(r[j][1]-r[i][1])+r[i][0] {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// mirrorRing mirrors the ring around the vertical line x, reversing it to keep its orientation.
+func mirrorRing(r [][2]float64, x float64) [][2]float64 {
+	m := make([][2]float64, len(r))
+	for i, p := range r {
+		m[len(r)-1-i] = [2]float64{2*x - p[0], p[1]}
+	}
+
+	return m
+}
+
+// dedupPoints removes consecutive duplicates points.
+func dedupPoints(r [][2]float64) [][2]float64 {
+	res := r[:0]
+
+	for i, p := range r {
+		if i > 0 && p == res[len(res)-1] {
+			continue
+		}
+
+		res = append(res, p)
+	}
+
+	for len(res) > 1 && res[0] == res[len(res)-1] {
+		res = res[:len(res)-1]
+	}
+
+	return res
+}
+
+// toPoints converts a closed flat lng lat ring to an open list of points, shifting the longitudes.
+func toPoints(c []float64, shift float64) [][2]float64 {
+	pts := make([][2]float64, 0, len(c)/2)
+	for i := 0; i+1 < len(c); i += 2 {
+		pts = append(pts, [2]float64{c[i] + shift, c[i+1]})
+	}
+
+	return dedupPoints(pts)
+}
+
+// fromPoints converts open rings of points to closed flat lng lat rings, shifting the longitudes.
+func fromPoints(rings [][][2]float64, shift float64) [][]float64 {
+	res := make([][]float64, 0, len(rings))
+
+	for _, r := range rings {
+		if len(r) == 0 {
+			continue
+		}
+
+		c := make([]float64, 0, len(r)*2+2)
+		for _, p := range r {
+			c = append(c, p[0]+shift, p[1])
+		}
+
+		c = append(c, r[0][0]+shift, r[0][1])
+		res = append(res, c)
+	}
+
+	return res
+}
@@ -0,0 +1,105 @@
+package insideout_test
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+)
+
+func TestCutAntimeridian(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		rings [][]float64
+		want  [][][]float64
+	}{
+		{
+			"not crossing",
+			[][]float64{{0, 0, 1, 0, 1, 1, 0, 1, 0, 0}},
+			[][][]float64{{{0, 0, 1, 0, 1, 1, 0, 1, 0, 0}}},
+		},
+		{
+			"crossing",
+			[][]float64{{179, -17, -179, -17, -179, -16, 179, -16, 179, -17}},
+			[][][]float64{
+				{{180, -16, 179, -16, 179, -17, 180, -17, 180, -16}},
+				{{-180, -17, -179, -17, -179, -16, -180, -16, -180, -17}},
+			},
+		},
+		{
+			"crossing several times",
+			[][]float64{{178, -20, -178, -20, -178, -18, 179.5, -18, 179.5, -12, -178, -12, -178, -10, 178, -10, 178, -20}},
+			[][][]float64{
+				{{180, -18, 179.5, -18, 179.5, -12, 180, -12, 180, -10, 178, -10, 178, -20, 180, -20, 180, -18}},
+				{{-180, -12, -178, -12, -178, -10, -180, -10, -180, -12}},
+				{{-180, -20, -178, -20, -178, -18, -180, -18, -180, -20}},
+			},
+		},
+		{
+			"crossing hole",
+			[][]float64{
+				{178, -20, -178, -20, -178, -10, 178, -10, 178, -20},
+				{179, -16, 179, -14, -179, -14, -179, -16, 179, -16},
+			},
+			[][][]float64{
+				{{180, -10, 178, -10, 178, -20, 180, -20, 180, -16, 179, -16, 179, -14, 180, -14, 180, -10}},
+				{{-180, -14, -179, -14, -179, -16, -180, -16, -180, -20, -178, -20, -178, -10, -180, -10, -180, -14}},
+			},
+		},
+		{
+			"around the south pole",
+			[][]float64{{0, -80, -90, -80, 180, -80, 90, -80, 0, -80}},
+			[][][]float64{{{180, -80, 90, -80, 0, -80, -90, -80, -180, -80, -180, -90, 180, -90, 180, -80}}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := insideout.CutAntimeridian(tt.rings)
+			if !cmp.Equal(got, tt.want) {
+				t.Fatalf("CutAntimeridian() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoordinatesFromPolygonAntimeridian(t *testing.T) {
+	t.Parallel()
+
+	// clockwise once unwrapped, the smallest loop is used
+	p, err := insideout.PolygonFromCoordinates([]float64{-179, -17, 179, -17, 179, -16, -179, -16, -179, -17}, []int{10})
+	require.NoError(t, err)
+
+	coords, endss := insideout.CoordinatesFromPolygon(p)
+	require.Equal(t, [][]int{{10}, {20}}, endss)
+
+	for i := 0; i < len(coords); i += 2 {
+		require.True(t, coords[i] >= 179 || coords[i] <= -179, "unexpected longitude %f", coords[i])
+	}
+}
+
+func TestPolygonFromCoordinatesClockwise(t *testing.T) {
+	t.Parallel()
+
+	ccw := []float64{2, 48, 2.1, 48, 2.1, 48.1, 2, 48.1, 2, 48}
+	cw := []float64{2, 48, 2, 48.1, 2.1, 48.1, 2.1, 48, 2, 48}
+
+	want, err := insideout.PolygonFromCoordinates(ccw, []int{10})
+	require.NoError(t, err)
+
+	// the clockwise ring is reversed instead of covering the rest of the world
+	p, err := insideout.PolygonFromCoordinates(cw, []int{10})
+	require.NoError(t, err)
+	require.InDelta(t, want.Area(), p.Area(), 1e-12)
+	require.True(t, p.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(48.05, 2.05))))
+	require.False(t, p.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(10, 10))))
+}
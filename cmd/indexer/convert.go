@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	sstorage "github.com/akhenakh/insideout/storage"
+	"github.com/akhenakh/insideout/storage/mmap"
+)
+
+// convertIndex writes the index at dbPath to a memory mapped file at mmapPath.
+func convertIndex(logger log.Logger, engine, dbPath, mmapPath string) error {
+	storage, clean, err := sstorage.NewROStorage(engine, dbPath, logger)
+	if err != nil {
+		return err
+	}
+
+	defer clean()
+
+	src, ok := storage.(mmap.Source)
+	if !ok {
+		return fmt.Errorf("storage engine %s can't be converted", engine)
+	}
+
+	if err := mmap.Write(mmapPath, src, mmap.WriteOptions{SnapLevel: *mmapSnapLevel}); err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(mmapPath)
+	if err != nil {
+		return err
+	}
+
+	level.Info(logger).Log("msg", "converted index", "mmap_path", mmapPath, "size", fi.Size())
+
+	return nil
+}
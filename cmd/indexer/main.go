@@ -3,10 +3,17 @@ package main
 import (
 	"bufio"
 	"compress/gzip"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
 	stdlog "log"
 	"os"
 	"path"
+	"runtime"
+	"sync"
+	"time"
 
 	log "github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -14,8 +21,10 @@ import (
 	"github.com/namsral/flag"
 	"github.com/twpayne/go-geom/encoding/geojson"
 
+	"github.com/akhenakh/insideout"
 	"github.com/akhenakh/insideout/loglevel"
-	sbbolt "github.com/akhenakh/insideout/storage/bbolt"
+	sstorage "github.com/akhenakh/insideout/storage"
+	"github.com/akhenakh/insideout/validation"
 )
 
 /*
@@ -66,8 +75,27 @@ var (
 	outsideMaxCellsCover = flag.Int("outsideMaxCellsCover", 16, "Max s2 Cells count for outside cover")
 	warningCellsCover    = flag.Int("warningCellsCover", 1000, "warning limit cover count")
 
-	filePath = flag.String("filePath", "-", "FeatureCollection GeoJSON file to index, default to stdin \"-\"")
+	filePath = flag.String("filePath", "", "GeoJSON FeatureCollection or GeoJSONSeq file to index, \"-\" for stdin, default to stdin except with -update")
 	dbPath   = flag.String("dbPath", "inside.db", "Database path")
+	dbEngine = flag.String("dbEngine", sstorage.BBolt, "Database engine: bbolt|leveldb, mmap files are converted with mmapPath")
+
+	validate   = flag.Bool("validate", true, "Validate and repair geometries before indexing")
+	reportPath = flag.String("reportPath", "", "Path to write the JSON validation report, or the verification report with -verify, empty to disable")
+
+	idProperty = flag.String("idProperty", "", "Property used as the feature external id instead of the GeoJSON id")
+
+	workers   = flag.Int("workers", runtime.NumCPU(), "Workers count covering the features, default to the CPU count")
+	batchSize = flag.Int("batchSize", 1000, "Features stored per transaction")
+	tmpDir    = flag.String("tmpDir", os.TempDir(), "Directory for the temporary cells files")
+
+	verify        = flag.Bool("verify", false, "Check the integrity of the index at dbPath instead of indexing")
+	verifySamples = flag.Int("verifySamples", 100, "Features tested with random points against the index, with -verify")
+
+	mmapPath      = flag.String("mmapPath", "", "Convert the index at dbPath to a read only memory mapped file at mmapPath instead of indexing")
+	mmapSnapLevel = flag.Int("mmapSnapLevel", 30, "Snap the vertices to the cells centers at this level when converting, smaller files, 0 to disable")
+
+	update     = flag.Bool("update", false, "Update an existing index: add or replace the features by id, delete the ids in deletePath")
+	deletePath = flag.String("deletePath", "", "Path to a file listing the feature ids to delete, one per line, with -update")
 )
 
 func main() {
@@ -84,64 +112,80 @@ func main() {
 
 	level.Info(logger).Log("msg", "Starting app", "version", version)
 
-	var fc geojson.FeatureCollection
-
-	// reading GeoJSON or gziped GeoJSON
-	r := bufio.NewReader(os.Stdin)
-
-	if *filePath != "-" {
-		file, err := os.Open(*filePath)
-		if err != nil {
-			level.Error(logger).Log("msg", "failed to open GeoJSON", "error", err, "file_path", *filePath)
+	if *verify {
+		if *dbEngine != sstorage.BBolt {
+			level.Error(logger).Log("msg", "verification is only available for bbolt DBs", "db_engine", *dbEngine)
 
 			exitcode = 1
 
 			return
 		}
-		defer file.Close()
 
-		r = bufio.NewReader(file)
-	} else {
-		*filePath = "stdin"
+		if err := verifyIndex(logger, *dbPath); err != nil {
+			level.Error(logger).Log("msg", "verification failed", "error", err, "db_path", *dbPath)
+
+			exitcode = 1
+		}
+
+		return
 	}
 
-	// read 2 bytes
-	if testBytes, err := r.Peek(2); err != nil {
-		level.Error(logger).Log("msg", "failed to read gziped GeoJSON", "error", err, "file_path", *filePath)
+	if *mmapPath != "" {
+		if err := convertIndex(logger, *dbEngine, *dbPath, *mmapPath); err != nil {
+			level.Error(logger).Log("msg", "conversion failed", "error", err, "db_path", *dbPath, "mmap_path", *mmapPath)
 
-		exitcode = 1
+			exitcode = 1
+		}
 
 		return
-	} else {
-		// found gzip
-		if testBytes[0] == 31 && testBytes[1] == 139 {
-			gzipReader, err := gzip.NewReader(r)
-			if err != nil {
-				level.Error(logger).Log("msg", "failed to open gziped GeoJSON", "error", err, "file_path", *filePath)
-
-				exitcode = 1
+	}
 
-				return
-			}
+	// in update mode stdin is only read when asked explicitly, so deleting does not wait for it
+	if *filePath == "" && !*update {
+		*filePath = "-"
+	}
 
-			r = bufio.NewReader(gzipReader)
-		}
+	fileName := path.Base(*filePath)
+	if *filePath == "-" {
+		fileName = "stdin"
 	}
 
-	decoder := json.NewDecoder(r)
+	// in update mode features are optional, only deleting
+	var features insideout.FeatureIterator = insideout.NewFeatureCollectionIterator(&geojson.FeatureCollection{})
 
-	err := decoder.Decode(&fc)
-	if err != nil {
-		level.Error(logger).Log("msg", "failed to decode GeoJSON", "error", err, "file_path", *filePath)
+	// checksum of the file as read
+	sum := sha256.New()
 
-		exitcode = 1
+	var r io.Reader
 
-		return
+	if *filePath != "" {
+		var (
+			closeFile func() error
+			err       error
+		)
+
+		r, closeFile, err = openGeoJSON(*filePath, sum)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to open GeoJSON", "error", err, "file_path", fileName)
+
+			exitcode = 1
+
+			return
+		}
+
+		defer closeFile()
+
+		features = insideout.NewFeatureDecoder(r)
+	}
+
+	src := &source{logger: logger, features: features}
+	if *validate {
+		src.report = &validation.Report{}
 	}
 
-	storage, clean, err := sbbolt.NewStorage(*dbPath, logger)
+	storage, clean, err := sstorage.NewStorage(*dbEngine, *dbPath, logger)
 	if err != nil {
-		level.Error(logger).Log("msg", "failed to open storage", "error", err, "db_path", *dbPath)
+		level.Error(logger).Log("msg", "failed to open storage", "error", err, "db_path", *dbPath, "db_engine", *dbEngine)
 
 		exitcode = 1
 
@@ -150,6 +194,13 @@ func main() {
 
 	defer clean()
 
+	storage.SetIndexOptions(sstorage.IndexOptions{
+		Workers:   *workers,
+		BatchSize: *batchSize,
+		TempDir:   *tmpDir,
+		Skipped:   src.skip,
+	})
+
 	icoverer := &s2.RegionCoverer{
 		MinLevel: *insideMinLevelCover,
 		MaxLevel: *insideMaxLevelCover,
@@ -161,7 +212,33 @@ func main() {
 		MaxCells: *outsideMaxCellsCover,
 	}
 
-	err = storage.Index(fc, icoverer, ocoverer, *warningCellsCover, path.Base(*filePath), version)
+	if *update {
+		infos, err := storage.LoadIndexInfos()
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to read index infos", "error", err)
+
+			exitcode = 1
+
+			return
+		}
+
+		// DBs indexed before the cover parameters were stored use the flags
+		if infos.SchemaVersion > 0 {
+			icoverer = infos.InsideCover.RegionCoverer()
+			ocoverer = infos.OutsideCover.RegionCoverer()
+			*warningCellsCover = infos.WarningCellsCover
+
+			level.Info(logger).Log("msg", "using the index cover parameters",
+				"inside_cover", fmt.Sprintf("%+v", infos.InsideCover),
+				"outside_cover", fmt.Sprintf("%+v", infos.OutsideCover),
+			)
+		}
+
+		err = updateIndex(logger, storage, src, icoverer, ocoverer)
+	} else {
+		err = storage.Index(src, icoverer, ocoverer, *warningCellsCover, fileName, version)
+	}
+
 	if err != nil {
 		level.Error(logger).Log("msg", "indexation failed", "error", err)
 
@@ -170,5 +247,195 @@ func main() {
 		return
 	}
 
-	level.Info(logger).Log("msg", "stored index_infos")
+	if src.skipped > 0 {
+		level.Warn(logger).Log("msg", "features not indexed, their cover failed", "skipped_count", src.skipped)
+	}
+
+	if src.report != nil {
+		level.Info(logger).Log(
+			"msg", "validated geometries",
+			"feature_count", src.report.FeatureCount,
+			"fixed_count", src.report.FixedCount,
+			"dropped_count", src.report.DroppedCount,
+			"skipped_count", src.report.SkippedCount,
+		)
+
+		if *reportPath != "" {
+			if err := writeReport(src.report, *reportPath); err != nil {
+				level.Error(logger).Log("msg", "failed to write validation report", "error", err, "report_path", *reportPath)
+
+				exitcode = 1
+
+				return
+			}
+		}
+	}
+
+	if r != nil {
+		// the checksum covers the whole file, even what follows the features
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			level.Error(logger).Log("msg", "failed to read GeoJSON", "error", err, "file_path", fileName)
+
+			exitcode = 1
+
+			return
+		}
+
+		err := storage.AddSource(insideout.SourceInfos{
+			Filename: fileName,
+			SHA256:   hex.EncodeToString(sum.Sum(nil)),
+			Time:     time.Now(),
+		})
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to store source infos", "error", err)
+
+			exitcode = 1
+
+			return
+		}
+	}
+
+	if !*update {
+		level.Info(logger).Log("msg", "stored index_infos")
+	}
+}
+
+// source reads the features to index, setting their id from idProperty
+// and validating their geometries when report is not nil.
+type source struct {
+	logger   log.Logger
+	features insideout.FeatureIterator
+	index    int
+
+	// mu protects the fields below, skip is called by the indexer while features are read
+	mu     sync.Mutex
+	report *validation.Report
+	// dropped source indexes of the features dropped by the validation, in order
+	dropped []int
+	// skipped count of features not indexed
+	skipped int
+}
+
+func (src *source) Next() (*geojson.Feature, error) {
+	for {
+		f, err := src.features.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		i := src.index
+		src.index++
+
+		if *idProperty != "" {
+			id, ok := insideout.PropertyID(f.Properties[*idProperty])
+			if !ok {
+				level.Warn(src.logger).Log("msg", "feature without a valid id property", "index", i, "id_property", *idProperty)
+			}
+
+			f.ID = id
+		}
+
+		if src.report == nil {
+			return f, nil
+		}
+
+		rf, issues := validation.Repair(f)
+
+		src.mu.Lock()
+		src.report.Add(i, f, rf == nil, issues)
+
+		if rf == nil {
+			src.dropped = append(src.dropped, i)
+		}
+
+		src.mu.Unlock()
+
+		if len(issues) > 0 {
+			level.Debug(src.logger).Log("msg", "geometry issues found", "index", i, "issues", fmt.Sprintf("%+v", issues))
+		}
+
+		if rf != nil {
+			return rf, nil
+		}
+	}
+}
+
+// skip records the feature f at position seq of the features returned by Next,
+// that could not be indexed because of err.
+func (src *source) skip(seq int, f *geojson.Feature, err error) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	src.skipped++
+
+	if src.report == nil {
+		return
+	}
+
+	// the source index also counts the features dropped before it
+	index := seq
+
+	for _, d := range src.dropped {
+		if d > index {
+			break
+		}
+
+		index++
+	}
+
+	src.report.AddSkipped(index, f, err)
+}
+
+// openGeoJSON opens the GeoJSON or gziped GeoJSON file at filePath, "-" for stdin,
+// the file content read is also written to sum.
+func openGeoJSON(filePath string, sum io.Writer) (io.Reader, func() error, error) {
+	r := bufio.NewReader(io.TeeReader(os.Stdin, sum))
+	closeFile := func() error { return nil }
+
+	if filePath != "-" {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		r = bufio.NewReader(io.TeeReader(file, sum))
+		closeFile = file.Close
+	}
+
+	// read 2 bytes
+	testBytes, err := r.Peek(2)
+	if err != nil {
+		closeFile()
+
+		return nil, nil, fmt.Errorf("failed to read GeoJSON: %w", err)
+	}
+
+	// found gzip
+	if testBytes[0] == 31 && testBytes[1] == 139 {
+		gzipReader, err := gzip.NewReader(r)
+		if err != nil {
+			closeFile()
+
+			return nil, nil, fmt.Errorf("failed to open gziped GeoJSON: %w", err)
+		}
+
+		return bufio.NewReader(gzipReader), closeFile, nil
+	}
+
+	return r, closeFile, nil
+}
+
+func writeReport(report *validation.Report, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := report.Write(f); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+
+	"github.com/akhenakh/insideout"
+)
+
+// updateIndex adds or replaces the features by their id, then deletes the ids listed in deletePath.
+func updateIndex(logger log.Logger, storage insideout.Updater, features insideout.FeatureIterator,
+	icoverer, ocoverer *s2.RegionCoverer) error {
+	var putCount, skipCount, deleteCount int
+
+	for i := 0; ; i++ {
+		f, err := features.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("can't read feature: %w", err)
+		}
+
+		if f.ID == "" {
+			level.Warn(logger).Log("msg", "feature without id can't be updated, skipping", "index", i)
+
+			skipCount++
+
+			continue
+		}
+
+		if _, err := storage.PutFeature(f.ID, f, icoverer, ocoverer, *warningCellsCover); err != nil {
+			return err
+		}
+
+		putCount++
+	}
+
+	if *deletePath != "" {
+		ids, err := readIDs(*deletePath)
+		if err != nil {
+			return fmt.Errorf("can't read ids to delete: %w", err)
+		}
+
+		for _, id := range ids {
+			err := storage.DeleteFeature(id)
+			if errors.Is(err, insideout.ErrFeatureNotFound) {
+				level.Warn(logger).Log("msg", "feature to delete not found", "feature_id", id)
+
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			deleteCount++
+		}
+	}
+
+	level.Info(logger).Log(
+		"msg", "updated index",
+		"put_count", putCount,
+		"skipped_count", skipCount,
+		"deleted_count", deleteCount,
+	)
+
+	return nil
+}
+
+// readIDs reads one id per line, ignoring blank lines.
+func readIDs(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ids []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, scanner.Err()
+}
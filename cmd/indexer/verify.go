@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	sbbolt "github.com/akhenakh/insideout/storage/bbolt"
+)
+
+// verifyIndex checks the index at dbPath, the JSON report is written to reportPath if set.
+func verifyIndex(logger log.Logger, dbPath string) error {
+	r, err := sbbolt.Check(dbPath, sbbolt.CheckOptions{
+		SampleFeatures: *verifySamples,
+		SamplePoints:   10,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range r.Issues {
+		level.Warn(logger).Log("msg", "index issue", "issue", issue)
+	}
+
+	level.Info(logger).Log(
+		"msg", "checked index",
+		"feature_count", r.FeatureCount,
+		"inside_cell_count", r.InsideCellCount,
+		"outside_cell_count", r.OutsideCellCount,
+		"issue_count", r.IssueCount,
+		"sampled_points", r.SampledPoints,
+		"false_negatives", r.FalseNegatives,
+		"skipped_polygons", r.SkippedPolygons,
+		"sampled_inside_cells", r.SampledInsideCells,
+		"inside_false_positives", r.InsideFalsePositives,
+	)
+
+	if *reportPath != "" {
+		f, err := os.Create(*reportPath)
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+
+			return err
+		}
+
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	if !r.OK() {
+		return errors.New("invalid index")
+	}
+
+	return nil
+}
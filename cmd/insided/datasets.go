@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/go-kit/kit/log"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/server"
+	sstorage "github.com/akhenakh/insideout/storage"
+)
+
+// datasetConfig a dataset served by insided.
+type datasetConfig struct {
+	name     string
+	dbPath   string
+	dbEngine string
+	opts     server.DatasetOptions
+}
+
+// flagsDataset returns the dataset configured by the flags.
+func flagsDataset() datasetConfig {
+	return datasetConfig{
+		name:     *datasetName,
+		dbPath:   *dbPath,
+		dbEngine: *dbEngine,
+		opts: server.DatasetOptions{
+			StopOnFirstFound: *stopOnFirstFound,
+			CacheCount:       *cacheCount,
+			Strategy:         *strategy,
+			DBURL:            *dbURL,
+		},
+	}
+}
+
+// parseDatasets parses datasets like name:dbPath=path,strategy=shapeindex separated by ;,
+// options not set default to the flags values.
+func parseDatasets(s string) ([]datasetConfig, error) {
+	var cfgs []datasetConfig
+
+	seen := map[string]struct{}{*datasetName: {}}
+
+	for _, spec := range strings.Split(s, ";") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		i := strings.Index(spec, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("missing dataset name in %q", spec)
+		}
+
+		cfg := flagsDataset()
+		cfg.name = spec[:i]
+		cfg.dbPath = ""
+
+		if _, ok := seen[cfg.name]; ok {
+			return nil, fmt.Errorf("duplicate dataset %q", cfg.name)
+		}
+
+		seen[cfg.name] = struct{}{}
+
+		for _, kv := range strings.Split(spec[i+1:], ",") {
+			kvs := strings.SplitN(kv, "=", 2)
+			if len(kvs) != 2 {
+				return nil, fmt.Errorf("invalid option %q for dataset %q", kv, cfg.name)
+			}
+
+			k, v := strings.TrimSpace(kvs[0]), strings.TrimSpace(kvs[1])
+
+			var err error
+
+			switch k {
+			case "dbPath":
+				cfg.dbPath = v
+			case "dbEngine":
+				cfg.dbEngine = v
+			case "strategy":
+				cfg.opts.Strategy = v
+			case "cacheCount":
+				cfg.opts.CacheCount, err = strconv.Atoi(v)
+			case "stopOnFirstFound":
+				cfg.opts.StopOnFirstFound, err = strconv.ParseBool(v)
+			case "dbURL":
+				cfg.opts.DBURL = v
+			default:
+				return nil, fmt.Errorf("unknown option %q for dataset %q", k, cfg.name)
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid option %q for dataset %q: %w", k, cfg.name, err)
+			}
+		}
+
+		if cfg.dbPath == "" {
+			return nil, fmt.Errorf("missing dbPath for dataset %q", cfg.name)
+		}
+
+		if !validStrategy(cfg.opts.Strategy) {
+			return nil, fmt.Errorf("unknown strategy %q for dataset %q", cfg.opts.Strategy, cfg.name)
+		}
+
+		cfgs = append(cfgs, cfg)
+	}
+
+	return cfgs, nil
+}
+
+func validStrategy(s string) bool {
+	switch s {
+	case insideout.InsideTreeStrategy, insideout.DBStrategy, insideout.ShapeIndexStrategy, insideout.PostgisIndexStrategy:
+		return true
+	default:
+		return false
+	}
+}
+
+// openDataset opens the dataset storage, the file info is read before opening
+// so a file replaced meanwhile is seen as changed, it is returned when the storage fails to open.
+func openDataset(logger log.Logger, cfg datasetConfig) (insideout.Store, func() error, os.FileInfo, error) {
+	fi, err := os.Stat(cfg.dbPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	storage, clean, err := sstorage.NewROStorage(cfg.dbEngine, cfg.dbPath, logger)
+	if err != nil {
+		return nil, nil, fi, err
+	}
+
+	return storage, clean, fi, nil
+}
@@ -36,10 +36,11 @@ import (
 
 	"github.com/akhenakh/insideout"
 	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
+	"github.com/akhenakh/insideout/geofence"
 	"github.com/akhenakh/insideout/loglevel"
 	"github.com/akhenakh/insideout/server"
 	"github.com/akhenakh/insideout/server/debug"
-	"github.com/akhenakh/insideout/storage/bbolt"
+	sstorage "github.com/akhenakh/insideout/storage"
 )
 
 const appName = "insided"
@@ -50,6 +51,9 @@ var (
 	logLevel        = flag.String("logLevel", "INFO", "DEBUG|INFO|WARN|ERROR")
 	cacheCount      = flag.Int("cacheCount", 200, "Features count to cache, 0 to disable the cache")
 	dbPath          = flag.String("dbPath", "inside.db", "Database path")
+	dbEngine        = flag.String("dbEngine", sstorage.BBolt, "Database engine: bbolt|leveldb|mmap")
+	datasetName     = flag.String("datasetName", server.DefaultDataset, "Name of the default dataset at dbPath")
+	datasets        = flag.String("datasets", "", "Other datasets like name:dbPath=path,strategy=shapeindex separated by ;")
 	httpMetricsPort = flag.Int("httpMetricsPort", 8088, "http port")
 	httpAPIPort     = flag.Int("httpAPIPort", 8080, "http API port")
 	grpcPort        = flag.Int("grpcPort", 9200, "gRPC API port")
@@ -58,7 +62,14 @@ var (
 	dbURL = flag.String("dbURL", "localhost", "database URL use with postgis index only")
 
 	stopOnFirstFound = flag.Bool("stopOnFirstFound", false, "Stop in first feature found")
+	dwellTime        = flag.Duration("dwellTime", 5*time.Minute, "Time inside a feature before a geofence dwell event, 0 to disable")
 	strategy         = flag.String("strategy", insideout.DBStrategy, "Strategy to use: insidetree|shapeindex|db|postgis")
+	order            = flag.String("order", "property", "Default order of the features containing a point: property|area|insertion")
+	orderProperty    = flag.String("orderProperty", server.DefaultOrderProperty, "Numeric property used by the property order")
+	watchInterval    = flag.Duration("watchInterval", 0, "Interval to check dbPath for changes and reload it, 0 to disable")
+
+	geofenceIdleTTL    = flag.Duration("geofenceIdleTTL", geofence.DefaultIdleTTL, "Time a device state is kept without updates")
+	maxNearestDistance = flag.Float64("maxNearestDistance", server.DefaultMaxNearestDistance, "Largest distance in meters searched by nearest and within radius requests")
 
 	httpServer        *http.Server
 	grpcHealthServer  *grpc.Server
@@ -79,9 +90,7 @@ func main() {
 
 	stdlog.SetOutput(log.NewStdlibAdapter(logger))
 
-	switch *strategy {
-	case insideout.InsideTreeStrategy, insideout.DBStrategy, insideout.ShapeIndexStrategy, insideout.PostgisIndexStrategy:
-	default:
+	if !validStrategy(*strategy) {
 		level.Error(logger).Log("msg", "unknown strategy", "strategy", *strategy)
 
 		exitcode = 1
@@ -89,6 +98,24 @@ func main() {
 		return
 	}
 
+	cfgs, err := parseDatasets(*datasets)
+	if err != nil {
+		level.Error(logger).Log("msg", "invalid datasets", "error", err)
+
+		exitcode = 1
+
+		return
+	}
+
+	defaultOrder, err := server.ParseOrder(*order)
+	if err != nil {
+		level.Error(logger).Log("msg", "invalid order", "error", err)
+
+		exitcode = 1
+
+		return
+	}
+
 	level.Info(logger).Log("msg", "Starting app", "version", version)
 
 	ctx := context.Background()
@@ -107,20 +134,11 @@ func main() {
 	// 	stdlog.Println(http.ListenAndServe("localhost:6060", nil))
 	// }()
 
-	storage, clean, err := bbolt.NewROStorage(*dbPath, logger)
-	if err != nil {
-		level.Error(logger).Log("msg", "failed to open storage", "error", err, "db_path", *dbPath)
+	defaultCfg := flagsDataset()
 
-		exitcode = 1
-
-		return
-	}
-
-	defer clean()
-
-	infos, err := storage.LoadIndexInfos()
+	storage, clean, loaded, err := openDataset(logger, defaultCfg)
 	if err != nil {
-		level.Error(logger).Log("msg", "failed to read infos", "error", err)
+		level.Error(logger).Log("msg", "failed to open storage", "error", err, "db_path", *dbPath, "db_engine", *dbEngine)
 
 		exitcode = 1
 
@@ -153,15 +171,60 @@ func main() {
 			CacheCount:       *cacheCount,
 			Strategy:         *strategy,
 			DBURL:            *dbURL,
+			DwellTime:        *dwellTime,
+			GeofenceIdleTTL:  *geofenceIdleTTL,
+			Order:            defaultOrder,
+			OrderProperty:    *orderProperty,
+			StorageClose:     clean,
+			Dataset:          defaultCfg.name,
+
+			MaxNearestDistance: *maxNearestDistance,
 		})
 	if err != nil {
 		level.Error(logger).Log("msg", "can't get a working server", "error", err)
 
+		clean()
+
 		exitcode = 1
 
 		return
 	}
 
+	defer server.Close()
+
+	rls := reloaders{newReloader(logger, server, defaultCfg, loaded)}
+
+	for _, cfg := range cfgs {
+		storage, clean, loaded, err := openDataset(logger, cfg)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to open storage", "error", err,
+				"dataset", cfg.name, "db_path", cfg.dbPath, "db_engine", cfg.dbEngine)
+
+			exitcode = 1
+
+			return
+		}
+
+		cfg.opts.StorageClose = clean
+
+		if err := server.AddDataset(ctx, cfg.name, storage, cfg.opts); err != nil {
+			level.Error(logger).Log("msg", "can't add dataset", "error", err, "dataset", cfg.name)
+
+			clean()
+
+			exitcode = 1
+
+			return
+		}
+
+		rls = append(rls, newReloader(logger, server, cfg, loaded))
+	}
+
+	// datasets reload on SIGHUP, dbPath changes or POST /admin/reload
+	g.Go(func() error {
+		return rls.watch(ctx, *watchInterval)
+	})
+
 	// web server metrics
 	g.Go(func() error {
 		httpMetricsServer = &http.Server{
@@ -172,13 +235,17 @@ func main() {
 		level.Info(logger).Log("msg", fmt.Sprintf("HTTP Metrics server listening at :%d", *httpMetricsPort))
 
 		versionGauge.WithLabelValues(version).Add(1)
-		dataVersionGauge.WithLabelValues(
-			fmt.Sprintf("%s %s", infos.Filename, infos.IndexTime.Format(time.RFC3339)),
-		).Add(1)
+
+		for name, infos := range server.Datasets() {
+			setDataVersion(name, nil, infos)
+		}
 
 		// Register Prometheus metrics handler.
 		http.Handle("/metrics", promhttp.Handler())
 
+		// admin reload handler, on the metrics port not exposed publicly
+		http.Handle("/admin/reload", rls)
+
 		if err := httpMetricsServer.ListenAndServe(); errors.Is(err, http.ErrServerClosed) {
 			return err
 		}
@@ -235,6 +302,50 @@ func main() {
 			handlers.CompressHandler(metricsMwr.Handler("/api/within/lat/lng",
 				http.HandlerFunc(server.WithinHandler))))
 
+		// within API handler on named datasets separated by commas,
+		// the other handlers take one dataset
+		r.Handle("/api/{dataset}/within/{lat}/{lng}",
+			handlers.CompressHandler(metricsMwr.Handler("/api/dataset/within/lat/lng",
+				http.HandlerFunc(server.WithinHandler))))
+
+		// nearest API handler
+		r.Handle("/api/nearest/{lat}/{lng}",
+			handlers.CompressHandler(metricsMwr.Handler("/api/nearest/lat/lng",
+				http.HandlerFunc(server.NearestHandler))))
+		r.Handle("/api/{dataset}/nearest/{lat}/{lng}",
+			handlers.CompressHandler(metricsMwr.Handler("/api/dataset/nearest/lat/lng",
+				http.HandlerFunc(server.NearestHandler))))
+
+		// within radius API handler
+		r.Handle("/api/radius/{lat}/{lng}/{radius}",
+			handlers.CompressHandler(metricsMwr.Handler("/api/radius/lat/lng/radius",
+				http.HandlerFunc(server.WithinRadiusHandler))))
+		r.Handle("/api/{dataset}/radius/{lat}/{lng}/{radius}",
+			handlers.CompressHandler(metricsMwr.Handler("/api/dataset/radius/lat/lng/radius",
+				http.HandlerFunc(server.WithinRadiusHandler))))
+
+		// intersects API handlers
+		r.Handle("/api/intersects/{min_lat}/{min_lng}/{max_lat}/{max_lng}",
+			handlers.CompressHandler(metricsMwr.Handler("/api/intersects/bbox",
+				http.HandlerFunc(server.IntersectsBBoxHandler))))
+		r.Handle("/api/intersects",
+			handlers.CompressHandler(metricsMwr.Handler("/api/intersects",
+				http.HandlerFunc(server.IntersectsHandler)))).Methods(http.MethodPost)
+		r.Handle("/api/{dataset}/intersects/{min_lat}/{min_lng}/{max_lat}/{max_lng}",
+			handlers.CompressHandler(metricsMwr.Handler("/api/dataset/intersects/bbox",
+				http.HandlerFunc(server.IntersectsBBoxHandler))))
+		r.Handle("/api/{dataset}/intersects",
+			handlers.CompressHandler(metricsMwr.Handler("/api/dataset/intersects",
+				http.HandlerFunc(server.IntersectsHandler)))).Methods(http.MethodPost)
+
+		// trajectory API handler
+		r.Handle("/api/trajectory",
+			handlers.CompressHandler(metricsMwr.Handler("/api/trajectory",
+				http.HandlerFunc(server.TrajectoryHandler)))).Methods(http.MethodPost)
+		r.Handle("/api/{dataset}/trajectory",
+			handlers.CompressHandler(metricsMwr.Handler("/api/dataset/trajectory",
+				http.HandlerFunc(server.TrajectoryHandler)))).Methods(http.MethodPost)
+
 		r.HandleFunc("/healthz", func(w http.ResponseWriter, request *http.Request) {
 			w.Header().Set("Content-Type", "application/json")
 
@@ -261,7 +372,11 @@ func main() {
 
 		r.HandleFunc("/version", func(w http.ResponseWriter, request *http.Request) {
 			w.Header().Set("Content-Type", "application/json")
-			m := map[string]interface{}{"version": version, "infos": infos}
+			m := map[string]interface{}{
+				"version":  version,
+				"infos":    server.IndexInfos(""),
+				"datasets": server.Datasets(),
+			}
 			b, _ := json.Marshal(m)
 			w.Write(b)
 		})
@@ -281,7 +396,15 @@ func main() {
 		return nil
 	})
 
-	level.Info(logger).Log("msg", "read index_infos", "feature_count", infos.FeatureCount)
+	for name, infos := range server.Datasets() {
+		level.Info(logger).Log("msg", "read index_infos", "dataset", name, "feature_count", infos.FeatureCount,
+			"schema_version", infos.SchemaVersion)
+
+		if infos.SchemaVersion == 0 {
+			level.Warn(logger).Log("msg", "DB indexed before schema versioning, reindex it to get the index parameters",
+				"dataset", name)
+		}
+	}
 
 	// TODO: perform a query first for shapeindex to be ready
 
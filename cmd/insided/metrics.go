@@ -1,8 +1,13 @@
 package main
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/akhenakh/insideout"
 )
 
 var (
@@ -16,5 +21,18 @@ var (
 		Namespace: "insided",
 		Name:      "dataset_version",
 		Help:      "Dataset version.",
-	}, []string{"version"})
+	}, []string{"dataset", "version"})
 )
+
+// setDataVersion sets the version label of the dataset name from infos, replacing the one from prev if any.
+func setDataVersion(name string, prev, infos *insideout.IndexInfos) {
+	if prev != nil {
+		dataVersionGauge.DeleteLabelValues(name, dataVersion(prev))
+	}
+
+	dataVersionGauge.WithLabelValues(name, dataVersion(infos)).Set(1)
+}
+
+func dataVersion(infos *insideout.IndexInfos) string {
+	return fmt.Sprintf("%s %s", infos.Filename, infos.IndexTime.Format(time.RFC3339))
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/server"
+)
+
+// reloader reopens a dataset and swaps it in the server, reloads are serialized.
+type reloader struct {
+	mu     sync.Mutex
+	logger log.Logger
+	server *server.Server
+	cfg    datasetConfig
+
+	// loaded is the dbPath file info when last loaded
+	loaded os.FileInfo
+}
+
+func newReloader(logger log.Logger, s *server.Server, cfg datasetConfig, loaded os.FileInfo) *reloader {
+	return &reloader{
+		logger: log.With(logger, "dataset", cfg.name, "db_path", cfg.dbPath),
+		server: s,
+		cfg:    cfg,
+		loaded: loaded,
+	}
+}
+
+// reload opens dbPath, builds its index while the current dataset is served then swaps them.
+func (r *reloader) reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	start := time.Now()
+
+	storage, clean, fi, err := openDataset(r.logger, r.cfg)
+	if fi != nil {
+		// a failing file is not retried until modified again
+		r.loaded = fi
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+
+	prev := r.server.IndexInfos(r.cfg.name)
+
+	if err := r.server.Reload(ctx, r.cfg.name, storage, clean); err != nil {
+		clean()
+
+		return err
+	}
+
+	infos := r.server.IndexInfos(r.cfg.name)
+	setDataVersion(r.cfg.name, prev, infos)
+
+	level.Info(r.logger).Log("msg", "reloaded dataset",
+		"feature_count", infos.FeatureCount,
+		"schema_version", infos.SchemaVersion,
+		"duration", time.Since(start))
+
+	return nil
+}
+
+// changed reports whether dbPath was replaced or modified since the last load.
+func (r *reloader) changed() (bool, error) {
+	fi, err := os.Stat(r.cfg.dbPath)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.loaded == nil {
+		return true, nil
+	}
+
+	return !os.SameFile(fi, r.loaded) || !fi.ModTime().Equal(r.loaded.ModTime()) || fi.Size() != r.loaded.Size(), nil
+}
+
+// reloaders the reloaders of every dataset.
+type reloaders []*reloader
+
+// watch reloads every dataset on SIGHUP and the ones whose dbPath changed, checked every interval,
+// 0 disables the checks.
+func (rs reloaders) watch(ctx context.Context, interval time.Duration) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			for _, r := range rs {
+				level.Info(r.logger).Log("msg", "received SIGHUP, reloading dataset")
+
+				if err := r.reload(ctx); err != nil {
+					level.Error(r.logger).Log("msg", "failed to reload dataset", "error", err)
+				}
+			}
+		case <-tick:
+			for _, r := range rs {
+				changed, err := r.changed()
+				if err != nil {
+					level.Warn(r.logger).Log("msg", "can't check db path", "error", err)
+
+					continue
+				}
+
+				if !changed {
+					continue
+				}
+
+				level.Info(r.logger).Log("msg", "db path changed, reloading dataset")
+
+				if err := r.reload(ctx); err != nil {
+					level.Error(r.logger).Log("msg", "failed to reload dataset", "error", err)
+				}
+			}
+		}
+	}
+}
+
+// ServeHTTP reloads on POST the dataset named by the dataset query parameter, all of them when missing,
+// and returns their infos.
+func (rs reloaders) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	name := req.URL.Query().Get("dataset")
+	infos := make(map[string]*insideout.IndexInfos)
+
+	for _, r := range rs {
+		if name != "" && r.cfg.name != name {
+			continue
+		}
+
+		if err := r.reload(req.Context()); err != nil {
+			level.Error(r.logger).Log("msg", "failed to reload dataset", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		infos[r.cfg.name] = r.server.IndexInfos(r.cfg.name)
+	}
+
+	if len(infos) == 0 {
+		http.Error(w, fmt.Sprintf("unknown dataset %q", name), http.StatusNotFound)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	b, _ := json.Marshal(map[string]interface{}{"datasets": infos})
+	w.Write(b)
+}
@@ -7,6 +7,7 @@ import (
 	"math/rand"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
@@ -19,6 +20,7 @@ import (
 	"github.com/rcrowley/go-metrics"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/balancer/roundrobin"
+	"google.golang.org/grpc/codes"
 
 	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
 	"github.com/akhenakh/insideout/loglevel"
@@ -26,6 +28,9 @@ import (
 
 const appName = "loadtester"
 
+// streamWindow is the max count of stream requests waiting for a response.
+const streamWindow = 64
+
 var (
 	logLevel      = flag.String("logLevel", "INFO", "DEBUG|INFO|WARN|ERROR")
 	testDuration  = flag.Duration("testDuration", 0, "performs the test for duration, 0 = infinite")
@@ -36,6 +41,7 @@ var (
 	lngMax        = flag.Float64("lngMax", 5.5, "Lng max")
 	removeGeo     = flag.Bool("removeGeometry", true, "do not return geometry in response")
 	removeFeature = flag.Bool("removeFeature", true, "do not return feature in response")
+	stream        = flag.Bool("stream", false, "use the WithinStream bidi streaming API")
 )
 
 func main() {
@@ -92,6 +98,20 @@ func main() {
 
 		tm := metrics.NewTimer()
 
+		if *stream {
+			if err := streamWithin(ctx, c, tm, logger); err != nil {
+				level.Error(logger).Log("msg", "error with stream", "error", err)
+			}
+
+			cancel()
+
+			msg := fmt.Sprintf("count %d rate mean %.0f/s rate1 %.0f/s 99p %.0f\n",
+				tm.Count(), tm.RateMean(), tm.Rate1(), tm.Percentile(99.0))
+			level.Info(logger).Log("msg", msg)
+
+			return
+		}
+
 		req := &insidesvc.WithinRequest{
 			Lat:              *latMin,
 			Lng:              *latMax,
@@ -147,3 +167,72 @@ func main() {
 
 	wg.Wait()
 }
+
+// streamWithin sends random points over a WithinStream until ctx is done,
+// responses are received in the sending order so the sending times are queued.
+func streamWithin(ctx context.Context, c insidesvc.InsideServiceClient, tm metrics.Timer, logger log.Logger) error {
+	stream, err := c.WithinStream(ctx)
+	if err != nil {
+		return err
+	}
+
+	sent := make(chan time.Time, streamWindow)
+
+	go func() {
+		defer close(sent)
+
+		for i := 0; ctx.Err() == nil; i++ {
+			req := &insidesvc.WithinStreamRequest{
+				CorrelationId: strconv.Itoa(i),
+				Request: &insidesvc.WithinRequest{
+					Lat:              *latMin + rand.Float64()*(*latMax-*latMin), // nolint: gosec
+					Lng:              *lngMin + rand.Float64()*(*lngMax-*lngMin), // nolint: gosec
+					RemoveGeometries: *removeGeo,
+					RemoveFeature:    *removeFeature,
+				},
+			}
+
+			sent <- time.Now()
+
+			if err := stream.Send(req); err != nil {
+				return
+			}
+		}
+
+		stream.CloseSend()
+	}()
+
+	for t := range sent {
+		resp, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		tm.UpdateSince(t)
+
+		if resp.Status != nil {
+			level.Warn(logger).Log(
+				"msg", "request failed",
+				"code", codes.Code(resp.Status.Code),
+				"error", resp.Status.Message,
+				"correlation_id", resp.CorrelationId,
+			)
+
+			continue
+		}
+
+		for _, fresp := range resp.Response.Responses {
+			level.Debug(logger).Log(
+				"msg", "found feature",
+				"fid", fresp.Id,
+				"correlation_id", resp.CorrelationId,
+			)
+		}
+	}
+
+	return nil
+}
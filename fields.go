@@ -0,0 +1,47 @@
+package insideout
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// FieldMask selects feature properties by name, an empty mask selects every property.
+// Fields are path.Match patterns, so name:* selects every localized name.
+type FieldMask []string
+
+// NewFieldMask returns a FieldMask from fields, empty fields are ignored.
+// It fails on malformed patterns.
+func NewFieldMask(fields []string) (FieldMask, error) {
+	var m FieldMask
+
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if _, err := path.Match(field, ""); err != nil {
+			return nil, fmt.Errorf("invalid field pattern %q: %w", field, err)
+		}
+
+		m = append(m, field)
+	}
+
+	return m, nil
+}
+
+// Match reports whether the property key is selected by the mask.
+func (m FieldMask) Match(key string) bool {
+	if len(m) == 0 {
+		return true
+	}
+
+	for _, field := range m {
+		if ok, _ := path.Match(field, key); ok {
+			return true
+		}
+	}
+
+	return false
+}
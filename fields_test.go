@@ -0,0 +1,66 @@
+package insideout_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+)
+
+func TestFieldMask_Match(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		fields []string
+		key    string
+		want   bool
+	}{
+		{"empty mask", nil, "name", true},
+		{"exact", []string{"name"}, "name", true},
+		{"exact no match", []string{"name"}, "name:fr", false},
+		{"wildcard", []string{"name:*"}, "name:fr", true},
+		{"wildcard no match", []string{"name:*"}, "name", false},
+		{"several fields", []string{"admin_level", "name:*"}, "admin_level", true},
+		{"blank fields ignored", []string{" ", ""}, "name", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := insideout.NewFieldMask(tt.fields)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, m.Match(tt.key))
+		})
+	}
+}
+
+func TestNewFieldMask_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := insideout.NewFieldMask([]string{"name:["})
+	require.Error(t, err)
+}
+
+func TestPropertiesToValues_Mask(t *testing.T) {
+	t.Parallel()
+
+	f := &insideout.Feature{Properties: map[string]interface{}{
+		"name":        "Paris",
+		"name:fr":     "Paris",
+		"name:en":     "Paris",
+		"admin_level": 8.0,
+	}}
+
+	m, err := insideout.NewFieldMask([]string{"name:*"})
+	require.NoError(t, err)
+
+	values := insideout.PropertiesToValues(f, m)
+	require.Len(t, values, 2)
+	require.Contains(t, values, "name:fr")
+	require.Contains(t, values, "name:en")
+}
@@ -0,0 +1,225 @@
+// Package filter parses and evaluates predicates on features properties.
+//
+// Supported expressions:
+//
+//	admin_level == 8
+//	name:fr != "Paris"
+//	population >= 10000 && population < 50000
+//	type in ["park", "forest"]
+//	!(boundary == "administrative") || admin_level <= 4
+//
+// Values are numbers, double quoted strings, true or false.
+// A comparison on a missing property or on a value of a different type is false, != is its negation.
+// Expressions are matched against the properties of each feature, there is no property index.
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expr a predicate on features properties.
+type Expr interface {
+	// Match reports whether the properties satisfy the predicate.
+	Match(props map[string]interface{}) bool
+	String() string
+}
+
+// Parse returns the Expr for the expression s.
+func Parse(s string) (Expr, error) {
+	p := &parser{lex: newLexer(s)}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+
+	return e, nil
+}
+
+type and struct{ left, right Expr }
+
+func (e and) Match(props map[string]interface{}) bool {
+	return e.left.Match(props) && e.right.Match(props)
+}
+
+func (e and) String() string { return fmt.Sprintf("(%s && %s)", e.left, e.right) }
+
+type or struct{ left, right Expr }
+
+func (e or) Match(props map[string]interface{}) bool {
+	return e.left.Match(props) || e.right.Match(props)
+}
+
+func (e or) String() string { return fmt.Sprintf("(%s || %s)", e.left, e.right) }
+
+type not struct{ e Expr }
+
+func (e not) Match(props map[string]interface{}) bool { return !e.e.Match(props) }
+
+func (e not) String() string { return fmt.Sprintf("!%s", e.e) }
+
+// comparison compares a property to a value.
+type comparison struct {
+	key   string
+	op    string
+	value interface{}
+}
+
+func (e comparison) Match(props map[string]interface{}) bool {
+	if e.op == "!=" {
+		return !equal(props[e.key], e.value)
+	}
+
+	v, ok := props[e.key]
+	if !ok {
+		return false
+	}
+
+	if e.op == "==" {
+		return equal(v, e.value)
+	}
+
+	c, ok := compare(v, e.value)
+	if !ok {
+		return false
+	}
+
+	switch e.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	default:
+		return false
+	}
+}
+
+func (e comparison) String() string {
+	return fmt.Sprintf("%s %s %s", e.key, e.op, formatValue(e.value))
+}
+
+// in tests a property against a list of values.
+type in struct {
+	key    string
+	values []interface{}
+}
+
+func (e in) Match(props map[string]interface{}) bool {
+	v, ok := props[e.key]
+	if !ok {
+		return false
+	}
+
+	for _, value := range e.values {
+		if equal(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e in) String() string {
+	values := make([]string, len(e.values))
+	for i, v := range e.values {
+		values[i] = formatValue(v)
+	}
+
+	return fmt.Sprintf("%s in [%s]", e.key, strings.Join(values, ", "))
+}
+
+// equal reports whether the property value v equals the expression value.
+func equal(v, value interface{}) bool {
+	if v == nil {
+		return false
+	}
+
+	c, ok := compare(v, value)
+
+	return ok && c == 0
+}
+
+// compare compares the property value v to the expression value,
+// ok is false when they are not comparable.
+func compare(v, value interface{}) (int, bool) {
+	switch tv := value.(type) {
+	case float64:
+		f, ok := Number(v)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case f < tv:
+			return -1, true
+		case f > tv:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case string:
+		s, ok := v.(string)
+		if !ok {
+			return 0, false
+		}
+
+		return strings.Compare(s, tv), true
+	case bool:
+		b, ok := v.(bool)
+		if !ok {
+			return 0, false
+		}
+
+		// false is before true
+		switch {
+		case b == tv:
+			return 0, true
+		case tv:
+			return -1, true
+		default:
+			return 1, true
+		}
+	default:
+		return 0, false
+	}
+}
+
+// Number converts numeric properties as decoded from GeoJSON or storage to float64,
+// ok is false when v is not a number.
+func Number(v interface{}) (float64, bool) {
+	switch tv := v.(type) {
+	case float64:
+		return tv, true
+	case float32:
+		return float64(tv), true
+	case int:
+		return float64(tv), true
+	case int64:
+		return float64(tv), true
+	case uint64:
+		return float64(tv), true
+	default:
+		return 0, false
+	}
+}
+
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,131 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout/filter"
+)
+
+func TestParse_Match(t *testing.T) {
+	t.Parallel()
+
+	props := map[string]interface{}{
+		"admin_level": 8.0,
+		"population":  uint64(25000),
+		"name":        "Vannes",
+		"name:fr":     "Vannes",
+		"type":        "park",
+		"capital":     false,
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		{"equal number", "admin_level == 8", true},
+		{"equal number no match", "admin_level == 6", false},
+		{"not equal", "admin_level != 6", true},
+		{"not equal missing property", "boundary != 6", true},
+		{"less", "admin_level < 10", true},
+		{"less or equal", "admin_level <= 8", true},
+		{"greater", "admin_level > 8", false},
+		{"greater or equal integer property", "population >= 10000", true},
+		{"equal string", `name == "Vannes"`, true},
+		{"key with colon", `name:fr == "Vannes"`, true},
+		{"compare string", `name > "Auray"`, true},
+		{"type mismatch", `admin_level == "8"`, false},
+		{"missing property", "boundary == 8", false},
+		{"bool", "capital == false", true},
+		{"in", `type in ["park", "forest"]`, true},
+		{"in no match", `type in ["forest"]`, false},
+		{"in empty", `type in []`, false},
+		{"in mixed", `admin_level in [4, 8]`, true},
+		{"and", `admin_level == 8 && type == "park"`, true},
+		{"and no match", `admin_level == 8 && type == "forest"`, false},
+		{"or", `admin_level == 4 || type == "park"`, true},
+		{"not", `!(type == "park")`, false},
+		{"precedence", `admin_level == 4 && type == "forest" || name == "Vannes"`, true},
+		{"parentheses", `admin_level == 4 && (type == "forest" || name == "Vannes")`, false},
+		{"negative exponent", "admin_level > -1e-3", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			e, err := filter.Parse(tt.expr)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, e.Match(props), e.String())
+		})
+	}
+}
+
+func TestParse_String(t *testing.T) {
+	t.Parallel()
+
+	e, err := filter.Parse(`a == 1 || !(b in ["x", "y"]) && c != true`)
+	require.NoError(t, err)
+	require.Equal(t, `(a == 1 || (!b in ["x", "y"] && c != true))`, e.String())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{
+		"",
+		"admin_level",
+		"admin_level ==",
+		"admin_level = 8",
+		"== 8",
+		`name == "Vannes`,
+		"(admin_level == 8",
+		"admin_level == 8)",
+		"type in [1, 2",
+		"type in 1",
+		"admin_level == 8 &&",
+		"admin_level == 8 & type == 1",
+		"admin_level == 1.2.3",
+		"admin_level == foo",
+		"admin_level == 8 $",
+	}
+
+	for _, expr := range tests {
+		expr := expr
+
+		t.Run(expr, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := filter.Parse(expr)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestNumber(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		v      interface{}
+		want   float64
+		wantOk bool
+	}{
+		{8.5, 8.5, true},
+		{float32(2), 2, true},
+		{8, 8, true},
+		{int64(-3), -3, true},
+		{uint64(4), 4, true},
+		{"8", 0, false},
+		{nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := filter.Number(tt.v)
+		require.Equal(t, tt.wantOk, ok, "%v", tt.v)
+		require.Equal(t, tt.want, got, "%v", tt.v)
+	}
+}
@@ -0,0 +1,387 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+
+	return fmt.Sprintf("%q at %d", t.text, t.pos)
+}
+
+type lexer struct {
+	s   string
+	pos int
+}
+
+func newLexer(s string) *lexer {
+	return &lexer{s: s}
+}
+
+// isKeyRune reports whether r can be part of a property key, like name:fr or addr:post_code.
+func isKeyRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == ':' || r == '.' || r == '-'
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.s) && unicode.IsSpace(rune(l.s[l.pos])) {
+		l.pos++
+	}
+
+	start := l.pos
+	if l.pos >= len(l.s) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	c := l.s[l.pos]
+
+	switch {
+	case c == '(':
+		l.pos++
+
+		return token{tokLParen, "(", start}, nil
+	case c == ')':
+		l.pos++
+
+		return token{tokRParen, ")", start}, nil
+	case c == '[':
+		l.pos++
+
+		return token{tokLBracket, "[", start}, nil
+	case c == ']':
+		l.pos++
+
+		return token{tokRBracket, "]", start}, nil
+	case c == ',':
+		l.pos++
+
+		return token{tokComma, ",", start}, nil
+	case c == '"':
+		return l.lexString()
+	case c == '-' || c == '+' || (c >= '0' && c <= '9'):
+		return l.lexNumber()
+	case strings.ContainsRune("=!<>&|", rune(c)):
+		return l.lexOp()
+	}
+
+	r := []rune(l.s[l.pos:])[0]
+	if !unicode.IsLetter(r) && r != '_' {
+		return token{}, fmt.Errorf("unexpected character %q at %d", r, start)
+	}
+
+	end := l.pos
+
+	for _, r := range l.s[l.pos:] {
+		if !isKeyRune(r) {
+			break
+		}
+
+		end += len(string(r))
+	}
+
+	l.pos = end
+
+	return token{tokIdent, l.s[start:end], start}, nil
+}
+
+func (l *lexer) lexString() (token, error) {
+	start := l.pos
+
+	for i := l.pos + 1; i < len(l.s); i++ {
+		switch l.s[i] {
+		case '\\':
+			i++
+		case '"':
+			text, err := strconv.Unquote(l.s[start : i+1])
+			if err != nil {
+				return token{}, fmt.Errorf("invalid string at %d: %w", start, err)
+			}
+
+			l.pos = i + 1
+
+			return token{tokString, text, start}, nil
+		}
+	}
+
+	return token{}, fmt.Errorf("unterminated string at %d", start)
+}
+
+func (l *lexer) lexNumber() (token, error) {
+	start := l.pos
+	end := l.pos + 1
+
+	for end < len(l.s) && strings.ContainsRune("0123456789.eE+-", rune(l.s[end])) {
+		// a sign is only part of a number after an exponent
+		if (l.s[end] == '+' || l.s[end] == '-') && l.s[end-1] != 'e' && l.s[end-1] != 'E' {
+			break
+		}
+
+		end++
+	}
+
+	if _, err := strconv.ParseFloat(l.s[start:end], 64); err != nil {
+		return token{}, fmt.Errorf("invalid number %q at %d", l.s[start:end], start)
+	}
+
+	l.pos = end
+
+	return token{tokNumber, l.s[start:end], start}, nil
+}
+
+func (l *lexer) lexOp() (token, error) {
+	start := l.pos
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!"} {
+		if strings.HasPrefix(l.s[l.pos:], op) {
+			l.pos += len(op)
+
+			return token{tokOp, op, start}, nil
+		}
+	}
+
+	return token{}, fmt.Errorf("unexpected operator %q at %d", l.s[l.pos], start)
+}
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+func (p *parser) next() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+
+	p.tok = tok
+
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid filter: "+format, args...)
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokOp && p.tok.text == "||" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = or{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokOp && p.tok.text == "&&" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = and{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	switch {
+	case p.tok.kind == tokOp && p.tok.text == "!":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return not{e}, nil
+	case p.tok.kind == tokLParen:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected ) got %s", p.tok)
+		}
+
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		return e, nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	if p.tok.kind != tokIdent {
+		return nil, p.errorf("expected a property got %s", p.tok)
+	}
+
+	key := p.tok.text
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokIdent && p.tok.text == "in" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+
+		return in{key: key, values: values}, nil
+	}
+
+	if p.tok.kind != tokOp || !isComparisonOp(p.tok.text) {
+		return nil, p.errorf("expected a comparison operator got %s", p.tok)
+	}
+
+	op := p.tok.text
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return comparison{key: key, op: op, value: value}, nil
+}
+
+func isComparisonOp(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *parser) parseList() ([]interface{}, error) {
+	if p.tok.kind != tokLBracket {
+		return nil, p.errorf("expected [ got %s", p.tok)
+	}
+
+	var values []interface{}
+
+	for {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+
+		if p.tok.kind == tokRBracket && len(values) == 0 {
+			break
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+
+		if p.tok.kind == tokRBracket {
+			break
+		}
+
+		if p.tok.kind != tokComma {
+			return nil, p.errorf("expected , or ] got %s", p.tok)
+		}
+	}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// parseValue parses a literal and moves to the next token.
+func (p *parser) parseValue() (interface{}, error) {
+	var value interface{}
+
+	switch {
+	case p.tok.kind == tokString:
+		value = p.tok.text
+	case p.tok.kind == tokNumber:
+		// already validated by the lexer
+		f, _ := strconv.ParseFloat(p.tok.text, 64)
+		value = f
+	case p.tok.kind == tokIdent && p.tok.text == "true":
+		value = true
+	case p.tok.kind == tokIdent && p.tok.text == "false":
+		value = false
+	default:
+		return nil, p.errorf("expected a value got %s", p.tok)
+	}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
@@ -10,6 +10,7 @@ import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 )
@@ -21,6 +22,115 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Order of the features containing a point
+type Order int32
+
+const (
+	Order_ORDER_UNSPECIFIED Order = 0
+	// by a numeric property, features without it last
+	Order_ORDER_PROPERTY Order = 1
+	// by polygon area, smallest first
+	Order_ORDER_AREA Order = 2
+	// by the order the features were indexed, by the requested datasets order first
+	Order_ORDER_INSERTION Order = 3
+)
+
+// Enum value maps for Order.
+var (
+	Order_name = map[int32]string{
+		0: "ORDER_UNSPECIFIED",
+		1: "ORDER_PROPERTY",
+		2: "ORDER_AREA",
+		3: "ORDER_INSERTION",
+	}
+	Order_value = map[string]int32{
+		"ORDER_UNSPECIFIED": 0,
+		"ORDER_PROPERTY":    1,
+		"ORDER_AREA":        2,
+		"ORDER_INSERTION":   3,
+	}
+)
+
+func (x Order) Enum() *Order {
+	p := new(Order)
+	*p = x
+	return p
+}
+
+func (x Order) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Order) Descriptor() protoreflect.EnumDescriptor {
+	return file_insidesvc_v1_insidesvc_proto_enumTypes[0].Descriptor()
+}
+
+func (Order) Type() protoreflect.EnumType {
+	return &file_insidesvc_v1_insidesvc_proto_enumTypes[0]
+}
+
+func (x Order) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Order.Descriptor instead.
+func (Order) EnumDescriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{0}
+}
+
+type GeofenceEvent_Type int32
+
+const (
+	GeofenceEvent_TYPE_UNSPECIFIED GeofenceEvent_Type = 0
+	GeofenceEvent_TYPE_ENTER       GeofenceEvent_Type = 1
+	GeofenceEvent_TYPE_EXIT        GeofenceEvent_Type = 2
+	// the device stayed inside the feature for the dwell time
+	GeofenceEvent_TYPE_DWELL GeofenceEvent_Type = 3
+)
+
+// Enum value maps for GeofenceEvent_Type.
+var (
+	GeofenceEvent_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "TYPE_ENTER",
+		2: "TYPE_EXIT",
+		3: "TYPE_DWELL",
+	}
+	GeofenceEvent_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"TYPE_ENTER":       1,
+		"TYPE_EXIT":        2,
+		"TYPE_DWELL":       3,
+	}
+)
+
+func (x GeofenceEvent_Type) Enum() *GeofenceEvent_Type {
+	p := new(GeofenceEvent_Type)
+	*p = x
+	return p
+}
+
+func (x GeofenceEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (GeofenceEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_insidesvc_v1_insidesvc_proto_enumTypes[1].Descriptor()
+}
+
+func (GeofenceEvent_Type) Type() protoreflect.EnumType {
+	return &file_insidesvc_v1_insidesvc_proto_enumTypes[1]
+}
+
+func (x GeofenceEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use GeofenceEvent_Type.Descriptor instead.
+func (GeofenceEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{19, 0}
+}
+
 type Geometry_Type int32
 
 const (
@@ -60,11 +170,11 @@ func (x Geometry_Type) String() string {
 }
 
 func (Geometry_Type) Descriptor() protoreflect.EnumDescriptor {
-	return file_insidesvc_v1_insidesvc_proto_enumTypes[0].Descriptor()
+	return file_insidesvc_v1_insidesvc_proto_enumTypes[2].Descriptor()
 }
 
 func (Geometry_Type) Type() protoreflect.EnumType {
-	return &file_insidesvc_v1_insidesvc_proto_enumTypes[0]
+	return &file_insidesvc_v1_insidesvc_proto_enumTypes[2]
 }
 
 func (x Geometry_Type) Number() protoreflect.EnumNumber {
@@ -73,7 +183,7 @@ func (x Geometry_Type) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use Geometry_Type.Descriptor instead.
 func (Geometry_Type) EnumDescriptor() ([]byte, []int) {
-	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{6, 0}
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{24, 0}
 }
 
 type WithinRequest struct {
@@ -88,6 +198,21 @@ type WithinRequest struct {
 	RemoveGeometries bool `protobuf:"varint,3,opt,name=remove_geometries,json=removeGeometries,proto3" json:"remove_geometries,omitempty"`
 	// remove the whole feature reponse
 	RemoveFeature bool `protobuf:"varint,4,opt,name=remove_feature,json=removeFeature,proto3" json:"remove_feature,omitempty"`
+	// properties to return, all when empty, patterns like name:* are supported
+	Fields []string `protobuf:"bytes,5,rep,name=fields,proto3" json:"fields,omitempty"`
+	// only return features whose properties match the filter expression
+	// like admin_level == 8 or type in ["park", "forest"],
+	// evaluated on the properties of each candidate before its polygons are loaded, there is no property index
+	Filter string `protobuf:"bytes,6,opt,name=filter,proto3" json:"filter,omitempty"`
+	// features ordering, the server default when unspecified
+	Order Order `protobuf:"varint,7,opt,name=order,proto3,enum=insidesvc.v1.Order" json:"order,omitempty"`
+	// numeric property used by ORDER_PROPERTY, the server default when empty
+	OrderProperty string `protobuf:"bytes,8,opt,name=order_property,json=orderProperty,proto3" json:"order_property,omitempty"`
+	// only return the smallest polygon containing the point
+	MostSpecific bool `protobuf:"varint,9,opt,name=most_specific,json=mostSpecific,proto3" json:"most_specific,omitempty"`
+	// datasets to query, the default one when empty,
+	// several datasets are queried concurrently, order and most_specific apply to the features of all of them
+	Datasets []string `protobuf:"bytes,10,rep,name=datasets,proto3" json:"datasets,omitempty"`
 }
 
 func (x *WithinRequest) Reset() {
@@ -150,6 +275,48 @@ func (x *WithinRequest) GetRemoveFeature() bool {
 	return false
 }
 
+func (x *WithinRequest) GetFields() []string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *WithinRequest) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+func (x *WithinRequest) GetOrder() Order {
+	if x != nil {
+		return x.Order
+	}
+	return Order_ORDER_UNSPECIFIED
+}
+
+func (x *WithinRequest) GetOrderProperty() string {
+	if x != nil {
+		return x.OrderProperty
+	}
+	return ""
+}
+
+func (x *WithinRequest) GetMostSpecific() bool {
+	if x != nil {
+		return x.MostSpecific
+	}
+	return false
+}
+
+func (x *WithinRequest) GetDatasets() []string {
+	if x != nil {
+		return x.Datasets
+	}
+	return nil
+}
+
 type WithinResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -205,18 +372,34 @@ func (x *WithinResponse) GetResponses() []*FeatureResponse {
 	return nil
 }
 
-type GetRequest struct {
+type BatchWithinRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id uint32 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	// internally stored as uint16
-	LoopIndex uint32 `protobuf:"varint,2,opt,name=loop_index,json=loopIndex,proto3" json:"loop_index,omitempty"`
-}
-
-func (x *GetRequest) Reset() {
-	*x = GetRequest{}
+	Points []*Point `protobuf:"bytes,1,rep,name=points,proto3" json:"points,omitempty"`
+	// return features geometries or not
+	// saving extra bytes
+	RemoveGeometries bool `protobuf:"varint,2,opt,name=remove_geometries,json=removeGeometries,proto3" json:"remove_geometries,omitempty"`
+	// remove the whole feature reponse
+	RemoveFeature bool `protobuf:"varint,3,opt,name=remove_feature,json=removeFeature,proto3" json:"remove_feature,omitempty"`
+	// properties to return, all when empty, patterns like name:* are supported
+	Fields []string `protobuf:"bytes,4,rep,name=fields,proto3" json:"fields,omitempty"`
+	// only return features whose properties match the filter expression, see WithinRequest
+	Filter string `protobuf:"bytes,5,opt,name=filter,proto3" json:"filter,omitempty"`
+	// features ordering, the server default when unspecified
+	Order Order `protobuf:"varint,6,opt,name=order,proto3,enum=insidesvc.v1.Order" json:"order,omitempty"`
+	// numeric property used by ORDER_PROPERTY, the server default when empty
+	OrderProperty string `protobuf:"bytes,7,opt,name=order_property,json=orderProperty,proto3" json:"order_property,omitempty"`
+	// only return the smallest polygon containing each point
+	MostSpecific bool `protobuf:"varint,8,opt,name=most_specific,json=mostSpecific,proto3" json:"most_specific,omitempty"`
+	// datasets to query, the default one when empty,
+	// order and most_specific apply to the features of all of them
+	Datasets []string `protobuf:"bytes,9,rep,name=datasets,proto3" json:"datasets,omitempty"`
+}
+
+func (x *BatchWithinRequest) Reset() {
+	*x = BatchWithinRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -224,13 +407,13 @@ func (x *GetRequest) Reset() {
 	}
 }
 
-func (x *GetRequest) String() string {
+func (x *BatchWithinRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetRequest) ProtoMessage() {}
+func (*BatchWithinRequest) ProtoMessage() {}
 
-func (x *GetRequest) ProtoReflect() protoreflect.Message {
+func (x *BatchWithinRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -242,36 +425,85 @@ func (x *GetRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
-func (*GetRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use BatchWithinRequest.ProtoReflect.Descriptor instead.
+func (*BatchWithinRequest) Descriptor() ([]byte, []int) {
 	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *GetRequest) GetId() uint32 {
+func (x *BatchWithinRequest) GetPoints() []*Point {
 	if x != nil {
-		return x.Id
+		return x.Points
 	}
-	return 0
+	return nil
 }
 
-func (x *GetRequest) GetLoopIndex() uint32 {
+func (x *BatchWithinRequest) GetRemoveGeometries() bool {
 	if x != nil {
-		return x.LoopIndex
+		return x.RemoveGeometries
 	}
-	return 0
+	return false
 }
 
-type GetResponse struct {
+func (x *BatchWithinRequest) GetRemoveFeature() bool {
+	if x != nil {
+		return x.RemoveFeature
+	}
+	return false
+}
+
+func (x *BatchWithinRequest) GetFields() []string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *BatchWithinRequest) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+func (x *BatchWithinRequest) GetOrder() Order {
+	if x != nil {
+		return x.Order
+	}
+	return Order_ORDER_UNSPECIFIED
+}
+
+func (x *BatchWithinRequest) GetOrderProperty() string {
+	if x != nil {
+		return x.OrderProperty
+	}
+	return ""
+}
+
+func (x *BatchWithinRequest) GetMostSpecific() bool {
+	if x != nil {
+		return x.MostSpecific
+	}
+	return false
+}
+
+func (x *BatchWithinRequest) GetDatasets() []string {
+	if x != nil {
+		return x.Datasets
+	}
+	return nil
+}
+
+type BatchWithinResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id      uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Feature *Feature `protobuf:"bytes,2,opt,name=feature,proto3" json:"feature,omitempty"`
+	// one response per point in the same order
+	Responses []*WithinResponse `protobuf:"bytes,1,rep,name=responses,proto3" json:"responses,omitempty"`
 }
 
-func (x *GetResponse) Reset() {
-	*x = GetResponse{}
+func (x *BatchWithinResponse) Reset() {
+	*x = BatchWithinResponse{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -279,13 +511,13 @@ func (x *GetResponse) Reset() {
 	}
 }
 
-func (x *GetResponse) String() string {
+func (x *BatchWithinResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetResponse) ProtoMessage() {}
+func (*BatchWithinResponse) ProtoMessage() {}
 
-func (x *GetResponse) ProtoReflect() protoreflect.Message {
+func (x *BatchWithinResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -297,37 +529,30 @@ func (x *GetResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
-func (*GetResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use BatchWithinResponse.ProtoReflect.Descriptor instead.
+func (*BatchWithinResponse) Descriptor() ([]byte, []int) {
 	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *GetResponse) GetId() uint32 {
-	if x != nil {
-		return x.Id
-	}
-	return 0
-}
-
-func (x *GetResponse) GetFeature() *Feature {
+func (x *BatchWithinResponse) GetResponses() []*WithinResponse {
 	if x != nil {
-		return x.Feature
+		return x.Responses
 	}
 	return nil
 }
 
-type FeatureResponse struct {
+type WithinStreamRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	// id in the index
-	Id      uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Feature *Feature `protobuf:"bytes,3,opt,name=feature,proto3" json:"feature,omitempty"`
+	// client provided id returned with the response
+	CorrelationId string         `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	Request       *WithinRequest `protobuf:"bytes,2,opt,name=request,proto3" json:"request,omitempty"`
 }
 
-func (x *FeatureResponse) Reset() {
-	*x = FeatureResponse{}
+func (x *WithinStreamRequest) Reset() {
+	*x = WithinStreamRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -335,13 +560,13 @@ func (x *FeatureResponse) Reset() {
 	}
 }
 
-func (x *FeatureResponse) String() string {
+func (x *WithinStreamRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FeatureResponse) ProtoMessage() {}
+func (*WithinStreamRequest) ProtoMessage() {}
 
-func (x *FeatureResponse) ProtoReflect() protoreflect.Message {
+func (x *WithinStreamRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -353,36 +578,39 @@ func (x *FeatureResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FeatureResponse.ProtoReflect.Descriptor instead.
-func (*FeatureResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use WithinStreamRequest.ProtoReflect.Descriptor instead.
+func (*WithinStreamRequest) Descriptor() ([]byte, []int) {
 	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *FeatureResponse) GetId() uint32 {
+func (x *WithinStreamRequest) GetCorrelationId() string {
 	if x != nil {
-		return x.Id
+		return x.CorrelationId
 	}
-	return 0
+	return ""
 }
 
-func (x *FeatureResponse) GetFeature() *Feature {
+func (x *WithinStreamRequest) GetRequest() *WithinRequest {
 	if x != nil {
-		return x.Feature
+		return x.Request
 	}
 	return nil
 }
 
-type Feature struct {
+type WithinStreamResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Geometry   *Geometry                  `protobuf:"bytes,1,opt,name=geometry,proto3" json:"geometry,omitempty"`
-	Properties map[string]*structpb.Value `protobuf:"bytes,2,rep,name=properties,proto3" json:"properties,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	CorrelationId string `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	// missing when the request failed
+	Response *WithinResponse `protobuf:"bytes,2,opt,name=response,proto3" json:"response,omitempty"`
+	// set when the request failed, the stream stays open
+	Status *WithinStreamResponse_Status `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
 }
 
-func (x *Feature) Reset() {
-	*x = Feature{}
+func (x *WithinStreamResponse) Reset() {
+	*x = WithinStreamResponse{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -390,13 +618,13 @@ func (x *Feature) Reset() {
 	}
 }
 
-func (x *Feature) String() string {
+func (x *WithinStreamResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Feature) ProtoMessage() {}
+func (*WithinStreamResponse) ProtoMessage() {}
 
-func (x *Feature) ProtoReflect() protoreflect.Message {
+func (x *WithinStreamResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -408,37 +636,53 @@ func (x *Feature) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Feature.ProtoReflect.Descriptor instead.
-func (*Feature) Descriptor() ([]byte, []int) {
+// Deprecated: Use WithinStreamResponse.ProtoReflect.Descriptor instead.
+func (*WithinStreamResponse) Descriptor() ([]byte, []int) {
 	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *Feature) GetGeometry() *Geometry {
+func (x *WithinStreamResponse) GetCorrelationId() string {
 	if x != nil {
-		return x.Geometry
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *WithinStreamResponse) GetResponse() *WithinResponse {
+	if x != nil {
+		return x.Response
 	}
 	return nil
 }
 
-func (x *Feature) GetProperties() map[string]*structpb.Value {
+func (x *WithinStreamResponse) GetStatus() *WithinStreamResponse_Status {
 	if x != nil {
-		return x.Properties
+		return x.Status
 	}
 	return nil
 }
 
-type Geometry struct {
+type NearestRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Type        Geometry_Type `protobuf:"varint,1,opt,name=type,proto3,enum=insidesvc.v1.Geometry_Type" json:"type,omitempty"`
-	Geometries  []*Geometry   `protobuf:"bytes,2,rep,name=geometries,proto3" json:"geometries,omitempty"`
-	Coordinates []float64     `protobuf:"fixed64,3,rep,packed,name=coordinates,proto3" json:"coordinates,omitempty"`
+	Lat float64 `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng float64 `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	// maximum distance in meters to look for features, defaults to 1000
+	MaxDistance float64 `protobuf:"fixed64,3,opt,name=max_distance,json=maxDistance,proto3" json:"max_distance,omitempty"`
+	// maximum count of features returned, defaults to 1
+	Limit uint32 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	// return features geometries or not
+	RemoveGeometries bool `protobuf:"varint,5,opt,name=remove_geometries,json=removeGeometries,proto3" json:"remove_geometries,omitempty"`
+	// remove the whole feature reponse
+	RemoveFeature bool `protobuf:"varint,6,opt,name=remove_feature,json=removeFeature,proto3" json:"remove_feature,omitempty"`
+	// dataset to query, the default one when empty
+	Dataset string `protobuf:"bytes,7,opt,name=dataset,proto3" json:"dataset,omitempty"`
 }
 
-func (x *Geometry) Reset() {
-	*x = Geometry{}
+func (x *NearestRequest) Reset() {
+	*x = NearestRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -446,13 +690,13 @@ func (x *Geometry) Reset() {
 	}
 }
 
-func (x *Geometry) String() string {
+func (x *NearestRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Geometry) ProtoMessage() {}
+func (*NearestRequest) ProtoMessage() {}
 
-func (x *Geometry) ProtoReflect() protoreflect.Message {
+func (x *NearestRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -464,43 +708,72 @@ func (x *Geometry) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Geometry.ProtoReflect.Descriptor instead.
-func (*Geometry) Descriptor() ([]byte, []int) {
+// Deprecated: Use NearestRequest.ProtoReflect.Descriptor instead.
+func (*NearestRequest) Descriptor() ([]byte, []int) {
 	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *Geometry) GetType() Geometry_Type {
+func (x *NearestRequest) GetLat() float64 {
 	if x != nil {
-		return x.Type
+		return x.Lat
 	}
-	return Geometry_TYPE_UNSPECIFIED
+	return 0
 }
 
-func (x *Geometry) GetGeometries() []*Geometry {
+func (x *NearestRequest) GetLng() float64 {
 	if x != nil {
-		return x.Geometries
+		return x.Lng
 	}
-	return nil
+	return 0
 }
 
-func (x *Geometry) GetCoordinates() []float64 {
+func (x *NearestRequest) GetMaxDistance() float64 {
 	if x != nil {
-		return x.Coordinates
+		return x.MaxDistance
 	}
-	return nil
+	return 0
 }
 
-type Point struct {
+func (x *NearestRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *NearestRequest) GetRemoveGeometries() bool {
+	if x != nil {
+		return x.RemoveGeometries
+	}
+	return false
+}
+
+func (x *NearestRequest) GetRemoveFeature() bool {
+	if x != nil {
+		return x.RemoveFeature
+	}
+	return false
+}
+
+func (x *NearestRequest) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+type NearestResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Lat float64 `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
-	Lng float64 `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	Point *Point `protobuf:"bytes,1,opt,name=point,proto3" json:"point,omitempty"`
+	// ordered by distance, closest first
+	Responses []*NearestFeatureResponse `protobuf:"bytes,2,rep,name=responses,proto3" json:"responses,omitempty"`
 }
 
-func (x *Point) Reset() {
-	*x = Point{}
+func (x *NearestResponse) Reset() {
+	*x = NearestResponse{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -508,13 +781,13 @@ func (x *Point) Reset() {
 	}
 }
 
-func (x *Point) String() string {
+func (x *NearestResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Point) ProtoMessage() {}
+func (*NearestResponse) ProtoMessage() {}
 
-func (x *Point) ProtoReflect() protoreflect.Message {
+func (x *NearestResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -526,12 +799,1344 @@ func (x *Point) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Point.ProtoReflect.Descriptor instead.
-func (*Point) Descriptor() ([]byte, []int) {
+// Deprecated: Use NearestResponse.ProtoReflect.Descriptor instead.
+func (*NearestResponse) Descriptor() ([]byte, []int) {
 	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *Point) GetLat() float64 {
+func (x *NearestResponse) GetPoint() *Point {
+	if x != nil {
+		return x.Point
+	}
+	return nil
+}
+
+func (x *NearestResponse) GetResponses() []*NearestFeatureResponse {
+	if x != nil {
+		return x.Responses
+	}
+	return nil
+}
+
+type NearestFeatureResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// id in the index
+	Id      uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Feature *Feature `protobuf:"bytes,2,opt,name=feature,proto3" json:"feature,omitempty"`
+	// distance in meters to the feature boundary, 0 when the point is inside
+	Distance float64 `protobuf:"fixed64,3,opt,name=distance,proto3" json:"distance,omitempty"`
+	// closest point on the feature boundary, the point itself when inside
+	ClosestPoint *Point `protobuf:"bytes,4,opt,name=closest_point,json=closestPoint,proto3" json:"closest_point,omitempty"`
+}
+
+func (x *NearestFeatureResponse) Reset() {
+	*x = NearestFeatureResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NearestFeatureResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NearestFeatureResponse) ProtoMessage() {}
+
+func (x *NearestFeatureResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NearestFeatureResponse.ProtoReflect.Descriptor instead.
+func (*NearestFeatureResponse) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *NearestFeatureResponse) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *NearestFeatureResponse) GetFeature() *Feature {
+	if x != nil {
+		return x.Feature
+	}
+	return nil
+}
+
+func (x *NearestFeatureResponse) GetDistance() float64 {
+	if x != nil {
+		return x.Distance
+	}
+	return 0
+}
+
+func (x *NearestFeatureResponse) GetClosestPoint() *Point {
+	if x != nil {
+		return x.ClosestPoint
+	}
+	return nil
+}
+
+type WithinRadiusRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Lat float64 `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng float64 `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	// radius in meters
+	Radius float64 `protobuf:"fixed64,3,opt,name=radius,proto3" json:"radius,omitempty"`
+	// return features geometries or not
+	RemoveGeometries bool `protobuf:"varint,4,opt,name=remove_geometries,json=removeGeometries,proto3" json:"remove_geometries,omitempty"`
+	// remove the whole feature reponse
+	RemoveFeature bool `protobuf:"varint,5,opt,name=remove_feature,json=removeFeature,proto3" json:"remove_feature,omitempty"`
+	// dataset to query, the default one when empty
+	Dataset string `protobuf:"bytes,6,opt,name=dataset,proto3" json:"dataset,omitempty"`
+}
+
+func (x *WithinRadiusRequest) Reset() {
+	*x = WithinRadiusRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WithinRadiusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithinRadiusRequest) ProtoMessage() {}
+
+func (x *WithinRadiusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithinRadiusRequest.ProtoReflect.Descriptor instead.
+func (*WithinRadiusRequest) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WithinRadiusRequest) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *WithinRadiusRequest) GetLng() float64 {
+	if x != nil {
+		return x.Lng
+	}
+	return 0
+}
+
+func (x *WithinRadiusRequest) GetRadius() float64 {
+	if x != nil {
+		return x.Radius
+	}
+	return 0
+}
+
+func (x *WithinRadiusRequest) GetRemoveGeometries() bool {
+	if x != nil {
+		return x.RemoveGeometries
+	}
+	return false
+}
+
+func (x *WithinRadiusRequest) GetRemoveFeature() bool {
+	if x != nil {
+		return x.RemoveFeature
+	}
+	return false
+}
+
+func (x *WithinRadiusRequest) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+type WithinRadiusResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Point *Point `protobuf:"bytes,1,opt,name=point,proto3" json:"point,omitempty"`
+	// ordered by distance, closest first
+	Responses []*NearestFeatureResponse `protobuf:"bytes,2,rep,name=responses,proto3" json:"responses,omitempty"`
+}
+
+func (x *WithinRadiusResponse) Reset() {
+	*x = WithinRadiusResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WithinRadiusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithinRadiusResponse) ProtoMessage() {}
+
+func (x *WithinRadiusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithinRadiusResponse.ProtoReflect.Descriptor instead.
+func (*WithinRadiusResponse) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *WithinRadiusResponse) GetPoint() *Point {
+	if x != nil {
+		return x.Point
+	}
+	return nil
+}
+
+func (x *WithinRadiusResponse) GetResponses() []*NearestFeatureResponse {
+	if x != nil {
+		return x.Responses
+	}
+	return nil
+}
+
+type IntersectsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Region:
+	//	*IntersectsRequest_Bbox
+	//	*IntersectsRequest_Polygon
+	Region isIntersectsRequest_Region `protobuf_oneof:"region"`
+	// return features geometries or not
+	RemoveGeometries bool `protobuf:"varint,3,opt,name=remove_geometries,json=removeGeometries,proto3" json:"remove_geometries,omitempty"`
+	// remove the whole feature reponse
+	RemoveFeature bool `protobuf:"varint,4,opt,name=remove_feature,json=removeFeature,proto3" json:"remove_feature,omitempty"`
+	// dataset to query, the default one when empty
+	Dataset string `protobuf:"bytes,5,opt,name=dataset,proto3" json:"dataset,omitempty"`
+}
+
+func (x *IntersectsRequest) Reset() {
+	*x = IntersectsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IntersectsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IntersectsRequest) ProtoMessage() {}
+
+func (x *IntersectsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IntersectsRequest.ProtoReflect.Descriptor instead.
+func (*IntersectsRequest) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{11}
+}
+
+func (m *IntersectsRequest) GetRegion() isIntersectsRequest_Region {
+	if m != nil {
+		return m.Region
+	}
+	return nil
+}
+
+func (x *IntersectsRequest) GetBbox() *BBox {
+	if x, ok := x.GetRegion().(*IntersectsRequest_Bbox); ok {
+		return x.Bbox
+	}
+	return nil
+}
+
+func (x *IntersectsRequest) GetPolygon() *Geometry {
+	if x, ok := x.GetRegion().(*IntersectsRequest_Polygon); ok {
+		return x.Polygon
+	}
+	return nil
+}
+
+func (x *IntersectsRequest) GetRemoveGeometries() bool {
+	if x != nil {
+		return x.RemoveGeometries
+	}
+	return false
+}
+
+func (x *IntersectsRequest) GetRemoveFeature() bool {
+	if x != nil {
+		return x.RemoveFeature
+	}
+	return false
+}
+
+func (x *IntersectsRequest) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+type isIntersectsRequest_Region interface {
+	isIntersectsRequest_Region()
+}
+
+type IntersectsRequest_Bbox struct {
+	Bbox *BBox `protobuf:"bytes,1,opt,name=bbox,proto3,oneof"`
+}
+
+type IntersectsRequest_Polygon struct {
+	// a polygon, holes included
+	Polygon *Geometry `protobuf:"bytes,2,opt,name=polygon,proto3,oneof"`
+}
+
+func (*IntersectsRequest_Bbox) isIntersectsRequest_Region() {}
+
+func (*IntersectsRequest_Polygon) isIntersectsRequest_Region() {}
+
+type IntersectsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Responses []*FeatureResponse `protobuf:"bytes,1,rep,name=responses,proto3" json:"responses,omitempty"`
+}
+
+func (x *IntersectsResponse) Reset() {
+	*x = IntersectsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IntersectsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IntersectsResponse) ProtoMessage() {}
+
+func (x *IntersectsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IntersectsResponse.ProtoReflect.Descriptor instead.
+func (*IntersectsResponse) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *IntersectsResponse) GetResponses() []*FeatureResponse {
+	if x != nil {
+		return x.Responses
+	}
+	return nil
+}
+
+// BBox a lat lng bounding box, min_lng greater than max_lng crosses the antimeridian
+type BBox struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MinLat float64 `protobuf:"fixed64,1,opt,name=min_lat,json=minLat,proto3" json:"min_lat,omitempty"`
+	MinLng float64 `protobuf:"fixed64,2,opt,name=min_lng,json=minLng,proto3" json:"min_lng,omitempty"`
+	MaxLat float64 `protobuf:"fixed64,3,opt,name=max_lat,json=maxLat,proto3" json:"max_lat,omitempty"`
+	MaxLng float64 `protobuf:"fixed64,4,opt,name=max_lng,json=maxLng,proto3" json:"max_lng,omitempty"`
+}
+
+func (x *BBox) Reset() {
+	*x = BBox{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BBox) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BBox) ProtoMessage() {}
+
+func (x *BBox) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BBox.ProtoReflect.Descriptor instead.
+func (*BBox) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *BBox) GetMinLat() float64 {
+	if x != nil {
+		return x.MinLat
+	}
+	return 0
+}
+
+func (x *BBox) GetMinLng() float64 {
+	if x != nil {
+		return x.MinLng
+	}
+	return 0
+}
+
+func (x *BBox) GetMaxLat() float64 {
+	if x != nil {
+		return x.MaxLat
+	}
+	return 0
+}
+
+func (x *BBox) GetMaxLng() float64 {
+	if x != nil {
+		return x.MaxLng
+	}
+	return 0
+}
+
+type TrajectoryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// the path, at least 2 points, times are optional
+	Points []*TrajectoryPoint `protobuf:"bytes,1,rep,name=points,proto3" json:"points,omitempty"`
+	// return features geometries or not
+	RemoveGeometries bool `protobuf:"varint,2,opt,name=remove_geometries,json=removeGeometries,proto3" json:"remove_geometries,omitempty"`
+	// remove the whole feature reponse
+	RemoveFeature bool `protobuf:"varint,3,opt,name=remove_feature,json=removeFeature,proto3" json:"remove_feature,omitempty"`
+	// dataset to query, the default one when empty
+	Dataset string `protobuf:"bytes,4,opt,name=dataset,proto3" json:"dataset,omitempty"`
+}
+
+func (x *TrajectoryRequest) Reset() {
+	*x = TrajectoryRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TrajectoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrajectoryRequest) ProtoMessage() {}
+
+func (x *TrajectoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrajectoryRequest.ProtoReflect.Descriptor instead.
+func (*TrajectoryRequest) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *TrajectoryRequest) GetPoints() []*TrajectoryPoint {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+func (x *TrajectoryRequest) GetRemoveGeometries() bool {
+	if x != nil {
+		return x.RemoveGeometries
+	}
+	return false
+}
+
+func (x *TrajectoryRequest) GetRemoveFeature() bool {
+	if x != nil {
+		return x.RemoveFeature
+	}
+	return false
+}
+
+func (x *TrajectoryRequest) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+type TrajectoryResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// ordered by entry along the path
+	Crossings []*TrajectoryCrossing `protobuf:"bytes,1,rep,name=crossings,proto3" json:"crossings,omitempty"`
+}
+
+func (x *TrajectoryResponse) Reset() {
+	*x = TrajectoryResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TrajectoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrajectoryResponse) ProtoMessage() {}
+
+func (x *TrajectoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrajectoryResponse.ProtoReflect.Descriptor instead.
+func (*TrajectoryResponse) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *TrajectoryResponse) GetCrossings() []*TrajectoryCrossing {
+	if x != nil {
+		return x.Crossings
+	}
+	return nil
+}
+
+type TrajectoryPoint struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Lat  float64                `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng  float64                `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	Time *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+}
+
+func (x *TrajectoryPoint) Reset() {
+	*x = TrajectoryPoint{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TrajectoryPoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrajectoryPoint) ProtoMessage() {}
+
+func (x *TrajectoryPoint) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrajectoryPoint.ProtoReflect.Descriptor instead.
+func (*TrajectoryPoint) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *TrajectoryPoint) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *TrajectoryPoint) GetLng() float64 {
+	if x != nil {
+		return x.Lng
+	}
+	return 0
+}
+
+func (x *TrajectoryPoint) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+// TrajectoryCrossing a continuous part of the path inside a feature,
+// a feature entered several times has several crossings
+type TrajectoryCrossing struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// id in the index
+	Id      uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Feature *Feature `protobuf:"bytes,2,opt,name=feature,proto3" json:"feature,omitempty"`
+	// the point where the path enters the feature, the first point if it starts inside,
+	// time is interpolated along the segment
+	Entry *TrajectoryPoint `protobuf:"bytes,3,opt,name=entry,proto3" json:"entry,omitempty"`
+	// the point where the path exits the feature, missing if it ends inside
+	Exit *TrajectoryPoint `protobuf:"bytes,4,opt,name=exit,proto3" json:"exit,omitempty"`
+}
+
+func (x *TrajectoryCrossing) Reset() {
+	*x = TrajectoryCrossing{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TrajectoryCrossing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrajectoryCrossing) ProtoMessage() {}
+
+func (x *TrajectoryCrossing) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrajectoryCrossing.ProtoReflect.Descriptor instead.
+func (*TrajectoryCrossing) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *TrajectoryCrossing) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *TrajectoryCrossing) GetFeature() *Feature {
+	if x != nil {
+		return x.Feature
+	}
+	return nil
+}
+
+func (x *TrajectoryCrossing) GetEntry() *TrajectoryPoint {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+func (x *TrajectoryCrossing) GetExit() *TrajectoryPoint {
+	if x != nil {
+		return x.Exit
+	}
+	return nil
+}
+
+type GeofenceRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DeviceId string  `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Lat      float64 `protobuf:"fixed64,2,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng      float64 `protobuf:"fixed64,3,opt,name=lng,proto3" json:"lng,omitempty"`
+	// time of the position, defaults to the server time,
+	// positions older than the last one received for the device are ignored
+	Time *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=time,proto3" json:"time,omitempty"`
+	// remove the feature from the events
+	RemoveFeature bool `protobuf:"varint,5,opt,name=remove_feature,json=removeFeature,proto3" json:"remove_feature,omitempty"`
+	// dataset to query, the default one when empty,
+	// memberships are tracked per dataset
+	Dataset string `protobuf:"bytes,6,opt,name=dataset,proto3" json:"dataset,omitempty"`
+}
+
+func (x *GeofenceRequest) Reset() {
+	*x = GeofenceRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GeofenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GeofenceRequest) ProtoMessage() {}
+
+func (x *GeofenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GeofenceRequest.ProtoReflect.Descriptor instead.
+func (*GeofenceRequest) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GeofenceRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *GeofenceRequest) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *GeofenceRequest) GetLng() float64 {
+	if x != nil {
+		return x.Lng
+	}
+	return 0
+}
+
+func (x *GeofenceRequest) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *GeofenceRequest) GetRemoveFeature() bool {
+	if x != nil {
+		return x.RemoveFeature
+	}
+	return false
+}
+
+func (x *GeofenceRequest) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+type GeofenceEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type     GeofenceEvent_Type `protobuf:"varint,1,opt,name=type,proto3,enum=insidesvc.v1.GeofenceEvent_Type" json:"type,omitempty"`
+	DeviceId string             `protobuf:"bytes,2,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	// id in the index
+	Id        uint32 `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
+	LoopIndex uint32 `protobuf:"varint,4,opt,name=loop_index,json=loopIndex,proto3" json:"loop_index,omitempty"`
+	// the position triggering the event
+	Point *Point                 `protobuf:"bytes,5,opt,name=point,proto3" json:"point,omitempty"`
+	Time  *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=time,proto3" json:"time,omitempty"`
+	// time the device entered the feature
+	EnteredAt *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=entered_at,json=enteredAt,proto3" json:"entered_at,omitempty"`
+	// the feature without geometry, missing for exits of features removed since entered
+	Feature *Feature `protobuf:"bytes,8,opt,name=feature,proto3" json:"feature,omitempty"`
+	// stable feature id, empty if the feature has none
+	ExternalId string `protobuf:"bytes,9,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	// dataset of the feature
+	Dataset string `protobuf:"bytes,10,opt,name=dataset,proto3" json:"dataset,omitempty"`
+}
+
+func (x *GeofenceEvent) Reset() {
+	*x = GeofenceEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GeofenceEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GeofenceEvent) ProtoMessage() {}
+
+func (x *GeofenceEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GeofenceEvent.ProtoReflect.Descriptor instead.
+func (*GeofenceEvent) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *GeofenceEvent) GetType() GeofenceEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return GeofenceEvent_TYPE_UNSPECIFIED
+}
+
+func (x *GeofenceEvent) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *GeofenceEvent) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *GeofenceEvent) GetLoopIndex() uint32 {
+	if x != nil {
+		return x.LoopIndex
+	}
+	return 0
+}
+
+func (x *GeofenceEvent) GetPoint() *Point {
+	if x != nil {
+		return x.Point
+	}
+	return nil
+}
+
+func (x *GeofenceEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *GeofenceEvent) GetEnteredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EnteredAt
+	}
+	return nil
+}
+
+func (x *GeofenceEvent) GetFeature() *Feature {
+	if x != nil {
+		return x.Feature
+	}
+	return nil
+}
+
+func (x *GeofenceEvent) GetExternalId() string {
+	if x != nil {
+		return x.ExternalId
+	}
+	return ""
+}
+
+func (x *GeofenceEvent) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+type GetRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id uint32 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// internally stored as uint16
+	LoopIndex uint32 `protobuf:"varint,2,opt,name=loop_index,json=loopIndex,proto3" json:"loop_index,omitempty"`
+	// properties to return, all when empty, patterns like name:* are supported
+	Fields []string `protobuf:"bytes,3,rep,name=fields,proto3" json:"fields,omitempty"`
+	// stable feature id from the GeoJSON id or the indexer id property, takes precedence over id
+	ExternalId string `protobuf:"bytes,4,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	// dataset of the feature, the default one when empty
+	Dataset string `protobuf:"bytes,5,opt,name=dataset,proto3" json:"dataset,omitempty"`
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *GetRequest) GetLoopIndex() uint32 {
+	if x != nil {
+		return x.LoopIndex
+	}
+	return 0
+}
+
+func (x *GetRequest) GetFields() []string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *GetRequest) GetExternalId() string {
+	if x != nil {
+		return x.ExternalId
+	}
+	return ""
+}
+
+func (x *GetRequest) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+type GetResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id      uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Feature *Feature `protobuf:"bytes,2,opt,name=feature,proto3" json:"feature,omitempty"`
+	// stable feature id, empty if the feature has none
+	ExternalId string `protobuf:"bytes,3,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+}
+
+func (x *GetResponse) Reset() {
+	*x = GetResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResponse) ProtoMessage() {}
+
+func (x *GetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
+func (*GetResponse) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetResponse) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *GetResponse) GetFeature() *Feature {
+	if x != nil {
+		return x.Feature
+	}
+	return nil
+}
+
+func (x *GetResponse) GetExternalId() string {
+	if x != nil {
+		return x.ExternalId
+	}
+	return ""
+}
+
+type FeatureResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// id in the index, changes when the dataset is reindexed
+	Id      uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Feature *Feature `protobuf:"bytes,3,opt,name=feature,proto3" json:"feature,omitempty"`
+	// stable feature id from the GeoJSON id or the indexer id property, empty if the feature has none
+	ExternalId string `protobuf:"bytes,4,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	// dataset of the feature
+	Dataset string `protobuf:"bytes,5,opt,name=dataset,proto3" json:"dataset,omitempty"`
+}
+
+func (x *FeatureResponse) Reset() {
+	*x = FeatureResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FeatureResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeatureResponse) ProtoMessage() {}
+
+func (x *FeatureResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeatureResponse.ProtoReflect.Descriptor instead.
+func (*FeatureResponse) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *FeatureResponse) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *FeatureResponse) GetFeature() *Feature {
+	if x != nil {
+		return x.Feature
+	}
+	return nil
+}
+
+func (x *FeatureResponse) GetExternalId() string {
+	if x != nil {
+		return x.ExternalId
+	}
+	return ""
+}
+
+func (x *FeatureResponse) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+type Feature struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Geometry   *Geometry                  `protobuf:"bytes,1,opt,name=geometry,proto3" json:"geometry,omitempty"`
+	Properties map[string]*structpb.Value `protobuf:"bytes,2,rep,name=properties,proto3" json:"properties,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *Feature) Reset() {
+	*x = Feature{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Feature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Feature) ProtoMessage() {}
+
+func (x *Feature) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Feature.ProtoReflect.Descriptor instead.
+func (*Feature) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Feature) GetGeometry() *Geometry {
+	if x != nil {
+		return x.Geometry
+	}
+	return nil
+}
+
+func (x *Feature) GetProperties() map[string]*structpb.Value {
+	if x != nil {
+		return x.Properties
+	}
+	return nil
+}
+
+type Geometry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type        Geometry_Type `protobuf:"varint,1,opt,name=type,proto3,enum=insidesvc.v1.Geometry_Type" json:"type,omitempty"`
+	Geometries  []*Geometry   `protobuf:"bytes,2,rep,name=geometries,proto3" json:"geometries,omitempty"`
+	Coordinates []float64     `protobuf:"fixed64,3,rep,packed,name=coordinates,proto3" json:"coordinates,omitempty"`
+	// ends of each ring in coordinates for polygons, as in flat GeoJSON coordinates,
+	// the first ring is the outer ring, the following are holes
+	Ends []uint32 `protobuf:"varint,4,rep,packed,name=ends,proto3" json:"ends,omitempty"`
+}
+
+func (x *Geometry) Reset() {
+	*x = Geometry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Geometry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Geometry) ProtoMessage() {}
+
+func (x *Geometry) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Geometry.ProtoReflect.Descriptor instead.
+func (*Geometry) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *Geometry) GetType() Geometry_Type {
+	if x != nil {
+		return x.Type
+	}
+	return Geometry_TYPE_UNSPECIFIED
+}
+
+func (x *Geometry) GetGeometries() []*Geometry {
+	if x != nil {
+		return x.Geometries
+	}
+	return nil
+}
+
+func (x *Geometry) GetCoordinates() []float64 {
+	if x != nil {
+		return x.Coordinates
+	}
+	return nil
+}
+
+func (x *Geometry) GetEnds() []uint32 {
+	if x != nil {
+		return x.Ends
+	}
+	return nil
+}
+
+type Point struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Lat float64 `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng float64 `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+}
+
+func (x *Point) Reset() {
+	*x = Point{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Point) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Point) ProtoMessage() {}
+
+func (x *Point) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Point.ProtoReflect.Descriptor instead.
+func (*Point) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *Point) GetLat() float64 {
 	if x != nil {
 		return x.Lat
 	}
@@ -545,6 +2150,62 @@ func (x *Point) GetLng() float64 {
 	return 0
 }
 
+type WithinStreamResponse_Status struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// gRPC status code
+	Code    uint32 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *WithinStreamResponse_Status) Reset() {
+	*x = WithinStreamResponse_Status{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WithinStreamResponse_Status) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithinStreamResponse_Status) ProtoMessage() {}
+
+func (x *WithinStreamResponse_Status) ProtoReflect() protoreflect.Message {
+	mi := &file_insidesvc_v1_insidesvc_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithinStreamResponse_Status.ProtoReflect.Descriptor instead.
+func (*WithinStreamResponse_Status) Descriptor() ([]byte, []int) {
+	return file_insidesvc_v1_insidesvc_proto_rawDescGZIP(), []int{5, 0}
+}
+
+func (x *WithinStreamResponse_Status) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *WithinStreamResponse_Status) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 var File_insidesvc_v1_insidesvc_proto protoreflect.FileDescriptor
 
 var file_insidesvc_v1_insidesvc_proto_rawDesc = []byte{
@@ -552,84 +2213,361 @@ var file_insidesvc_v1_insidesvc_proto_rawDesc = []byte{
 	0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0c,
 	0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x1a, 0x1c, 0x67, 0x6f,
 	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x73, 0x74,
-	0x72, 0x75, 0x63, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x87, 0x01, 0x0a, 0x0d, 0x57,
-	0x69, 0x74, 0x68, 0x69, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03,
-	0x6c, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x61, 0x74, 0x12, 0x10,
-	0x0a, 0x03, 0x6c, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x6e, 0x67,
-	0x12, 0x2b, 0x0a, 0x11, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x67, 0x65, 0x6f, 0x6d, 0x65,
-	0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x72, 0x65, 0x6d,
-	0x6f, 0x76, 0x65, 0x47, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x25, 0x0a,
-	0x0e, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18,
-	0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x46, 0x65, 0x61,
-	0x74, 0x75, 0x72, 0x65, 0x22, 0x78, 0x0a, 0x0e, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x29, 0x0a, 0x05, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76,
-	0x63, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x05, 0x70, 0x6f, 0x69, 0x6e,
-	0x74, 0x12, 0x3b, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73, 0x18, 0x02,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63,
-	0x2e, 0x76, 0x31, 0x2e, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x52, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73, 0x22, 0x3b,
-	0x0a, 0x0a, 0x47, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02,
-	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1d, 0x0a, 0x0a,
-	0x6c, 0x6f, 0x6f, 0x70, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d,
-	0x52, 0x09, 0x6c, 0x6f, 0x6f, 0x70, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x22, 0x4e, 0x0a, 0x0b, 0x47,
-	0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x2f, 0x0a, 0x07, 0x66, 0x65,
-	0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x69, 0x6e,
-	0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x65, 0x61, 0x74, 0x75,
-	0x72, 0x65, 0x52, 0x07, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x22, 0x52, 0x0a, 0x0f, 0x46,
-	0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e,
-	0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x2f,
-	0x0a, 0x07, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x15, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x46,
-	0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x07, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x22,
-	0xdb, 0x01, 0x0a, 0x07, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x32, 0x0a, 0x08, 0x67,
-	0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e,
-	0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6f,
-	0x6d, 0x65, 0x74, 0x72, 0x79, 0x52, 0x08, 0x67, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x79, 0x12,
-	0x45, 0x0a, 0x0a, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x18, 0x02, 0x20,
-	0x03, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e,
-	0x76, 0x31, 0x2e, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x2e, 0x50, 0x72, 0x6f, 0x70, 0x65,
-	0x72, 0x74, 0x69, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0a, 0x70, 0x72, 0x6f, 0x70,
-	0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x1a, 0x55, 0x0a, 0x0f, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72,
-	0x74, 0x69, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x2c, 0x0a, 0x05, 0x76,
-	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x67, 0x6f, 0x6f,
-	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x56, 0x61, 0x6c,
-	0x75, 0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x81, 0x02,
-	0x0a, 0x08, 0x47, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x79, 0x12, 0x2f, 0x0a, 0x04, 0x74, 0x79,
-	0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1b, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64,
+	0x72, 0x75, 0x63, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xca, 0x02, 0x0a, 0x0d,
+	0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a,
+	0x03, 0x6c, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x61, 0x74, 0x12,
+	0x10, 0x0a, 0x03, 0x6c, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x6e,
+	0x67, 0x12, 0x2b, 0x0a, 0x11, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x67, 0x65, 0x6f, 0x6d,
+	0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x72, 0x65,
+	0x6d, 0x6f, 0x76, 0x65, 0x47, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x25,
+	0x0a, 0x0e, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x46, 0x65,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x18,
+	0x05, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x12, 0x16, 0x0a,
+	0x06, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x66,
+	0x69, 0x6c, 0x74, 0x65, 0x72, 0x12, 0x29, 0x0a, 0x05, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63,
+	0x2e, 0x76, 0x31, 0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x52, 0x05, 0x6f, 0x72, 0x64, 0x65, 0x72,
+	0x12, 0x25, 0x0a, 0x0e, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x5f, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72,
+	0x74, 0x79, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x50,
+	0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x12, 0x23, 0x0a, 0x0d, 0x6d, 0x6f, 0x73, 0x74, 0x5f,
+	0x73, 0x70, 0x65, 0x63, 0x69, 0x66, 0x69, 0x63, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c,
+	0x6d, 0x6f, 0x73, 0x74, 0x53, 0x70, 0x65, 0x63, 0x69, 0x66, 0x69, 0x63, 0x12, 0x1a, 0x0a, 0x08,
+	0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08,
+	0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x73, 0x22, 0x78, 0x0a, 0x0e, 0x57, 0x69, 0x74, 0x68,
+	0x69, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x29, 0x0a, 0x05, 0x70, 0x6f,
+	0x69, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x69, 0x6e, 0x73, 0x69,
+	0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x05,
+	0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x3b, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64,
+	0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x73, 0x22, 0xd8, 0x02, 0x0a, 0x12, 0x42, 0x61, 0x74, 0x63, 0x68, 0x57, 0x69, 0x74, 0x68,
+	0x69, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2b, 0x0a, 0x06, 0x70, 0x6f, 0x69,
+	0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x69, 0x6e, 0x73, 0x69,
+	0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x06,
+	0x70, 0x6f, 0x69, 0x6e, 0x74, 0x73, 0x12, 0x2b, 0x0a, 0x11, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65,
+	0x5f, 0x67, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x10, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x47, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72,
+	0x69, 0x65, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x66, 0x65,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x72, 0x65, 0x6d,
+	0x6f, 0x76, 0x65, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x69,
+	0x65, 0x6c, 0x64, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x66, 0x69, 0x65, 0x6c,
+	0x64, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x12, 0x29, 0x0a, 0x05, 0x6f, 0x72,
+	0x64, 0x65, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x69, 0x6e, 0x73, 0x69,
+	0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x52, 0x05,
+	0x6f, 0x72, 0x64, 0x65, 0x72, 0x12, 0x25, 0x0a, 0x0e, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x5f, 0x70,
+	0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6f,
+	0x72, 0x64, 0x65, 0x72, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x12, 0x23, 0x0a, 0x0d,
+	0x6d, 0x6f, 0x73, 0x74, 0x5f, 0x73, 0x70, 0x65, 0x63, 0x69, 0x66, 0x69, 0x63, 0x18, 0x08, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x0c, 0x6d, 0x6f, 0x73, 0x74, 0x53, 0x70, 0x65, 0x63, 0x69, 0x66, 0x69,
+	0x63, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x73, 0x18, 0x09, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x08, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x73, 0x22, 0x51, 0x0a,
+	0x13, 0x42, 0x61, 0x74, 0x63, 0x68, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3a, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65,
+	0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73,
+	0x22, 0x73, 0x0a, 0x13, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x6f, 0x72, 0x72, 0x65,
+	0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0d, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x35,
+	0x0a, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1b, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x57,
+	0x69, 0x74, 0x68, 0x69, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x07, 0x72, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xf2, 0x01, 0x0a, 0x14, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x25,
+	0x0a, 0x0e, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x6c, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x38, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65,
+	0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x41, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x29, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x57,
+	0x69, 0x74, 0x68, 0x69, 0x6e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x1a, 0x36, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x12, 0x0a, 0x04,
+	0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65,
+	0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0xdb, 0x01, 0x0a, 0x0e, 0x4e,
+	0x65, 0x61, 0x72, 0x65, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a,
+	0x03, 0x6c, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x61, 0x74, 0x12,
+	0x10, 0x0a, 0x03, 0x6c, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x6e,
+	0x67, 0x12, 0x21, 0x0a, 0x0c, 0x6d, 0x61, 0x78, 0x5f, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0b, 0x6d, 0x61, 0x78, 0x44, 0x69, 0x73, 0x74,
+	0x61, 0x6e, 0x63, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x2b, 0x0a, 0x11, 0x72, 0x65,
+	0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x67, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x47, 0x65, 0x6f,
+	0x6d, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x65, 0x6d, 0x6f, 0x76,
+	0x65, 0x5f, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x18,
+	0x0a, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x22, 0x80, 0x01, 0x0a, 0x0f, 0x4e, 0x65, 0x61,
+	0x72, 0x65, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x29, 0x0a, 0x05,
+	0x70, 0x6f, 0x69, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x69, 0x6e,
+	0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x69, 0x6e, 0x74,
+	0x52, 0x05, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x42, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x69, 0x6e, 0x73,
+	0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x65, 0x61, 0x72, 0x65, 0x73,
+	0x74, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x52, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73, 0x22, 0xaf, 0x01, 0x0a, 0x16,
+	0x4e, 0x65, 0x61, 0x72, 0x65, 0x73, 0x74, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x2f, 0x0a, 0x07, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65,
+	0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x07,
+	0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x74, 0x61,
+	0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x64, 0x69, 0x73, 0x74, 0x61,
+	0x6e, 0x63, 0x65, 0x12, 0x38, 0x0a, 0x0d, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x73, 0x74, 0x5f, 0x70,
+	0x6f, 0x69, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x69, 0x6e, 0x73,
+	0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x52,
+	0x0c, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x73, 0x74, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x22, 0xbf, 0x01,
+	0x0a, 0x13, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x52, 0x61, 0x64, 0x69, 0x75, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x03, 0x6c, 0x61, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x6e, 0x67, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x6e, 0x67, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x61, 0x64,
+	0x69, 0x75, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x72, 0x61, 0x64, 0x69, 0x75,
+	0x73, 0x12, 0x2b, 0x0a, 0x11, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x67, 0x65, 0x6f, 0x6d,
+	0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x72, 0x65,
+	0x6d, 0x6f, 0x76, 0x65, 0x47, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x25,
+	0x0a, 0x0e, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x46, 0x65,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x22,
+	0x85, 0x01, 0x0a, 0x14, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x52, 0x61, 0x64, 0x69, 0x75, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x29, 0x0a, 0x05, 0x70, 0x6f, 0x69, 0x6e,
+	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65,
+	0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x05, 0x70, 0x6f,
+	0x69, 0x6e, 0x74, 0x12, 0x42, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73,
+	0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x24, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73,
+	0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x65, 0x61, 0x72, 0x65, 0x73, 0x74, 0x46, 0x65, 0x61,
+	0x74, 0x75, 0x72, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x09, 0x72, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73, 0x22, 0xe9, 0x01, 0x0a, 0x11, 0x49, 0x6e, 0x74, 0x65,
+	0x72, 0x73, 0x65, 0x63, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x28, 0x0a,
+	0x04, 0x62, 0x62, 0x6f, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x69, 0x6e,
+	0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x42, 0x6f, 0x78, 0x48,
+	0x00, 0x52, 0x04, 0x62, 0x62, 0x6f, 0x78, 0x12, 0x32, 0x0a, 0x07, 0x70, 0x6f, 0x6c, 0x79, 0x67,
+	0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64,
 	0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x79,
-	0x2e, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x36, 0x0a, 0x0a, 0x67,
-	0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x16, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x47,
-	0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x79, 0x52, 0x0a, 0x67, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72,
-	0x69, 0x65, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6f, 0x72, 0x64, 0x69, 0x6e, 0x61, 0x74,
-	0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x01, 0x52, 0x0b, 0x63, 0x6f, 0x6f, 0x72, 0x64, 0x69,
-	0x6e, 0x61, 0x74, 0x65, 0x73, 0x22, 0x6a, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x14, 0x0a,
-	0x10, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45,
-	0x44, 0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x50, 0x4f, 0x49, 0x4e,
-	0x54, 0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x50, 0x4f, 0x4c, 0x59,
-	0x47, 0x4f, 0x4e, 0x10, 0x02, 0x12, 0x15, 0x0a, 0x11, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x4d, 0x55,
-	0x4c, 0x54, 0x49, 0x50, 0x4f, 0x4c, 0x59, 0x47, 0x4f, 0x4e, 0x10, 0x03, 0x12, 0x13, 0x0a, 0x0f,
-	0x54, 0x59, 0x50, 0x45, 0x5f, 0x4c, 0x49, 0x4e, 0x45, 0x53, 0x54, 0x52, 0x49, 0x4e, 0x47, 0x10,
-	0x04, 0x22, 0x2b, 0x0a, 0x05, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x61,
-	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x61, 0x74, 0x12, 0x10, 0x0a, 0x03,
-	0x6c, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x6e, 0x67, 0x32, 0x94,
-	0x01, 0x0a, 0x0d, 0x49, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
-	0x12, 0x45, 0x0a, 0x06, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x12, 0x1b, 0x2e, 0x69, 0x6e, 0x73,
+	0x48, 0x00, 0x52, 0x07, 0x70, 0x6f, 0x6c, 0x79, 0x67, 0x6f, 0x6e, 0x12, 0x2b, 0x0a, 0x11, 0x72,
+	0x65, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x67, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x47, 0x65,
+	0x6f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x65, 0x6d, 0x6f,
+	0x76, 0x65, 0x5f, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12,
+	0x18, 0x0a, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x42, 0x08, 0x0a, 0x06, 0x72, 0x65, 0x67,
+	0x69, 0x6f, 0x6e, 0x22, 0x51, 0x0a, 0x12, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x65, 0x63, 0x74,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x09, 0x72, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x69,
+	0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x65, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x09, 0x72, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73, 0x22, 0x6a, 0x0a, 0x04, 0x42, 0x42, 0x6f, 0x78, 0x12, 0x17,
+	0x0a, 0x07, 0x6d, 0x69, 0x6e, 0x5f, 0x6c, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x06, 0x6d, 0x69, 0x6e, 0x4c, 0x61, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x69, 0x6e, 0x5f, 0x6c,
+	0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x6d, 0x69, 0x6e, 0x4c, 0x6e, 0x67,
+	0x12, 0x17, 0x0a, 0x07, 0x6d, 0x61, 0x78, 0x5f, 0x6c, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x06, 0x6d, 0x61, 0x78, 0x4c, 0x61, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x61, 0x78,
+	0x5f, 0x6c, 0x6e, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x6d, 0x61, 0x78, 0x4c,
+	0x6e, 0x67, 0x22, 0xb8, 0x01, 0x0a, 0x11, 0x54, 0x72, 0x61, 0x6a, 0x65, 0x63, 0x74, 0x6f, 0x72,
+	0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x35, 0x0a, 0x06, 0x70, 0x6f, 0x69, 0x6e,
+	0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64,
+	0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x61, 0x6a, 0x65, 0x63, 0x74, 0x6f,
+	0x72, 0x79, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x06, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x73, 0x12,
+	0x2b, 0x0a, 0x11, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x67, 0x65, 0x6f, 0x6d, 0x65, 0x74,
+	0x72, 0x69, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x72, 0x65, 0x6d, 0x6f,
+	0x76, 0x65, 0x47, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x25, 0x0a, 0x0e,
+	0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x46, 0x65, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x22, 0x54, 0x0a,
+	0x12, 0x54, 0x72, 0x61, 0x6a, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x3e, 0x0a, 0x09, 0x63, 0x72, 0x6f, 0x73, 0x73, 0x69, 0x6e, 0x67, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73,
+	0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x61, 0x6a, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79,
+	0x43, 0x72, 0x6f, 0x73, 0x73, 0x69, 0x6e, 0x67, 0x52, 0x09, 0x63, 0x72, 0x6f, 0x73, 0x73, 0x69,
+	0x6e, 0x67, 0x73, 0x22, 0x65, 0x0a, 0x0f, 0x54, 0x72, 0x61, 0x6a, 0x65, 0x63, 0x74, 0x6f, 0x72,
+	0x79, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x61, 0x74, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x61, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x6e, 0x67, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x6e, 0x67, 0x12, 0x2e, 0x0a, 0x04, 0x74, 0x69,
+	0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x22, 0xbd, 0x01, 0x0a, 0x12, 0x54,
+	0x72, 0x61, 0x6a, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x43, 0x72, 0x6f, 0x73, 0x73, 0x69, 0x6e,
+	0x67, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69,
+	0x64, 0x12, 0x2f, 0x0a, 0x07, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x15, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76,
+	0x31, 0x2e, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x07, 0x66, 0x65, 0x61, 0x74, 0x75,
+	0x72, 0x65, 0x12, 0x33, 0x0a, 0x05, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1d, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31,
+	0x2e, 0x54, 0x72, 0x61, 0x6a, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x50, 0x6f, 0x69, 0x6e, 0x74,
+	0x52, 0x05, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x31, 0x0a, 0x04, 0x65, 0x78, 0x69, 0x74, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76,
+	0x63, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x61, 0x6a, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x50,
+	0x6f, 0x69, 0x6e, 0x74, 0x52, 0x04, 0x65, 0x78, 0x69, 0x74, 0x22, 0xc3, 0x01, 0x0a, 0x0f, 0x47,
+	0x65, 0x6f, 0x66, 0x65, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b,
+	0x0a, 0x09, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x6c,
+	0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x61, 0x74, 0x12, 0x10, 0x0a,
+	0x03, 0x6c, 0x6e, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x6e, 0x67, 0x12,
+	0x2e, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12,
+	0x25, 0x0a, 0x0e, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72,
+	0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x46,
+	0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65,
+	0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74,
+	0x22, 0xe0, 0x03, 0x0a, 0x0d, 0x47, 0x65, 0x6f, 0x66, 0x65, 0x6e, 0x63, 0x65, 0x45, 0x76, 0x65,
+	0x6e, 0x74, 0x12, 0x34, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x20, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e,
+	0x47, 0x65, 0x6f, 0x66, 0x65, 0x6e, 0x63, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x2e, 0x54, 0x79,
+	0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x64, 0x65, 0x76, 0x69,
+	0x63, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x65, 0x76,
+	0x69, 0x63, 0x65, 0x49, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x6f, 0x6f, 0x70, 0x5f, 0x69, 0x6e,
+	0x64, 0x65, 0x78, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x6c, 0x6f, 0x6f, 0x70, 0x49,
+	0x6e, 0x64, 0x65, 0x78, 0x12, 0x29, 0x0a, 0x05, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e,
+	0x76, 0x31, 0x2e, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x05, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12,
+	0x2e, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12,
+	0x39, 0x0a, 0x0a, 0x65, 0x6e, 0x74, 0x65, 0x72, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52,
+	0x09, 0x65, 0x6e, 0x74, 0x65, 0x72, 0x65, 0x64, 0x41, 0x74, 0x12, 0x2f, 0x0a, 0x07, 0x66, 0x65,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x69, 0x6e,
+	0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x65, 0x61, 0x74, 0x75,
+	0x72, 0x65, 0x52, 0x07, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x65,
+	0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x5f, 0x69, 0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0a, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x49, 0x64, 0x12, 0x18, 0x0a, 0x07,
+	0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x64,
+	0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x22, 0x4b, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x14,
+	0x0a, 0x10, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49,
+	0x45, 0x44, 0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x45, 0x4e, 0x54,
+	0x45, 0x52, 0x10, 0x01, 0x12, 0x0d, 0x0a, 0x09, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x45, 0x58, 0x49,
+	0x54, 0x10, 0x02, 0x12, 0x0e, 0x0a, 0x0a, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x44, 0x57, 0x45, 0x4c,
+	0x4c, 0x10, 0x03, 0x22, 0x8e, 0x01, 0x0a, 0x0a, 0x47, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02,
+	0x69, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x6f, 0x6f, 0x70, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x6c, 0x6f, 0x6f, 0x70, 0x49, 0x6e, 0x64, 0x65,
+	0x78, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x65, 0x78, 0x74,
+	0x65, 0x72, 0x6e, 0x61, 0x6c, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
+	0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x49, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x61,
+	0x74, 0x61, 0x73, 0x65, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x64, 0x61, 0x74,
+	0x61, 0x73, 0x65, 0x74, 0x22, 0x6f, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x02, 0x69, 0x64, 0x12, 0x2f, 0x0a, 0x07, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63,
+	0x2e, 0x76, 0x31, 0x2e, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x07, 0x66, 0x65, 0x61,
+	0x74, 0x75, 0x72, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c,
+	0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x65, 0x78, 0x74, 0x65, 0x72,
+	0x6e, 0x61, 0x6c, 0x49, 0x64, 0x22, 0x8d, 0x01, 0x0a, 0x0f, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x2f, 0x0a, 0x07, 0x66, 0x65, 0x61,
+	0x74, 0x75, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x69, 0x6e, 0x73,
+	0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72,
+	0x65, 0x52, 0x07, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x65, 0x78,
+	0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0a, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x49, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x64,
+	0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x64, 0x61,
+	0x74, 0x61, 0x73, 0x65, 0x74, 0x22, 0xdb, 0x01, 0x0a, 0x07, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72,
+	0x65, 0x12, 0x32, 0x0a, 0x08, 0x67, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x79, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e,
+	0x76, 0x31, 0x2e, 0x47, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x79, 0x52, 0x08, 0x67, 0x65, 0x6f,
+	0x6d, 0x65, 0x74, 0x72, 0x79, 0x12, 0x45, 0x0a, 0x0a, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74,
+	0x69, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x69, 0x6e, 0x73, 0x69,
+	0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x2e, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x52, 0x0a, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x1a, 0x55, 0x0a, 0x0f,
+	0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x2c, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
+	0x02, 0x38, 0x01, 0x22, 0x95, 0x02, 0x0a, 0x08, 0x47, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x79,
+	0x12, 0x2f, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1b,
+	0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65,
+	0x6f, 0x6d, 0x65, 0x74, 0x72, 0x79, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70,
+	0x65, 0x12, 0x36, 0x0a, 0x0a, 0x67, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76,
+	0x63, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x79, 0x52, 0x0a, 0x67,
+	0x65, 0x6f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6f,
+	0x72, 0x64, 0x69, 0x6e, 0x61, 0x74, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x01, 0x52, 0x0b,
+	0x63, 0x6f, 0x6f, 0x72, 0x64, 0x69, 0x6e, 0x61, 0x74, 0x65, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x65,
+	0x6e, 0x64, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x04, 0x65, 0x6e, 0x64, 0x73, 0x22,
+	0x6a, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x10, 0x54, 0x59, 0x50, 0x45, 0x5f,
+	0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0e, 0x0a,
+	0x0a, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x50, 0x4f, 0x49, 0x4e, 0x54, 0x10, 0x01, 0x12, 0x10, 0x0a,
+	0x0c, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x50, 0x4f, 0x4c, 0x59, 0x47, 0x4f, 0x4e, 0x10, 0x02, 0x12,
+	0x15, 0x0a, 0x11, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x4d, 0x55, 0x4c, 0x54, 0x49, 0x50, 0x4f, 0x4c,
+	0x59, 0x47, 0x4f, 0x4e, 0x10, 0x03, 0x12, 0x13, 0x0a, 0x0f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x4c,
+	0x49, 0x4e, 0x45, 0x53, 0x54, 0x52, 0x49, 0x4e, 0x47, 0x10, 0x04, 0x22, 0x2b, 0x0a, 0x05, 0x50,
+	0x6f, 0x69, 0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x03, 0x6c, 0x61, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x6e, 0x67, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x6e, 0x67, 0x2a, 0x57, 0x0a, 0x05, 0x4f, 0x72, 0x64, 0x65,
+	0x72, 0x12, 0x15, 0x0a, 0x11, 0x4f, 0x52, 0x44, 0x45, 0x52, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45,
+	0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x12, 0x0a, 0x0e, 0x4f, 0x52, 0x44, 0x45,
+	0x52, 0x5f, 0x50, 0x52, 0x4f, 0x50, 0x45, 0x52, 0x54, 0x59, 0x10, 0x01, 0x12, 0x0e, 0x0a, 0x0a,
+	0x4f, 0x52, 0x44, 0x45, 0x52, 0x5f, 0x41, 0x52, 0x45, 0x41, 0x10, 0x02, 0x12, 0x13, 0x0a, 0x0f,
+	0x4f, 0x52, 0x44, 0x45, 0x52, 0x5f, 0x49, 0x4e, 0x53, 0x45, 0x52, 0x54, 0x49, 0x4f, 0x4e, 0x10,
+	0x03, 0x32, 0xde, 0x05, 0x0a, 0x0d, 0x49, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x12, 0x45, 0x0a, 0x06, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x12, 0x1b, 0x2e,
+	0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x69, 0x74,
+	0x68, 0x69, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x69, 0x6e, 0x73,
 	0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65,
-	0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x3c, 0x0a, 0x03, 0x47, 0x65, 0x74, 0x12, 0x18,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x54, 0x0a, 0x0b, 0x42, 0x61,
+	0x74, 0x63, 0x68, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x12, 0x20, 0x2e, 0x69, 0x6e, 0x73, 0x69,
+	0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x57, 0x69,
+	0x74, 0x68, 0x69, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x69, 0x6e,
+	0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68,
+	0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
+	0x12, 0x5b, 0x0a, 0x0c, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x12, 0x21, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e,
+	0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e,
+	0x76, 0x31, 0x2e, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x28, 0x01, 0x30, 0x01, 0x12, 0x3c, 0x0a,
+	0x03, 0x47, 0x65, 0x74, 0x12, 0x18, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63,
+	0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19,
 	0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65,
-	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64,
-	0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x3d, 0x5a, 0x3b, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
-	0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x6b, 0x68, 0x65, 0x6e, 0x61, 0x6b, 0x68, 0x2f, 0x69, 0x6e, 0x73,
-	0x69, 0x64, 0x65, 0x6f, 0x75, 0x74, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x67, 0x6f, 0x2f, 0x69, 0x6e,
-	0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2f, 0x76, 0x31, 0x3b, 0x69, 0x6e, 0x73, 0x69, 0x64,
-	0x65, 0x73, 0x76, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x48, 0x0a, 0x07, 0x4e,
+	0x65, 0x61, 0x72, 0x65, 0x73, 0x74, 0x12, 0x1c, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73,
+	0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x65, 0x61, 0x72, 0x65, 0x73, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63,
+	0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x65, 0x61, 0x72, 0x65, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x51, 0x0a, 0x0a, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x65,
+	0x63, 0x74, 0x73, 0x12, 0x1f, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e,
+	0x76, 0x31, 0x2e, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x65, 0x63, 0x74, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63,
+	0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x65, 0x63, 0x74, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x57, 0x0a, 0x0c, 0x57, 0x69, 0x74, 0x68,
+	0x69, 0x6e, 0x52, 0x61, 0x64, 0x69, 0x75, 0x73, 0x12, 0x21, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64,
+	0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x69, 0x74, 0x68, 0x69, 0x6e, 0x52, 0x61,
+	0x64, 0x69, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e, 0x69, 0x6e,
+	0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x69, 0x74, 0x68, 0x69,
+	0x6e, 0x52, 0x61, 0x64, 0x69, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0x00, 0x12, 0x51, 0x0a, 0x0a, 0x54, 0x72, 0x61, 0x6a, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x12,
+	0x1f, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x54,
+	0x72, 0x61, 0x6a, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x20, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e,
+	0x54, 0x72, 0x61, 0x6a, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x00, 0x12, 0x4c, 0x0a, 0x08, 0x47, 0x65, 0x6f, 0x66, 0x65, 0x6e, 0x63, 0x65,
+	0x12, 0x1d, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e,
+	0x47, 0x65, 0x6f, 0x66, 0x65, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1b, 0x2e, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76, 0x63, 0x2e, 0x76, 0x31, 0x2e, 0x47,
+	0x65, 0x6f, 0x66, 0x65, 0x6e, 0x63, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x22, 0x00, 0x28, 0x01,
+	0x30, 0x01, 0x42, 0x3d, 0x5a, 0x3b, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
+	0x2f, 0x61, 0x6b, 0x68, 0x65, 0x6e, 0x61, 0x6b, 0x68, 0x2f, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65,
+	0x6f, 0x75, 0x74, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x67, 0x6f, 0x2f, 0x69, 0x6e, 0x73, 0x69, 0x64,
+	0x65, 0x73, 0x76, 0x63, 0x2f, 0x76, 0x31, 0x3b, 0x69, 0x6e, 0x73, 0x69, 0x64, 0x65, 0x73, 0x76,
+	0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -644,40 +2582,104 @@ func file_insidesvc_v1_insidesvc_proto_rawDescGZIP() []byte {
 	return file_insidesvc_v1_insidesvc_proto_rawDescData
 }
 
-var file_insidesvc_v1_insidesvc_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_insidesvc_v1_insidesvc_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_insidesvc_v1_insidesvc_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_insidesvc_v1_insidesvc_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
 var file_insidesvc_v1_insidesvc_proto_goTypes = []interface{}{
-	(Geometry_Type)(0),      // 0: insidesvc.v1.Geometry.Type
-	(*WithinRequest)(nil),   // 1: insidesvc.v1.WithinRequest
-	(*WithinResponse)(nil),  // 2: insidesvc.v1.WithinResponse
-	(*GetRequest)(nil),      // 3: insidesvc.v1.GetRequest
-	(*GetResponse)(nil),     // 4: insidesvc.v1.GetResponse
-	(*FeatureResponse)(nil), // 5: insidesvc.v1.FeatureResponse
-	(*Feature)(nil),         // 6: insidesvc.v1.Feature
-	(*Geometry)(nil),        // 7: insidesvc.v1.Geometry
-	(*Point)(nil),           // 8: insidesvc.v1.Point
-	nil,                     // 9: insidesvc.v1.Feature.PropertiesEntry
-	(*structpb.Value)(nil),  // 10: google.protobuf.Value
+	(Order)(0),                          // 0: insidesvc.v1.Order
+	(GeofenceEvent_Type)(0),             // 1: insidesvc.v1.GeofenceEvent.Type
+	(Geometry_Type)(0),                  // 2: insidesvc.v1.Geometry.Type
+	(*WithinRequest)(nil),               // 3: insidesvc.v1.WithinRequest
+	(*WithinResponse)(nil),              // 4: insidesvc.v1.WithinResponse
+	(*BatchWithinRequest)(nil),          // 5: insidesvc.v1.BatchWithinRequest
+	(*BatchWithinResponse)(nil),         // 6: insidesvc.v1.BatchWithinResponse
+	(*WithinStreamRequest)(nil),         // 7: insidesvc.v1.WithinStreamRequest
+	(*WithinStreamResponse)(nil),        // 8: insidesvc.v1.WithinStreamResponse
+	(*NearestRequest)(nil),              // 9: insidesvc.v1.NearestRequest
+	(*NearestResponse)(nil),             // 10: insidesvc.v1.NearestResponse
+	(*NearestFeatureResponse)(nil),      // 11: insidesvc.v1.NearestFeatureResponse
+	(*WithinRadiusRequest)(nil),         // 12: insidesvc.v1.WithinRadiusRequest
+	(*WithinRadiusResponse)(nil),        // 13: insidesvc.v1.WithinRadiusResponse
+	(*IntersectsRequest)(nil),           // 14: insidesvc.v1.IntersectsRequest
+	(*IntersectsResponse)(nil),          // 15: insidesvc.v1.IntersectsResponse
+	(*BBox)(nil),                        // 16: insidesvc.v1.BBox
+	(*TrajectoryRequest)(nil),           // 17: insidesvc.v1.TrajectoryRequest
+	(*TrajectoryResponse)(nil),          // 18: insidesvc.v1.TrajectoryResponse
+	(*TrajectoryPoint)(nil),             // 19: insidesvc.v1.TrajectoryPoint
+	(*TrajectoryCrossing)(nil),          // 20: insidesvc.v1.TrajectoryCrossing
+	(*GeofenceRequest)(nil),             // 21: insidesvc.v1.GeofenceRequest
+	(*GeofenceEvent)(nil),               // 22: insidesvc.v1.GeofenceEvent
+	(*GetRequest)(nil),                  // 23: insidesvc.v1.GetRequest
+	(*GetResponse)(nil),                 // 24: insidesvc.v1.GetResponse
+	(*FeatureResponse)(nil),             // 25: insidesvc.v1.FeatureResponse
+	(*Feature)(nil),                     // 26: insidesvc.v1.Feature
+	(*Geometry)(nil),                    // 27: insidesvc.v1.Geometry
+	(*Point)(nil),                       // 28: insidesvc.v1.Point
+	(*WithinStreamResponse_Status)(nil), // 29: insidesvc.v1.WithinStreamResponse.Status
+	nil,                                 // 30: insidesvc.v1.Feature.PropertiesEntry
+	(*timestamppb.Timestamp)(nil),       // 31: google.protobuf.Timestamp
+	(*structpb.Value)(nil),              // 32: google.protobuf.Value
 }
 var file_insidesvc_v1_insidesvc_proto_depIdxs = []int32{
-	8,  // 0: insidesvc.v1.WithinResponse.point:type_name -> insidesvc.v1.Point
-	5,  // 1: insidesvc.v1.WithinResponse.responses:type_name -> insidesvc.v1.FeatureResponse
-	6,  // 2: insidesvc.v1.GetResponse.feature:type_name -> insidesvc.v1.Feature
-	6,  // 3: insidesvc.v1.FeatureResponse.feature:type_name -> insidesvc.v1.Feature
-	7,  // 4: insidesvc.v1.Feature.geometry:type_name -> insidesvc.v1.Geometry
-	9,  // 5: insidesvc.v1.Feature.properties:type_name -> insidesvc.v1.Feature.PropertiesEntry
-	0,  // 6: insidesvc.v1.Geometry.type:type_name -> insidesvc.v1.Geometry.Type
-	7,  // 7: insidesvc.v1.Geometry.geometries:type_name -> insidesvc.v1.Geometry
-	10, // 8: insidesvc.v1.Feature.PropertiesEntry.value:type_name -> google.protobuf.Value
-	1,  // 9: insidesvc.v1.InsideService.Within:input_type -> insidesvc.v1.WithinRequest
-	3,  // 10: insidesvc.v1.InsideService.Get:input_type -> insidesvc.v1.GetRequest
-	2,  // 11: insidesvc.v1.InsideService.Within:output_type -> insidesvc.v1.WithinResponse
-	4,  // 12: insidesvc.v1.InsideService.Get:output_type -> insidesvc.v1.GetResponse
-	11, // [11:13] is the sub-list for method output_type
-	9,  // [9:11] is the sub-list for method input_type
-	9,  // [9:9] is the sub-list for extension type_name
-	9,  // [9:9] is the sub-list for extension extendee
-	0,  // [0:9] is the sub-list for field type_name
+	0,  // 0: insidesvc.v1.WithinRequest.order:type_name -> insidesvc.v1.Order
+	28, // 1: insidesvc.v1.WithinResponse.point:type_name -> insidesvc.v1.Point
+	25, // 2: insidesvc.v1.WithinResponse.responses:type_name -> insidesvc.v1.FeatureResponse
+	28, // 3: insidesvc.v1.BatchWithinRequest.points:type_name -> insidesvc.v1.Point
+	0,  // 4: insidesvc.v1.BatchWithinRequest.order:type_name -> insidesvc.v1.Order
+	4,  // 5: insidesvc.v1.BatchWithinResponse.responses:type_name -> insidesvc.v1.WithinResponse
+	3,  // 6: insidesvc.v1.WithinStreamRequest.request:type_name -> insidesvc.v1.WithinRequest
+	4,  // 7: insidesvc.v1.WithinStreamResponse.response:type_name -> insidesvc.v1.WithinResponse
+	29, // 8: insidesvc.v1.WithinStreamResponse.status:type_name -> insidesvc.v1.WithinStreamResponse.Status
+	28, // 9: insidesvc.v1.NearestResponse.point:type_name -> insidesvc.v1.Point
+	11, // 10: insidesvc.v1.NearestResponse.responses:type_name -> insidesvc.v1.NearestFeatureResponse
+	26, // 11: insidesvc.v1.NearestFeatureResponse.feature:type_name -> insidesvc.v1.Feature
+	28, // 12: insidesvc.v1.NearestFeatureResponse.closest_point:type_name -> insidesvc.v1.Point
+	28, // 13: insidesvc.v1.WithinRadiusResponse.point:type_name -> insidesvc.v1.Point
+	11, // 14: insidesvc.v1.WithinRadiusResponse.responses:type_name -> insidesvc.v1.NearestFeatureResponse
+	16, // 15: insidesvc.v1.IntersectsRequest.bbox:type_name -> insidesvc.v1.BBox
+	27, // 16: insidesvc.v1.IntersectsRequest.polygon:type_name -> insidesvc.v1.Geometry
+	25, // 17: insidesvc.v1.IntersectsResponse.responses:type_name -> insidesvc.v1.FeatureResponse
+	19, // 18: insidesvc.v1.TrajectoryRequest.points:type_name -> insidesvc.v1.TrajectoryPoint
+	20, // 19: insidesvc.v1.TrajectoryResponse.crossings:type_name -> insidesvc.v1.TrajectoryCrossing
+	31, // 20: insidesvc.v1.TrajectoryPoint.time:type_name -> google.protobuf.Timestamp
+	26, // 21: insidesvc.v1.TrajectoryCrossing.feature:type_name -> insidesvc.v1.Feature
+	19, // 22: insidesvc.v1.TrajectoryCrossing.entry:type_name -> insidesvc.v1.TrajectoryPoint
+	19, // 23: insidesvc.v1.TrajectoryCrossing.exit:type_name -> insidesvc.v1.TrajectoryPoint
+	31, // 24: insidesvc.v1.GeofenceRequest.time:type_name -> google.protobuf.Timestamp
+	1,  // 25: insidesvc.v1.GeofenceEvent.type:type_name -> insidesvc.v1.GeofenceEvent.Type
+	28, // 26: insidesvc.v1.GeofenceEvent.point:type_name -> insidesvc.v1.Point
+	31, // 27: insidesvc.v1.GeofenceEvent.time:type_name -> google.protobuf.Timestamp
+	31, // 28: insidesvc.v1.GeofenceEvent.entered_at:type_name -> google.protobuf.Timestamp
+	26, // 29: insidesvc.v1.GeofenceEvent.feature:type_name -> insidesvc.v1.Feature
+	26, // 30: insidesvc.v1.GetResponse.feature:type_name -> insidesvc.v1.Feature
+	26, // 31: insidesvc.v1.FeatureResponse.feature:type_name -> insidesvc.v1.Feature
+	27, // 32: insidesvc.v1.Feature.geometry:type_name -> insidesvc.v1.Geometry
+	30, // 33: insidesvc.v1.Feature.properties:type_name -> insidesvc.v1.Feature.PropertiesEntry
+	2,  // 34: insidesvc.v1.Geometry.type:type_name -> insidesvc.v1.Geometry.Type
+	27, // 35: insidesvc.v1.Geometry.geometries:type_name -> insidesvc.v1.Geometry
+	32, // 36: insidesvc.v1.Feature.PropertiesEntry.value:type_name -> google.protobuf.Value
+	3,  // 37: insidesvc.v1.InsideService.Within:input_type -> insidesvc.v1.WithinRequest
+	5,  // 38: insidesvc.v1.InsideService.BatchWithin:input_type -> insidesvc.v1.BatchWithinRequest
+	7,  // 39: insidesvc.v1.InsideService.WithinStream:input_type -> insidesvc.v1.WithinStreamRequest
+	23, // 40: insidesvc.v1.InsideService.Get:input_type -> insidesvc.v1.GetRequest
+	9,  // 41: insidesvc.v1.InsideService.Nearest:input_type -> insidesvc.v1.NearestRequest
+	14, // 42: insidesvc.v1.InsideService.Intersects:input_type -> insidesvc.v1.IntersectsRequest
+	12, // 43: insidesvc.v1.InsideService.WithinRadius:input_type -> insidesvc.v1.WithinRadiusRequest
+	17, // 44: insidesvc.v1.InsideService.Trajectory:input_type -> insidesvc.v1.TrajectoryRequest
+	21, // 45: insidesvc.v1.InsideService.Geofence:input_type -> insidesvc.v1.GeofenceRequest
+	4,  // 46: insidesvc.v1.InsideService.Within:output_type -> insidesvc.v1.WithinResponse
+	6,  // 47: insidesvc.v1.InsideService.BatchWithin:output_type -> insidesvc.v1.BatchWithinResponse
+	8,  // 48: insidesvc.v1.InsideService.WithinStream:output_type -> insidesvc.v1.WithinStreamResponse
+	24, // 49: insidesvc.v1.InsideService.Get:output_type -> insidesvc.v1.GetResponse
+	10, // 50: insidesvc.v1.InsideService.Nearest:output_type -> insidesvc.v1.NearestResponse
+	15, // 51: insidesvc.v1.InsideService.Intersects:output_type -> insidesvc.v1.IntersectsResponse
+	13, // 52: insidesvc.v1.InsideService.WithinRadius:output_type -> insidesvc.v1.WithinRadiusResponse
+	18, // 53: insidesvc.v1.InsideService.Trajectory:output_type -> insidesvc.v1.TrajectoryResponse
+	22, // 54: insidesvc.v1.InsideService.Geofence:output_type -> insidesvc.v1.GeofenceEvent
+	46, // [46:55] is the sub-list for method output_type
+	37, // [37:46] is the sub-list for method input_type
+	37, // [37:37] is the sub-list for extension type_name
+	37, // [37:37] is the sub-list for extension extendee
+	0,  // [0:37] is the sub-list for field type_name
 }
 
 func init() { file_insidesvc_v1_insidesvc_proto_init() }
@@ -711,7 +2713,7 @@ func file_insidesvc_v1_insidesvc_proto_init() {
 			}
 		}
 		file_insidesvc_v1_insidesvc_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetRequest); i {
+			switch v := v.(*BatchWithinRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -723,7 +2725,7 @@ func file_insidesvc_v1_insidesvc_proto_init() {
 			}
 		}
 		file_insidesvc_v1_insidesvc_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetResponse); i {
+			switch v := v.(*BatchWithinResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -735,7 +2737,7 @@ func file_insidesvc_v1_insidesvc_proto_init() {
 			}
 		}
 		file_insidesvc_v1_insidesvc_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*FeatureResponse); i {
+			switch v := v.(*WithinStreamRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -747,7 +2749,7 @@ func file_insidesvc_v1_insidesvc_proto_init() {
 			}
 		}
 		file_insidesvc_v1_insidesvc_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Feature); i {
+			switch v := v.(*WithinStreamResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -759,7 +2761,7 @@ func file_insidesvc_v1_insidesvc_proto_init() {
 			}
 		}
 		file_insidesvc_v1_insidesvc_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Geometry); i {
+			switch v := v.(*NearestRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -771,6 +2773,222 @@ func file_insidesvc_v1_insidesvc_proto_init() {
 			}
 		}
 		file_insidesvc_v1_insidesvc_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NearestResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NearestFeatureResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WithinRadiusRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WithinRadiusResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IntersectsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IntersectsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BBox); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TrajectoryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TrajectoryResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TrajectoryPoint); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TrajectoryCrossing); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GeofenceRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GeofenceEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FeatureResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Feature); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Geometry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Point); i {
 			case 0:
 				return &v.state
@@ -782,14 +3000,30 @@ func file_insidesvc_v1_insidesvc_proto_init() {
 				return nil
 			}
 		}
+		file_insidesvc_v1_insidesvc_proto_msgTypes[26].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WithinStreamResponse_Status); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_insidesvc_v1_insidesvc_proto_msgTypes[11].OneofWrappers = []interface{}{
+		(*IntersectsRequest_Bbox)(nil),
+		(*IntersectsRequest_Polygon)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_insidesvc_v1_insidesvc_proto_rawDesc,
-			NumEnums:      1,
-			NumMessages:   9,
+			NumEnums:      3,
+			NumMessages:   28,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
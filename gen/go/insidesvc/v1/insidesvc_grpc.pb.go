@@ -20,8 +20,25 @@ const _ = grpc.SupportPackageIsVersion7
 type InsideServiceClient interface {
 	//  Stab returns features containing lat lng
 	Within(ctx context.Context, in *WithinRequest, opts ...grpc.CallOption) (*WithinResponse, error)
+	// BatchWithin returns features containing each point, responses are in the same order as the points
+	BatchWithin(ctx context.Context, in *BatchWithinRequest, opts ...grpc.CallOption) (*BatchWithinResponse, error)
+	// WithinStream receives points and streams back their responses tagged with the request correlation id,
+	// responses are sent in the order the requests were received
+	WithinStream(ctx context.Context, opts ...grpc.CallOption) (InsideService_WithinStreamClient, error)
 	// Get returns a feature by its internal ID and polygon index
 	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	// Nearest returns the closest features to lat lng up to a maximum distance,
+	// useful when a point falls just outside every polygon
+	Nearest(ctx context.Context, in *NearestRequest, opts ...grpc.CallOption) (*NearestResponse, error)
+	// Intersects returns features intersecting a bounding box or a polygon
+	Intersects(ctx context.Context, in *IntersectsRequest, opts ...grpc.CallOption) (*IntersectsResponse, error)
+	// WithinRadius returns every feature closer than radius to lat lng
+	WithinRadius(ctx context.Context, in *WithinRadiusRequest, opts ...grpc.CallOption) (*WithinRadiusResponse, error)
+	// Trajectory returns the features crossed by a path with the entry and exit points
+	Trajectory(ctx context.Context, in *TrajectoryRequest, opts ...grpc.CallOption) (*TrajectoryResponse, error)
+	// Geofence receives devices positions and streams ENTER, EXIT and DWELL events
+	// when the features containing a device change
+	Geofence(ctx context.Context, opts ...grpc.CallOption) (InsideService_GeofenceClient, error)
 }
 
 type insideServiceClient struct {
@@ -41,6 +58,46 @@ func (c *insideServiceClient) Within(ctx context.Context, in *WithinRequest, opt
 	return out, nil
 }
 
+func (c *insideServiceClient) BatchWithin(ctx context.Context, in *BatchWithinRequest, opts ...grpc.CallOption) (*BatchWithinResponse, error) {
+	out := new(BatchWithinResponse)
+	err := c.cc.Invoke(ctx, "/insidesvc.v1.InsideService/BatchWithin", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideServiceClient) WithinStream(ctx context.Context, opts ...grpc.CallOption) (InsideService_WithinStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &InsideService_ServiceDesc.Streams[0], "/insidesvc.v1.InsideService/WithinStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &insideServiceWithinStreamClient{stream}
+	return x, nil
+}
+
+type InsideService_WithinStreamClient interface {
+	Send(*WithinStreamRequest) error
+	Recv() (*WithinStreamResponse, error)
+	grpc.ClientStream
+}
+
+type insideServiceWithinStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *insideServiceWithinStreamClient) Send(m *WithinStreamRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *insideServiceWithinStreamClient) Recv() (*WithinStreamResponse, error) {
+	m := new(WithinStreamResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *insideServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
 	out := new(GetResponse)
 	err := c.cc.Invoke(ctx, "/insidesvc.v1.InsideService/Get", in, out, opts...)
@@ -50,14 +107,98 @@ func (c *insideServiceClient) Get(ctx context.Context, in *GetRequest, opts ...g
 	return out, nil
 }
 
+func (c *insideServiceClient) Nearest(ctx context.Context, in *NearestRequest, opts ...grpc.CallOption) (*NearestResponse, error) {
+	out := new(NearestResponse)
+	err := c.cc.Invoke(ctx, "/insidesvc.v1.InsideService/Nearest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideServiceClient) Intersects(ctx context.Context, in *IntersectsRequest, opts ...grpc.CallOption) (*IntersectsResponse, error) {
+	out := new(IntersectsResponse)
+	err := c.cc.Invoke(ctx, "/insidesvc.v1.InsideService/Intersects", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideServiceClient) WithinRadius(ctx context.Context, in *WithinRadiusRequest, opts ...grpc.CallOption) (*WithinRadiusResponse, error) {
+	out := new(WithinRadiusResponse)
+	err := c.cc.Invoke(ctx, "/insidesvc.v1.InsideService/WithinRadius", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideServiceClient) Trajectory(ctx context.Context, in *TrajectoryRequest, opts ...grpc.CallOption) (*TrajectoryResponse, error) {
+	out := new(TrajectoryResponse)
+	err := c.cc.Invoke(ctx, "/insidesvc.v1.InsideService/Trajectory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *insideServiceClient) Geofence(ctx context.Context, opts ...grpc.CallOption) (InsideService_GeofenceClient, error) {
+	stream, err := c.cc.NewStream(ctx, &InsideService_ServiceDesc.Streams[1], "/insidesvc.v1.InsideService/Geofence", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &insideServiceGeofenceClient{stream}
+	return x, nil
+}
+
+type InsideService_GeofenceClient interface {
+	Send(*GeofenceRequest) error
+	Recv() (*GeofenceEvent, error)
+	grpc.ClientStream
+}
+
+type insideServiceGeofenceClient struct {
+	grpc.ClientStream
+}
+
+func (x *insideServiceGeofenceClient) Send(m *GeofenceRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *insideServiceGeofenceClient) Recv() (*GeofenceEvent, error) {
+	m := new(GeofenceEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // InsideServiceServer is the server API for InsideService service.
 // All implementations should embed UnimplementedInsideServiceServer
 // for forward compatibility
 type InsideServiceServer interface {
 	//  Stab returns features containing lat lng
 	Within(context.Context, *WithinRequest) (*WithinResponse, error)
+	// BatchWithin returns features containing each point, responses are in the same order as the points
+	BatchWithin(context.Context, *BatchWithinRequest) (*BatchWithinResponse, error)
+	// WithinStream receives points and streams back their responses tagged with the request correlation id,
+	// responses are sent in the order the requests were received
+	WithinStream(InsideService_WithinStreamServer) error
 	// Get returns a feature by its internal ID and polygon index
 	Get(context.Context, *GetRequest) (*GetResponse, error)
+	// Nearest returns the closest features to lat lng up to a maximum distance,
+	// useful when a point falls just outside every polygon
+	Nearest(context.Context, *NearestRequest) (*NearestResponse, error)
+	// Intersects returns features intersecting a bounding box or a polygon
+	Intersects(context.Context, *IntersectsRequest) (*IntersectsResponse, error)
+	// WithinRadius returns every feature closer than radius to lat lng
+	WithinRadius(context.Context, *WithinRadiusRequest) (*WithinRadiusResponse, error)
+	// Trajectory returns the features crossed by a path with the entry and exit points
+	Trajectory(context.Context, *TrajectoryRequest) (*TrajectoryResponse, error)
+	// Geofence receives devices positions and streams ENTER, EXIT and DWELL events
+	// when the features containing a device change
+	Geofence(InsideService_GeofenceServer) error
 }
 
 // UnimplementedInsideServiceServer should be embedded to have forward compatible implementations.
@@ -67,9 +208,30 @@ type UnimplementedInsideServiceServer struct {
 func (UnimplementedInsideServiceServer) Within(context.Context, *WithinRequest) (*WithinResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Within not implemented")
 }
+func (UnimplementedInsideServiceServer) BatchWithin(context.Context, *BatchWithinRequest) (*BatchWithinResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchWithin not implemented")
+}
+func (UnimplementedInsideServiceServer) WithinStream(InsideService_WithinStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method WithinStream not implemented")
+}
 func (UnimplementedInsideServiceServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
 }
+func (UnimplementedInsideServiceServer) Nearest(context.Context, *NearestRequest) (*NearestResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Nearest not implemented")
+}
+func (UnimplementedInsideServiceServer) Intersects(context.Context, *IntersectsRequest) (*IntersectsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Intersects not implemented")
+}
+func (UnimplementedInsideServiceServer) WithinRadius(context.Context, *WithinRadiusRequest) (*WithinRadiusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WithinRadius not implemented")
+}
+func (UnimplementedInsideServiceServer) Trajectory(context.Context, *TrajectoryRequest) (*TrajectoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Trajectory not implemented")
+}
+func (UnimplementedInsideServiceServer) Geofence(InsideService_GeofenceServer) error {
+	return status.Errorf(codes.Unimplemented, "method Geofence not implemented")
+}
 
 // UnsafeInsideServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to InsideServiceServer will
@@ -100,6 +262,50 @@ func _InsideService_Within_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _InsideService_BatchWithin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchWithinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServiceServer).BatchWithin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/insidesvc.v1.InsideService/BatchWithin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServiceServer).BatchWithin(ctx, req.(*BatchWithinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsideService_WithinStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(InsideServiceServer).WithinStream(&insideServiceWithinStreamServer{stream})
+}
+
+type InsideService_WithinStreamServer interface {
+	Send(*WithinStreamResponse) error
+	Recv() (*WithinStreamRequest, error)
+	grpc.ServerStream
+}
+
+type insideServiceWithinStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *insideServiceWithinStreamServer) Send(m *WithinStreamResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *insideServiceWithinStreamServer) Recv() (*WithinStreamRequest, error) {
+	m := new(WithinStreamRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _InsideService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetRequest)
 	if err := dec(in); err != nil {
@@ -118,6 +324,104 @@ func _InsideService_Get_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _InsideService_Nearest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NearestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServiceServer).Nearest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/insidesvc.v1.InsideService/Nearest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServiceServer).Nearest(ctx, req.(*NearestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsideService_Intersects_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IntersectsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServiceServer).Intersects(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/insidesvc.v1.InsideService/Intersects",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServiceServer).Intersects(ctx, req.(*IntersectsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsideService_WithinRadius_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WithinRadiusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServiceServer).WithinRadius(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/insidesvc.v1.InsideService/WithinRadius",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServiceServer).WithinRadius(ctx, req.(*WithinRadiusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsideService_Trajectory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrajectoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InsideServiceServer).Trajectory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/insidesvc.v1.InsideService/Trajectory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InsideServiceServer).Trajectory(ctx, req.(*TrajectoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InsideService_Geofence_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(InsideServiceServer).Geofence(&insideServiceGeofenceServer{stream})
+}
+
+type InsideService_GeofenceServer interface {
+	Send(*GeofenceEvent) error
+	Recv() (*GeofenceRequest, error)
+	grpc.ServerStream
+}
+
+type insideServiceGeofenceServer struct {
+	grpc.ServerStream
+}
+
+func (x *insideServiceGeofenceServer) Send(m *GeofenceEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *insideServiceGeofenceServer) Recv() (*GeofenceRequest, error) {
+	m := new(GeofenceRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // InsideService_ServiceDesc is the grpc.ServiceDesc for InsideService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -129,11 +433,44 @@ var InsideService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Within",
 			Handler:    _InsideService_Within_Handler,
 		},
+		{
+			MethodName: "BatchWithin",
+			Handler:    _InsideService_BatchWithin_Handler,
+		},
 		{
 			MethodName: "Get",
 			Handler:    _InsideService_Get_Handler,
 		},
+		{
+			MethodName: "Nearest",
+			Handler:    _InsideService_Nearest_Handler,
+		},
+		{
+			MethodName: "Intersects",
+			Handler:    _InsideService_Intersects_Handler,
+		},
+		{
+			MethodName: "WithinRadius",
+			Handler:    _InsideService_WithinRadius_Handler,
+		},
+		{
+			MethodName: "Trajectory",
+			Handler:    _InsideService_Trajectory_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WithinStream",
+			Handler:       _InsideService_WithinStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Geofence",
+			Handler:       _InsideService_Geofence_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "insidesvc/v1/insidesvc.proto",
 }
@@ -1,8 +1,18 @@
 package insidesvc
 
 const (
-	LoopIndexProperty = "insided_loop_index"
-	FeatureIDProperty = "insided_fid"
-	CellsInProperty   = "insided_cells_in"
-	CellsOutProperty  = "insided_cells_out"
+	LoopIndexProperty  = "insided_loop_index"
+	FeatureIDProperty  = "insided_fid"
+	CellsInProperty    = "insided_cells_in"
+	CellsOutProperty   = "insided_cells_out"
+	DistanceProperty   = "insided_distance"
+	ClosestLatProperty = "insided_closest_lat"
+	ClosestLngProperty = "insided_closest_lng"
+	EntryLatProperty   = "insided_entry_lat"
+	EntryLngProperty   = "insided_entry_lng"
+	EntryTimeProperty  = "insided_entry_time"
+	ExitLatProperty    = "insided_exit_lat"
+	ExitLngProperty    = "insided_exit_lng"
+	ExitTimeProperty   = "insided_exit_time"
+	DatasetProperty    = "insided_dataset"
 )
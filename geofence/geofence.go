@@ -0,0 +1,245 @@
+// Package geofence keeps devices memberships to features and emits events when they change.
+package geofence
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventType the kind of geofencing event.
+type EventType int
+
+const (
+	// Enter the device entered a feature.
+	Enter EventType = iota + 1
+	// Exit the device exited a feature.
+	Exit
+	// Dwell the device stayed inside a feature for the dwell time.
+	Dwell
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Enter:
+		return "ENTER"
+	case Exit:
+		return "EXIT"
+	case Dwell:
+		return "DWELL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Feature a feature polygon containing a device,
+// identified across reloads by its dataset and external id, or by its id and dataset version when it has none.
+type Feature struct {
+	Dataset    string
+	ExternalID string
+	ID         uint32
+	Pos        uint16
+	// Version of the dataset, ids of features without external id are only valid in the same version
+	Version int64
+}
+
+// featureKey identifies a feature polygon across reloads.
+type featureKey struct {
+	dataset string
+	extID   string
+	id      uint32
+	version int64
+	pos     uint16
+}
+
+func (f Feature) key() featureKey {
+	k := featureKey{dataset: f.Dataset, extID: f.ExternalID, pos: f.Pos}
+	if f.ExternalID == "" {
+		k.id, k.version = f.ID, f.Version
+	}
+
+	return k
+}
+
+// Membership a feature polygon containing a device.
+type Membership struct {
+	Feature
+	EnteredAt time.Time
+	// Dwelled is true once the dwell event has been emitted
+	Dwelled bool
+}
+
+// State the memberships of a device.
+type State struct {
+	LastSeen    time.Time
+	Memberships []Membership
+}
+
+// Event a change in a device memberships,
+// the id of an exit event is the one stored on enter and may not exist anymore.
+type Event struct {
+	Type     EventType
+	DeviceID string
+	Feature
+	Time      time.Time
+	EnteredAt time.Time
+}
+
+// StateStore persists devices states, implementations must be safe for concurrent use.
+type StateStore interface {
+	// Get returns the state of a device, nil for unknown devices.
+	Get(deviceID string) (*State, error)
+	Set(deviceID string, state *State) error
+}
+
+// Options for a Tracker.
+type Options struct {
+	// DwellTime is the time spent inside a feature before a dwell event, 0 disables dwell events
+	DwellTime time.Duration
+}
+
+// lockCount is the number of locks serializing updates of the same device.
+const lockCount = 64
+
+// Tracker computes devices events from their successive positions.
+type Tracker struct {
+	store StateStore
+	opts  Options
+	locks [lockCount]sync.Mutex
+}
+
+// NewTracker returns a Tracker persisting states in store.
+func NewTracker(store StateStore, opts Options) *Tracker {
+	return &Tracker{store: store, opts: opts}
+}
+
+// Update sets the features of dataset containing the device at time ts, and returns the resulting events,
+// exits first then enters and dwells, memberships of other datasets are kept.
+// Updates older than the last one seen for the device are ignored.
+func (t *Tracker) Update(deviceID, dataset string, ts time.Time, fs []Feature) ([]Event, error) {
+	h := fnv.New32a()
+	h.Write([]byte(deviceID))
+
+	lock := &t.locks[h.Sum32()%lockCount]
+	lock.Lock()
+	defer lock.Unlock()
+
+	prev, err := t.store.Get(deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get device state: %w", err)
+	}
+
+	if prev == nil {
+		prev = &State{}
+	}
+
+	if ts.Before(prev.LastSeen) {
+		return nil, nil
+	}
+
+	events, next := diff(deviceID, dataset, prev, ts, fs, t.opts.DwellTime)
+
+	if err := t.store.Set(deviceID, next); err != nil {
+		return nil, fmt.Errorf("can't set device state: %w", err)
+	}
+
+	return events, nil
+}
+
+// diff returns the events between the previous state and the features containing the device at ts,
+// and the new state.
+func diff(deviceID, dataset string, prev *State, ts time.Time, fs []Feature,
+	dwell time.Duration) ([]Event, *State) {
+	current := make(map[featureKey]Feature, len(fs))
+	for _, f := range fs {
+		current[f.key()] = f
+	}
+
+	next := &State{LastSeen: ts}
+
+	var exits, enters, dwells []Event
+
+	previous := make(map[featureKey]struct{}, len(prev.Memberships))
+
+	for _, m := range prev.Memberships {
+		if m.Dataset != dataset {
+			next.Memberships = append(next.Memberships, m)
+
+			continue
+		}
+
+		k := m.key()
+		previous[k] = struct{}{}
+
+		f, ok := current[k]
+		if !ok {
+			exits = append(exits, Event{
+				Type: Exit, DeviceID: deviceID, Feature: m.Feature, Time: ts, EnteredAt: m.EnteredAt,
+			})
+
+			continue
+		}
+
+		// the id changes when the dataset was reindexed
+		m.Feature = f
+		next.Memberships = append(next.Memberships, m)
+	}
+
+	for _, f := range fs {
+		k := f.key()
+		if _, ok := previous[k]; ok {
+			continue
+		}
+
+		// the same feature can be returned several times
+		previous[k] = struct{}{}
+
+		enters = append(enters, Event{
+			Type: Enter, DeviceID: deviceID, Feature: f, Time: ts, EnteredAt: ts,
+		})
+		next.Memberships = append(next.Memberships, Membership{Feature: f, EnteredAt: ts})
+	}
+
+	if dwell > 0 {
+		for i, m := range next.Memberships {
+			if m.Dataset != dataset || m.Dwelled || ts.Sub(m.EnteredAt) < dwell {
+				continue
+			}
+
+			next.Memberships[i].Dwelled = true
+			dwells = append(dwells, Event{
+				Type: Dwell, DeviceID: deviceID, Feature: m.Feature, Time: ts, EnteredAt: m.EnteredAt,
+			})
+		}
+	}
+
+	sort.Slice(next.Memberships, func(i, j int) bool {
+		if next.Memberships[i].Dataset != next.Memberships[j].Dataset {
+			return next.Memberships[i].Dataset < next.Memberships[j].Dataset
+		}
+
+		if next.Memberships[i].ID != next.Memberships[j].ID {
+			return next.Memberships[i].ID < next.Memberships[j].ID
+		}
+
+		return next.Memberships[i].Pos < next.Memberships[j].Pos
+	})
+
+	var events []Event
+
+	for _, evs := range [][]Event{exits, enters, dwells} {
+		sort.Slice(evs, func(i, j int) bool {
+			if evs[i].ID != evs[j].ID {
+				return evs[i].ID < evs[j].ID
+			}
+
+			return evs[i].Pos < evs[j].Pos
+		})
+
+		events = append(events, evs...)
+	}
+
+	return events, next
+}
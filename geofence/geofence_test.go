@@ -0,0 +1,136 @@
+package geofence_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout/geofence"
+)
+
+func TestTracker_Update(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	type update struct {
+		at      time.Duration
+		dataset string
+		fids    []geofence.Feature
+	}
+
+	type event struct {
+		typ geofence.EventType
+		id  uint32
+	}
+
+	tests := []struct {
+		name    string
+		updates []update
+		want    [][]event
+	}{
+		{
+			"enter then exit",
+			[]update{
+				{0, "", nil},
+				{time.Minute, "", []geofence.Feature{{ID: 1}}},
+				{2 * time.Minute, "", nil},
+			},
+			[][]event{nil, {{geofence.Enter, 1}}, {{geofence.Exit, 1}}},
+		},
+		{
+			"moving between features",
+			[]update{
+				{0, "", []geofence.Feature{{ID: 1}, {ID: 2}}},
+				{time.Minute, "", []geofence.Feature{{ID: 2}, {ID: 3}}},
+			},
+			[][]event{
+				{{geofence.Enter, 1}, {geofence.Enter, 2}},
+				{{geofence.Exit, 1}, {geofence.Enter, 3}},
+			},
+		},
+		{
+			"dwell once",
+			[]update{
+				{0, "", []geofence.Feature{{ID: 1}}},
+				{4 * time.Minute, "", []geofence.Feature{{ID: 1}}},
+				{5 * time.Minute, "", []geofence.Feature{{ID: 1}}},
+				{6 * time.Minute, "", []geofence.Feature{{ID: 1}}},
+			},
+			[][]event{{{geofence.Enter, 1}}, nil, {{geofence.Dwell, 1}}, nil},
+		},
+		{
+			"out of order update ignored",
+			[]update{
+				{time.Minute, "", []geofence.Feature{{ID: 1}}},
+				{0, "", nil},
+			},
+			[][]event{{{geofence.Enter, 1}}, nil},
+		},
+		{
+			"reindexed feature keeps its membership",
+			[]update{
+				{0, "", []geofence.Feature{{ExternalID: "a", ID: 1}}},
+				{time.Minute, "", []geofence.Feature{{ExternalID: "a", ID: 4}}},
+				{2 * time.Minute, "", nil},
+			},
+			[][]event{{{geofence.Enter, 1}}, nil, {{geofence.Exit, 4}}},
+		},
+		{
+			"other feature with the same id",
+			[]update{
+				{0, "", []geofence.Feature{{ExternalID: "a", ID: 1}}},
+				{time.Minute, "", []geofence.Feature{{ExternalID: "b", ID: 1}}},
+			},
+			[][]event{{{geofence.Enter, 1}}, {{geofence.Exit, 1}, {geofence.Enter, 1}}},
+		},
+		{
+			"feature without external id in another version",
+			[]update{
+				{0, "", []geofence.Feature{{ID: 1, Version: 1}}},
+				{time.Minute, "", []geofence.Feature{{ID: 1, Version: 2}}},
+			},
+			[][]event{{{geofence.Enter, 1}}, {{geofence.Exit, 1}, {geofence.Enter, 1}}},
+		},
+		{
+			"same feature in two datasets",
+			[]update{
+				{0, "a", []geofence.Feature{{Dataset: "a", ID: 1}}},
+				{time.Minute, "b", []geofence.Feature{{Dataset: "b", ID: 1}}},
+				{2 * time.Minute, "a", nil},
+			},
+			[][]event{{{geofence.Enter, 1}}, {{geofence.Enter, 1}}, {{geofence.Exit, 1}}},
+		},
+		{
+			"duplicate features",
+			[]update{
+				{0, "", []geofence.Feature{{ID: 1}, {ID: 1}}},
+			},
+			[][]event{{{geofence.Enter, 1}}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tracker := geofence.NewTracker(geofence.NewMemoryStore(0), geofence.Options{DwellTime: 5 * time.Minute})
+
+			for i, u := range tt.updates {
+				events, err := tracker.Update("device", u.dataset, start.Add(u.at), u.fids)
+				require.NoError(t, err)
+
+				var got []event
+				for _, e := range events {
+					require.Equal(t, "device", e.DeviceID)
+					got = append(got, event{e.Type, e.ID})
+				}
+
+				require.Equal(t, tt.want[i], got, "update %d", i)
+			}
+		})
+	}
+}
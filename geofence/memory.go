@@ -0,0 +1,89 @@
+package geofence
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultIdleTTL is the time a MemoryStore keeps the state of a device without updates.
+const DefaultIdleTTL = 24 * time.Hour
+
+// MemoryStore an in memory StateStore, states are lost on restart.
+// States not set for idleTTL are forgotten, idle states are removed at most once per idleTTL on Set.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	states  map[string]*memoryState
+	idleTTL time.Duration
+
+	// swept is the last time idle states were removed
+	swept time.Time
+}
+
+type memoryState struct {
+	state *State
+	setAt time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore forgetting devices not updated for idleTTL,
+// 0 keeps them forever.
+func NewMemoryStore(idleTTL time.Duration) *MemoryStore {
+	return &MemoryStore{
+		states:  make(map[string]*memoryState),
+		idleTTL: idleTTL,
+		swept:   time.Now(),
+	}
+}
+
+// Get returns a copy of the state of a device, nil for unknown or idle devices.
+func (m *MemoryStore) Get(deviceID string) (*State, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ms, ok := m.states[deviceID]
+	if !ok || m.idle(ms, time.Now()) {
+		return nil, nil
+	}
+
+	return copyState(ms.state), nil
+}
+
+// Set stores a copy of the state of a device.
+func (m *MemoryStore) Set(deviceID string, state *State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+
+	m.states[deviceID] = &memoryState{state: copyState(state), setAt: now}
+
+	if m.idleTTL > 0 && now.Sub(m.swept) >= m.idleTTL {
+		for id, ms := range m.states {
+			if m.idle(ms, now) {
+				delete(m.states, id)
+			}
+		}
+
+		m.swept = now
+	}
+
+	return nil
+}
+
+// Len returns the count of stored states, idle ones included until removed.
+func (m *MemoryStore) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.states)
+}
+
+func (m *MemoryStore) idle(ms *memoryState, now time.Time) bool {
+	return m.idleTTL > 0 && now.Sub(ms.setAt) >= m.idleTTL
+}
+
+func copyState(s *State) *State {
+	c := &State{LastSeen: s.LastSeen}
+	c.Memberships = append(c.Memberships, s.Memberships...)
+
+	return c
+}
@@ -0,0 +1,51 @@
+package geofence_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout/geofence"
+)
+
+func TestMemoryStore_IdleTTL(t *testing.T) {
+	ttl := 50 * time.Millisecond
+	m := geofence.NewMemoryStore(ttl)
+
+	state := &geofence.State{LastSeen: time.Now()}
+
+	require.NoError(t, m.Set("idle", state))
+
+	got, err := m.Get("idle")
+	require.NoError(t, err)
+	require.Equal(t, state.LastSeen, got.LastSeen)
+
+	time.Sleep(ttl)
+
+	// idle states are not returned
+	got, err = m.Get("idle")
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	// then removed by the next Set
+	require.NoError(t, m.Set("active", state))
+	require.Equal(t, 1, m.Len())
+
+	got, err = m.Get("active")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+}
+
+func TestMemoryStore_NoTTL(t *testing.T) {
+	m := geofence.NewMemoryStore(0)
+
+	require.NoError(t, m.Set("device", &geofence.State{}))
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, m.Set("other", &geofence.State{}))
+	require.Equal(t, 2, m.Len())
+
+	got, err := m.Get("device")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+}
@@ -0,0 +1,385 @@
+package insideout
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+// DecodeFeatureCollection decodes a GeoJSON FeatureCollection,
+// unlike encoding/json integer properties are decoded as int64 or uint64 to preserve large values.
+func DecodeFeatureCollection(r io.Reader) (*geojson.FeatureCollection, error) {
+	var raw struct {
+		Type     string            `json:"type"`
+		Features []json.RawMessage `json:"features"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	if raw.Type != "FeatureCollection" {
+		return nil, geojson.ErrUnsupportedType(raw.Type)
+	}
+
+	fc := &geojson.FeatureCollection{Features: make([]*geojson.Feature, len(raw.Features))}
+
+	for i, data := range raw.Features {
+		f, err := DecodeFeature(data)
+		if err != nil {
+			return nil, fmt.Errorf("can't decode feature #%d: %w", i, err)
+		}
+
+		fc.Features[i] = f
+	}
+
+	return fc, nil
+}
+
+// FeatureIterator iterates over features, Next returns io.EOF after the last one.
+type FeatureIterator interface {
+	Next() (*geojson.Feature, error)
+}
+
+type featureCollectionIterator struct {
+	features []*geojson.Feature
+}
+
+// NewFeatureCollectionIterator returns an iterator over the features of fc.
+func NewFeatureCollectionIterator(fc *geojson.FeatureCollection) FeatureIterator {
+	return &featureCollectionIterator{features: fc.Features}
+}
+
+func (it *featureCollectionIterator) Next() (*geojson.Feature, error) {
+	if len(it.features) == 0 {
+		return nil, io.EOF
+	}
+
+	f := it.features[0]
+	it.features = it.features[1:]
+
+	return f, nil
+}
+
+type decoderState int
+
+const (
+	stateStart decoderState = iota
+	stateCollection
+	stateSequence
+	stateDone
+)
+
+// FeatureDecoder reads features one at a time from a GeoJSON FeatureCollection
+// or from a GeoJSON text sequence (RFC 8142) with or without record separators,
+// only one feature is kept in memory.
+type FeatureDecoder struct {
+	dec   *json.Decoder
+	state decoderState
+	count int
+}
+
+// NewFeatureDecoder returns a FeatureDecoder reading from r.
+func NewFeatureDecoder(r io.Reader) *FeatureDecoder {
+	return &FeatureDecoder{dec: json.NewDecoder(&rsReader{r: r})}
+}
+
+// Next returns the next feature, io.EOF when there are no more features.
+func (d *FeatureDecoder) Next() (*geojson.Feature, error) {
+	for {
+		switch d.state {
+		case stateStart:
+			f, err := d.start()
+			if err != nil || f != nil {
+				return f, err
+			}
+		case stateCollection:
+			if !d.dec.More() {
+				return nil, d.end()
+			}
+
+			return d.decodeFeature()
+		case stateSequence:
+			return d.decodeFeature()
+		default:
+			return nil, io.EOF
+		}
+	}
+}
+
+// start reads the first object, up to the features array of a collection,
+// or the whole object when it's the first feature of a sequence.
+func (d *FeatureDecoder) start() (*geojson.Feature, error) {
+	d.state = stateDone
+
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected a GeoJSON object got %v", tok)
+	}
+
+	fields := make(map[string]json.RawMessage)
+
+	for d.dec.More() {
+		tok, err := d.token()
+		if err != nil {
+			return nil, err
+		}
+
+		key, _ := tok.(string)
+		if key == "features" {
+			if err := checkType(fields["type"], "FeatureCollection"); err != nil {
+				return nil, err
+			}
+
+			tok, err := d.token()
+			if err != nil {
+				return nil, err
+			}
+
+			if tok != json.Delim('[') {
+				return nil, fmt.Errorf("expected a features array got %v", tok)
+			}
+
+			d.state = stateCollection
+
+			return nil, nil
+		}
+
+		var raw json.RawMessage
+		if err := d.dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+
+		fields[key] = raw
+	}
+
+	if _, err := d.token(); err != nil {
+		return nil, err
+	}
+
+	// a collection without features
+	if typeOf(fields["type"]) == "FeatureCollection" {
+		return nil, io.EOF
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	d.state = stateSequence
+
+	return d.feature(data)
+}
+
+// end reads the end of the collection after the features array.
+func (d *FeatureDecoder) end() error {
+	d.state = stateDone
+
+	// ]
+	if _, err := d.token(); err != nil {
+		return err
+	}
+
+	for d.dec.More() {
+		tok, err := d.token()
+		if err != nil {
+			return err
+		}
+
+		var raw json.RawMessage
+		if err := d.dec.Decode(&raw); err != nil {
+			return err
+		}
+
+		if tok == "type" {
+			if err := checkType(raw, "FeatureCollection"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := d.token(); err != nil {
+		return err
+	}
+
+	return io.EOF
+}
+
+// token returns the next JSON token, the input ending is unexpected inside an object.
+func (d *FeatureDecoder) token() (json.Token, error) {
+	tok, err := d.dec.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return tok, err
+}
+
+func (d *FeatureDecoder) decodeFeature() (*geojson.Feature, error) {
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		if err != io.EOF {
+			d.state = stateDone
+		}
+
+		return nil, err
+	}
+
+	return d.feature(raw)
+}
+
+func (d *FeatureDecoder) feature(data []byte) (*geojson.Feature, error) {
+	f, err := DecodeFeature(data)
+	if err != nil {
+		d.state = stateDone
+
+		return nil, fmt.Errorf("can't decode feature #%d: %w", d.count, err)
+	}
+
+	d.count++
+
+	return f, nil
+}
+
+// checkType returns an error if the raw GeoJSON type is set and is not typ.
+func checkType(raw json.RawMessage, typ string) error {
+	if raw == nil {
+		return nil
+	}
+
+	if t := typeOf(raw); t != typ {
+		return geojson.ErrUnsupportedType(t)
+	}
+
+	return nil
+}
+
+// typeOf returns the raw GeoJSON type, empty when not a string.
+func typeOf(raw json.RawMessage) string {
+	var t string
+
+	_ = json.Unmarshal(raw, &t)
+
+	return t
+}
+
+// rsReader replaces the RFC 8142 record separators by spaces so the records can be read as a JSON stream.
+type rsReader struct {
+	r io.Reader
+}
+
+func (r *rsReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+
+	for i, c := range p[:n] {
+		if c == 0x1e {
+			p[i] = ' '
+		}
+	}
+
+	return n, err
+}
+
+// DecodeFeature decodes a GeoJSON Feature, integer properties are decoded as int64 or uint64,
+// a numeric feature id is converted to its string form.
+func DecodeFeature(data []byte) (*geojson.Feature, error) {
+	var gf struct {
+		Type       string                 `json:"type"`
+		ID         interface{}            `json:"id"`
+		Geometry   *geojson.Geometry      `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(&gf); err != nil {
+		return nil, err
+	}
+
+	if gf.Type != "Feature" {
+		return nil, geojson.ErrUnsupportedType(gf.Type)
+	}
+
+	g, err := gf.Geometry.Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range gf.Properties {
+		gf.Properties[k] = decodeNumbers(v)
+	}
+
+	f := &geojson.Feature{Geometry: g, Properties: gf.Properties}
+
+	if gf.ID != nil {
+		id, ok := PropertyID(decodeNumbers(gf.ID))
+		if !ok {
+			return nil, fmt.Errorf("invalid feature id %v", gf.ID)
+		}
+
+		f.ID = id
+	}
+
+	return f, nil
+}
+
+// PropertyID returns the string form of a feature id or of a property used as id,
+// ok is false for values other than strings and numbers.
+func PropertyID(v interface{}) (string, bool) {
+	switch tv := v.(type) {
+	case string:
+		return tv, tv != ""
+	case int:
+		return strconv.Itoa(tv), true
+	case int64:
+		return strconv.FormatInt(tv, 10), true
+	case uint64:
+		return strconv.FormatUint(tv, 10), true
+	case float64:
+		return strconv.FormatFloat(tv, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// decodeNumbers replaces json.Number in v by int64, uint64 or float64.
+func decodeNumbers(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case json.Number:
+		if i, err := tv.Int64(); err == nil {
+			return i
+		}
+
+		if u, err := strconv.ParseUint(tv.String(), 10, 64); err == nil {
+			return u
+		}
+
+		f, _ := tv.Float64()
+
+		return f
+	case []interface{}:
+		for i, e := range tv {
+			tv[i] = decodeNumbers(e)
+		}
+
+		return tv
+	case map[string]interface{}:
+		for k, e := range tv {
+			tv[k] = decodeNumbers(e)
+		}
+
+		return tv
+	default:
+		return v
+	}
+}
@@ -0,0 +1,191 @@
+package insideout_test
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+const nestedPropertiesFC = `{"type": "FeatureCollection", "features": [
+	{"type": "Feature", "geometry": {"type": "Point", "coordinates": [2.0, 48.0]}, "properties": {
+		"osm_id": 9007199254740993,
+		"negative": -9007199254740993,
+		"max": 18446744073709551615,
+		"admin_level": 8,
+		"ratio": 1.5,
+		"name": "Paris",
+		"capital": true,
+		"missing": null,
+		"tags": ["a", 1, null, {"k": true}],
+		"names": {"fr": "Paris", "ranks": {"n": 2}}
+	}}
+]}`
+
+func TestDecodeFeatureCollection(t *testing.T) {
+	t.Parallel()
+
+	fc, err := insideout.DecodeFeatureCollection(strings.NewReader(nestedPropertiesFC))
+	require.NoError(t, err)
+	require.Len(t, fc.Features, 1)
+	require.NotNil(t, fc.Features[0].Geometry)
+
+	props := fc.Features[0].Properties
+	require.Equal(t, int64(9007199254740993), props["osm_id"])
+	require.Equal(t, int64(-9007199254740993), props["negative"])
+	require.Equal(t, uint64(math.MaxUint64), props["max"])
+	require.Equal(t, int64(8), props["admin_level"])
+	require.Equal(t, 1.5, props["ratio"])
+	require.Equal(t, []interface{}{"a", int64(1), nil, map[string]interface{}{"k": true}}, props["tags"])
+}
+
+func TestDecodeFeature_ID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{"string", `"way/42"`, "way/42", false},
+		{"integer", `9007199254740993`, "9007199254740993", false},
+		{"float", `1.5`, "1.5", false},
+		{"missing", `null`, "", false},
+		{"invalid", `true`, "", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := insideout.DecodeFeature([]byte(`{"type": "Feature", "id": ` + tt.id +
+				`, "geometry": {"type": "Point", "coordinates": [2.0, 48.0]}, "properties": {}}`))
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, f.ID)
+		})
+	}
+}
+
+func TestFeatureDecoder(t *testing.T) {
+	t.Parallel()
+
+	const (
+		fa = `{"type": "Feature", "id": "a", "geometry": {"type": "Point", "coordinates": [2.0, 48.0]}, "properties": {}}`
+		fb = `{"properties": {"n": 2}, "id": 2, "type": "Feature", "geometry": {"type": "Point", "coordinates": [3.0, 49.0]}}`
+	)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"collection", `{"type": "FeatureCollection", "features": [` + fa + `,` + fb + `]}`, []string{"a", "2"}, false},
+		{"collection type last", `{"features": [` + fa + `], "bbox": [0, 0, 1, 1], "type": "FeatureCollection"}`, []string{"a"}, false},
+		{"empty collection", `{"type": "FeatureCollection", "features": []}`, nil, false},
+		{"collection without features", `{"type": "FeatureCollection"}`, nil, false},
+		{"sequence", fa + "\n" + fb + "\n", []string{"a", "2"}, false},
+		{"sequence with record separators", "\x1e" + fa + "\n\x1e" + fb + "\n", []string{"a", "2"}, false},
+		{"single feature", fb, []string{"2"}, false},
+		{"empty", "", nil, false},
+		{"truncated collection", `{"type": "FeatureCollection", "features": [` + fa, []string{"a"}, true},
+		{"wrong collection type", `{"type": "Feature", "features": []}`, nil, true},
+		{"invalid feature in sequence", fa + "\n" + `{"type": "Point", "coordinates": [2.0, 48.0]}`, []string{"a"}, true},
+		{"not an object", `[]`, nil, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dec := insideout.NewFeatureDecoder(strings.NewReader(tt.input))
+
+			var (
+				ids []string
+				err error
+			)
+
+			for {
+				var f *geojson.Feature
+
+				f, err = dec.Next()
+				if err != nil {
+					break
+				}
+
+				ids = append(ids, f.ID)
+			}
+
+			if tt.wantErr {
+				require.NotEqual(t, io.EOF, err)
+			} else {
+				require.Equal(t, io.EOF, err)
+			}
+
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestDecodeFeatureCollection_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := insideout.DecodeFeatureCollection(strings.NewReader(`{"type": "Feature"}`))
+	require.Error(t, err)
+
+	_, err = insideout.DecodeFeatureCollection(strings.NewReader(`{"type": "FeatureCollection", "features": [{"type": "Point"}]}`))
+	require.Error(t, err)
+}
+
+func TestPropertiesToValues_CBORRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	fc, err := insideout.DecodeFeatureCollection(strings.NewReader(nestedPropertiesFC))
+	require.NoError(t, err)
+
+	// same encoding as the storage
+	b := new(bytes.Buffer)
+	err = cbor.NewEncoder(b, cbor.CanonicalEncOptions()).Encode(&insideout.FeatureStorage{Properties: fc.Features[0].Properties})
+	require.NoError(t, err)
+
+	fs := &insideout.FeatureStorage{}
+	require.NoError(t, cbor.NewDecoder(b).Decode(fs))
+
+	values := insideout.PropertiesToValues(&insideout.Feature{Properties: fs.Properties}, nil)
+
+	want := map[string]interface{}{
+		"osm_id":      "9007199254740993",
+		"negative":    "-9007199254740993",
+		"max":         "18446744073709551615",
+		"admin_level": 8.0,
+		"ratio":       1.5,
+		"name":        "Paris",
+		"capital":     true,
+		"tags":        []interface{}{"a", 1.0, nil, map[string]interface{}{"k": true}},
+		"names":       map[string]interface{}{"fr": "Paris", "ranks": map[string]interface{}{"n": 2.0}},
+	}
+
+	got := insideout.ValueToProperties(values)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("PropertiesToValues() mismatch (-want +got):\n%s", diff)
+	}
+}
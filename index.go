@@ -1,6 +1,9 @@
 package insideout
 
 import (
+	"runtime"
+	"sync"
+
 	"github.com/golang/geo/s2"
 )
 
@@ -8,6 +11,83 @@ import (
 type Index interface {
 	// Stab returns ids of polygon we are inside and polygons we may be inside
 	Stab(lat, lng float64) (IndexResponse, error)
+
+	// StabBatch returns Stab responses for every points in the same order
+	StabBatch(lls []s2.LatLng) ([]IndexResponse, error)
+
+	// Intersects returns ids of polygons intersecting p and polygons that may intersect p
+	Intersects(p *s2.Polygon) (IndexResponse, error)
+}
+
+// queryCoverer is used to cover the regions of queries
+var queryCoverer = &s2.RegionCoverer{MaxLevel: 30, MaxCells: 16}
+
+// QueryCoverings returns the interior covering and the covering of a queried region,
+// polygons with an inside cover intersecting the interior covering are intersecting the region,
+// polygons with an outside cover intersecting the covering may intersect the region.
+func QueryCoverings(p *s2.Polygon) (s2.CellUnion, s2.CellUnion) {
+	return queryCoverer.InteriorCovering(p), queryCoverer.Covering(p)
+}
+
+// StabFunc stabs one point.
+type StabFunc func(ll s2.LatLng) (IndexResponse, error)
+
+// StabParallel stabs the points using one worker per CPU,
+// newStab is called once per worker so it can return a non concurrent safe StabFunc.
+// Responses are in the same order as lls.
+func StabParallel(lls []s2.LatLng, newStab func() StabFunc) ([]IndexResponse, error) {
+	resps := make([]IndexResponse, len(lls))
+	if len(lls) == 0 {
+		return resps, nil
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(lls) {
+		workers = len(lls)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		err     error
+	)
+
+	// each worker stabs a contiguous chunk
+	chunk := (len(lls) + workers - 1) / workers
+
+	for w := 0; w < workers; w++ {
+		start, end := w*chunk, (w+1)*chunk
+		if end > len(lls) {
+			end = len(lls)
+		}
+
+		wg.Add(1)
+
+		go func(start, end int) {
+			defer wg.Done()
+
+			stab := newStab()
+
+			for i := start; i < end; i++ {
+				resp, serr := stab(lls[i])
+				if serr != nil {
+					errOnce.Do(func() { err = serr })
+
+					return
+				}
+
+				resps[i] = resp
+			}
+		}(start, end)
+	}
+
+	wg.Wait()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resps, nil
 }
 
 // IndexResponse a response to find back a feature from an index.
@@ -26,6 +106,9 @@ type FeatureIndexResponse struct {
 
 // Feature representation in memory
 type Feature struct {
-	Loops      []*s2.Loop
+	// Polygons, one per polygon in case of multipolygon, holes included
+	Polygons   []*s2.Polygon
 	Properties map[string]interface{}
+	// ExternalID the GeoJSON id or the property chosen at indexing, empty if none
+	ExternalID string
 }
@@ -1,6 +1,8 @@
 package dbindex
 
 import (
+	"github.com/golang/geo/s2"
+
 	"github.com/akhenakh/insideout"
 )
 
@@ -29,3 +31,42 @@ func New(storage insideout.Store, opts Options) *Index {
 func (idx *Index) Stab(lat, lng float64) (insideout.IndexResponse, error) {
 	return idx.storage.StabDB(lat, lng, idx.opts.StopOnInsideFound)
 }
+
+// StabBatch returns Stab responses for every points in the same order,
+// the storage is read in one ordered pass.
+func (idx *Index) StabBatch(lls []s2.LatLng) ([]insideout.IndexResponse, error) {
+	return idx.storage.StabBatchDB(lls, idx.opts.StopOnInsideFound)
+}
+
+// Intersects returns polygon's ids intersecting p and polygon's ids that may intersect p.
+func (idx *Index) Intersects(p *s2.Polygon) (insideout.IndexResponse, error) {
+	var idxResp insideout.IndexResponse
+
+	interior, covering := insideout.QueryCoverings(p)
+
+	in, err := idx.storage.IntersectsDB(interior)
+	if err != nil {
+		return idxResp, err
+	}
+
+	out, err := idx.storage.IntersectsDB(covering)
+	if err != nil {
+		return idxResp, err
+	}
+
+	idxResp.IDsInside = in.IDsInside
+
+	mi := make(map[insideout.FeatureIndexResponse]struct{}, len(in.IDsInside))
+	for _, res := range in.IDsInside {
+		mi[res] = struct{}{}
+	}
+
+	for _, res := range append(out.IDsInside, out.IDsMayBeInside...) {
+		// remove any answer matching inside
+		if _, ok := mi[res]; !ok {
+			idxResp.IDsMayBeInside = append(idxResp.IDsMayBeInside, res)
+		}
+	}
+
+	return idxResp, nil
+}
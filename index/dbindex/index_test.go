@@ -1,25 +1,17 @@
 package dbindex_test
 
 import (
-	"encoding/json"
-	"io/ioutil"
-	"os"
 	"testing"
 
-	"github.com/go-kit/kit/log"
-	"github.com/golang/geo/s2"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/require"
-	"github.com/twpayne/go-geom/encoding/geojson"
 
 	"github.com/akhenakh/insideout"
 	"github.com/akhenakh/insideout/index/dbindex"
-	"github.com/akhenakh/insideout/storage/bbolt"
+	"github.com/akhenakh/insideout/index/indextest"
 )
 
 func TestDBIndex_Stab(t *testing.T) {
-	dbidx, _ := setup(t)
-
 	t.Parallel()
 
 	tests := []struct {
@@ -73,77 +65,138 @@ func TestDBIndex_Stab(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"inside hole not within inside index",
+			48.05, 2.05,
+			insideout.IndexResponse{
+				IDsInside: nil,
+				IDsMayBeInside: []insideout.FeatureIndexResponse{{
+					ID:  1,
+					Pos: 0,
+				}},
+			},
+			false,
+		},
 	}
 
-	// This Run will not return until the parallel tests finish.
-	t.Run("group", func(t *testing.T) {
-		for _, tt := range tests {
-			tt := tt
-
-			t.Run(tt.name, func(t *testing.T) {
-				t.Parallel()
-
-				got, err := dbidx.Stab(tt.lat, tt.lng)
-				if (err != nil) != tt.wantErr {
-					t.Fatalf("Stab() error = %v, wantErr %v", err, tt.wantErr)
-				}
-				if !cmp.Equal(got, tt.want) {
-					t.Fatalf("Stab() got = %v, want %v", got, tt.want)
-				}
-			})
-		}
-	})
+	for _, e := range indextest.Engines() {
+		dbidx, clean := setup(t, e)
+
+		// This Run will not return until the parallel tests finish.
+		t.Run(e.Name, func(t *testing.T) {
+			for _, tt := range tests {
+				tt := tt
+
+				t.Run(tt.name, func(t *testing.T) {
+					t.Parallel()
+
+					got, err := dbidx.Stab(tt.lat, tt.lng)
+					if (err != nil) != tt.wantErr {
+						t.Fatalf("Stab() error = %v, wantErr %v", err, tt.wantErr)
+					}
+					if !cmp.Equal(got, tt.want) {
+						t.Fatalf("Stab() got = %v, want %v", got, tt.want)
+					}
+				})
+			}
+		})
+
+		clean()
+	}
 }
 
-func setup(t *testing.T) (*dbindex.Index, func()) {
-	t.Helper()
+func TestDBIndex_Intersects(t *testing.T) {
+	tests := []struct {
+		name string
+		bbox [4]float64
+		want insideout.IndexResponse
+	}{
+		{
+			"bbox around polygon with hole",
+			[4]float64{47.99, 1.99, 48.11, 2.11},
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{{
+					ID:  1,
+					Pos: 0,
+				}},
+				IDsMayBeInside: nil,
+			},
+		},
+		{
+			"bbox inside hole",
+			[4]float64{48.045, 2.045, 48.055, 2.055},
+			insideout.IndexResponse{
+				IDsInside: nil,
+				IDsMayBeInside: []insideout.FeatureIndexResponse{{
+					ID:  1,
+					Pos: 0,
+				}},
+			},
+		},
+		{
+			"bbox outside",
+			[4]float64{10, 10, 11, 11},
+			insideout.IndexResponse{
+				IDsInside:      nil,
+				IDsMayBeInside: nil,
+			},
+		},
+		{
+			"bbox on island",
+			[4]float64{47.38, -2.97, 47.40, -2.95},
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{{
+					ID:  0,
+					Pos: 1,
+				}},
+				IDsMayBeInside: nil,
+			},
+		},
+	}
+
+	for _, e := range indextest.Engines() {
+		dbidx, clean := setup(t, e)
 
-	logger := log.NewLogfmtLogger(os.Stdout)
+		t.Run(e.Name, func(t *testing.T) {
+			for _, tt := range tests {
+				tt := tt
 
-	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
-	require.NoError(t, err)
-	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
-	require.NoError(t, err)
+				t.Run(tt.name, func(t *testing.T) {
+					p, err := insideout.PolygonFromBBox(tt.bbox[0], tt.bbox[1], tt.bbox[2], tt.bbox[3])
+					require.NoError(t, err)
 
-	t.Log("db path", tmpFile.Name())
+					got, err := dbidx.Intersects(p)
+					require.NoError(t, err)
 
-	var fc geojson.FeatureCollection
+					if !cmp.Equal(got, tt.want) {
+						t.Fatalf("Intersects() got = %v, want %v", got, tt.want)
+					}
+				})
+			}
+		})
 
-	file, err := os.Open("../testdata/poly.geojson")
-	require.NoError(t, err)
+		clean()
+	}
+}
 
-	defer file.Close()
+func TestDBIndex_StabBatch(t *testing.T) {
+	for _, e := range indextest.Engines() {
+		dbidx, clean := setup(t, e)
 
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&fc)
-	require.NoError(t, err)
+		t.Run(e.Name, func(t *testing.T) {
+			indextest.TestStabBatch(t, dbidx)
+		})
 
-	icoverer := &s2.RegionCoverer{
-		MinLevel: 10,
-		MaxLevel: 16,
-		MaxCells: 24,
-	}
-	ocoverer := &s2.RegionCoverer{
-		MinLevel: 10,
-		MaxLevel: 15,
-		MaxCells: 16,
+		clean()
 	}
+}
 
-	err = wstorage.Index(fc, icoverer, ocoverer, 100, "poly.geojson", "unittest")
-	require.NoError(t, err)
-
-	err = wclose()
-	require.NoError(t, err)
+func setup(t *testing.T, e indextest.Engine) (*dbindex.Index, func()) {
+	t.Helper()
 
-	// RO storage
-	storage, bclose, err := bbolt.NewROStorage(tmpFile.Name(), logger)
-	require.NoError(t, err)
+	storage, clean := e.Open(t)
 
 	dbidx := dbindex.New(storage, dbindex.Options{StopOnInsideFound: true})
 
-	return dbidx, func() {
-		t.Log("clean called")
-		bclose()
-		os.Remove(tmpFile.Name())
-	}
+	return dbidx, clean
 }
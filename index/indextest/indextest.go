@@ -0,0 +1,60 @@
+// Package indextest implements tests shared by the indexes, every index must pass TestStabBatch
+// on the storages of Engines.
+package indextest
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/insideout"
+)
+
+// TestStabBatch checks the StabBatch responses of idx, loaded with index/testdata/poly.geojson,
+// are the Stab responses of each point in the same order.
+func TestStabBatch(t *testing.T, idx insideout.Index) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		lls  []s2.LatLng
+	}{
+		{"empty", nil},
+		{"one point", []s2.LatLng{s2.LatLngFromDegrees(48.02, 2.02)}},
+		{"inside outside and duplicated points", []s2.LatLng{
+			s2.LatLngFromDegrees(47.39444367083928, -2.992874768945723),
+			s2.LatLngFromDegrees(48.05, 2.05),
+			s2.LatLngFromDegrees(47.39650628189986, -2.9876390969486524),
+			s2.LatLngFromDegrees(48.02, 2.02),
+			s2.LatLngFromDegrees(47.37616957736262, -3.004367209321472),
+			s2.LatLngFromDegrees(-16.5, 179.5),
+			s2.LatLngFromDegrees(48.05, 2.05),
+		}},
+	}
+
+	sortResps := cmpopts.SortSlices(func(a, b insideout.FeatureIndexResponse) bool {
+		return a.ID < b.ID || (a.ID == b.ID && a.Pos < b.Pos)
+	})
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := idx.StabBatch(tt.lls)
+			require.NoError(t, err)
+			require.Len(t, got, len(tt.lls))
+
+			for i, ll := range tt.lls {
+				want, err := idx.Stab(ll.Lat.Degrees(), ll.Lng.Degrees())
+				require.NoError(t, err)
+
+				if !cmp.Equal(got[i], want, sortResps, cmpopts.EquateEmpty()) {
+					t.Fatalf("StabBatch() point %d got = %v, want %v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
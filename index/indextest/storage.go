@@ -0,0 +1,101 @@
+package indextest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/bbolt"
+	"github.com/akhenakh/insideout/storage/memory"
+)
+
+// polyPath is index/testdata/poly.geojson relative to the indexes packages.
+const polyPath = "../testdata/poly.geojson"
+
+// Engine a storage engine the indexes are tested on.
+type Engine struct {
+	Name string
+	// Open returns a storage loaded with index/testdata/poly.geojson and its clean function
+	Open func(t *testing.T) (insideout.Store, func())
+}
+
+// Engines returns the engines every index is tested on:
+// bbolt opened read only as served by insided, and memory.
+func Engines() []Engine {
+	return []Engine{
+		{Name: "bbolt", Open: openBBolt},
+		{Name: "memory", Open: openMemory},
+	}
+}
+
+func openBBolt(t *testing.T) (insideout.Store, func()) {
+	t.Helper()
+
+	logger := log.NewNopLogger()
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+
+	t.Log("db path", tmpFile.Name())
+
+	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	indexPoly(t, wstorage)
+
+	require.NoError(t, wclose())
+
+	storage, rclose, err := bbolt.NewROStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	return storage, func() {
+		rclose()
+		os.Remove(tmpFile.Name())
+	}
+}
+
+func openMemory(t *testing.T) (insideout.Store, func()) {
+	t.Helper()
+
+	storage := memory.NewStorage(log.NewNopLogger())
+
+	indexPoly(t, storage)
+
+	return storage, func() {}
+}
+
+// indexPoly indexes index/testdata/poly.geojson into storage.
+func indexPoly(t *testing.T, storage insideout.Store) {
+	t.Helper()
+
+	file, err := os.Open(polyPath)
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	var fc geojson.FeatureCollection
+
+	err = json.NewDecoder(file).Decode(&fc)
+	require.NoError(t, err)
+
+	icoverer := &s2.RegionCoverer{
+		MinLevel: 10,
+		MaxLevel: 16,
+		MaxCells: 24,
+	}
+	ocoverer := &s2.RegionCoverer{
+		MinLevel: 10,
+		MaxLevel: 15,
+		MaxCells: 16,
+	}
+
+	err = storage.Index(insideout.NewFeatureCollectionIterator(&fc), icoverer, ocoverer, 100, "poly.geojson", "unittest")
+	require.NoError(t, err)
+}
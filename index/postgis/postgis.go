@@ -6,8 +6,11 @@ import (
 	"time"
 
 	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
 	"github.com/jackc/pgx/v4/log/kitlogadapter"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/wkt"
 
 	"github.com/akhenakh/insideout"
 )
@@ -66,3 +69,58 @@ func (idx *Index) Stab(lat, lng float64) (insideout.IndexResponse, error) {
 
 	return idxResp, nil
 }
+
+// StabBatch returns Stab responses for every points in the same order, one query per point.
+func (idx *Index) StabBatch(lls []s2.LatLng) ([]insideout.IndexResponse, error) {
+	resps := make([]insideout.IndexResponse, len(lls))
+
+	for i, ll := range lls {
+		resp, err := idx.Stab(ll.Lat.Degrees(), ll.Lng.Degrees())
+		if err != nil {
+			return nil, err
+		}
+
+		resps[i] = resp
+	}
+
+	return resps, nil
+}
+
+// Intersects returns polygon's ids intersecting p,
+// in case of this index the intersection is exact.
+func (idx *Index) Intersects(p *s2.Polygon) (insideout.IndexResponse, error) {
+	var idxResp insideout.IndexResponse
+
+	coords, endss := insideout.CoordinatesFromPolygon(p)
+
+	w, err := wkt.Marshal(geom.NewMultiPolygonFlat(geom.XY, coords, endss))
+	if err != nil {
+		return idxResp, fmt.Errorf("can't encode polygon to WKT: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
+	defer cancel()
+
+	q := fmt.Sprintf(`SELECT ogc_fid FROM france
+			WHERE ST_Intersects(wkb_geometry,
+				ST_Transform(ST_GeomFromText('%s', 4326), 4326)
+			)`, w)
+
+	rows, err := idx.Query(ctx, q)
+	if err != nil {
+		return idxResp, err
+	}
+
+	for rows.Next() {
+		var ogcFID int
+		if err := rows.Scan(&ogcFID); err != nil {
+			return idxResp, err
+		}
+
+		res := insideout.FeatureIndexResponse{}
+		res.ID = uint32(ogcFID)
+		idxResp.IDsInside = append(idxResp.IDsInside, res)
+	}
+
+	return idxResp, nil
+}
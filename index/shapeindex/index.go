@@ -1,8 +1,8 @@
 package shapeindex
 
 import (
-	"bytes"
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/golang/geo/s2"
@@ -12,16 +12,25 @@ import (
 
 // Index using s2.ShapeIndexStrategy.
 type Index struct {
-	sync.Mutex
+	sync.RWMutex
 	*s2.ShapeIndex
-	*s2.ContainsPointQuery
+
+	// vertices the cell of the first vertex of every shape sorted by cell,
+	// to find the shapes lying inside a region
+	vertices []shapeVertex
+	sorted   bool
 }
 
-type indexedLoop struct {
-	*s2.Loop
+type indexedPolygon struct {
+	*s2.Polygon
 	insideout.FeatureIndexResponse
 }
 
+type shapeVertex struct {
+	cell    s2.CellID
+	shapeID int32
+}
+
 func New() *Index {
 	return &Index{
 		ShapeIndex: s2.NewShapeIndex(),
@@ -32,21 +41,29 @@ func (idx *Index) Add(si *insideout.FeatureStorage, id uint32) error {
 	idx.Lock()
 	defer idx.Unlock()
 
-	for i := 0; i < len(si.LoopsBytes); i++ {
-		l := &s2.Loop{}
-		if err := l.Decode(bytes.NewReader(si.LoopsBytes[i])); err != nil {
-			return err
-		}
+	polygons, err := si.Polygons()
+	if err != nil {
+		return err
+	}
 
-		il := indexedLoop{
-			Loop: l,
+	for i, p := range polygons {
+		ip := indexedPolygon{
+			Polygon: p,
 			FeatureIndexResponse: insideout.FeatureIndexResponse{
 				ID:  id,
 				Pos: uint16(i),
 			},
 		}
 
-		idx.ShapeIndex.Add(il)
+		shapeID := idx.ShapeIndex.Add(ip)
+
+		if p.NumLoops() > 0 {
+			idx.vertices = append(idx.vertices, shapeVertex{
+				cell:    s2.CellFromPoint(p.Loop(0).Vertex(0)).ID(),
+				shapeID: shapeID,
+			})
+			idx.sorted = false
+		}
 	}
 
 	return nil
@@ -55,27 +72,138 @@ func (idx *Index) Add(si *insideout.FeatureStorage, id uint32) error {
 // Stab returns polygon's ids we are inside and polygon's ids we may be inside
 // in case of this index we are always in.
 func (idx *Index) Stab(lat, lng float64) (insideout.IndexResponse, error) {
-	idx.Lock()
-	defer idx.Unlock()
+	idx.RLock()
+	defer idx.RUnlock()
+
+	q := s2.NewContainsPointQuery(idx.ShapeIndex, s2.VertexModelOpen)
+
+	return containing(q, s2.LatLngFromDegrees(lat, lng))
+}
+
+// StabBatch returns Stab responses for every points in the same order,
+// points are processed in parallel, each worker using its own query.
+func (idx *Index) StabBatch(lls []s2.LatLng) ([]insideout.IndexResponse, error) {
+	idx.RLock()
+	defer idx.RUnlock()
+
+	return insideout.StabParallel(lls, func() insideout.StabFunc {
+		q := s2.NewContainsPointQuery(idx.ShapeIndex, s2.VertexModelOpen)
 
-	p := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+		return func(ll s2.LatLng) (insideout.IndexResponse, error) {
+			return containing(q, ll)
+		}
+	})
+}
+
+// containing returns the polygons containing ll.
+func containing(q *s2.ContainsPointQuery, ll s2.LatLng) (insideout.IndexResponse, error) {
+	var idxResp insideout.IndexResponse
+
+	for _, shape := range q.ContainingShapes(s2.PointFromLatLng(ll)) {
+		ip, ok := shape.(indexedPolygon)
+		if !ok {
+			return idxResp, errors.New("invalid type read from db")
+		}
+
+		idxResp.IDsInside = append(idxResp.IDsInside, ip.FeatureIndexResponse)
+	}
+
+	return idxResp, nil
+}
 
+// Intersects returns polygon's ids intersecting p,
+// in case of this index the intersection is exact.
+// Candidates are the polygons crossing p edges, containing p or with a vertex in p cover.
+func (idx *Index) Intersects(p *s2.Polygon) (insideout.IndexResponse, error) {
 	var idxResp insideout.IndexResponse
 
-	if idx.ContainsPointQuery == nil {
-		idx.ContainsPointQuery = s2.NewContainsPointQuery(idx.ShapeIndex, s2.VertexModelOpen)
+	// vertices added since the last query are sorted first
+	for {
+		idx.sortVertices()
+		idx.RLock()
+
+		if idx.sorted {
+			break
+		}
+
+		idx.RUnlock()
 	}
+	defer idx.RUnlock()
 
-	shapes := idx.ContainsPointQuery.ContainingShapes(p)
+	candidates := make(map[insideout.FeatureIndexResponse]*s2.Polygon)
 
-	for _, shape := range shapes {
-		il, ok := shape.(indexedLoop)
+	add := func(shape s2.Shape) error {
+		ip, ok := shape.(indexedPolygon)
 		if !ok {
-			return idxResp, errors.New("invalid type read from db")
+			return errors.New("invalid type read from db")
+		}
+
+		candidates[ip.FeatureIndexResponse] = ip.Polygon
+
+		return nil
+	}
+
+	// polygons crossing p
+	eq := s2.NewCrossingEdgeQuery(idx.ShapeIndex)
+
+	for _, l := range p.Loops() {
+		for i := 0; i < l.NumEdges(); i++ {
+			e := l.Edge(i)
+			for shape := range eq.CrossingsEdgeMap(e.V0, e.V1, s2.CrossingTypeAll) {
+				if err := add(shape); err != nil {
+					return idxResp, err
+				}
+			}
 		}
+	}
 
-		idxResp.IDsInside = append(idxResp.IDsInside, il.FeatureIndexResponse)
+	// polygons containing p
+	if p.NumLoops() > 0 {
+		q := s2.NewContainsPointQuery(idx.ShapeIndex, s2.VertexModelClosed)
+		for _, shape := range q.ContainingShapes(p.Loop(0).Vertex(0)) {
+			if err := add(shape); err != nil {
+				return idxResp, err
+			}
+		}
 	}
 
+	// polygons inside p
+	rc := &s2.RegionCoverer{MaxLevel: 30, MaxCells: 16}
+	for _, c := range rc.Covering(p) {
+		min, max := c.RangeMin(), c.RangeMax()
+
+		i := sort.Search(len(idx.vertices), func(i int) bool { return idx.vertices[i].cell >= min })
+		for ; i < len(idx.vertices) && idx.vertices[i].cell <= max; i++ {
+			if err := add(idx.ShapeIndex.Shape(idx.vertices[i].shapeID)); err != nil {
+				return idxResp, err
+			}
+		}
+	}
+
+	for fir, poly := range candidates {
+		if poly.Intersects(p) {
+			idxResp.IDsInside = append(idxResp.IDsInside, fir)
+		}
+	}
+
+	sort.Slice(idxResp.IDsInside, func(i, j int) bool {
+		a, b := idxResp.IDsInside[i], idxResp.IDsInside[j]
+
+		return a.ID < b.ID || (a.ID == b.ID && a.Pos < b.Pos)
+	})
+
 	return idxResp, nil
 }
+
+// sortVertices sorts the vertices by cell.
+func (idx *Index) sortVertices() {
+	idx.Lock()
+	defer idx.Unlock()
+
+	if idx.sorted {
+		return
+	}
+
+	sort.Slice(idx.vertices, func(i, j int) bool { return idx.vertices[i].cell < idx.vertices[j].cell })
+	idx.sorted = true
+}
@@ -1,29 +1,20 @@
 package shapeindex_test
 
 import (
-	"encoding/json"
-	"io/ioutil"
-	"os"
 	"testing"
 
-	log "github.com/go-kit/kit/log"
-	"github.com/golang/geo/s2"
 	"github.com/google/go-cmp/cmp"
-	"github.com/twpayne/go-geom/encoding/geojson"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/index/indextest"
 	"github.com/akhenakh/insideout/index/shapeindex"
-	"github.com/akhenakh/insideout/storage/bbolt"
 )
 
 func TestShapeIndex_Stab(t *testing.T) {
 	t.Parallel()
 
-	shapeidx, clean := setup(t)
-	defer clean()
-
 	tests := []struct {
 		name     string
 		lat, lng float64
@@ -51,75 +42,222 @@ func TestShapeIndex_Stab(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"inside polygon with hole",
+			48.02, 2.02,
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{{
+					ID:  1,
+					Pos: 0,
+				}},
+				IDsMayBeInside: nil,
+			},
+			false,
+		},
+		{
+			"inside hole",
+			48.05, 2.05,
+			insideout.IndexResponse{
+				IDsInside:      nil,
+				IDsMayBeInside: nil,
+			},
+			false,
+		},
+		{
+			"inside antimeridian west",
+			-16.5, 179.5,
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{{
+					ID:  2,
+					Pos: 0,
+				}},
+				IDsMayBeInside: nil,
+			},
+			false,
+		},
+		{
+			"inside antimeridian east",
+			-16.5, -179.5,
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{{
+					ID:  2,
+					Pos: 0,
+				}},
+				IDsMayBeInside: nil,
+			},
+			false,
+		},
+		{
+			"outside antimeridian",
+			-16.5, 0,
+			insideout.IndexResponse{
+				IDsInside:      nil,
+				IDsMayBeInside: nil,
+			},
+			false,
+		},
 	}
 
-	// This Run will not return until the parallel tests finish.
-	t.Run("group", func(t *testing.T) {
-		for _, tt := range tests {
-			tt := tt
-			t.Run(tt.name, func(t *testing.T) {
-				t.Parallel()
-
-				got, err := shapeidx.Stab(tt.lat, tt.lng)
-				if (err != nil) != tt.wantErr {
-					t.Fatalf("Stab() error = %v, wantErr %v", err, tt.wantErr)
-				}
-				if !cmp.Equal(got, tt.want) {
-					t.Fatalf("Stab() got = %v, want %v", got, tt.want)
-				}
-			})
-		}
-	})
-}
+	for _, e := range indextest.Engines() {
+		shapeidx, clean := setup(t, e)
 
-func setup(t *testing.T) (*shapeindex.Index, func()) {
-	t.Helper()
+		// This Run will not return until the parallel tests finish.
+		t.Run(e.Name, func(t *testing.T) {
+			for _, tt := range tests {
+				tt := tt
+				t.Run(tt.name, func(t *testing.T) {
+					t.Parallel()
 
-	logger := log.NewNopLogger()
+					got, err := shapeidx.Stab(tt.lat, tt.lng)
+					if (err != nil) != tt.wantErr {
+						t.Fatalf("Stab() error = %v, wantErr %v", err, tt.wantErr)
+					}
+					if !cmp.Equal(got, tt.want) {
+						t.Fatalf("Stab() got = %v, want %v", got, tt.want)
+					}
+				})
+			}
+		})
 
-	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
-	require.NoError(t, err)
-	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
-	require.NoError(t, err)
+		clean()
+	}
+}
 
-	var fc geojson.FeatureCollection
+func TestShapeIndex_Intersects(t *testing.T) {
+	tests := []struct {
+		name string
+		bbox [4]float64
+		want insideout.IndexResponse
+	}{
+		{
+			"bbox around polygon with hole",
+			[4]float64{47.99, 1.99, 48.11, 2.11},
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{{
+					ID:  1,
+					Pos: 0,
+				}},
+				IDsMayBeInside: nil,
+			},
+		},
+		{
+			"bbox inside hole",
+			[4]float64{48.045, 2.045, 48.055, 2.055},
+			insideout.IndexResponse{
+				IDsInside:      nil,
+				IDsMayBeInside: nil,
+			},
+		},
+		{
+			"bbox crossing the antimeridian",
+			[4]float64{-16.8, 179.8, -16.2, -179.8},
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{{
+					ID:  2,
+					Pos: 0,
+				}},
+				IDsMayBeInside: nil,
+			},
+		},
+		{
+			"bbox outside",
+			[4]float64{10, 10, 11, 11},
+			insideout.IndexResponse{
+				IDsInside:      nil,
+				IDsMayBeInside: nil,
+			},
+		},
+		{
+			"bbox inside polygon",
+			[4]float64{48.01, 2.01, 48.02, 2.02},
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{{
+					ID:  1,
+					Pos: 0,
+				}},
+				IDsMayBeInside: nil,
+			},
+		},
+		{
+			"bbox crossing polygon edge",
+			[4]float64{48.05, 2.09, 48.2, 2.2},
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{{
+					ID:  1,
+					Pos: 0,
+				}},
+				IDsMayBeInside: nil,
+			},
+		},
+		{
+			"bbox around both islands",
+			[4]float64{47.37, -3.01, 47.40, -2.95},
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{
+					{ID: 0, Pos: 0},
+					{ID: 0, Pos: 1},
+				},
+				IDsMayBeInside: nil,
+			},
+		},
+		{
+			"bbox on island",
+			[4]float64{47.38, -2.97, 47.40, -2.95},
+			insideout.IndexResponse{
+				IDsInside: []insideout.FeatureIndexResponse{{
+					ID:  0,
+					Pos: 1,
+				}},
+				IDsMayBeInside: nil,
+			},
+		},
+	}
 
-	file, err := os.Open("../testdata/poly.geojson")
-	require.NoError(t, err)
+	for _, e := range indextest.Engines() {
+		shapeidx, clean := setup(t, e)
 
-	defer file.Close()
+		t.Run(e.Name, func(t *testing.T) {
+			for _, tt := range tests {
+				tt := tt
 
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&fc)
-	require.NoError(t, err)
+				t.Run(tt.name, func(t *testing.T) {
+					p, err := insideout.PolygonFromBBox(tt.bbox[0], tt.bbox[1], tt.bbox[2], tt.bbox[3])
+					require.NoError(t, err)
 
-	icoverer := &s2.RegionCoverer{
-		MinLevel: 10,
-		MaxLevel: 16,
-		MaxCells: 24,
-	}
-	ocoverer := &s2.RegionCoverer{
-		MinLevel: 10,
-		MaxLevel: 15,
-		MaxCells: 16,
+					got, err := shapeidx.Intersects(p)
+					require.NoError(t, err)
+
+					if !cmp.Equal(got, tt.want) {
+						t.Fatalf("Intersects() got = %v, want %v", got, tt.want)
+					}
+				})
+			}
+		})
+
+		clean()
 	}
+}
 
-	err = wstorage.Index(fc, icoverer, ocoverer, 100, "poly.geojson", "unittest")
-	require.NoError(t, err)
+func TestShapeIndex_StabBatch(t *testing.T) {
+	for _, e := range indextest.Engines() {
+		shapeidx, clean := setup(t, e)
 
-	err = wclose()
-	require.NoError(t, err)
+		t.Run(e.Name, func(t *testing.T) {
+			indextest.TestStabBatch(t, shapeidx)
+		})
 
-	// RO storage
-	storage, bclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
-	require.NoError(t, err)
+		clean()
+	}
+}
+
+func setup(t *testing.T, e indextest.Engine) (*shapeindex.Index, func()) {
+	t.Helper()
+
+	storage, clean := e.Open(t)
 
 	shapeidx := shapeindex.New()
-	err = storage.LoadAllFeatures(shapeidx.Add)
+	err := storage.LoadAllFeatures(shapeidx.Add)
 	require.NoError(t, err)
 
-	return shapeidx, func() {
-		bclose()
-		os.Remove(tmpFile.Name())
-	}
+	return shapeidx, clean
 }
@@ -92,3 +92,56 @@ func (idx *Index) Stab(lat, lng float64) (insideout.IndexResponse, error) {
 
 	return idxResp, nil
 }
+
+// StabBatch returns Stab responses for every points in the same order, points are processed in parallel.
+func (idx *Index) StabBatch(lls []s2.LatLng) ([]insideout.IndexResponse, error) {
+	return insideout.StabParallel(lls, func() insideout.StabFunc {
+		return func(ll s2.LatLng) (insideout.IndexResponse, error) {
+			return idx.Stab(ll.Lat.Degrees(), ll.Lng.Degrees())
+		}
+	})
+}
+
+// Intersects returns polygon's ids intersecting p and polygon's ids that may intersect p.
+func (idx *Index) Intersects(p *s2.Polygon) (insideout.IndexResponse, error) {
+	var idxResp insideout.IndexResponse
+
+	interior, covering := insideout.QueryCoverings(p)
+
+	mi := intersecting(idx.itree, interior)
+	for fres := range mi {
+		idxResp.IDsInside = append(idxResp.IDsInside, fres)
+	}
+
+	mo := intersecting(idx.itree, covering)
+	for fres := range intersecting(idx.otree, covering) {
+		mo[fres] = struct{}{}
+	}
+
+	for fres := range mo {
+		// remove any answer matching inside
+		if _, ok := mi[fres]; !ok {
+			idxResp.IDsMayBeInside = append(idxResp.IDsMayBeInside, fres)
+		}
+	}
+
+	return idxResp, nil
+}
+
+// intersecting returns the values indexed under cells intersecting the cell union:
+// parent cells and children cells.
+func intersecting(tree *insidetree.Tree, cu s2.CellUnion) map[insideout.FeatureIndexResponse]struct{} {
+	m := make(map[insideout.FeatureIndexResponse]struct{})
+
+	for _, c := range cu {
+		for _, r := range tree.Stab(c) {
+			m[r.(insideout.FeatureIndexResponse)] = struct{}{}
+		}
+
+		for _, r := range tree.Mask(c) {
+			m[r.(insideout.FeatureIndexResponse)] = struct{}{}
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/opentracing/opentracing-go"
+	slog "github.com/opentracing/opentracing-go/log"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
+)
+
+// BatchWithin query exposed via gRPC.
+func (s *Server) BatchWithin(
+	ctx context.Context, req *insidesvc.BatchWithinRequest,
+) (resp *insidesvc.BatchWithinResponse, terr error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "BatchWithin")
+	defer span.Finish()
+
+	defer func() { s.handleError(terr, span) }()
+
+	span.LogFields(slog.Int("points_count", len(req.Points)))
+
+	opts, err := s.requestOptions(req)
+	if err != nil {
+		return nil, err
+	}
+
+	lls := make([]s2.LatLng, len(req.Points))
+	for i, p := range req.Points {
+		lls[i] = s2.LatLngFromDegrees(p.Lat, p.Lng)
+	}
+
+	ds, err := s.acquireAll(req.Datasets)
+	if err != nil {
+		return nil, err
+	}
+
+	defer releaseAll(ds)
+
+	// the founds of each point by dataset
+	founds := make([][][]found, len(req.Points))
+	for i := range founds {
+		founds[i] = make([][]found, len(ds))
+	}
+
+	for rank, d := range ds {
+		if err := s.datasetBatchWithin(ctx, d, rank, req.Points, lls, opts, founds); err != nil {
+			return nil, err
+		}
+	}
+
+	resps := make([]*insidesvc.WithinResponse, len(req.Points))
+
+	for i, p := range req.Points {
+		resps[i], err = s.withinResponse(p.Lat, p.Lng, mergeFounds(founds[i]), opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &insidesvc.BatchWithinResponse{Responses: resps}, nil
+}
+
+// datasetBatchWithin sets the founds of d ranked rank containing each point.
+func (s *Server) datasetBatchWithin(ctx context.Context, d *dataset, rank int, points []*insidesvc.Point,
+	lls []s2.LatLng, opts featureOptions, founds [][][]found) error {
+	idxResps, err := d.idx.StabBatch(lls)
+	if err != nil {
+		return fmt.Errorf("stabbing error: %w", err)
+	}
+
+	// features are loaded once per batch
+	features := make(map[uint32]*insideout.Feature)
+	load := func(id uint32) (*insideout.Feature, error) {
+		if f, ok := features[id]; ok {
+			return f, nil
+		}
+
+		f, err := d.feature(id)
+		if err != nil {
+			return nil, err
+		}
+
+		features[id] = f
+
+		return f, nil
+	}
+
+	for i, p := range points {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		founds[i][rank], err = s.withinFounds(d, p.Lat, p.Lng, idxResps[i], load, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	level.Debug(s.logger).Log("msg", "result batch within",
+		"points_count", len(points),
+		"dataset", d.name,
+		"loaded_features_count", len(features))
+
+	return nil
+}
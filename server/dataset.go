@@ -0,0 +1,433 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dgraph-io/ristretto"
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/index/dbindex"
+	"github.com/akhenakh/insideout/index/postgis"
+	"github.com/akhenakh/insideout/index/shapeindex"
+	"github.com/akhenakh/insideout/index/treeindex"
+)
+
+// DefaultDataset is the name of the dataset passed to New when Options.Dataset is empty.
+const DefaultDataset = "default"
+
+// DatasetOptions how a dataset is indexed and cached.
+type DatasetOptions struct {
+	StopOnFirstFound bool
+	CacheCount       int
+	Strategy         string
+	DBURL            string
+
+	// StorageClose closes the storage once replaced by a reload or on Close,
+	// nil when the caller closes it
+	StorageClose func() error
+}
+
+// dataset is an opened storage with its strategy index and features cache,
+// queries hold a reference to it so a reload can drain them before closing the storage.
+type dataset struct {
+	name    string
+	opts    DatasetOptions
+	storage insideout.Store
+	idx     insideout.Index
+	cache   *ristretto.Cache
+	infos   *insideout.IndexInfos
+
+	// inflight counts the queries using the dataset
+	inflight sync.WaitGroup
+}
+
+// newDataset builds the index of the configured strategy and the cache over storage.
+func newDataset(ctx context.Context, name string, storage insideout.Store, logger log.Logger,
+	opts DatasetOptions) (*dataset, error) {
+	infos, err := storage.LoadIndexInfos()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read infos: %w", err)
+	}
+
+	var idx insideout.Index
+
+	switch opts.Strategy {
+	case insideout.InsideTreeStrategy:
+		treeidx := treeindex.New(treeindex.Options{StopOnInsideFound: opts.StopOnFirstFound})
+
+		err := storage.LoadFeaturesCells(treeidx.Add)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to load cells from storage", "error", err, "strategy", opts.Strategy)
+
+			return nil, fmt.Errorf("failed to load cells from storage: %w", err)
+		}
+
+		idx = treeidx
+	case insideout.ShapeIndexStrategy:
+		shapeidx := shapeindex.New()
+
+		err := storage.LoadAllFeatures(shapeidx.Add)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to load feature from storage", "error", err, "strategy", opts.Strategy)
+
+			return nil, fmt.Errorf("failed to load feature from storage: %w", err)
+		}
+
+		idx = shapeidx
+	case insideout.DBStrategy:
+		dbidx := dbindex.New(storage, dbindex.Options{StopOnInsideFound: opts.StopOnFirstFound})
+		idx = dbidx
+
+	case insideout.PostgisIndexStrategy:
+		dbidx, err := postgis.New(ctx, logger, opts.DBURL)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to read storage", "error", err, "strategy", opts.Strategy)
+
+			return nil, fmt.Errorf("failed to read storage: %w", err)
+		}
+
+		idx = dbidx
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", opts.Strategy)
+	}
+
+	d := &dataset{
+		name:    name,
+		opts:    opts,
+		storage: storage,
+		idx:     idx,
+		infos:   infos,
+	}
+
+	// cache
+	if opts.CacheCount > 0 {
+		cache, err := ristretto.NewCache(&ristretto.Config{
+			NumCounters: int64(opts.CacheCount) * 10, // number of keys to track frequency
+			MaxCost:     int64(opts.CacheCount),      // maximum cost of cache
+			BufferItems: 64,                          // number of keys per Get buffer.
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cache error: %w", err)
+		}
+
+		d.cache = cache
+	}
+
+	return d, nil
+}
+
+// version identifies the indexing of the dataset, a reload of the same index keeps it.
+func (d *dataset) version() int64 {
+	return d.infos.IndexTime.UnixNano()
+}
+
+// release ends a query started with Server.acquire.
+func (d *dataset) release() {
+	d.inflight.Done()
+}
+
+// close waits for the in-flight queries then closes the cache and the storage.
+func (d *dataset) close() error {
+	d.inflight.Wait()
+
+	d.closeCache()
+
+	if d.opts.StorageClose == nil {
+		return nil
+	}
+
+	return d.opts.StorageClose()
+}
+
+func (d *dataset) closeCache() {
+	if d.cache != nil {
+		d.cache.Close()
+	}
+}
+
+// feature fetch feature from cache or from storage.
+func (d *dataset) feature(id uint32) (*insideout.Feature, error) {
+	if d.cache == nil {
+		return d.storage.LoadFeature(id)
+	}
+
+	fi, found := d.cache.Get(id)
+	if !found {
+		lf, err := d.storage.LoadFeature(id)
+		if err != nil {
+			return nil, fmt.Errorf("error loading feature: %w", err)
+		}
+
+		d.cache.Set(id, lf, 1)
+		featureMissCounter.Inc()
+
+		return lf, nil
+	}
+
+	featureHitCounter.Inc()
+
+	return fi.(*insideout.Feature), nil
+}
+
+// propertiesKey is the cache key of the properties of the feature id, apart from the feature ids keys.
+func propertiesKey(id uint32) uint64 {
+	return 1<<32 | uint64(id)
+}
+
+// featureProperties fetch feature properties from cache or from storage,
+// avoiding to decode the polygons of features that may be filtered out.
+func (d *dataset) featureProperties(id uint32) (map[string]interface{}, error) {
+	if d.cache != nil {
+		if fi, found := d.cache.Get(id); found {
+			return fi.(*insideout.Feature).Properties, nil
+		}
+
+		if pi, found := d.cache.Get(propertiesKey(id)); found {
+			return pi.(map[string]interface{}), nil
+		}
+	}
+
+	props, err := d.storage.LoadFeatureProperties(id)
+	if err != nil {
+		return nil, fmt.Errorf("error loading feature properties: %w", err)
+	}
+
+	if d.cache != nil {
+		d.cache.Set(propertiesKey(id), props, 1)
+	}
+
+	return props, nil
+}
+
+// externalID returns the external id of the feature id, from the cached feature when present.
+func (d *dataset) externalID(id uint32) (string, error) {
+	if d.cache != nil {
+		if fi, found := d.cache.Get(id); found {
+			return fi.(*insideout.Feature).ExternalID, nil
+		}
+	}
+
+	extID, err := d.storage.LoadExternalID(id)
+	if err != nil {
+		return "", fmt.Errorf("error loading external id: %w", err)
+	}
+
+	return extID, nil
+}
+
+// matchFilter reports whether the feature id is selected by the filter.
+func (d *dataset) matchFilter(id uint32, opts featureOptions) (bool, error) {
+	if opts.filter == nil {
+		return true, nil
+	}
+
+	props, err := d.featureProperties(id)
+	if err != nil {
+		return false, err
+	}
+
+	return opts.filter.Match(props), nil
+}
+
+// acquire returns the dataset called name, the default one when empty,
+// release must be called once the query is done.
+func (s *Server) acquire(name string) (*dataset, error) {
+	if name == "" {
+		name = s.defaultDataset
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, status.Error(codes.Unavailable, "server closed")
+	}
+
+	d, ok := s.datasets[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown dataset %q", name)
+	}
+
+	d.inflight.Add(1)
+
+	return d, nil
+}
+
+// acquireAll returns the datasets called names, the default one when empty,
+// releaseAll must be called once the query is done.
+func (s *Server) acquireAll(names []string) ([]*dataset, error) {
+	if len(names) == 0 {
+		d, err := s.acquire("")
+		if err != nil {
+			return nil, err
+		}
+
+		return []*dataset{d}, nil
+	}
+
+	ds := make([]*dataset, 0, len(names))
+	seen := make(map[string]struct{}, len(names))
+
+	for _, name := range names {
+		if _, ok := seen[name]; ok {
+			releaseAll(ds)
+
+			return nil, status.Errorf(codes.InvalidArgument, "duplicate dataset %q", name)
+		}
+
+		seen[name] = struct{}{}
+
+		d, err := s.acquire(name)
+		if err != nil {
+			releaseAll(ds)
+
+			return nil, err
+		}
+
+		ds = append(ds, d)
+	}
+
+	return ds, nil
+}
+
+// releaseAll releases datasets returned by acquireAll.
+func releaseAll(ds []*dataset) {
+	for _, d := range ds {
+		d.release()
+	}
+}
+
+// AddDataset builds the index and the cache of another dataset served as name.
+func (s *Server) AddDataset(ctx context.Context, name string, storage insideout.Store, opts DatasetOptions) error {
+	if name == "" {
+		return errors.New("empty dataset name")
+	}
+
+	d, err := newDataset(ctx, name, storage, s.logger, opts)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		d.closeCache()
+
+		return errors.New("server closed")
+	}
+
+	if _, ok := s.datasets[name]; ok {
+		d.closeCache()
+
+		return fmt.Errorf("dataset %q already exists", name)
+	}
+
+	s.datasets[name] = d
+
+	return nil
+}
+
+// Reload builds the index and the cache of the dataset called name, the default one when empty, over storage
+// in the calling goroutine while the current dataset keeps serving, then swaps them.
+// The replaced storage is closed once its in-flight queries are done.
+// On success closeStorage is owned by the server, on error the storage is left open.
+func (s *Server) Reload(ctx context.Context, name string, storage insideout.Store, closeStorage func() error) error {
+	if name == "" {
+		name = s.defaultDataset
+	}
+
+	s.mu.RLock()
+	cur, ok := s.datasets[name]
+	s.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("unknown dataset %q", name)
+	}
+
+	opts := cur.opts
+	opts.StorageClose = closeStorage
+
+	d, err := newDataset(ctx, name, storage, s.logger, opts)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+
+	old, ok := s.datasets[name]
+	if !ok {
+		// closed meanwhile
+		s.mu.Unlock()
+		d.closeCache()
+
+		return fmt.Errorf("unknown dataset %q", name)
+	}
+
+	s.datasets[name] = d
+	s.mu.Unlock()
+
+	// the new storage is served, a failure to close the replaced one is only logged
+	if err := old.close(); err != nil {
+		level.Error(s.logger).Log("msg", "failed to close replaced storage", "error", err, "dataset", name)
+	}
+
+	return nil
+}
+
+// IndexInfos returns the infos of the dataset called name, the default one when empty, nil if unknown.
+func (s *Server) IndexInfos(name string) *insideout.IndexInfos {
+	if name == "" {
+		name = s.defaultDataset
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.datasets[name]
+	if !ok {
+		return nil
+	}
+
+	return d.infos
+}
+
+// Datasets returns the infos of every served dataset by name.
+func (s *Server) Datasets() map[string]*insideout.IndexInfos {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m := make(map[string]*insideout.IndexInfos, len(s.datasets))
+	for name, d := range s.datasets {
+		m[name] = d.infos
+	}
+
+	return m
+}
+
+// Close waits for the in-flight queries then closes the storages owned by the server,
+// queries started after fail as Unavailable.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	ds := s.datasets
+	s.datasets = make(map[string]*dataset)
+	s.closed = true
+	s.mu.Unlock()
+
+	var cerr error
+
+	for _, d := range ds {
+		if err := d.close(); err != nil && cerr == nil {
+			cerr = err
+		}
+	}
+
+	return cerr
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
+	"github.com/akhenakh/insideout/geofence"
+)
+
+var geofenceEventCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "insided_server",
+	Name:      "geofence_event_total",
+	Help:      "The total number of geofence events by type",
+}, []string{"type"})
+
+// Geofence streaming exposed via gRPC,
+// positions are received from the client and events are streamed back.
+func (s *Server) Geofence(stream insidesvc.InsideService_GeofenceServer) (terr error) {
+	span, ctx := opentracing.StartSpanFromContext(stream.Context(), "Geofence")
+	defer span.Finish()
+
+	defer func() { s.handleError(terr, span) }()
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		events, err := s.geofenceUpdate(req)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			if err := stream.Send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// geofenceUpdate updates a device position and returns the resulting events.
+func (s *Server) geofenceUpdate(req *insidesvc.GeofenceRequest) ([]*insidesvc.GeofenceEvent, error) {
+	if req.DeviceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing device id")
+	}
+
+	ts := time.Now()
+
+	if req.Time != nil {
+		if err := req.Time.CheckValid(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		ts = req.Time.AsTime()
+	}
+
+	// the dataset is held per position so a reload is not blocked by long lived streams
+	d, err := s.acquire(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer d.release()
+
+	fs, err := s.containing(d, req.Lat, req.Lng)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.tracker.Update(req.DeviceId, d.name, ts, fs)
+	if err != nil {
+		return nil, fmt.Errorf("geofence update error: %w", err)
+	}
+
+	level.Debug(s.logger).Log("msg", "geofence update",
+		"device_id", req.DeviceId,
+		"dataset", d.name,
+		"lat", req.Lat,
+		"lng", req.Lng,
+		"events_count", len(events))
+
+	gevents := make([]*insidesvc.GeofenceEvent, 0, len(events))
+
+	for _, e := range events {
+		ge := &insidesvc.GeofenceEvent{
+			Type:       geofenceEventType(e.Type),
+			DeviceId:   e.DeviceID,
+			Id:         e.ID,
+			LoopIndex:  uint32(e.Pos),
+			Point:      &insidesvc.Point{Lat: req.Lat, Lng: req.Lng},
+			Time:       timestamppb.New(e.Time),
+			EnteredAt:  timestamppb.New(e.EnteredAt),
+			ExternalId: e.ExternalID,
+			Dataset:    e.Dataset,
+		}
+
+		if !req.RemoveFeature {
+			fid, f, err := s.eventFeature(d, e)
+			if err != nil {
+				return nil, err
+			}
+
+			if f != nil {
+				ge.Feature, err = protoFeature(f, fid, true, nil)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		geofenceEventCounter.WithLabelValues(e.Type.String()).Inc()
+
+		gevents = append(gevents, ge)
+	}
+
+	return gevents, nil
+}
+
+// eventFeature returns the feature of a geofence event, exited features are looked up by external id
+// since the dataset may have been reloaded, nil when it does not exist anymore.
+func (s *Server) eventFeature(d *dataset, e geofence.Event) (insideout.FeatureIndexResponse, *insideout.Feature, error) {
+	fid := insideout.FeatureIndexResponse{ID: e.ID, Pos: e.Pos}
+
+	if e.Type != geofence.Exit {
+		f, err := d.feature(e.ID)
+
+		return fid, f, err
+	}
+
+	if e.ExternalID == "" && e.Version != d.version() {
+		return fid, nil, nil
+	}
+
+	if e.ExternalID != "" {
+		id, err := d.storage.LookupExternalID(e.ExternalID)
+		if errors.Is(err, insideout.ErrFeatureNotFound) {
+			return fid, nil, nil
+		}
+
+		if err != nil {
+			return fid, nil, err
+		}
+
+		fid.ID = id
+	}
+
+	f, err := d.feature(fid.ID)
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "exited feature not found", "id", fid.ID, "error", err)
+
+		return fid, nil, nil
+	}
+
+	return fid, f, nil
+}
+
+// containing returns every feature polygon of d containing lat lng, even when stopping on the first found.
+func (s *Server) containing(d *dataset, lat, lng float64) ([]geofence.Feature, error) {
+	var (
+		idxResp insideout.IndexResponse
+		err     error
+	)
+
+	if d.opts.StopOnFirstFound {
+		idxResp, err = d.storage.StabDB(lat, lng, false)
+	} else {
+		idxResp, err = d.idx.Stab(lat, lng)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("stabbing error: %w", err)
+	}
+
+	fs := make([]geofence.Feature, 0, len(idxResp.IDsInside))
+
+	for _, fid := range idxResp.IDsInside {
+		extID, err := d.externalID(fid.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		fs = append(fs, geofence.Feature{
+			Dataset: d.name, ExternalID: extID, ID: fid.ID, Pos: fid.Pos, Version: d.version(),
+		})
+	}
+
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+
+	for _, fid := range idxResp.IDsMayBeInside {
+		f, err := d.feature(fid.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if int(fid.Pos) >= len(f.Polygons) || !f.Polygons[fid.Pos].ContainsPoint(p) {
+			continue
+		}
+
+		fs = append(fs, geofence.Feature{
+			Dataset: d.name, ExternalID: f.ExternalID, ID: fid.ID, Pos: fid.Pos, Version: d.version(),
+		})
+	}
+
+	return fs, nil
+}
+
+func geofenceEventType(t geofence.EventType) insidesvc.GeofenceEvent_Type {
+	switch t {
+	case geofence.Enter:
+		return insidesvc.GeofenceEvent_TYPE_ENTER
+	case geofence.Exit:
+		return insidesvc.GeofenceEvent_TYPE_EXIT
+	case geofence.Dwell:
+		return insidesvc.GeofenceEvent_TYPE_DWELL
+	default:
+		return insidesvc.GeofenceEvent_TYPE_UNSPECIFIED
+	}
+}
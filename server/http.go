@@ -1,8 +1,12 @@
 package server
 
 import (
+	"errors"
+	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/golang/protobuf/jsonpb"
 	structpb "github.com/golang/protobuf/ptypes/struct"
@@ -10,11 +14,17 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/twpayne/go-geom"
 	"github.com/twpayne/go-geom/encoding/geojson"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/akhenakh/insideout"
 	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
 )
 
+// maxBodySize is the max size of posted bodies.
+const maxBodySize = 1 << 20
+
 // DebugGetHandler HTTP 1.1 Handler to debug a feature.
 func (s *Server) DebugGetHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -35,9 +45,13 @@ func (s *Server) DebugGetHandler(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
+	dataset := r.URL.Query().Get("dataset")
+
 	resp, err := s.Get(ctx, &insidesvc.GetRequest{
 		Id:        uint32(fid),
 		LoopIndex: uint32(lidx),
+		Fields:    fieldsFromQuery(r),
+		Dataset:   dataset,
 	})
 	if err != nil {
 		http.Error(w, err.Error(), 500)
@@ -45,8 +59,17 @@ func (s *Server) DebugGetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	d, err := s.acquire(dataset)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+
+	defer d.release()
+
 	// get the s2 cells from the index
-	cs, err := s.storage.LoadCellStorage(uint32(fid))
+	cs, err := d.storage.LoadCellStorage(uint32(fid))
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 
@@ -100,11 +123,34 @@ func (s *Server) WithinHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var datasets []string
+	if v := vars["dataset"]; v != "" {
+		datasets = strings.Split(v, ",")
+	}
+
 	resp, err := s.Within(ctx, &insidesvc.WithinRequest{
-		Lat: lat,
-		Lng: lng,
+		Lat:           lat,
+		Lng:           lng,
+		Fields:        fieldsFromQuery(r),
+		Filter:        r.URL.Query().Get("filter"),
+		Order:         orderFromQuery(r),
+		OrderProperty: r.URL.Query().Get("order_property"),
+		MostSpecific:  r.URL.Query().Get("most_specific") == "true",
+		Datasets:      datasets,
 	})
 	if err != nil {
+		if st, ok := status.FromError(err); ok && st.Code() == codes.InvalidArgument {
+			http.Error(w, st.Message(), 400)
+
+			return
+		}
+
+		if st, ok := status.FromError(err); ok && st.Code() == codes.NotFound {
+			http.Error(w, st.Message(), 404)
+
+			return
+		}
+
 		http.Error(w, err.Error(), 500)
 
 		return
@@ -120,9 +166,366 @@ func (s *Server) WithinHandler(w http.ResponseWriter, r *http.Request) {
 
 	for _, fres := range resp.Responses {
 		f := &geojson.Feature{}
-		ng := geom.NewPolygonFlat(geom.XY, fres.Feature.Geometry.Coordinates, []int{len(fres.Feature.Geometry.Coordinates)})
-		f.Geometry = ng
+		f.Geometry = geometryFromProto(fres.Feature.Geometry)
 		f.Properties = insideout.ValueToProperties(fres.Feature.Properties)
+		f.Properties[insidesvc.DatasetProperty] = fres.Dataset
+		fc.Features = append(fc.Features, f)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json, err := fc.MarshalJSON()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+
+	w.Write(json)
+}
+
+// NearestHandler HTTP 1.1 Handler to query the nearest features returns GeoJSON,
+// max_distance in meters and limit can be passed as query parameters.
+func (s *Server) NearestHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "NearestHandler")
+	defer span.Finish()
+
+	vars := mux.Vars(r)
+
+	lat, err := strconv.ParseFloat(vars["lat"], 64)
+	if err != nil {
+		http.Error(w, "invalid parameter lat", 400)
+
+		return
+	}
+
+	lng, err := strconv.ParseFloat(vars["lng"], 64)
+	if err != nil {
+		http.Error(w, "invalid parameter lng", 400)
+
+		return
+	}
+
+	req := &insidesvc.NearestRequest{
+		Lat:     lat,
+		Lng:     lng,
+		Dataset: vars["dataset"],
+	}
+
+	if v := r.URL.Query().Get("max_distance"); v != "" {
+		req.MaxDistance, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			http.Error(w, "invalid parameter max_distance", 400)
+
+			return
+		}
+	}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			http.Error(w, "invalid parameter limit", 400)
+
+			return
+		}
+
+		req.Limit = uint32(limit)
+	}
+
+	resp, err := s.Nearest(ctx, req)
+	if err != nil {
+		if st, ok := status.FromError(err); ok && st.Code() == codes.InvalidArgument {
+			http.Error(w, st.Message(), 400)
+
+			return
+		}
+
+		if st, ok := status.FromError(err); ok && st.Code() == codes.NotFound {
+			http.Error(w, st.Message(), 404)
+
+			return
+		}
+
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+
+	if len(resp.Responses) == 0 {
+		http.Error(w, "{\"msg\": \"no features found near this location\"}", 404)
+
+		return
+	}
+
+	writeNearestFeatureCollection(w, resp.Responses)
+}
+
+// WithinRadiusHandler HTTP 1.1 Handler to query features within a radius in meters returns GeoJSON.
+func (s *Server) WithinRadiusHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "WithinRadiusHandler")
+	defer span.Finish()
+
+	vars := mux.Vars(r)
+
+	lat, err := strconv.ParseFloat(vars["lat"], 64)
+	if err != nil {
+		http.Error(w, "invalid parameter lat", 400)
+
+		return
+	}
+
+	lng, err := strconv.ParseFloat(vars["lng"], 64)
+	if err != nil {
+		http.Error(w, "invalid parameter lng", 400)
+
+		return
+	}
+
+	radius, err := strconv.ParseFloat(vars["radius"], 64)
+	if err != nil || radius <= 0 {
+		http.Error(w, "invalid parameter radius", 400)
+
+		return
+	}
+
+	resp, err := s.WithinRadius(ctx, &insidesvc.WithinRadiusRequest{
+		Lat:     lat,
+		Lng:     lng,
+		Radius:  radius,
+		Dataset: vars["dataset"],
+	})
+	if err != nil {
+		if st, ok := status.FromError(err); ok && st.Code() == codes.InvalidArgument {
+			http.Error(w, st.Message(), 400)
+
+			return
+		}
+
+		if st, ok := status.FromError(err); ok && st.Code() == codes.NotFound {
+			http.Error(w, st.Message(), 404)
+
+			return
+		}
+
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+
+	writeNearestFeatureCollection(w, resp.Responses)
+}
+
+// writeNearestFeatureCollection writes the features responses as a GeoJSON FeatureCollection,
+// adding the distance and the closest point as properties.
+func writeNearestFeatureCollection(w http.ResponseWriter, fresps []*insidesvc.NearestFeatureResponse) {
+	fc := &geojson.FeatureCollection{}
+
+	for _, fres := range fresps {
+		f := &geojson.Feature{}
+		f.Geometry = geometryFromProto(fres.Feature.Geometry)
+		f.Properties = insideout.ValueToProperties(fres.Feature.Properties)
+		f.Properties[insidesvc.DistanceProperty] = fres.Distance
+		f.Properties[insidesvc.ClosestLatProperty] = fres.ClosestPoint.Lat
+		f.Properties[insidesvc.ClosestLngProperty] = fres.ClosestPoint.Lng
+		fc.Features = append(fc.Features, f)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json, err := fc.MarshalJSON()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+
+	w.Write(json)
+}
+
+// IntersectsBBoxHandler HTTP 1.1 Handler to query features intersecting a bbox returns GeoJSON.
+func (s *Server) IntersectsBBoxHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "IntersectsBBoxHandler")
+	defer span.Finish()
+
+	vars := mux.Vars(r)
+
+	var bbox [4]float64
+
+	for i, name := range []string{"min_lat", "min_lng", "max_lat", "max_lng"} {
+		v, err := strconv.ParseFloat(vars[name], 64)
+		if err != nil {
+			http.Error(w, "invalid parameter "+name, 400)
+
+			return
+		}
+
+		bbox[i] = v
+	}
+
+	resp, err := s.Intersects(ctx, &insidesvc.IntersectsRequest{
+		Region: &insidesvc.IntersectsRequest_Bbox{Bbox: &insidesvc.BBox{
+			MinLat: bbox[0],
+			MinLng: bbox[1],
+			MaxLat: bbox[2],
+			MaxLng: bbox[3],
+		}},
+		Dataset: vars["dataset"],
+	})
+	if err != nil {
+		if st, ok := status.FromError(err); ok && st.Code() == codes.InvalidArgument {
+			http.Error(w, st.Message(), 400)
+
+			return
+		}
+
+		if st, ok := status.FromError(err); ok && st.Code() == codes.NotFound {
+			http.Error(w, st.Message(), 404)
+
+			return
+		}
+
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+
+	writeFeatureCollection(w, resp.Responses)
+}
+
+// IntersectsHandler HTTP 1.1 Handler to query features intersecting a GeoJSON polygon
+// posted as body returns GeoJSON.
+func (s *Server) IntersectsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "IntersectsHandler")
+	defer span.Finish()
+
+	vars := mux.Vars(r)
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "can't read body", 400)
+
+		return
+	}
+
+	var g geom.T
+	if err := geojson.Unmarshal(body, &g); err != nil {
+		http.Error(w, "invalid GeoJSON geometry", 400)
+
+		return
+	}
+
+	p, ok := g.(*geom.Polygon)
+	if !ok {
+		http.Error(w, "geometry is not a polygon", 400)
+
+		return
+	}
+
+	pg := &insidesvc.Geometry{
+		Type:        insidesvc.Geometry_TYPE_POLYGON,
+		Coordinates: p.FlatCoords(),
+		Ends:        uint32Ends(p.Ends(), 0),
+	}
+
+	resp, err := s.Intersects(ctx, &insidesvc.IntersectsRequest{
+		Region:  &insidesvc.IntersectsRequest_Polygon{Polygon: pg},
+		Dataset: vars["dataset"],
+	})
+	if err != nil {
+		if st, ok := status.FromError(err); ok && st.Code() == codes.InvalidArgument {
+			http.Error(w, st.Message(), 400)
+
+			return
+		}
+
+		if st, ok := status.FromError(err); ok && st.Code() == codes.NotFound {
+			http.Error(w, st.Message(), 404)
+
+			return
+		}
+
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+
+	writeFeatureCollection(w, resp.Responses)
+}
+
+// TrajectoryHandler HTTP 1.1 Handler to query features crossed by a path returns GeoJSON,
+// the body is a GeoJSON LineString or a Feature with a LineString geometry,
+// the feature optional coordTimes property holds a RFC 3339 time for each point.
+func (s *Server) TrajectoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "TrajectoryHandler")
+	defer span.Finish()
+
+	vars := mux.Vars(r)
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "can't read body", 400)
+
+		return
+	}
+
+	points, err := trajectoryPointsFromGeoJSON(body)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+
+		return
+	}
+
+	resp, err := s.Trajectory(ctx, &insidesvc.TrajectoryRequest{Points: points, Dataset: vars["dataset"]})
+	if err != nil {
+		if st, ok := status.FromError(err); ok && st.Code() == codes.InvalidArgument {
+			http.Error(w, st.Message(), 400)
+
+			return
+		}
+
+		if st, ok := status.FromError(err); ok && st.Code() == codes.NotFound {
+			http.Error(w, st.Message(), 404)
+
+			return
+		}
+
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+
+	fc := &geojson.FeatureCollection{}
+
+	for _, c := range resp.Crossings {
+		f := &geojson.Feature{}
+		f.Geometry = geometryFromProto(c.Feature.Geometry)
+		f.Properties = insideout.ValueToProperties(c.Feature.Properties)
+		f.Properties[insidesvc.EntryLatProperty] = c.Entry.Lat
+		f.Properties[insidesvc.EntryLngProperty] = c.Entry.Lng
+
+		if c.Entry.Time != nil {
+			f.Properties[insidesvc.EntryTimeProperty] = c.Entry.Time.AsTime().Format(time.RFC3339Nano)
+		}
+
+		if c.Exit != nil {
+			f.Properties[insidesvc.ExitLatProperty] = c.Exit.Lat
+			f.Properties[insidesvc.ExitLngProperty] = c.Exit.Lng
+
+			if c.Exit.Time != nil {
+				f.Properties[insidesvc.ExitTimeProperty] = c.Exit.Time.AsTime().Format(time.RFC3339Nano)
+			}
+		}
+
 		fc.Features = append(fc.Features, f)
 	}
 
@@ -137,3 +540,137 @@ func (s *Server) WithinHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Write(json)
 }
+
+// trajectoryPointsFromGeoJSON returns the path points from a GeoJSON LineString or LineString Feature.
+func trajectoryPointsFromGeoJSON(body []byte) ([]*insidesvc.TrajectoryPoint, error) {
+	var (
+		g     geom.T
+		times []interface{}
+	)
+
+	var f geojson.Feature
+	if err := f.UnmarshalJSON(body); err == nil && f.Geometry != nil {
+		g = f.Geometry
+		times, _ = f.Properties["coordTimes"].([]interface{})
+	} else if err := geojson.Unmarshal(body, &g); err != nil {
+		return nil, errors.New("invalid GeoJSON geometry")
+	}
+
+	ls, ok := g.(*geom.LineString)
+	if !ok {
+		return nil, errors.New("geometry is not a linestring")
+	}
+
+	if times != nil && len(times) != ls.NumCoords() {
+		return nil, errors.New("coordTimes count does not match coordinates count")
+	}
+
+	points := make([]*insidesvc.TrajectoryPoint, ls.NumCoords())
+
+	for i := range points {
+		c := ls.Coord(i)
+		points[i] = &insidesvc.TrajectoryPoint{Lat: c.Y(), Lng: c.X()}
+
+		if times == nil {
+			continue
+		}
+
+		ts, _ := times[i].(string)
+
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return nil, errors.New("invalid coordTimes")
+		}
+
+		points[i].Time = timestamppb.New(t)
+	}
+
+	return points, nil
+}
+
+// orderFromQuery returns the order query parameter: property, area or insertion,
+// unspecified when missing or unknown.
+func orderFromQuery(r *http.Request) insidesvc.Order {
+	o, err := ParseOrder(r.URL.Query().Get("order"))
+	if err != nil {
+		return insidesvc.Order_ORDER_UNSPECIFIED
+	}
+
+	switch o {
+	case OrderArea:
+		return insidesvc.Order_ORDER_AREA
+	case OrderInsertion:
+		return insidesvc.Order_ORDER_INSERTION
+	default:
+		return insidesvc.Order_ORDER_PROPERTY
+	}
+}
+
+// fieldsFromQuery returns the properties selected by the fields query parameter,
+// a comma separated list that can be repeated.
+func fieldsFromQuery(r *http.Request) []string {
+	var fields []string
+
+	for _, v := range r.URL.Query()["fields"] {
+		fields = append(fields, strings.Split(v, ",")...)
+	}
+
+	return fields
+}
+
+// writeFeatureCollection writes the features responses as a GeoJSON FeatureCollection.
+func writeFeatureCollection(w http.ResponseWriter, fresps []*insidesvc.FeatureResponse) {
+	fc := &geojson.FeatureCollection{}
+
+	for _, fres := range fresps {
+		f := &geojson.Feature{ID: fres.ExternalId}
+		if fres.Feature != nil {
+			f.Geometry = geometryFromProto(fres.Feature.Geometry)
+			f.Properties = insideout.ValueToProperties(fres.Feature.Properties)
+		}
+
+		fc.Features = append(fc.Features, f)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json, err := fc.MarshalJSON()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+
+		return
+	}
+
+	w.Write(json)
+}
+
+// geometryFromProto returns a GeoJSON polygon or multipolygon from a protobuf Geometry.
+func geometryFromProto(g *insidesvc.Geometry) geom.T {
+	if g.Type != insidesvc.Geometry_TYPE_MULTIPOLYGON {
+		return polygonFromGeometry(g)
+	}
+
+	mp := geom.NewMultiPolygon(geom.XY)
+
+	for _, pg := range g.Geometries {
+		// polygons are all XY, Push can't fail on layout
+		_ = mp.Push(polygonFromGeometry(pg))
+	}
+
+	return mp
+}
+
+// polygonFromGeometry returns a GeoJSON polygon from a protobuf Geometry.
+func polygonFromGeometry(g *insidesvc.Geometry) *geom.Polygon {
+	ends := make([]int, len(g.Ends))
+	for i, end := range g.Ends {
+		ends[i] = int(end)
+	}
+
+	// geometries without ends are single ring polygons
+	if len(ends) == 0 {
+		ends = []int{len(g.Coordinates)}
+	}
+
+	return geom.NewPolygonFlat(geom.XY, g.Coordinates, ends)
+}
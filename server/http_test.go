@@ -0,0 +1,123 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
+	"github.com/akhenakh/insideout/server"
+)
+
+func TestServer_HTTPDatasets(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	err := s.AddDataset(context.Background(), "nested", memoryStorage(t, "testdata/nested.geojson"),
+		server.DatasetOptions{Strategy: insideout.ShapeIndexStrategy})
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/nearest/{lat}/{lng}", s.NearestHandler)
+	r.HandleFunc("/api/{dataset}/nearest/{lat}/{lng}", s.NearestHandler)
+	r.HandleFunc("/api/{dataset}/radius/{lat}/{lng}/{radius}", s.WithinRadiusHandler)
+	r.HandleFunc("/api/{dataset}/intersects/{min_lat}/{min_lng}/{max_lat}/{max_lng}", s.IntersectsBBoxHandler)
+	r.HandleFunc("/api/{dataset}/intersects", s.IntersectsHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/{dataset}/trajectory", s.TrajectoryHandler).Methods(http.MethodPost)
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		wantCode  int
+		wantCount int
+	}{
+		{"nearest default", http.MethodGet, "/api/nearest/47.39650628189986/-2.9876390969486524?limit=1", "", 200, 1},
+		{"nearest", http.MethodGet, "/api/nested/nearest/47.5/1.5?limit=3", "", 200, 3},
+		{"radius", http.MethodGet, "/api/nested/radius/47.5/1.5/100", "", 200, 3},
+		{"intersects bbox", http.MethodGet, "/api/nested/intersects/47.49/1.49/47.51/1.51", "", 200, 3},
+		{"intersects invalid bbox", http.MethodGet, "/api/nested/intersects/47.51/1.49/47.49/1.51", "", 400, 0},
+		{
+			"intersects", http.MethodPost, "/api/nested/intersects",
+			`{"type":"Polygon","coordinates":[[[1.41,47.41],[1.43,47.41],[1.43,47.43],[1.41,47.43],[1.41,47.41]]]}`,
+			200, 1,
+		},
+		{
+			"trajectory", http.MethodPost, "/api/nested/trajectory",
+			`{"type":"LineString","coordinates":[[1.3,47.5],[1.5,47.5]]}`,
+			200, 3,
+		},
+		{"unknown dataset", http.MethodGet, "/api/unknown/nearest/47.5/1.5", "", 404, 0},
+		{
+			"unknown dataset post", http.MethodPost, "/api/unknown/intersects",
+			`{"type":"Polygon","coordinates":[[[1.41,47.41],[1.43,47.41],[1.43,47.43],[1.41,47.43],[1.41,47.41]]]}`,
+			404, 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.wantCode, w.Code, w.Body.String())
+
+			if tt.wantCode != 200 {
+				return
+			}
+
+			var fc geojson.FeatureCollection
+			require.NoError(t, fc.UnmarshalJSON(w.Body.Bytes()))
+			require.Len(t, fc.Features, tt.wantCount)
+		})
+	}
+}
+
+func TestServer_IntersectsHandlerIDs(t *testing.T) {
+	s, clean := setupFile(t, "testdata/nested.geojson", server.Options{Strategy: insideout.DBStrategy})
+	defer clean()
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/intersects/{min_lat}/{min_lng}/{max_lat}/{max_lng}", s.IntersectsBBoxHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/intersects/47.49/1.49/47.51/1.51", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+	require.Equal(t, 200, w.Code, w.Body.String())
+
+	var fc geojson.FeatureCollection
+	require.NoError(t, fc.UnmarshalJSON(w.Body.Bytes()))
+
+	var ids []string
+	for _, f := range fc.Features {
+		ids = append(ids, f.ID)
+	}
+
+	require.Equal(t, []string{"44", "city-1", ""}, ids)
+
+	// ids are returned without the features too
+	resp, err := s.Intersects(context.Background(), &insidesvc.IntersectsRequest{
+		Region: &insidesvc.IntersectsRequest_Bbox{
+			Bbox: &insidesvc.BBox{MinLat: 47.49, MinLng: 1.49, MaxLat: 47.51, MaxLng: 1.51},
+		},
+		RemoveFeature: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 3)
+
+	for i, fresp := range resp.Responses {
+		require.Equal(t, ids[i], fresp.ExternalId)
+		require.Equal(t, server.DefaultDataset, fresp.Dataset)
+		require.Nil(t, fresp.Feature)
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
+)
+
+// Intersects query exposed via gRPC.
+func (s *Server) Intersects(
+	ctx context.Context, req *insidesvc.IntersectsRequest,
+) (resp *insidesvc.IntersectsResponse, terr error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Intersects")
+	defer span.Finish()
+
+	defer func() { s.handleError(terr, span) }()
+
+	p, err := regionFromRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	d, err := s.acquire(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer d.release()
+
+	idxResp, err := d.idx.Intersects(p)
+	if err != nil {
+		return nil, fmt.Errorf("intersecting error: %w", err)
+	}
+
+	level.Debug(s.logger).Log("msg", "querying intersects",
+		"idx_resp", idxResp,
+	)
+
+	fids := idxResp.IDsInside
+
+	for _, fid := range idxResp.IDsMayBeInside {
+		f, err := d.feature(fid.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if int(fid.Pos) >= len(f.Polygons) || !f.Polygons[fid.Pos].Intersects(p) {
+			continue
+		}
+
+		fids = append(fids, fid)
+	}
+
+	sort.Slice(fids, func(i, j int) bool {
+		if fids[i].ID != fids[j].ID {
+			return fids[i].ID < fids[j].ID
+		}
+
+		return fids[i].Pos < fids[j].Pos
+	})
+
+	fresps := make([]*insidesvc.FeatureResponse, 0, len(fids))
+
+	for _, fid := range fids {
+		fresp := &insidesvc.FeatureResponse{Id: fid.ID, Dataset: d.name}
+
+		if req.RemoveFeature {
+			extID, err := d.externalID(fid.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			fresp.ExternalId = extID
+		} else {
+			f, err := d.feature(fid.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			fresp.ExternalId = f.ExternalID
+
+			fresp.Feature, err = protoFeature(f, fid, req.RemoveGeometries, nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		fresps = append(fresps, fresp)
+	}
+
+	level.Debug(s.logger).Log("msg", "result intersects",
+		"features_count", len(fresps))
+
+	return &insidesvc.IntersectsResponse{Responses: fresps}, nil
+}
+
+// regionFromRequest returns the queried region as an s2 Polygon.
+func regionFromRequest(req *insidesvc.IntersectsRequest) (*s2.Polygon, error) {
+	switch r := req.Region.(type) {
+	case *insidesvc.IntersectsRequest_Bbox:
+		return insideout.PolygonFromBBox(r.Bbox.MinLat, r.Bbox.MinLng, r.Bbox.MaxLat, r.Bbox.MaxLng)
+	case *insidesvc.IntersectsRequest_Polygon:
+		return polygonFromProto(r.Polygon)
+	default:
+		return nil, errors.New("missing region")
+	}
+}
+
+// polygonFromProto returns an s2 Polygon from a protobuf polygon Geometry.
+func polygonFromProto(g *insidesvc.Geometry) (*s2.Polygon, error) {
+	if g == nil || g.Type != insidesvc.Geometry_TYPE_POLYGON {
+		return nil, errors.New("geometry is not a polygon")
+	}
+
+	ends := make([]int, len(g.Ends))
+	for i, end := range g.Ends {
+		ends[i] = int(end)
+	}
+
+	// geometries without ends are single ring polygons
+	if len(ends) == 0 {
+		ends = []int{len(g.Coordinates)}
+	}
+
+	return insideout.PolygonFromCoordinates(g.Coordinates, ends)
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/opentracing/opentracing-go"
+	slog "github.com/opentracing/opentracing-go/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
+)
+
+const (
+	// DefaultNearestMaxDistance is the distance in meters used when a nearest request has none.
+	DefaultNearestMaxDistance = 1000.0
+
+	// DefaultMaxNearestDistance is the largest distance in meters a nearest or within radius request can search
+	// when Options.MaxNearestDistance is not set.
+	DefaultMaxNearestDistance = 100000.0
+
+	// DefaultNearestLimit is the features count returned when a nearest request has none.
+	DefaultNearestLimit = 1
+
+	// nearestMaxCells is the max cells count used to cover the searched area.
+	nearestMaxCells = 16
+)
+
+// Nearest query exposed via gRPC.
+func (s *Server) Nearest(
+	ctx context.Context, req *insidesvc.NearestRequest,
+) (resp *insidesvc.NearestResponse, terr error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Nearest")
+	defer span.Finish()
+
+	defer func() { s.handleError(terr, span) }()
+
+	maxDistance := req.MaxDistance
+	if maxDistance <= 0 {
+		maxDistance = DefaultNearestMaxDistance
+	}
+
+	if maxDistance > s.maxNearestDistance {
+		return nil, status.Errorf(codes.InvalidArgument, "max distance must be at most %g", s.maxNearestDistance)
+	}
+
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = DefaultNearestLimit
+	}
+
+	span.LogFields(
+		slog.Float64("lat", req.Lat),
+		slog.Float64("lng", req.Lng),
+		slog.Float64("max_distance", maxDistance),
+	)
+
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(req.Lat, req.Lng))
+
+	d, err := s.acquire(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer d.release()
+
+	fresps, err := s.closestFeatures(d, p, maxDistance, limit, req.RemoveGeometries, req.RemoveFeature)
+	if err != nil {
+		return nil, err
+	}
+
+	level.Debug(s.logger).Log("msg", "result nearest",
+		"lat", req.Lat,
+		"lng", req.Lng,
+		"features_count", len(fresps))
+
+	return &insidesvc.NearestResponse{
+		Point: &insidesvc.Point{
+			Lat: req.Lat,
+			Lng: req.Lng,
+		},
+		Responses: fresps,
+	}, nil
+}
+
+// closestFeatures returns the features of d closer than maxDistance meters to p ordered by distance,
+// limit 0 returns all of them.
+func (s *Server) closestFeatures(d *dataset, p s2.Point, maxDistance float64, limit int,
+	removeGeometries, removeFeature bool) ([]*insidesvc.NearestFeatureResponse, error) {
+	angle := insideout.AngleFromMeters(maxDistance)
+
+	// candidates are the features with a cover intersecting the searched area
+	coverer := &s2.RegionCoverer{MaxLevel: 30, MaxCells: nearestMaxCells}
+	cu := coverer.Covering(s2.CapFromCenterAngle(p, angle))
+
+	idxResp, err := d.storage.IntersectsDB(cu)
+	if err != nil {
+		return nil, fmt.Errorf("intersecting error: %w", err)
+	}
+
+	candidates := append(idxResp.IDsInside, idxResp.IDsMayBeInside...)
+
+	level.Debug(s.logger).Log("msg", "closest features candidates",
+		"candidates_count", len(candidates))
+
+	opts := s2.NewClosestEdgeQueryOptions().
+		MaxResults(1).
+		DistanceLimit(s1.ChordAngleFromAngle(angle)).
+		IncludeInteriors(true)
+	target := s2.NewMinDistanceToPointTarget(p)
+
+	// the closest polygon of each feature
+	closests := make(map[uint32]*nearest)
+
+	for _, fid := range candidates {
+		f, err := d.feature(fid.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if int(fid.Pos) >= len(f.Polygons) {
+			continue
+		}
+
+		n := closestPolygon(f, fid, p, target, opts)
+		if n == nil {
+			continue
+		}
+
+		if c, ok := closests[fid.ID]; ok && c.distance <= n.distance {
+			continue
+		}
+
+		closests[fid.ID] = n
+	}
+
+	// keeps the limit closest features, the farthest on top
+	h := make(nearestHeap, 0, len(closests))
+
+	for _, n := range closests {
+		heap.Push(&h, n)
+
+		if limit > 0 && h.Len() > limit {
+			heap.Pop(&h)
+		}
+	}
+
+	sort.Slice(h, func(i, j int) bool {
+		return h[i].less(h[j])
+	})
+
+	fresps := make([]*insidesvc.NearestFeatureResponse, 0, len(h))
+
+	for _, n := range h {
+		cll := s2.LatLngFromPoint(n.closest)
+
+		fresp := &insidesvc.NearestFeatureResponse{
+			Id:       n.fid.ID,
+			Distance: insideout.MetersFromAngle(n.distance.Angle()),
+			ClosestPoint: &insidesvc.Point{
+				Lat: cll.Lat.Degrees(),
+				Lng: cll.Lng.Degrees(),
+			},
+		}
+
+		if !removeFeature {
+			feature, err := protoFeature(n.f, n.fid, removeGeometries, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			fresp.Feature = feature
+		}
+
+		fresps = append(fresps, fresp)
+	}
+
+	if len(fresps) == 0 {
+		fresps = nil
+	}
+
+	return fresps, nil
+}
+
+// nearest the closest point of a feature polygon.
+type nearest struct {
+	fid      insideout.FeatureIndexResponse
+	f        *insideout.Feature
+	distance s1.ChordAngle
+	closest  s2.Point
+}
+
+// less orders by distance then by feature id.
+func (n *nearest) less(o *nearest) bool {
+	if n.distance != o.distance {
+		return n.distance < o.distance
+	}
+
+	return n.fid.ID < o.fid.ID
+}
+
+// closestPolygon returns the closest point of the polygon fid of f to p, nil when farther than the opts limit.
+func closestPolygon(f *insideout.Feature, fid insideout.FeatureIndexResponse, p s2.Point,
+	target *s2.MinDistanceToPointTarget, opts *s2.EdgeQueryOptions) *nearest {
+	index := s2.NewShapeIndex()
+	index.Add(f.Polygons[fid.Pos])
+
+	rs := s2.NewClosestEdgeQuery(index, opts).FindEdges(target)
+	if len(rs) == 0 {
+		return nil
+	}
+
+	n := &nearest{fid: fid, f: f, distance: rs[0].Distance(), closest: p}
+
+	if !rs[0].IsInterior() {
+		e := index.Shape(rs[0].ShapeID()).Edge(int(rs[0].EdgeID()))
+		n.closest = s2.Project(p, e.V0, e.V1)
+	}
+
+	return n
+}
+
+// nearestHeap is a max heap of the closest features, the farthest on top.
+type nearestHeap []*nearest
+
+func (h nearestHeap) Len() int            { return len(h) }
+func (h nearestHeap) Less(i, j int) bool  { return h[j].less(h[i]) }
+func (h nearestHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *nearestHeap) Push(x interface{}) { *h = append(*h, x.(*nearest)) }
+
+func (h *nearestHeap) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return n
+}
+
+// protoFeature returns the protobuf Feature of the polygon fid of f with the properties selected by mask.
+func protoFeature(f *insideout.Feature, fid insideout.FeatureIndexResponse,
+	removeGeometries bool, mask insideout.FieldMask) (*insidesvc.Feature, error) {
+	if int(fid.Pos) >= len(f.Polygons) {
+		return nil, fmt.Errorf("invalid polygon index %d for feature %d", fid.Pos, fid.ID)
+	}
+
+	feature := &insidesvc.Feature{}
+
+	if !removeGeometries {
+		feature.Geometry = geometryFromPolygon(f.Polygons[fid.Pos])
+	}
+
+	feature.Properties = insideout.PropertiesToValues(f, mask)
+	feature.Properties[insidesvc.LoopIndexProperty] = &structpb.Value{
+		Kind: &structpb.Value_NumberValue{NumberValue: float64(fid.Pos)},
+	}
+	feature.Properties[insidesvc.FeatureIDProperty] = &structpb.Value{
+		Kind: &structpb.Value_NumberValue{NumberValue: float64(fid.ID)},
+	}
+
+	return feature, nil
+}
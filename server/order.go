@@ -0,0 +1,188 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/filter"
+	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
+)
+
+// Order how the features containing a point are sorted.
+type Order int
+
+const (
+	// OrderProperty sorts by a numeric property, features without it last.
+	OrderProperty Order = iota
+	// OrderArea sorts by polygon area, smallest first.
+	OrderArea
+	// OrderInsertion sorts by the order the features were indexed.
+	OrderInsertion
+)
+
+// DefaultOrderProperty is the property used by OrderProperty when none is set.
+const DefaultOrderProperty = "admin_level"
+
+// ParseOrder returns the Order named s: property, area or insertion.
+func ParseOrder(s string) (Order, error) {
+	switch s {
+	case "property":
+		return OrderProperty, nil
+	case "area":
+		return OrderArea, nil
+	case "insertion":
+		return OrderInsertion, nil
+	default:
+		return 0, fmt.Errorf("unknown order %q", s)
+	}
+}
+
+// requestOrder returns the order requested by the client, falling back to the server defaults.
+func (s *Server) requestOrder(order insidesvc.Order, property string) (Order, string) {
+	o := s.order
+
+	switch order {
+	case insidesvc.Order_ORDER_PROPERTY:
+		o = OrderProperty
+	case insidesvc.Order_ORDER_AREA:
+		o = OrderArea
+	case insidesvc.Order_ORDER_INSERTION:
+		o = OrderInsertion
+	}
+
+	if property == "" {
+		property = s.orderProperty
+	}
+
+	return o, property
+}
+
+// found a feature polygon containing a point.
+type found struct {
+	d   *dataset
+	fid insideout.FeatureIndexResponse
+	// f is nil when the feature was not loaded
+	f *insideout.Feature
+	// rank of the dataset in the request, the insertion order sorts by dataset first
+	rank int
+}
+
+// mergeFounds concatenates the founds of each dataset ranked in the datasets order.
+func mergeFounds(dsFounds [][]found) []found {
+	var founds []found
+
+	for rank, fs := range dsFounds {
+		for _, fd := range fs {
+			fd.rank = rank
+			founds = append(founds, fd)
+		}
+	}
+
+	return founds
+}
+
+// sortFounds sorts the features according to opts, keeping only the smallest one in most specific mode,
+// features are loaded from their dataset when their polygons are needed.
+func (s *Server) sortFounds(founds []found, opts featureOptions) ([]found, error) {
+	if len(founds) == 0 {
+		return founds, nil
+	}
+
+	var areas []float64
+
+	if opts.order == OrderArea || opts.mostSpecific {
+		areas = make([]float64, len(founds))
+
+		for i := range founds {
+			if founds[i].f == nil {
+				f, err := founds[i].d.feature(founds[i].fid.ID)
+				if err != nil {
+					return nil, err
+				}
+
+				founds[i].f = f
+			}
+
+			if pos := int(founds[i].fid.Pos); pos < len(founds[i].f.Polygons) {
+				areas[i] = founds[i].f.Polygons[pos].Area()
+			}
+		}
+	}
+
+	// sort an index so areas follow the founds
+	idx := make([]int, len(founds))
+	for i := range idx {
+		idx[i] = i
+	}
+
+	switch opts.order {
+	case OrderProperty:
+		values := make([]float64, len(founds))
+		missing := make([]bool, len(founds))
+
+		for i, fd := range founds {
+			var props map[string]interface{}
+
+			if fd.f != nil {
+				props = fd.f.Properties
+			} else {
+				var err error
+
+				props, err = fd.d.featureProperties(fd.fid.ID)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			v, ok := filter.Number(props[opts.orderProperty])
+			values[i], missing[i] = v, !ok
+		}
+
+		sort.SliceStable(idx, func(i, j int) bool {
+			a, b := idx[i], idx[j]
+			if missing[a] || missing[b] {
+				return !missing[a] && missing[b]
+			}
+
+			return values[a] < values[b]
+		})
+	case OrderArea:
+		sort.SliceStable(idx, func(i, j int) bool {
+			return areas[idx[i]] < areas[idx[j]]
+		})
+	case OrderInsertion:
+		sort.SliceStable(idx, func(i, j int) bool {
+			if ra, rb := founds[idx[i]].rank, founds[idx[j]].rank; ra != rb {
+				return ra < rb
+			}
+
+			a, b := founds[idx[i]].fid, founds[idx[j]].fid
+			if a.ID != b.ID {
+				return a.ID < b.ID
+			}
+
+			return a.Pos < b.Pos
+		})
+	}
+
+	sorted := make([]found, len(founds))
+	for i, k := range idx {
+		sorted[i] = founds[k]
+	}
+
+	if !opts.mostSpecific {
+		return sorted, nil
+	}
+
+	// the first smallest in the requested order
+	smallest := 0
+
+	for i, k := range idx {
+		if areas[k] < areas[idx[smallest]] {
+			smallest = i
+		}
+	}
+
+	return sorted[smallest : smallest+1], nil
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/opentracing/opentracing-go"
+	slog "github.com/opentracing/opentracing-go/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
+)
+
+// WithinRadius query exposed via gRPC.
+func (s *Server) WithinRadius(
+	ctx context.Context, req *insidesvc.WithinRadiusRequest,
+) (resp *insidesvc.WithinRadiusResponse, terr error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "WithinRadius")
+	defer span.Finish()
+
+	defer func() { s.handleError(terr, span) }()
+
+	if req.Radius <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "radius must be positive")
+	}
+
+	if req.Radius > s.maxNearestDistance {
+		return nil, status.Errorf(codes.InvalidArgument, "radius must be at most %g", s.maxNearestDistance)
+	}
+
+	span.LogFields(
+		slog.Float64("lat", req.Lat),
+		slog.Float64("lng", req.Lng),
+		slog.Float64("radius", req.Radius),
+	)
+
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(req.Lat, req.Lng))
+
+	d, err := s.acquire(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer d.release()
+
+	fresps, err := s.closestFeatures(d, p, req.Radius, 0, req.RemoveGeometries, req.RemoveFeature)
+	if err != nil {
+		return nil, err
+	}
+
+	level.Debug(s.logger).Log("msg", "result within radius",
+		"lat", req.Lat,
+		"lng", req.Lng,
+		"radius", req.Radius,
+		"features_count", len(fresps))
+
+	return &insidesvc.WithinRadiusResponse{
+		Point: &insidesvc.Point{
+			Lat: req.Lat,
+			Lng: req.Lng,
+		},
+		Responses: fresps,
+	}, nil
+}
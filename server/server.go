@@ -2,29 +2,28 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
-	"sort"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/dgraph-io/ristretto"
 	log "github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/golang/geo/s2"
-	structpb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/opentracing/opentracing-go"
 	slog "github.com/opentracing/opentracing-go/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/status"
 
 	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/filter"
 	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
-	"github.com/akhenakh/insideout/index/dbindex"
-	"github.com/akhenakh/insideout/index/postgis"
-	"github.com/akhenakh/insideout/index/shapeindex"
-	"github.com/akhenakh/insideout/index/treeindex"
+	"github.com/akhenakh/insideout/geofence"
 )
 
 var (
@@ -49,11 +48,20 @@ var (
 
 // Server exposes indexes services.
 type Server struct {
-	storage      insideout.Store
 	logger       log.Logger
-	cache        *ristretto.Cache
 	healthServer *health.Server
-	idx          insideout.Index
+	tracker      *geofence.Tracker
+
+	// mu protects datasets, swapped by Reload, and closed
+	mu             sync.RWMutex
+	datasets       map[string]*dataset
+	defaultDataset string
+	closed         bool
+
+	order         Order
+	orderProperty string
+
+	maxNearestDistance float64
 }
 
 type Options struct {
@@ -61,6 +69,30 @@ type Options struct {
 	CacheCount       int
 	Strategy         string
 	DBURL            string
+
+	// GeofenceStore persists devices states, defaults to an in memory store
+	GeofenceStore geofence.StateStore
+	// GeofenceIdleTTL is the time the default in memory store keeps a device without updates,
+	// defaults to geofence.DefaultIdleTTL
+	GeofenceIdleTTL time.Duration
+	// DwellTime is the time inside a feature before a geofence dwell event, 0 disables dwell events
+	DwellTime time.Duration
+
+	// Order is the default order of the features containing a point
+	Order Order
+	// OrderProperty is the numeric property used by OrderProperty, defaults to DefaultOrderProperty
+	OrderProperty string
+
+	// StorageClose closes the storage passed to New once replaced by a reload or on Close,
+	// nil when the caller closes it
+	StorageClose func() error
+
+	// Dataset is the name of the storage passed to New, defaults to DefaultDataset
+	Dataset string
+
+	// MaxNearestDistance is the largest distance in meters a nearest or within radius request can search,
+	// defaults to DefaultMaxNearestDistance
+	MaxNearestDistance float64
 }
 
 // New returns a Server.
@@ -68,125 +100,219 @@ func New(ctx context.Context, storage insideout.Store, logger log.Logger, health
 	opts Options) (*Server, error) {
 	logger = log.With(logger, "component", "server")
 
-	var idx insideout.Index
-
-	switch opts.Strategy {
-	case insideout.InsideTreeStrategy:
-		treeidx := treeindex.New(treeindex.Options{StopOnInsideFound: opts.StopOnFirstFound})
+	name := opts.Dataset
+	if name == "" {
+		name = DefaultDataset
+	}
 
-		err := storage.LoadFeaturesCells(treeidx.Add)
-		if err != nil {
-			level.Error(logger).Log("msg", "failed to load cells from storage", "error", err, "strategy", opts.Strategy)
+	ds, err := newDataset(ctx, name, storage, logger, DatasetOptions{
+		StopOnFirstFound: opts.StopOnFirstFound,
+		CacheCount:       opts.CacheCount,
+		Strategy:         opts.Strategy,
+		DBURL:            opts.DBURL,
+		StorageClose:     opts.StorageClose,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			return nil, fmt.Errorf("ailed to load cells from storage: %w", err)
+	gstore := opts.GeofenceStore
+	if gstore == nil {
+		ttl := opts.GeofenceIdleTTL
+		if ttl <= 0 {
+			ttl = geofence.DefaultIdleTTL
 		}
 
-		idx = treeidx
-	case insideout.ShapeIndexStrategy:
-		shapeidx := shapeindex.New()
+		gstore = geofence.NewMemoryStore(ttl)
+	}
 
-		err := storage.LoadAllFeatures(shapeidx.Add)
-		if err != nil {
-			level.Error(logger).Log("msg", "failed to load feature from storage", "error", err, "strategy", opts.Strategy)
+	s := &Server{
+		logger:         logger,
+		healthServer:   healthServer,
+		datasets:       map[string]*dataset{name: ds},
+		defaultDataset: name,
+		tracker:        geofence.NewTracker(gstore, geofence.Options{DwellTime: opts.DwellTime}),
+		order:          opts.Order,
+		orderProperty:  opts.OrderProperty,
+
+		maxNearestDistance: opts.MaxNearestDistance,
+	}
 
-			return nil, fmt.Errorf("failed to load feature from storage: %w", err)
-		}
+	if s.orderProperty == "" {
+		s.orderProperty = DefaultOrderProperty
+	}
 
-		idx = shapeidx
-	case insideout.DBStrategy:
-		dbidx := dbindex.New(storage, dbindex.Options{StopOnInsideFound: opts.StopOnFirstFound})
-		idx = dbidx
+	if s.maxNearestDistance <= 0 {
+		s.maxNearestDistance = DefaultMaxNearestDistance
+	}
 
-	case insideout.PostgisIndexStrategy:
-		dbidx, err := postgis.New(ctx, logger, *&opts.DBURL)
-		if err != nil {
-			level.Error(logger).Log("msg", "failed to read storage", "error", err, "strategy", opts.Strategy)
-			os.Exit(2)
-		}
+	return s, nil
+}
 
-		idx = dbidx
-	}
+// Within query exposed via gRPC.
+func (s *Server) Within(
+	ctx context.Context, req *insidesvc.WithinRequest,
+) (resp *insidesvc.WithinResponse, terr error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Within")
+	defer span.Finish()
 
-	s := &Server{
-		storage:      storage,
-		logger:       logger,
-		healthServer: healthServer,
-		idx:          idx,
-	}
-
-	// cache
-	if opts.CacheCount > 0 {
-		cache, err := ristretto.NewCache(&ristretto.Config{
-			NumCounters: int64(opts.CacheCount) * 10, // number of keys to track frequency
-			MaxCost:     int64(opts.CacheCount),      // maximum cost of cache
-			BufferItems: 64,                          // number of keys per Get buffer.
-		})
-		if err != nil {
-			return nil, fmt.Errorf("cache error: %w", err)
-		}
+	defer func() { s.handleError(terr, span) }()
 
-		s.cache = cache
+	opts, err := s.requestOptions(req)
+	if err != nil {
+		return nil, err
 	}
 
-	return s, nil
-}
+	ds, err := s.acquireAll(req.Datasets)
+	if err != nil {
+		return nil, err
+	}
+
+	defer releaseAll(ds)
+
+	span.LogFields(
+		slog.Float64("lat", req.Lat),
+		slog.Float64("lng", req.Lng),
+		slog.Int("datasets_count", len(ds)),
+	)
 
-// feature fetch feature from cache or from storage.
-func (s *Server) feature(id uint32) (*insideout.Feature, error) {
-	if s.cache == nil {
-		return s.storage.LoadFeature(id)
+	resp, err = s.within(ds, req.Lat, req.Lng, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	fi, found := s.cache.Get(id)
-	if !found {
-		lf, err := s.storage.LoadFeature(id)
+	level.Debug(s.logger).Log("msg", "result stab",
+		"lat", req.Lat,
+		"lng", req.Lng,
+		"features_count", len(resp.Responses))
+
+	return resp, nil
+}
+
+// within returns the features of the datasets containing lat lng,
+// several datasets are queried concurrently and their features sorted together.
+func (s *Server) within(ds []*dataset, lat, lng float64, opts featureOptions) (*insidesvc.WithinResponse, error) {
+	if len(ds) == 1 {
+		founds, err := s.datasetWithin(ds[0], lat, lng, opts)
 		if err != nil {
-			return nil, fmt.Errorf("error loading feature: %w", err)
+			return nil, err
 		}
 
-		s.cache.Set(id, lf, 1)
-		featureMissCounter.Inc()
-
-		return lf, nil
+		return s.withinResponse(lat, lng, founds, opts)
 	}
 
-	featureHitCounter.Inc()
+	dsFounds := make([][]found, len(ds))
 
-	return fi.(*insideout.Feature), nil
-}
+	var g errgroup.Group
 
-// Within query exposed via gRPC.
-func (s *Server) Within(
-	ctx context.Context, req *insidesvc.WithinRequest,
-) (resp *insidesvc.WithinResponse, terr error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "Within")
-	defer span.Finish()
+	for i, d := range ds {
+		i, d := i, d
 
-	defer s.handleError(terr, span)
+		g.Go(func() error {
+			var err error
 
-	idxResp, err := s.idx.Stab(req.Lat, req.Lng)
+			dsFounds[i], err = s.datasetWithin(d, lat, lng, opts)
+
+			return err
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return s.withinResponse(lat, lng, mergeFounds(dsFounds), opts)
+}
+
+// datasetWithin returns the features of d containing lat lng.
+func (s *Server) datasetWithin(d *dataset, lat, lng float64, opts featureOptions) ([]found, error) {
+	idxResp, err := d.idx.Stab(lat, lng)
 	if err != nil {
 		return nil, fmt.Errorf("stabbing error: %w", err)
 	}
 
 	level.Debug(s.logger).Log("msg", "querying within",
-		"lat", req.Lat,
-		"lng", req.Lng,
+		"lat", lat,
+		"lng", lng,
+		"dataset", d.name,
 		"idx_resp", idxResp,
 	)
 
-	span.LogFields(
-		slog.Float64("lat", req.Lat),
-		slog.Float64("lng", req.Lng),
-	)
+	return s.withinFounds(d, lat, lng, idxResp, d.feature, opts)
+}
 
-	var fresps []*insidesvc.FeatureResponse
+// featureOptions how features are returned.
+type featureOptions struct {
+	removeGeometries bool
+	removeFeature    bool
+	// mask selects the returned properties
+	mask insideout.FieldMask
+	// filter selects the returned features, nil for all
+	filter filter.Expr
+	// order of the returned features
+	order         Order
+	orderProperty string
+	// mostSpecific only returns the smallest polygon
+	mostSpecific bool
+}
+
+// featureRequest the options shared by within requests.
+type featureRequest interface {
+	GetRemoveGeometries() bool
+	GetRemoveFeature() bool
+	GetFields() []string
+	GetFilter() string
+	GetOrder() insidesvc.Order
+	GetOrderProperty() string
+	GetMostSpecific() bool
+}
+
+// requestOptions validates the request options, errors are InvalidArgument.
+func (s *Server) requestOptions(req featureRequest) (featureOptions, error) {
+	mask, err := insideout.NewFieldMask(req.GetFields())
+	if err != nil {
+		return featureOptions{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	opts := featureOptions{
+		removeGeometries: req.GetRemoveGeometries(),
+		removeFeature:    req.GetRemoveFeature(),
+		mask:             mask,
+		mostSpecific:     req.GetMostSpecific(),
+	}
+
+	opts.order, opts.orderProperty = s.requestOrder(req.GetOrder(), req.GetOrderProperty())
+
+	if expr := req.GetFilter(); strings.TrimSpace(expr) != "" {
+		opts.filter, err = filter.Parse(expr)
+		if err != nil {
+			return featureOptions{}, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	return opts, nil
+}
+
+// withinFounds returns the features of d containing lat lng from an index response,
+// polygons that may contain the point are tested, load is used to fetch features.
+func (s *Server) withinFounds(d *dataset, lat, lng float64, idxResp insideout.IndexResponse,
+	load func(uint32) (*insideout.Feature, error), opts featureOptions) ([]found, error) {
+	var founds []found
 
 	for _, fid := range idxResp.IDsInside {
-		var feature *insidesvc.Feature
+		ok, err := d.matchFilter(fid.ID, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		var f *insideout.Feature
 
-		if !req.RemoveFeature {
-			f, err := s.feature(fid.ID)
+		if !opts.removeFeature {
+			f, err = load(fid.ID)
 			if err != nil {
 				return nil, err
 			}
@@ -195,132 +321,127 @@ func (s *Server) Within(
 				"fid", fid.ID,
 				"properties", f.Properties,
 				"loop #", fid.Pos)
+		}
 
-			feature = &insidesvc.Feature{}
+		founds = append(founds, found{d: d, fid: fid, f: f})
+	}
 
-			if !req.RemoveGeometries {
-				l := f.Loops[fid.Pos]
-				feature.Geometry = &insidesvc.Geometry{
-					Type:        insidesvc.Geometry_TYPE_POLYGON,
-					Coordinates: insideout.CoordinatesFromLoops(l),
-				}
-			}
+	p := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
 
-			// TODO: filter properties
-			prop, err := insideout.PropertiesToValues(f)
-			if err != nil {
-				return nil, fmt.Errorf("can't transfor property to value: %w", err)
-			}
+	for _, fid := range idxResp.IDsMayBeInside {
+		// filtering before loading the feature and the PIP test
+		ok, err := d.matchFilter(fid.ID, opts)
+		if err != nil {
+			return nil, err
+		}
 
-			feature.Properties = prop
-			feature.Properties[insidesvc.LoopIndexProperty] = &structpb.Value{
-				Kind: &structpb.Value_NumberValue{NumberValue: float64(fid.Pos)},
-			}
-			feature.Properties[insidesvc.FeatureIDProperty] = &structpb.Value{
-				Kind: &structpb.Value_NumberValue{NumberValue: float64(fid.ID)},
-			}
+		if !ok {
+			continue
 		}
 
-		fresp := &insidesvc.FeatureResponse{
-			Id:      fid.ID,
-			Feature: feature,
+		f, err := load(fid.ID)
+		if err != nil {
+			return nil, err
 		}
-		fresps = append(fresps, fresp)
-	}
 
-	p := s2.PointFromLatLng(s2.LatLngFromDegrees(req.Lat, req.Lng))
+		level.Debug(s.logger).Log("msg", "Found maybe inside feature",
+			"fid", fid.ID,
+			"properties", f.Properties,
+			"loop #", fid.Pos)
 
-	for _, fid := range idxResp.IDsMayBeInside {
-		var feature *insidesvc.Feature
-		if !req.RemoveFeature {
-			f, err := s.feature(fid.ID)
-			if err != nil {
-				return nil, err
-			}
+		if int(fid.Pos) >= len(f.Polygons) || !f.Polygons[fid.Pos].ContainsPoint(p) {
+			continue
+		}
 
-			level.Debug(s.logger).Log("msg", "Found maybe inside feature",
-				"fid", fid.ID,
-				"properties", f.Properties,
-				"loop #", fid.Pos)
+		level.Debug(s.logger).Log("msg", "Found maybe inside feature PIP valid",
+			"fid", fid.ID,
+			"properties", f.Properties,
+			"loop #", fid.Pos)
 
-			l := f.Loops[fid.Pos]
-			if !l.ContainsPoint(p) {
-				continue
-			}
+		founds = append(founds, found{d: d, fid: fid, f: f})
+	}
 
-			level.Debug(s.logger).Log("msg", "Found maybe inside feature PIP valid",
-				"fid", fid.ID,
-				"properties", f.Properties,
-				"loop #", fid.Pos)
+	return founds, nil
+}
 
-			feature = &insidesvc.Feature{}
+// withinResponse returns the response of the features containing lat lng,
+// sorted across their datasets before properties are masked.
+func (s *Server) withinResponse(lat, lng float64, founds []found, opts featureOptions) (*insidesvc.WithinResponse, error) {
+	founds, err := s.sortFounds(founds, opts)
+	if err != nil {
+		return nil, err
+	}
 
-			if !req.RemoveGeometries {
-				feature.Geometry = &insidesvc.Geometry{
-					Type:        insidesvc.Geometry_TYPE_POLYGON,
-					Coordinates: insideout.CoordinatesFromLoops(l),
-				}
-			}
+	fresps := make([]*insidesvc.FeatureResponse, 0, len(founds))
 
-			prop, err := insideout.PropertiesToValues(f)
+	for _, fd := range founds {
+		fresp := &insidesvc.FeatureResponse{Id: fd.fid.ID, Dataset: fd.d.name}
+
+		if fd.f != nil {
+			fresp.ExternalId = fd.f.ExternalID
+		} else {
+			fresp.ExternalId, err = fd.d.externalID(fd.fid.ID)
 			if err != nil {
 				return nil, err
 			}
+		}
 
-			feature.Properties = prop
-			feature.Properties[insidesvc.LoopIndexProperty] = &structpb.Value{
-				Kind: &structpb.Value_NumberValue{NumberValue: float64(fid.Pos)},
-			}
-			feature.Properties[insidesvc.FeatureIDProperty] = &structpb.Value{
-				Kind: &structpb.Value_NumberValue{NumberValue: float64(fid.ID)},
+		if !opts.removeFeature {
+			fresp.Feature, err = protoFeature(fd.f, fd.fid, opts.removeGeometries, opts.mask)
+			if err != nil {
+				return nil, err
 			}
 		}
 
-		fresp := &insidesvc.FeatureResponse{
-			Id:      fid.ID,
-			Feature: feature,
-		}
 		fresps = append(fresps, fresp)
 	}
 
-	// sort features by "admin_level"
-	sort.SliceStable(fresps, func(i, j int) bool {
-		if fresps[i].Feature == nil {
-			return false
-		}
-
-		return fresps[i].Feature.Properties["admin_level"].GetNumberValue() <
-			fresps[j].Feature.Properties["admin_level"].GetNumberValue()
-	})
-
-	level.Debug(s.logger).Log("msg", "result stab",
-		"lat", req.Lat,
-		"lng", req.Lng,
-		"features_count", len(fresps))
+	if len(fresps) == 0 {
+		fresps = nil
+	}
 
-	resp = &insidesvc.WithinResponse{
+	return &insidesvc.WithinResponse{
 		Point: &insidesvc.Point{
-			Lat: req.Lat,
-			Lng: req.Lng,
+			Lat: lat,
+			Lng: lng,
 		},
 		Responses: fresps,
-	}
-
-	return resp, nil
+	}, nil
 }
 
 func (s *Server) Get(ctx context.Context, req *insidesvc.GetRequest) (resp *insidesvc.GetResponse, terr error) {
 	span, _ := opentracing.StartSpanFromContext(ctx, "Get")
 	defer span.Finish()
 
-	defer s.handleError(terr, span)
+	defer func() { s.handleError(terr, span) }()
 
 	span.LogFields(
 		slog.Uint32("feature_id", req.Id),
+		slog.String("external_id", req.ExternalId),
 		slog.Uint32("loop_index", req.LoopIndex),
 	)
 
-	f, err := s.feature(req.Id)
+	d, err := s.acquire(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer d.release()
+
+	id := req.Id
+
+	if req.ExternalId != "" {
+		id, err = d.storage.LookupExternalID(req.ExternalId)
+		if errors.Is(err, insideout.ErrFeatureNotFound) {
+			return nil, status.Errorf(codes.NotFound, "unknown external id %q", req.ExternalId)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := d.feature(id)
 	if err != nil {
 		return nil, err
 	}
@@ -329,48 +450,47 @@ func (s *Server) Get(ctx context.Context, req *insidesvc.GetRequest) (resp *insi
 		return nil, status.Error(codes.NotFound, "can't found feature")
 	}
 
-	if req.LoopIndex >= uint32(len(f.Loops)) {
+	if req.LoopIndex >= uint32(len(f.Polygons)) {
 		return nil, status.Error(codes.NotFound, "loop index out of range")
 	}
 
-	l := f.Loops[req.LoopIndex]
-
-	prop, err := insideout.PropertiesToValues(f)
+	mask, err := insideout.NewFieldMask(req.Fields)
 	if err != nil {
-		return nil, err
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	feature := &insidesvc.Feature{
-		Geometry: &insidesvc.Geometry{
-			Type:        insidesvc.Geometry_TYPE_POLYGON,
-			Coordinates: insideout.CoordinatesFromLoops(l),
-		},
-		Properties: prop,
-	}
+	fid := insideout.FeatureIndexResponse{ID: id, Pos: uint16(req.LoopIndex)}
 
-	feature.Properties[insidesvc.LoopIndexProperty] = &structpb.Value{
-		Kind: &structpb.Value_NumberValue{NumberValue: float64(req.LoopIndex)},
-	}
-	feature.Properties[insidesvc.FeatureIDProperty] = &structpb.Value{
-		Kind: &structpb.Value_NumberValue{NumberValue: float64(req.Id)},
+	feature, err := protoFeature(f, fid, false, mask)
+	if err != nil {
+		return nil, err
 	}
-	resp.Id = req.Id
-	resp.Feature = feature
 
-	return resp, nil
+	return &insidesvc.GetResponse{
+		Id:         id,
+		Feature:    feature,
+		ExternalId: f.ExternalID,
+	}, nil
 }
 
 // Stab returns features containing lat lng.
 func (s *Server) IndexStab(lat, lng float64) ([]*insideout.Feature, error) {
 	var res []*insideout.Feature
 
-	idxResp, err := s.idx.Stab(lat, lng)
+	d, err := s.acquire("")
+	if err != nil {
+		return nil, err
+	}
+
+	defer d.release()
+
+	idxResp, err := d.idx.Stab(lat, lng)
 	if err != nil {
 		return nil, fmt.Errorf("stabbing error: %w", err)
 	}
 
 	for _, fid := range idxResp.IDsInside {
-		f, err := s.feature(fid.ID)
+		f, err := d.feature(fid.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -384,13 +504,13 @@ func (s *Server) IndexStab(lat, lng float64) ([]*insideout.Feature, error) {
 	}
 
 	for _, fid := range idxResp.IDsMayBeInside {
-		f, err := s.feature(fid.ID)
+		f, err := d.feature(fid.ID)
 		if err != nil {
 			return nil, err
 		}
 
-		l := f.Loops[fid.Pos]
-		if l.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))) {
+		poly := f.Polygons[fid.Pos]
+		if poly.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))) {
 			level.Warn(s.logger).Log("msg", "Found outside + PIP feature",
 				"fid", fid.ID,
 				"properties", f.Properties,
@@ -403,6 +523,49 @@ func (s *Server) IndexStab(lat, lng float64) ([]*insideout.Feature, error) {
 	return res, nil
 }
 
+// geometryFromPolygon returns the protobuf Geometry of a polygon, holes included,
+// polygons crossing the antimeridian are returned cut as a multipolygon.
+func geometryFromPolygon(p *s2.Polygon) *insidesvc.Geometry {
+	coords, endss := insideout.CoordinatesFromPolygon(p)
+
+	if len(endss) == 1 {
+		return &insidesvc.Geometry{
+			Type:        insidesvc.Geometry_TYPE_POLYGON,
+			Coordinates: coords,
+			Ends:        uint32Ends(endss[0], 0),
+		}
+	}
+
+	g := &insidesvc.Geometry{
+		Type:       insidesvc.Geometry_TYPE_MULTIPOLYGON,
+		Geometries: make([]*insidesvc.Geometry, len(endss)),
+	}
+
+	start := 0
+
+	for i, ends := range endss {
+		end := ends[len(ends)-1]
+		g.Geometries[i] = &insidesvc.Geometry{
+			Type:        insidesvc.Geometry_TYPE_POLYGON,
+			Coordinates: coords[start:end],
+			Ends:        uint32Ends(ends, start),
+		}
+		start = end
+	}
+
+	return g
+}
+
+// uint32Ends returns the ends relative to start.
+func uint32Ends(ends []int, start int) []uint32 {
+	res := make([]uint32, len(ends))
+	for i, end := range ends {
+		res[i] = uint32(end - start)
+	}
+
+	return res
+}
+
 func (s *Server) handleError(terr error, span opentracing.Span) {
 	if terr != nil {
 		// do not log not found as error
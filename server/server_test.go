@@ -0,0 +1,1376 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
+	"github.com/akhenakh/insideout/server"
+	"github.com/akhenakh/insideout/storage/bbolt"
+	"github.com/akhenakh/insideout/storage/memory"
+)
+
+func TestServer_WithinFields(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	tests := []struct {
+		name     string
+		fields   []string
+		wantKeys []string
+		wantErr  bool
+	}{
+		{
+			"all properties",
+			nil,
+			[]string{"insee", "nom", "surf_ha", "wikipedia", insidesvc.FeatureIDProperty, insidesvc.LoopIndexProperty},
+			false,
+		},
+		{
+			"exact field",
+			[]string{"nom"},
+			[]string{"nom", insidesvc.FeatureIDProperty, insidesvc.LoopIndexProperty},
+			false,
+		},
+		{
+			"wildcard",
+			[]string{"*i*"},
+			[]string{"insee", "wikipedia", insidesvc.FeatureIDProperty, insidesvc.LoopIndexProperty},
+			false,
+		},
+		{
+			"invalid pattern",
+			[]string{"nom["},
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{
+				Lat:    47.39650628189986,
+				Lng:    -2.9876390969486524,
+				Fields: tt.fields,
+			})
+			if tt.wantErr {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, resp.Responses, 1)
+
+			var keys []string
+			for k := range resp.Responses[0].Feature.Properties {
+				keys = append(keys, k)
+			}
+
+			require.ElementsMatch(t, tt.wantKeys, keys)
+
+			gresp, err := s.Get(context.Background(), &insidesvc.GetRequest{Id: 0, LoopIndex: 1, Fields: tt.fields})
+			require.NoError(t, err)
+
+			keys = nil
+			for k := range gresp.Feature.Properties {
+				keys = append(keys, k)
+			}
+
+			require.ElementsMatch(t, tt.wantKeys, keys)
+		})
+	}
+}
+
+func TestServer_WithinFilter(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	tests := []struct {
+		name      string
+		filter    string
+		wantCount int
+		wantErr   bool
+	}{
+		{"no filter", "", 1, false},
+		{"matching number", "surf_ha >= 300", 1, false},
+		{"not matching number", "surf_ha < 300", 0, false},
+		{"matching in", `insee in ["56086", "56000"]`, 1, false},
+		{"not matching string", `nom == "Paris"`, 0, false},
+		{"missing property", "admin_level == 8", 0, false},
+		{"invalid expression", "surf_ha >=", 0, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{
+				Lat:           47.39650628189986,
+				Lng:           -2.9876390969486524,
+				RemoveFeature: true,
+				Filter:        tt.filter,
+			})
+			if tt.wantErr {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, resp.Responses, tt.wantCount)
+		})
+	}
+}
+
+// countingStore counts the properties loaded from the storage.
+type countingStore struct {
+	insideout.Store
+	propertiesLoads int32
+}
+
+func (s *countingStore) LoadFeatureProperties(id uint32) (map[string]interface{}, error) {
+	atomic.AddInt32(&s.propertiesLoads, 1)
+
+	return s.Store.LoadFeatureProperties(id)
+}
+
+func TestServer_WithinFilterCache(t *testing.T) {
+	storage := &countingStore{Store: memoryStorage(t, "../index/testdata/poly.geojson")}
+
+	s, err := server.New(context.Background(), storage, log.NewNopLogger(), health.NewServer(),
+		server.Options{Strategy: insideout.DBStrategy, CacheCount: 10})
+	require.NoError(t, err)
+
+	within := func() {
+		resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{
+			Lat:           47.39650628189986,
+			Lng:           -2.9876390969486524,
+			RemoveFeature: true,
+			Filter:        "surf_ha < 300",
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Responses)
+	}
+
+	within()
+	require.Equal(t, int32(1), atomic.LoadInt32(&storage.propertiesLoads))
+
+	// filtered out properties are cached once set
+	require.Eventually(t, func() bool {
+		loads := atomic.LoadInt32(&storage.propertiesLoads)
+		within()
+
+		return atomic.LoadInt32(&storage.propertiesLoads) == loads
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServer_WithinOrder(t *testing.T) {
+	s, clean := setupFile(t, "testdata/nested.geojson", server.Options{Strategy: insideout.DBStrategy})
+	defer clean()
+
+	tests := []struct {
+		name          string
+		order         insidesvc.Order
+		orderProperty string
+		mostSpecific  bool
+		want          []string
+	}{
+		{"server default", insidesvc.Order_ORDER_UNSPECIFIED, "", false, []string{"region", "city", "park"}},
+		{"property", insidesvc.Order_ORDER_PROPERTY, "admin_level", false, []string{"region", "city", "park"}},
+		{"area", insidesvc.Order_ORDER_AREA, "", false, []string{"city", "park", "region"}},
+		{"insertion", insidesvc.Order_ORDER_INSERTION, "", false, []string{"region", "city", "park"}},
+		{"most specific", insidesvc.Order_ORDER_UNSPECIFIED, "", true, []string{"city"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{
+				Lat:           47.5,
+				Lng:           1.5,
+				Order:         tt.order,
+				OrderProperty: tt.orderProperty,
+				MostSpecific:  tt.mostSpecific,
+				Fields:        []string{"name"},
+			})
+			require.NoError(t, err)
+
+			names := make([]string, len(resp.Responses))
+			for i, r := range resp.Responses {
+				names[i] = r.Feature.Properties["name"].GetStringValue()
+			}
+
+			require.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestServer_DatasetsOrder(t *testing.T) {
+	s, clean := setupFile(t, "testdata/nested.geojson", server.Options{Strategy: insideout.DBStrategy})
+	defer clean()
+
+	err := s.AddDataset(context.Background(), "copy", memoryStorage(t, "testdata/nested.geojson"),
+		server.DatasetOptions{Strategy: insideout.ShapeIndexStrategy})
+	require.NoError(t, err)
+
+	datasets := []string{"copy", server.DefaultDataset}
+
+	tests := []struct {
+		name         string
+		order        insidesvc.Order
+		mostSpecific bool
+		want         []string
+	}{
+		{"property", insidesvc.Order_ORDER_PROPERTY, false, []string{
+			"copy/region", "default/region", "copy/city", "default/city", "copy/park", "default/park",
+		}},
+		{"area", insidesvc.Order_ORDER_AREA, false, []string{
+			"copy/city", "default/city", "copy/park", "default/park", "copy/region", "default/region",
+		}},
+		{"insertion", insidesvc.Order_ORDER_INSERTION, false, []string{
+			"copy/region", "copy/city", "copy/park", "default/region", "default/city", "default/park",
+		}},
+		{"most specific", insidesvc.Order_ORDER_UNSPECIFIED, true, []string{"copy/city"}},
+	}
+
+	names := func(resp *insidesvc.WithinResponse) []string {
+		names := make([]string, len(resp.Responses))
+		for i, r := range resp.Responses {
+			names[i] = r.Dataset + "/" + r.Feature.Properties["name"].GetStringValue()
+		}
+
+		return names
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{
+				Lat:          47.5,
+				Lng:          1.5,
+				Order:        tt.order,
+				MostSpecific: tt.mostSpecific,
+				Fields:       []string{"name"},
+				Datasets:     datasets,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, names(resp))
+
+			bresp, err := s.BatchWithin(context.Background(), &insidesvc.BatchWithinRequest{
+				Points:       []*insidesvc.Point{{Lat: 47.5, Lng: 1.5}},
+				Order:        tt.order,
+				MostSpecific: tt.mostSpecific,
+				Fields:       []string{"name"},
+				Datasets:     datasets,
+			})
+			require.NoError(t, err)
+			require.Len(t, bresp.Responses, 1)
+			require.Equal(t, tt.want, names(bresp.Responses[0]))
+		})
+	}
+}
+
+func TestServer_WithinMostSpecificRemoveFeature(t *testing.T) {
+	s, clean := setupFile(t, "testdata/nested.geojson", server.Options{Strategy: insideout.DBStrategy, Order: server.OrderInsertion})
+	defer clean()
+
+	resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{
+		Lat:           47.5,
+		Lng:           1.5,
+		MostSpecific:  true,
+		RemoveFeature: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	require.Equal(t, uint32(1), resp.Responses[0].Id)
+	require.Nil(t, resp.Responses[0].Feature)
+}
+
+func TestServer_ExternalID(t *testing.T) {
+	s, clean := setupFile(t, "testdata/nested.geojson", server.Options{Strategy: insideout.DBStrategy, Order: server.OrderInsertion})
+	defer clean()
+
+	for _, removeFeature := range []bool{false, true} {
+		resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{
+			Lat:           47.5,
+			Lng:           1.5,
+			RemoveFeature: removeFeature,
+		})
+		require.NoError(t, err)
+
+		ids := make([]string, len(resp.Responses))
+		for i, r := range resp.Responses {
+			ids[i] = r.ExternalId
+		}
+
+		require.Equal(t, []string{"44", "city-1", ""}, ids)
+	}
+
+	gresp, err := s.Get(context.Background(), &insidesvc.GetRequest{ExternalId: "city-1"})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), gresp.Id)
+	require.Equal(t, "city-1", gresp.ExternalId)
+	require.Equal(t, "city", gresp.Feature.Properties["name"].GetStringValue())
+
+	_, err = s.Get(context.Background(), &insidesvc.GetRequest{ExternalId: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_BatchWithin(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	points := []*insidesvc.Point{
+		{Lat: 48.02, Lng: 2.02},
+		{Lat: 48.05, Lng: 2.05},
+		{Lat: 47.39650628189986, Lng: -2.9876390969486524},
+		{Lat: 10, Lng: 10},
+		{Lat: 48.02, Lng: 2.02},
+	}
+
+	resp, err := s.BatchWithin(context.Background(), &insidesvc.BatchWithinRequest{Points: points})
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, len(points))
+
+	for i, p := range points {
+		want, err := s.Within(context.Background(), &insidesvc.WithinRequest{Lat: p.Lat, Lng: p.Lng})
+		require.NoError(t, err)
+
+		if !proto.Equal(want, resp.Responses[i]) {
+			t.Fatalf("BatchWithin() point %d got = %v, want %v", i, resp.Responses[i], want)
+		}
+	}
+
+	var gotIDs [][]uint32
+
+	for _, wresp := range resp.Responses {
+		var ids []uint32
+		for _, fresp := range wresp.Responses {
+			ids = append(ids, fresp.Id)
+		}
+
+		gotIDs = append(gotIDs, ids)
+	}
+
+	require.Equal(t, [][]uint32{{1}, nil, {0}, nil, {1}}, gotIDs)
+}
+
+func TestServer_Nearest(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	tests := []struct {
+		name         string
+		req          *insidesvc.NearestRequest
+		wantIDs      []uint32
+		wantDistance []float64
+	}{
+		{
+			"inside polygon",
+			&insidesvc.NearestRequest{Lat: 48.02, Lng: 2.02},
+			[]uint32{1},
+			[]float64{0},
+		},
+		{
+			"inside hole",
+			&insidesvc.NearestRequest{Lat: 48.05, Lng: 2.05},
+			[]uint32{1},
+			[]float64{744},
+		},
+		{
+			"inside hole too far",
+			&insidesvc.NearestRequest{Lat: 48.05, Lng: 2.05, MaxDistance: 500},
+			nil,
+			nil,
+		},
+		{
+			"offshore",
+			&insidesvc.NearestRequest{Lat: 47.3930, Lng: -2.9990, MaxDistance: 500, Limit: 2},
+			[]uint32{0},
+			[]float64{139},
+		},
+		{
+			"far away",
+			&insidesvc.NearestRequest{Lat: 10, Lng: 10},
+			nil,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Nearest(context.Background(), tt.req)
+			require.NoError(t, err)
+
+			var (
+				gotIDs      []uint32
+				gotDistance []float64
+			)
+
+			for _, fresp := range resp.Responses {
+				gotIDs = append(gotIDs, fresp.Id)
+				gotDistance = append(gotDistance, fresp.Distance)
+
+				require.NotNil(t, fresp.Feature)
+				require.NotNil(t, fresp.ClosestPoint)
+			}
+
+			require.Equal(t, tt.wantIDs, gotIDs)
+			require.InDeltaSlice(t, tt.wantDistance, gotDistance, 1)
+		})
+	}
+}
+
+func TestServer_NearestLimit(t *testing.T) {
+	s, clean := setupFile(t, "testdata/nested.geojson", server.Options{Strategy: insideout.DBStrategy})
+	defer clean()
+
+	// features containing the point are ordered by id
+	tests := []struct {
+		name    string
+		req     *insidesvc.NearestRequest
+		wantIDs []uint32
+	}{
+		{"containing", &insidesvc.NearestRequest{Lat: 47.5, Lng: 1.5, Limit: 2}, []uint32{0, 1}},
+		{"all", &insidesvc.NearestRequest{Lat: 47.5, Lng: 1.5, Limit: 10}, []uint32{0, 1, 2}},
+		{"outside", &insidesvc.NearestRequest{Lat: 47.5, Lng: 1.61, MaxDistance: 10000, Limit: 2}, []uint32{0, 2}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Nearest(context.Background(), tt.req)
+			require.NoError(t, err)
+
+			var gotIDs []uint32
+			for _, fresp := range resp.Responses {
+				gotIDs = append(gotIDs, fresp.Id)
+			}
+
+			require.Equal(t, tt.wantIDs, gotIDs)
+		})
+	}
+}
+
+func TestServer_NearestMaxDistance(t *testing.T) {
+	s, clean := setupFile(t, "../index/testdata/poly.geojson",
+		server.Options{Strategy: insideout.DBStrategy, MaxNearestDistance: 1000})
+	defer clean()
+
+	_, err := s.Nearest(context.Background(), &insidesvc.NearestRequest{Lat: 48.05, Lng: 2.05, MaxDistance: 1000})
+	require.NoError(t, err)
+
+	_, err = s.Nearest(context.Background(), &insidesvc.NearestRequest{Lat: 48.05, Lng: 2.05, MaxDistance: 1001})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.WithinRadius(context.Background(), &insidesvc.WithinRadiusRequest{Lat: 48.05, Lng: 2.05, Radius: 1000})
+	require.NoError(t, err)
+
+	_, err = s.WithinRadius(context.Background(), &insidesvc.WithinRadiusRequest{Lat: 48.05, Lng: 2.05, Radius: 1001})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_ErrorCounter(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	errorCount := func() float64 {
+		mfs, err := prometheus.DefaultGatherer.Gather()
+		require.NoError(t, err)
+
+		for _, mf := range mfs {
+			if mf.GetName() == "insided_server_error_total" {
+				return mf.GetMetric()[0].GetCounter().GetValue()
+			}
+		}
+
+		return 0
+	}
+
+	before := errorCount()
+
+	// the returned error is handled, not the one at defer time
+	_, err := s.Nearest(context.Background(), &insidesvc.NearestRequest{Lat: 48.05, Lng: 2.05, MaxDistance: 1e9})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.Intersects(context.Background(), &insidesvc.IntersectsRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	require.Equal(t, before+2, errorCount())
+}
+
+func TestServer_Intersects(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	tests := []struct {
+		name    string
+		req     *insidesvc.IntersectsRequest
+		wantIDs []uint32
+		wantErr bool
+	}{
+		{
+			"bbox around polygon with hole",
+			&insidesvc.IntersectsRequest{Region: &insidesvc.IntersectsRequest_Bbox{
+				Bbox: &insidesvc.BBox{MinLat: 47.99, MinLng: 1.99, MaxLat: 48.11, MaxLng: 2.11},
+			}},
+			[]uint32{1},
+			false,
+		},
+		{
+			"bbox inside hole",
+			&insidesvc.IntersectsRequest{Region: &insidesvc.IntersectsRequest_Bbox{
+				Bbox: &insidesvc.BBox{MinLat: 48.045, MinLng: 2.045, MaxLat: 48.055, MaxLng: 2.055},
+			}},
+			nil,
+			false,
+		},
+		{
+			"polygon crossing the hole",
+			&insidesvc.IntersectsRequest{Region: &insidesvc.IntersectsRequest_Polygon{
+				Polygon: &insidesvc.Geometry{
+					Type:        insidesvc.Geometry_TYPE_POLYGON,
+					Coordinates: []float64{2.05, 48.05, 2.2, 48.05, 2.2, 48.2, 2.05, 48.05},
+				},
+			}},
+			[]uint32{1},
+			false,
+		},
+		{
+			"invalid polygon",
+			&insidesvc.IntersectsRequest{Region: &insidesvc.IntersectsRequest_Polygon{
+				Polygon: &insidesvc.Geometry{
+					Type:        insidesvc.Geometry_TYPE_POLYGON,
+					Coordinates: []float64{2.05, 48.05, 2.2},
+				},
+			}},
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Intersects(context.Background(), tt.req)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			var gotIDs []uint32
+			for _, fresp := range resp.Responses {
+				gotIDs = append(gotIDs, fresp.Id)
+			}
+
+			require.Equal(t, tt.wantIDs, gotIDs)
+		})
+	}
+}
+
+func TestServer_WithinRadius(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	tests := []struct {
+		name         string
+		req          *insidesvc.WithinRadiusRequest
+		wantIDs      []uint32
+		wantDistance []float64
+		wantErr      bool
+	}{
+		{
+			"offshore island feature once",
+			&insidesvc.WithinRadiusRequest{Lat: 47.3930, Lng: -2.9990, Radius: 500},
+			[]uint32{0},
+			[]float64{139},
+			false,
+		},
+		{
+			"offshore closest island polygon",
+			&insidesvc.WithinRadiusRequest{Lat: 47.3930, Lng: -2.9990, Radius: 200},
+			[]uint32{0},
+			[]float64{139},
+			false,
+		},
+		{
+			"inside hole",
+			&insidesvc.WithinRadiusRequest{Lat: 48.05, Lng: 2.05, Radius: 800},
+			[]uint32{1},
+			[]float64{744},
+			false,
+		},
+		{
+			"invalid radius",
+			&insidesvc.WithinRadiusRequest{Lat: 48.05, Lng: 2.05},
+			nil,
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.WithinRadius(context.Background(), tt.req)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			var (
+				gotIDs      []uint32
+				gotDistance []float64
+			)
+
+			for _, fresp := range resp.Responses {
+				gotIDs = append(gotIDs, fresp.Id)
+				gotDistance = append(gotDistance, fresp.Distance)
+			}
+
+			require.Equal(t, tt.wantIDs, gotIDs)
+			require.InDeltaSlice(t, tt.wantDistance, gotDistance, 1)
+		})
+	}
+}
+
+func TestServer_Trajectory(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	start := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		points    []*insidesvc.TrajectoryPoint
+		wantIDs   []uint32
+		wantEntry [][2]float64
+		wantExit  [][2]float64
+		wantErr   bool
+	}{
+		{
+			"crossing the polygon and its hole",
+			[]*insidesvc.TrajectoryPoint{
+				{Lat: 48.05, Lng: 1.95, Time: timestamppb.New(start)},
+				{Lat: 48.05, Lng: 2.15, Time: timestamppb.New(start.Add(200 * time.Second))},
+			},
+			[]uint32{1, 1},
+			[][2]float64{{48.05, 2.0}, {48.05, 2.06}},
+			[][2]float64{{48.05, 2.04}, {48.05, 2.1}},
+			false,
+		},
+		{
+			"starting inside",
+			[]*insidesvc.TrajectoryPoint{
+				{Lat: 48.02, Lng: 2.02},
+				{Lat: 48.02, Lng: 2.2},
+			},
+			[]uint32{1},
+			[][2]float64{{48.02, 2.02}},
+			[][2]float64{{48.02, 2.1}},
+			false,
+		},
+		{
+			"ending inside",
+			[]*insidesvc.TrajectoryPoint{
+				{Lat: 47.9, Lng: 2.02},
+				{Lat: 47.95, Lng: 2.02},
+				{Lat: 48.02, Lng: 2.02},
+			},
+			[]uint32{1},
+			[][2]float64{{48.0, 2.02}},
+			[][2]float64{{}},
+			false,
+		},
+		{
+			"far away",
+			[]*insidesvc.TrajectoryPoint{
+				{Lat: 10, Lng: 10},
+				{Lat: 10.1, Lng: 10.1},
+			},
+			nil,
+			nil,
+			nil,
+			false,
+		},
+		{
+			"single point",
+			[]*insidesvc.TrajectoryPoint{{Lat: 48.02, Lng: 2.02}},
+			nil,
+			nil,
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Trajectory(context.Background(), &insidesvc.TrajectoryRequest{Points: tt.points})
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			var (
+				gotIDs   []uint32
+				gotEntry [][2]float64
+				gotExit  [][2]float64
+			)
+
+			for _, c := range resp.Crossings {
+				gotIDs = append(gotIDs, c.Id)
+				gotEntry = append(gotEntry, [2]float64{c.Entry.Lat, c.Entry.Lng})
+
+				var exit [2]float64
+				if c.Exit != nil {
+					exit = [2]float64{c.Exit.Lat, c.Exit.Lng}
+				}
+
+				gotExit = append(gotExit, exit)
+			}
+
+			require.Equal(t, tt.wantIDs, gotIDs)
+
+			for i := range tt.wantEntry {
+				require.InDeltaSlice(t, tt.wantEntry[i][:], gotEntry[i][:], 1e-3)
+				require.InDeltaSlice(t, tt.wantExit[i][:], gotExit[i][:], 1e-3)
+			}
+		})
+	}
+
+	// entry time is interpolated along the segment
+	resp, err := s.Trajectory(context.Background(), &insidesvc.TrajectoryRequest{Points: tests[0].points})
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Crossings)
+	require.NotNil(t, resp.Crossings[0].Entry.Time)
+	require.InDelta(t, 50, resp.Crossings[0].Entry.Time.AsTime().Sub(start).Seconds(), 1)
+}
+
+func TestServer_Geofence(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	c, cclose := grpcClient(t, s)
+	defer cclose()
+
+	stream, err := c.Geofence(context.Background())
+	require.NoError(t, err)
+
+	start := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	updates := []struct {
+		lat, lng float64
+		at       time.Duration
+		want     []insidesvc.GeofenceEvent_Type
+	}{
+		{47.9, 2.02, 0, nil},
+		{48.02, 2.02, time.Minute, []insidesvc.GeofenceEvent_Type{insidesvc.GeofenceEvent_TYPE_ENTER}},
+		{48.03, 2.02, 10 * time.Minute, []insidesvc.GeofenceEvent_Type{insidesvc.GeofenceEvent_TYPE_DWELL}},
+		{48.05, 2.05, 11 * time.Minute, []insidesvc.GeofenceEvent_Type{insidesvc.GeofenceEvent_TYPE_EXIT}},
+	}
+
+	for i, u := range updates {
+		err := stream.Send(&insidesvc.GeofenceRequest{
+			DeviceId: "device",
+			Lat:      u.lat,
+			Lng:      u.lng,
+			Time:     timestamppb.New(start.Add(u.at)),
+		})
+		require.NoError(t, err)
+
+		for _, want := range u.want {
+			e, err := stream.Recv()
+			require.NoError(t, err)
+			require.Equal(t, want, e.Type, "update %d", i)
+			require.Equal(t, uint32(1), e.Id)
+			require.Equal(t, "device", e.DeviceId)
+			require.NotNil(t, e.Feature)
+			require.Nil(t, e.Feature.Geometry)
+		}
+	}
+
+	require.NoError(t, stream.CloseSend())
+
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestServer_GeofenceReload(t *testing.T) {
+	s, clean := setupFile(t, "testdata/nested.geojson",
+		server.Options{Strategy: insideout.InsideTreeStrategy, StopOnFirstFound: true})
+	defer clean()
+
+	c, cclose := grpcClient(t, s)
+	defer cclose()
+
+	stream, err := c.Geofence(context.Background())
+	require.NoError(t, err)
+
+	recv := func(count int) []*insidesvc.GeofenceEvent {
+		events := make([]*insidesvc.GeofenceEvent, count)
+		for i := range events {
+			events[i], err = stream.Recv()
+			require.NoError(t, err)
+		}
+
+		return events
+	}
+
+	// every overlapping feature is entered even when stopping on the first found
+	err = stream.Send(&insidesvc.GeofenceRequest{DeviceId: "device", Lat: 47.5, Lng: 1.5})
+	require.NoError(t, err)
+
+	extIDs := make(map[string]bool)
+
+	for _, e := range recv(3) {
+		require.Equal(t, insidesvc.GeofenceEvent_TYPE_ENTER, e.Type)
+		require.NotNil(t, e.Feature)
+
+		extIDs[e.ExternalId] = true
+	}
+
+	require.Equal(t, map[string]bool{"44": true, "city-1": true, "": true}, extIDs)
+
+	// the features are gone after a reload
+	err = s.Reload(context.Background(), "", memoryStorage(t, "../index/testdata/poly.geojson"), nil)
+	require.NoError(t, err)
+
+	err = stream.Send(&insidesvc.GeofenceRequest{DeviceId: "device", Lat: 47.5, Lng: 1.5})
+	require.NoError(t, err)
+
+	for _, e := range recv(3) {
+		require.Equal(t, insidesvc.GeofenceEvent_TYPE_EXIT, e.Type)
+		require.Nil(t, e.Feature)
+	}
+
+	require.NoError(t, stream.CloseSend())
+
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestServer_GeofenceDatasets(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	err := s.AddDataset(context.Background(), "nested", memoryStorage(t, "testdata/nested.geojson"),
+		server.DatasetOptions{Strategy: insideout.ShapeIndexStrategy})
+	require.NoError(t, err)
+
+	c, cclose := grpcClient(t, s)
+	defer cclose()
+
+	stream, err := c.Geofence(context.Background())
+	require.NoError(t, err)
+
+	start := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// memberships of a dataset are not exited by positions queried on another one
+	updates := []struct {
+		dataset  string
+		lat, lng float64
+		want     []insidesvc.GeofenceEvent_Type
+	}{
+		{"nested", 47.5, 1.5, []insidesvc.GeofenceEvent_Type{
+			insidesvc.GeofenceEvent_TYPE_ENTER, insidesvc.GeofenceEvent_TYPE_ENTER, insidesvc.GeofenceEvent_TYPE_ENTER,
+		}},
+		{"", 47.39650628189986, -2.9876390969486524, []insidesvc.GeofenceEvent_Type{insidesvc.GeofenceEvent_TYPE_ENTER}},
+		{"nested", 47.39650628189986, -2.9876390969486524, []insidesvc.GeofenceEvent_Type{
+			insidesvc.GeofenceEvent_TYPE_EXIT, insidesvc.GeofenceEvent_TYPE_EXIT, insidesvc.GeofenceEvent_TYPE_EXIT,
+		}},
+	}
+
+	for i, u := range updates {
+		err := stream.Send(&insidesvc.GeofenceRequest{
+			DeviceId: "device",
+			Lat:      u.lat,
+			Lng:      u.lng,
+			Time:     timestamppb.New(start.Add(time.Duration(i) * time.Minute)),
+			Dataset:  u.dataset,
+		})
+		require.NoError(t, err)
+
+		wantDataset := u.dataset
+		if wantDataset == "" {
+			wantDataset = server.DefaultDataset
+		}
+
+		for _, want := range u.want {
+			e, err := stream.Recv()
+			require.NoError(t, err)
+			require.Equal(t, want, e.Type, "update %d", i)
+			require.Equal(t, wantDataset, e.Dataset, "update %d", i)
+			require.NotNil(t, e.Feature)
+		}
+	}
+
+	err = stream.Send(&insidesvc.GeofenceRequest{DeviceId: "device", Lat: 47.5, Lng: 1.5, Dataset: "unknown"})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_WithinStream(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	c, cclose := grpcClient(t, s)
+	defer cclose()
+
+	stream, err := c.WithinStream(context.Background())
+	require.NoError(t, err)
+
+	points := []*insidesvc.Point{
+		{Lat: 48.02, Lng: 2.02},
+		{Lat: 48.05, Lng: 2.05},
+		{Lat: 47.39650628189986, Lng: -2.9876390969486524},
+		{Lat: 10, Lng: 10},
+	}
+	wantIDs := [][]uint32{{1}, nil, {0}, nil}
+
+	// send more requests than the in flight limit before reading
+	count := server.WithinStreamMaxInFlight * 2
+
+	go func() {
+		for i := 0; i < count; i++ {
+			p := points[i%len(points)]
+
+			err := stream.Send(&insidesvc.WithinStreamRequest{
+				CorrelationId: strconv.Itoa(i),
+				Request:       &insidesvc.WithinRequest{Lat: p.Lat, Lng: p.Lng, RemoveFeature: true},
+			})
+			if err != nil {
+				return
+			}
+		}
+
+		stream.CloseSend()
+	}()
+
+	for i := 0; i < count; i++ {
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+
+		// responses are in the requests order
+		require.Equal(t, strconv.Itoa(i), resp.CorrelationId)
+
+		var ids []uint32
+		for _, fresp := range resp.Response.Responses {
+			ids = append(ids, fresp.Id)
+		}
+
+		require.Equal(t, wantIDs[i%len(points)], ids)
+	}
+
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestServer_WithinStreamInvalidRequest(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	c, cclose := grpcClient(t, s)
+	defer cclose()
+
+	stream, err := c.WithinStream(context.Background())
+	require.NoError(t, err)
+
+	houat := &insidesvc.WithinRequest{Lat: 47.39650628189986, Lng: -2.9876390969486524, RemoveFeature: true}
+
+	// failed requests are answered with their status and the stream stays open
+	reqs := []struct {
+		req      *insidesvc.WithinStreamRequest
+		wantCode codes.Code
+	}{
+		{&insidesvc.WithinStreamRequest{CorrelationId: "missing"}, codes.InvalidArgument},
+		{&insidesvc.WithinStreamRequest{CorrelationId: "filter", Request: &insidesvc.WithinRequest{
+			Lat: houat.Lat, Lng: houat.Lng, Filter: "admin_level ==",
+		}}, codes.InvalidArgument},
+		{&insidesvc.WithinStreamRequest{CorrelationId: "dataset", Request: &insidesvc.WithinRequest{
+			Lat: houat.Lat, Lng: houat.Lng, Datasets: []string{"unknown"},
+		}}, codes.NotFound},
+		{&insidesvc.WithinStreamRequest{CorrelationId: "valid", Request: houat}, codes.OK},
+	}
+
+	for _, r := range reqs {
+		require.NoError(t, stream.Send(r.req))
+	}
+
+	require.NoError(t, stream.CloseSend())
+
+	for _, r := range reqs {
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, r.req.CorrelationId, resp.CorrelationId)
+
+		if r.wantCode == codes.OK {
+			require.Nil(t, resp.Status)
+			require.Len(t, resp.Response.Responses, 1)
+
+			continue
+		}
+
+		require.NotNil(t, resp.Status)
+		require.Equal(t, r.wantCode, codes.Code(resp.Status.Code))
+		require.NotEmpty(t, resp.Status.Message)
+		require.Nil(t, resp.Response)
+	}
+
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestServer_Reload(t *testing.T) {
+	s, clean := setupFile(t, "../index/testdata/poly.geojson",
+		server.Options{Strategy: insideout.ShapeIndexStrategy, CacheCount: 10})
+	defer clean()
+
+	houat := &insidesvc.WithinRequest{Lat: 47.39650628189986, Lng: -2.9876390969486524, RemoveGeometries: true}
+	nested := &insidesvc.WithinRequest{Lat: 47.5, Lng: 1.5, RemoveGeometries: true}
+
+	resp, err := s.Within(context.Background(), houat)
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+
+	// queries are served by the previous dataset during the reload
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var (
+		wg     sync.WaitGroup
+		failed int32
+	)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				if _, err := s.Within(context.Background(), houat); err != nil {
+					atomic.AddInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+
+	var firstClosed, secondClosed int32
+
+	err = s.Reload(context.Background(), "", memoryStorage(t, "../index/testdata/poly.geojson"), func() error {
+		atomic.AddInt32(&firstClosed, 1)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	cancel()
+	wg.Wait()
+	require.Zero(t, failed)
+
+	err = s.Reload(context.Background(), "", memoryStorage(t, "testdata/nested.geojson"), func() error {
+		atomic.AddInt32(&secondClosed, 1)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// the replaced storage is closed once drained
+	require.Equal(t, int32(1), atomic.LoadInt32(&firstClosed))
+	require.Zero(t, atomic.LoadInt32(&secondClosed))
+	require.Equal(t, "nested.geojson", s.IndexInfos("").Filename)
+
+	resp, err = s.Within(context.Background(), houat)
+	require.NoError(t, err)
+	require.Empty(t, resp.Responses)
+
+	resp, err = s.Within(context.Background(), nested)
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 3)
+
+	require.NoError(t, s.Close())
+	require.Equal(t, int32(1), atomic.LoadInt32(&secondClosed))
+}
+
+func TestServer_Close(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	houat := &insidesvc.WithinRequest{Lat: 47.39650628189986, Lng: -2.9876390969486524}
+
+	// queries racing the close either succeed or fail as unavailable
+	var (
+		wg         sync.WaitGroup
+		unexpected int32
+	)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				_, err := s.Within(context.Background(), houat)
+				if err != nil {
+					if status.Code(err) != codes.Unavailable {
+						atomic.AddInt32(&unexpected, 1)
+					}
+
+					return
+				}
+			}
+		}()
+	}
+
+	require.NoError(t, s.Close())
+	wg.Wait()
+	require.Zero(t, unexpected)
+
+	_, err := s.Within(context.Background(), houat)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	err = s.AddDataset(context.Background(), "nested", memoryStorage(t, "testdata/nested.geojson"),
+		server.DatasetOptions{Strategy: insideout.ShapeIndexStrategy})
+	require.Error(t, err)
+}
+
+func TestServer_Datasets(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	err := s.AddDataset(context.Background(), "nested", memoryStorage(t, "testdata/nested.geojson"),
+		server.DatasetOptions{Strategy: insideout.ShapeIndexStrategy})
+	require.NoError(t, err)
+
+	err = s.AddDataset(context.Background(), "copy", memoryStorage(t, "../index/testdata/poly.geojson"),
+		server.DatasetOptions{Strategy: insideout.InsideTreeStrategy, CacheCount: 10})
+	require.NoError(t, err)
+
+	err = s.AddDataset(context.Background(), "nested", memoryStorage(t, "testdata/nested.geojson"),
+		server.DatasetOptions{Strategy: insideout.DBStrategy})
+	require.Error(t, err)
+
+	houat := &insidesvc.Point{Lat: 47.39650628189986, Lng: -2.9876390969486524}
+	nested := &insidesvc.Point{Lat: 47.5, Lng: 1.5}
+
+	tests := []struct {
+		name         string
+		point        *insidesvc.Point
+		datasets     []string
+		wantDatasets []string
+		wantCode     codes.Code
+	}{
+		{"default", houat, nil, []string{server.DefaultDataset}, codes.OK},
+		{"named default", houat, []string{server.DefaultDataset}, []string{server.DefaultDataset}, codes.OK},
+		{"other", nested, []string{"nested"}, []string{"nested", "nested", "nested"}, codes.OK},
+		{"other misses", houat, []string{"nested"}, nil, codes.OK},
+		{"fan out", houat, []string{"copy", "nested", server.DefaultDataset}, []string{"copy", server.DefaultDataset}, codes.OK},
+		{"unknown", houat, []string{"nested", "unknown"}, nil, codes.NotFound},
+		{"duplicate", houat, []string{"nested", "nested"}, nil, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Within(context.Background(), &insidesvc.WithinRequest{
+				Lat:              tt.point.Lat,
+				Lng:              tt.point.Lng,
+				RemoveGeometries: true,
+				Datasets:         tt.datasets,
+			})
+			if tt.wantCode != codes.OK {
+				require.Equal(t, tt.wantCode, status.Code(err))
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			var datasets []string
+			for _, fresp := range resp.Responses {
+				datasets = append(datasets, fresp.Dataset)
+			}
+
+			require.Equal(t, tt.wantDatasets, datasets)
+		})
+	}
+
+	// batches fan out per point
+	bresp, err := s.BatchWithin(context.Background(), &insidesvc.BatchWithinRequest{
+		Points:           []*insidesvc.Point{houat, nested},
+		RemoveGeometries: true,
+		Datasets:         []string{server.DefaultDataset, "nested"},
+	})
+	require.NoError(t, err)
+	require.Len(t, bresp.Responses, 2)
+	require.Len(t, bresp.Responses[0].Responses, 1)
+	require.Len(t, bresp.Responses[1].Responses, 3)
+
+	// ids are per dataset
+	gresp, err := s.Get(context.Background(), &insidesvc.GetRequest{Id: 0, Dataset: "nested"})
+	require.NoError(t, err)
+	require.Equal(t, "region", gresp.Feature.Properties["name"].GetStringValue())
+
+	_, err = s.Get(context.Background(), &insidesvc.GetRequest{Id: 0, Dataset: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	datasets := s.Datasets()
+	require.Len(t, datasets, 3)
+	require.Equal(t, "nested.geojson", datasets["nested"].Filename)
+	require.Equal(t, "poly.geojson", s.IndexInfos("").Filename)
+	require.Nil(t, s.IndexInfos("unknown"))
+
+	// reloading a named dataset keeps its options
+	err = s.Reload(context.Background(), "nested", memoryStorage(t, "../index/testdata/poly.geojson"), nil)
+	require.NoError(t, err)
+	require.Equal(t, "poly.geojson", s.IndexInfos("nested").Filename)
+
+	err = s.Reload(context.Background(), "unknown", memoryStorage(t, "../index/testdata/poly.geojson"), nil)
+	require.Error(t, err)
+}
+
+// memoryStorage returns a memory storage indexing the GeoJSON file at path.
+func memoryStorage(t *testing.T, path string) insideout.Store {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	fc, err := insideout.DecodeFeatureCollection(file)
+	require.NoError(t, err)
+
+	storage := memory.NewStorage(log.NewNopLogger())
+
+	err = storage.Index(insideout.NewFeatureCollectionIterator(fc), icoverer, ocoverer, 100, filepath.Base(path), "unittest")
+	require.NoError(t, err)
+
+	return storage
+}
+
+// grpcClient returns a client connected to s over an in memory connection.
+func grpcClient(t *testing.T, s *server.Server) (insidesvc.InsideServiceClient, func()) {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	insidesvc.RegisterInsideServiceServer(gs, s)
+
+	go gs.Serve(lis)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure())
+	require.NoError(t, err)
+
+	return insidesvc.NewInsideServiceClient(conn), func() {
+		conn.Close()
+		gs.Stop()
+	}
+}
+
+var (
+	icoverer = &s2.RegionCoverer{
+		MinLevel: 10,
+		MaxLevel: 16,
+		MaxCells: 24,
+	}
+	ocoverer = &s2.RegionCoverer{
+		MinLevel: 10,
+		MaxLevel: 15,
+		MaxCells: 16,
+	}
+)
+
+func setup(t *testing.T) (*server.Server, func()) {
+	t.Helper()
+
+	return setupFile(t, "../index/testdata/poly.geojson",
+		server.Options{Strategy: insideout.DBStrategy, DwellTime: 5 * time.Minute})
+}
+
+// setupFile returns a Server indexing the GeoJSON file at path.
+func setupFile(t *testing.T, path string, opts server.Options) (*server.Server, func()) {
+	t.Helper()
+
+	logger := log.NewLogfmtLogger(os.Stdout)
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+	wstorage, wclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	t.Log("db path", tmpFile.Name())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	fc, err := insideout.DecodeFeatureCollection(file)
+	require.NoError(t, err)
+
+	err = wstorage.Index(insideout.NewFeatureCollectionIterator(fc), icoverer, ocoverer, 100, filepath.Base(path), "unittest")
+	require.NoError(t, err)
+
+	err = wclose()
+	require.NoError(t, err)
+
+	// RO storage
+	storage, bclose, err := bbolt.NewROStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	s, err := server.New(context.Background(), storage, logger, health.NewServer(), opts)
+	require.NoError(t, err)
+
+	return s, func() {
+		t.Log("clean called")
+		bclose()
+		os.Remove(tmpFile.Name())
+	}
+}
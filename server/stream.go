@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
+)
+
+// WithinStreamMaxInFlight is the max count of requests processed concurrently per stream,
+// once reached the server stops reading the stream until the oldest response is sent.
+const WithinStreamMaxInFlight = 64
+
+// withinStreamResult the result of one stream request, resp holds the status of a failed request.
+type withinStreamResult struct {
+	resp *insidesvc.WithinStreamResponse
+	err  error
+}
+
+// WithinStream streaming exposed via gRPC.
+// Requests are processed concurrently, responses are sent in the order the requests were received,
+// a failed request is answered with its status and does not end the stream.
+// A client not reading the responses stops the server from reading new requests.
+func (s *Server) WithinStream(stream insidesvc.InsideService_WithinStreamServer) (terr error) {
+	span, ctx := opentracing.StartSpanFromContext(stream.Context(), "WithinStream")
+	defer span.Finish()
+
+	defer func() { s.handleError(terr, span) }()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// pending results in receiving order, its capacity bounds the requests in flight
+	pending := make(chan chan withinStreamResult, WithinStreamMaxInFlight)
+	recvErr := make(chan error, 1)
+
+	go func() {
+		defer close(pending)
+
+		for {
+			req, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				recvErr <- nil
+
+				return
+			}
+
+			if err != nil {
+				recvErr <- err
+
+				return
+			}
+
+			rc := make(chan withinStreamResult, 1)
+
+			select {
+			case pending <- rc:
+			case <-ctx.Done():
+				recvErr <- ctx.Err()
+
+				return
+			}
+
+			go func() {
+				resp, err := s.withinStreamResponse(req)
+				if err != nil {
+					resp = withinStreamErrorResponse(req.CorrelationId, err)
+				}
+
+				rc <- withinStreamResult{resp: resp, err: err}
+			}()
+		}
+	}()
+
+	for rc := range pending {
+		r := <-rc
+		if r.err != nil {
+			s.handleError(r.err, span)
+		}
+
+		if err := stream.Send(r.resp); err != nil {
+			return err
+		}
+	}
+
+	return <-recvErr
+}
+
+// withinStreamResponse returns the response to a stream request.
+func (s *Server) withinStreamResponse(req *insidesvc.WithinStreamRequest) (*insidesvc.WithinStreamResponse, error) {
+	wreq := req.Request
+	if wreq == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "missing request for correlation id %q", req.CorrelationId)
+	}
+
+	opts, err := s.requestOptions(wreq)
+	if err != nil {
+		return nil, err
+	}
+
+	// the datasets are held per request so a reload is not blocked by long lived streams
+	ds, err := s.acquireAll(wreq.Datasets)
+	if err != nil {
+		return nil, err
+	}
+
+	defer releaseAll(ds)
+
+	resp, err := s.within(ds, wreq.Lat, wreq.Lng, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &insidesvc.WithinStreamResponse{
+		CorrelationId: req.CorrelationId,
+		Response:      resp,
+	}, nil
+}
+
+// withinStreamErrorResponse returns the response to a failed stream request.
+func withinStreamErrorResponse(correlationID string, err error) *insidesvc.WithinStreamResponse {
+	st, _ := status.FromError(err)
+
+	return &insidesvc.WithinStreamResponse{
+		CorrelationId: correlationID,
+		Status: &insidesvc.WithinStreamResponse_Status{
+			Code:    uint32(st.Code()),
+			Message: st.Message(),
+		},
+	}
+}
@@ -0,0 +1,300 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/opentracing/opentracing-go"
+	slog "github.com/opentracing/opentracing-go/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/gen/go/insidesvc/v1"
+)
+
+// trajectoryMaxCells is the max cells count used to cover the path.
+const trajectoryMaxCells = 64
+
+// pathPosition is a position along a path, a segment index and a fraction of this segment.
+type pathPosition struct {
+	segment  int
+	fraction float64
+}
+
+// less reports whether p is before o along the path.
+func (p pathPosition) less(o pathPosition) bool {
+	if p.segment != o.segment {
+		return p.segment < o.segment
+	}
+
+	return p.fraction < o.fraction
+}
+
+// trajectoryCrossing is a continuous part of the path inside a polygon.
+type trajectoryCrossing struct {
+	shape int
+	entry pathPosition
+	exit  *pathPosition
+}
+
+// Trajectory query exposed via gRPC.
+func (s *Server) Trajectory(
+	ctx context.Context, req *insidesvc.TrajectoryRequest,
+) (resp *insidesvc.TrajectoryResponse, terr error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "Trajectory")
+	defer span.Finish()
+
+	defer func() { s.handleError(terr, span) }()
+
+	pts, err := pathFromRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	span.LogFields(slog.Int("points_count", len(pts)))
+
+	// candidates are the features with a cover intersecting the path
+	pl := s2.Polyline(pts)
+	coverer := &s2.RegionCoverer{MaxLevel: 30, MaxCells: trajectoryMaxCells}
+	cu := coverer.Covering(&pl)
+
+	d, err := s.acquire(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer d.release()
+
+	idxResp, err := d.storage.IntersectsDB(cu)
+	if err != nil {
+		return nil, fmt.Errorf("intersecting error: %w", err)
+	}
+
+	index := s2.NewShapeIndex()
+
+	var (
+		fids     []insideout.FeatureIndexResponse
+		features []*insideout.Feature
+		polygons []*s2.Polygon
+	)
+
+	shapes := make(map[s2.Shape]int)
+
+	for _, fid := range append(idxResp.IDsInside, idxResp.IDsMayBeInside...) {
+		f, err := d.feature(fid.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if int(fid.Pos) >= len(f.Polygons) {
+			continue
+		}
+
+		p := f.Polygons[fid.Pos]
+		if _, ok := shapes[p]; ok {
+			continue
+		}
+
+		shapes[p] = len(polygons)
+
+		index.Add(p)
+
+		fids = append(fids, fid)
+		features = append(features, f)
+		polygons = append(polygons, p)
+	}
+
+	level.Debug(s.logger).Log("msg", "trajectory candidates",
+		"candidates_count", len(fids))
+
+	crossings := pathCrossings(index, shapes, polygons, pts)
+
+	tcs := make([]*insidesvc.TrajectoryCrossing, 0, len(crossings))
+
+	for _, c := range crossings {
+		fid := fids[c.shape]
+
+		tc := &insidesvc.TrajectoryCrossing{
+			Id:    fid.ID,
+			Entry: trajectoryPoint(req.Points, pts, c.entry),
+		}
+
+		if c.exit != nil {
+			tc.Exit = trajectoryPoint(req.Points, pts, *c.exit)
+		}
+
+		if !req.RemoveFeature {
+			tc.Feature, err = protoFeature(features[c.shape], fid, req.RemoveGeometries, nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		tcs = append(tcs, tc)
+	}
+
+	level.Debug(s.logger).Log("msg", "result trajectory",
+		"crossings_count", len(tcs))
+
+	return &insidesvc.TrajectoryResponse{Crossings: tcs}, nil
+}
+
+// pathFromRequest returns the path of the request as s2 Points.
+func pathFromRequest(req *insidesvc.TrajectoryRequest) ([]s2.Point, error) {
+	if len(req.Points) < 2 {
+		return nil, errors.New("a trajectory needs at least 2 points")
+	}
+
+	pts := make([]s2.Point, len(req.Points))
+
+	for i, tp := range req.Points {
+		ll := s2.LatLngFromDegrees(tp.Lat, tp.Lng)
+		if !ll.IsValid() {
+			return nil, fmt.Errorf("invalid point %d lat %f lng %f", i, tp.Lat, tp.Lng)
+		}
+
+		if tp.Time != nil {
+			if err := tp.Time.CheckValid(); err != nil {
+				return nil, fmt.Errorf("invalid time for point %d: %w", i, err)
+			}
+		}
+
+		pts[i] = s2.PointFromLatLng(ll)
+	}
+
+	return pts, nil
+}
+
+// pathCrossings returns the parts of the path inside the polygons of the index,
+// ordered by entry along the path.
+// Entries and exits are computed from the intersections between the path segments and the loops edges,
+// the inside state between two intersections is tested at the middle of the interval.
+func pathCrossings(index *s2.ShapeIndex, shapes map[s2.Shape]int,
+	polygons []*s2.Polygon, pts []s2.Point) []trajectoryCrossing {
+	var crossings []trajectoryCrossing
+
+	// current crossing for each polygon inside
+	current := make(map[int]int)
+
+	for i, p := range polygons {
+		if p.ContainsPoint(pts[0]) {
+			current[i] = len(crossings)
+			crossings = append(crossings, trajectoryCrossing{shape: i})
+		}
+	}
+
+	q := s2.NewCrossingEdgeQuery(index)
+
+	for seg := 0; seg+1 < len(pts); seg++ {
+		a, b := pts[seg], pts[seg+1]
+		if a == b {
+			continue
+		}
+
+		length := a.Angle(b.Vector)
+
+		for shape, edges := range q.CrossingsEdgeMap(a, b, s2.CrossingTypeAll) {
+			i := shapes[shape]
+
+			fractions := []float64{0, 1}
+
+			for _, e := range edges {
+				x := edgeIntersection(a, b, shape.Edge(e))
+				fractions = append(fractions, math.Min(1, float64(a.Angle(x.Vector)/length)))
+			}
+
+			sort.Float64s(fractions)
+
+			for k := 0; k+1 < len(fractions); k++ {
+				if fractions[k+1]-fractions[k] <= 0 {
+					continue
+				}
+
+				mid := s2.Interpolate((fractions[k]+fractions[k+1])/2, a, b)
+				in := polygons[i].ContainsPoint(mid)
+
+				ci, wasIn := current[i]
+
+				switch {
+				case in && !wasIn:
+					current[i] = len(crossings)
+					crossings = append(crossings, trajectoryCrossing{
+						shape: i,
+						entry: pathPosition{segment: seg, fraction: fractions[k]},
+					})
+				case !in && wasIn:
+					crossings[ci].exit = &pathPosition{segment: seg, fraction: fractions[k]}
+
+					delete(current, i)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(crossings, func(i, j int) bool {
+		if crossings[i].entry != crossings[j].entry {
+			return crossings[i].entry.less(crossings[j].entry)
+		}
+
+		return crossings[i].shape < crossings[j].shape
+	})
+
+	return crossings
+}
+
+// edgeIntersection returns the intersection point of the segment ab and the edge e crossing it,
+// the shared vertex when they are only touching.
+func edgeIntersection(a, b s2.Point, e s2.Edge) s2.Point {
+	if s2.CrossingSign(a, b, e.V0, e.V1) == s2.Cross {
+		return s2.Intersection(a, b, e.V0, e.V1)
+	}
+
+	for _, v := range []s2.Point{e.V0, e.V1} {
+		if v == a || v == b {
+			return v
+		}
+	}
+
+	// a or b is on the edge
+	if s2.DistanceFromSegment(a, e.V0, e.V1) < s2.DistanceFromSegment(b, e.V0, e.V1) {
+		return a
+	}
+
+	return b
+}
+
+// trajectoryPoint returns the point at position pos along the path,
+// time is interpolated when both ends of the segment have one.
+func trajectoryPoint(tps []*insidesvc.TrajectoryPoint, pts []s2.Point, pos pathPosition) *insidesvc.TrajectoryPoint {
+	start, end := tps[pos.segment], tps[pos.segment+1]
+
+	var p s2.Point
+
+	switch pos.fraction {
+	case 0:
+		return &insidesvc.TrajectoryPoint{Lat: start.Lat, Lng: start.Lng, Time: start.Time}
+	case 1:
+		return &insidesvc.TrajectoryPoint{Lat: end.Lat, Lng: end.Lng, Time: end.Time}
+	default:
+		p = s2.Interpolate(pos.fraction, pts[pos.segment], pts[pos.segment+1])
+	}
+
+	ll := s2.LatLngFromPoint(p)
+	tp := &insidesvc.TrajectoryPoint{Lat: ll.Lat.Degrees(), Lng: ll.Lng.Degrees()}
+
+	if start.Time != nil && end.Time != nil {
+		st, et := start.Time.AsTime(), end.Time.AsTime()
+		d := time.Duration(float64(et.Sub(st)) * pos.fraction)
+		tp.Time = timestamppb.New(st.Add(d))
+	}
+
+	return tp
+}
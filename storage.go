@@ -1,32 +1,98 @@
 package insideout
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang/geo/s2"
 	"github.com/twpayne/go-geom/encoding/geojson"
 )
 
+// ErrFeatureNotFound is returned when looking up an unknown external id.
+var ErrFeatureNotFound = errors.New("feature not found")
+
+// ErrSchemaVersion is returned for DBs written with a newer storage schema.
+var ErrSchemaVersion = errors.New("unsupported schema version")
+
+// SchemaVersion is the version of the storage schema written by the indexer,
+// DBs indexed before versioning have no version and are still readable.
+const SchemaVersion = 1
+
 type Store interface {
 	LoadFeature(id uint32) (*Feature, error)
+	LoadFeatureProperties(id uint32) (map[string]interface{}, error)
+	// LoadExternalID returns the external id of the feature id, empty when it has none.
+	LoadExternalID(id uint32) (string, error)
+	// LookupExternalID returns the feature id for the external id, ErrFeatureNotFound when unknown.
+	LookupExternalID(extID string) (uint32, error)
 	LoadAllFeatures(add func(*FeatureStorage, uint32) error) error
 	LoadFeaturesCells(add func([]s2.CellUnion, []s2.CellUnion, uint32)) error
 	LoadCellStorage(id uint32) (*CellsStorage, error)
 	LoadIndexInfos() (*IndexInfos, error)
 	LoadMapInfos() (*MapInfos, bool, error)
 	StabDB(lat, lng float64, StopOnInsideFound bool) (IndexResponse, error)
-	Index(fc geojson.FeatureCollection, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	StabBatchDB(lls []s2.LatLng, StopOnInsideFound bool) ([]IndexResponse, error)
+	IntersectsDB(cu s2.CellUnion) (IndexResponse, error)
+	// Index indexes the features read from features until io.EOF.
+	Index(features FeatureIterator, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
 		warningCellsCover int, fileName, version string) error
 }
 
+// Updater adds, replaces and deletes features of an existing index, features are identified by an external id.
+type Updater interface {
+	// PutFeature adds or replaces the feature extID and returns its internal id,
+	// a replaced feature keeps its internal id.
+	PutFeature(extID string, f *geojson.Feature, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+		warningCellsCover int) (uint32, error)
+	// DeleteFeature deletes the feature extID.
+	DeleteFeature(extID string) error
+}
+
 // FeatureStorage on disk storage of the feature
 type FeatureStorage struct {
 	Properties map[string]interface{}
 
-	// Next entries are arrays since a multipolygon may contains multiple loop
-	// LoopsBytes encoded with s2 Loop encoder
-	LoopsBytes [][]byte
+	// Next entries are arrays since a multipolygon may contains multiple polygons
+	// PolygonsBytes encoded with s2 Polygon encoder, holes included
+	PolygonsBytes [][]byte `cbor:",omitempty"`
+
+	// LoopsBytes encoded with s2 Loop encoder, outer rings only
+	// only found in DBs indexed before holes support
+	LoopsBytes [][]byte `cbor:",omitempty"`
+}
+
+// Polygons decodes the stored polygons, falling back to outer loops for older DBs.
+func (fs *FeatureStorage) Polygons() ([]*s2.Polygon, error) {
+	if len(fs.PolygonsBytes) == 0 && len(fs.LoopsBytes) > 0 {
+		polygons := make([]*s2.Polygon, len(fs.LoopsBytes))
+
+		for i, lb := range fs.LoopsBytes {
+			l := &s2.Loop{}
+			if err := l.Decode(bytes.NewReader(lb)); err != nil {
+				return nil, fmt.Errorf("can't decode loop %d: %w", i, err)
+			}
+
+			polygons[i] = s2.PolygonFromLoops([]*s2.Loop{l})
+		}
+
+		return polygons, nil
+	}
+
+	polygons := make([]*s2.Polygon, len(fs.PolygonsBytes))
+
+	for i, pb := range fs.PolygonsBytes {
+		p := &s2.Polygon{}
+		if err := p.Decode(bytes.NewReader(pb)); err != nil {
+			return nil, fmt.Errorf("can't decode polygon %d: %w", i, err)
+		}
+
+		polygons[i] = p
+	}
+
+	return polygons, nil
 }
 
 // CellsStorage are used to store indexed cells
@@ -46,6 +112,57 @@ type IndexInfos struct {
 	IndexerVersion string
 	FeatureCount   uint32
 	MinCoverLevel  int
+	// UpdateTime is the time of the last incremental update if any
+	UpdateTime time.Time
+
+	// next entries are zero for DBs indexed before SchemaVersion 1
+	SchemaVersion     int
+	InsideCover       CoverInfos
+	OutsideCover      CoverInfos
+	WarningCellsCover int
+	// InsideCellCount and OutsideCellCount are the number of cells entries
+	InsideCellCount  uint64
+	OutsideCellCount uint64
+	// Sources are the files used to index then update the index
+	Sources []SourceInfos `cbor:",omitempty"`
+	// NextID is the id of the next added feature, ids are never reused,
+	// zero for DBs indexed before it was kept
+	NextID uint32 `cbor:",omitempty"`
+}
+
+// CoverInfos the parameters of a s2 RegionCoverer.
+type CoverInfos struct {
+	MinLevel int
+	MaxLevel int
+	LevelMod int
+	MaxCells int
+}
+
+// SourceInfos a file used to index or update the index.
+type SourceInfos struct {
+	Filename string
+	// SHA256 hex checksum of the file content
+	SHA256 string
+	Time   time.Time
+}
+
+// NewCoverInfos returns the parameters of c.
+func NewCoverInfos(c *s2.RegionCoverer) CoverInfos {
+	return CoverInfos{MinLevel: c.MinLevel, MaxLevel: c.MaxLevel, LevelMod: c.LevelMod, MaxCells: c.MaxCells}
+}
+
+// RegionCoverer returns a coverer using these parameters.
+func (ci CoverInfos) RegionCoverer() *s2.RegionCoverer {
+	return &s2.RegionCoverer{MinLevel: ci.MinLevel, MaxLevel: ci.MaxLevel, LevelMod: ci.LevelMod, MaxCells: ci.MaxCells}
+}
+
+// CheckSchema returns ErrSchemaVersion if the DB schema is newer than the supported one.
+func (infos *IndexInfos) CheckSchema() error {
+	if infos.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("%w %d, up to %d is supported", ErrSchemaVersion, infos.SchemaVersion, SchemaVersion)
+	}
+
+	return nil
 }
 
 // MapInfos used to store information about the map if any in DB
@@ -57,10 +174,27 @@ type MapInfos struct {
 }
 
 func (infos *IndexInfos) String() string {
-	return fmt.Sprintf("Filename: %s\nIndexTime: %s\nIndexerVersion: %s\nFeatureCount %d\n",
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Filename: %s\nIndexTime: %s\nUpdateTime: %s\nIndexerVersion: %s\nSchemaVersion: %d\n"+
+		"FeatureCount %d\nInsideCellCount %d\nOutsideCellCount %d\n"+
+		"InsideCover: %+v\nOutsideCover: %+v\nWarningCellsCover: %d\n",
 		infos.Filename,
 		infos.IndexTime,
+		infos.UpdateTime,
 		infos.IndexerVersion,
+		infos.SchemaVersion,
 		infos.FeatureCount,
+		infos.InsideCellCount,
+		infos.OutsideCellCount,
+		infos.InsideCover,
+		infos.OutsideCover,
+		infos.WarningCellsCover,
 	)
+
+	for _, src := range infos.Sources {
+		fmt.Fprintf(&sb, "Source: %s sha256:%s %s\n", src.Filename, src.SHA256, src.Time)
+	}
+
+	return sb.String()
 }
@@ -0,0 +1,432 @@
+package bbolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/fxamacker/cbor"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"go.etcd.io/bbolt"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/internal/kv"
+)
+
+// maxIssues issues kept in a CheckReport, the others are only counted.
+const maxIssues = 100
+
+// CheckOptions tunes Check.
+type CheckOptions struct {
+	// SampleFeatures features tested with random points against the index, 0 to disable
+	SampleFeatures int
+	// SamplePoints random points per sampled feature
+	SamplePoints int
+	// Seed of the random sampling
+	Seed int64
+}
+
+// CheckReport is the result of an integrity check.
+type CheckReport struct {
+	FeatureCount     int    `json:"feature_count"`
+	InsideCellCount  uint64 `json:"inside_cell_count"`
+	OutsideCellCount uint64 `json:"outside_cell_count"`
+
+	IssueCount int `json:"issue_count"`
+	// Issues only lists the first issues found
+	Issues []string `json:"issues"`
+
+	// SampledPoints points inside a sampled polygon looked up in the index
+	SampledPoints int `json:"sampled_points"`
+	// FalseNegatives points inside a polygon the index does not return it for
+	FalseNegatives int `json:"false_negatives"`
+	// SkippedPolygons sampled polygons not in the cells entries, their cover was larger than warningCellsCover
+	SkippedPolygons int `json:"skipped_polygons"`
+	// SampledInsideCells inside cover cells of the sampled features tested against their polygon
+	SampledInsideCells int `json:"sampled_inside_cells"`
+	// InsideFalsePositives inside cover cells whose center is not in the polygon
+	InsideFalsePositives int `json:"inside_false_positives"`
+}
+
+// OK reports whether the DB is valid.
+func (r *CheckReport) OK() bool {
+	return r.IssueCount == 0 && r.FalseNegatives == 0 && r.InsideFalsePositives == 0
+}
+
+func (r *CheckReport) addIssue(format string, args ...interface{}) {
+	r.IssueCount++
+
+	if len(r.Issues) < maxIssues {
+		r.Issues = append(r.Issues, fmt.Sprintf(format, args...))
+	}
+}
+
+// Check opens the DB at path read only and verifies its consistency:
+// features and cells storage decode, every cells entry references an existing feature polygon,
+// counts match the index infos and random points in sampled features are found by the index.
+func Check(path string, opts CheckOptions) (*CheckReport, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DB for reading at %s: %w", path, err)
+	}
+	defer db.Close()
+
+	r := &CheckReport{}
+
+	// polygons count per feature id
+	features := make(map[uint32]int)
+
+	var infos *insideout.IndexInfos
+
+	err = db.View(func(tx *bbolt.Tx) error {
+		for _, prefix := range []byte{insideout.FeaturePrefix(), insideout.CellPrefix()} {
+			if tx.Bucket([]byte{prefix}) == nil {
+				r.addIssue("missing bucket %c", prefix)
+			}
+		}
+
+		if r.IssueCount > 0 {
+			return nil
+		}
+
+		infos = checkInfos(tx, r)
+		checkFeatures(tx, r, features)
+		checkCells(tx, r, features)
+		checkOptionalBuckets(tx, r, features, infos)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.FeatureCount = len(features)
+
+	if infos == nil {
+		return r, nil
+	}
+
+	if uint32(r.FeatureCount) != infos.FeatureCount {
+		r.addIssue("infos feature count %d, found %d features", infos.FeatureCount, r.FeatureCount)
+	}
+
+	// DBs indexed before cells counting
+	if infos.SchemaVersion > 0 &&
+		(r.InsideCellCount != infos.InsideCellCount || r.OutsideCellCount != infos.OutsideCellCount) {
+		r.addIssue("infos cells count %d inside %d outside, found %d inside %d outside",
+			infos.InsideCellCount, infos.OutsideCellCount, r.InsideCellCount, r.OutsideCellCount)
+	}
+
+	if opts.SampleFeatures > 0 && r.IssueCount == 0 {
+		s := &Storage{DB: db, minCoverLevel: infos.MinCoverLevel}
+		if err := s.checkSamples(r, features, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func checkInfos(tx *bbolt.Tx, r *CheckReport) *insideout.IndexInfos {
+	b := tx.Bucket(insideout.InfoKey())
+	if b == nil {
+		r.addIssue("missing infos bucket, the indexation did not complete")
+
+		return nil
+	}
+
+	v := b.Get(insideout.InfoKey())
+	if v == nil {
+		r.addIssue("missing infos entry, the indexation did not complete")
+
+		return nil
+	}
+
+	infos := &insideout.IndexInfos{}
+	if err := cbor.NewDecoder(bytes.NewReader(v)).Decode(infos); err != nil {
+		r.addIssue("can't decode infos: %v", err)
+
+		return nil
+	}
+
+	if err := infos.CheckSchema(); err != nil {
+		r.addIssue("%v", err)
+
+		return nil
+	}
+
+	return infos
+}
+
+// checkFeatures decodes every feature and its cells storage, filling features with their polygons count.
+func checkFeatures(tx *bbolt.Tx, r *CheckReport, features map[uint32]int) {
+	cb := tx.Bucket([]byte{insideout.CellPrefix()})
+
+	_ = tx.Bucket([]byte{insideout.FeaturePrefix()}).ForEach(func(k, v []byte) error {
+		if len(k) != 5 {
+			r.addIssue("invalid feature key %x", k)
+
+			return nil
+		}
+
+		id := binary.BigEndian.Uint32(k[1:])
+
+		fs := &insideout.FeatureStorage{}
+		if err := cbor.NewDecoder(bytes.NewReader(v)).Decode(fs); err != nil {
+			r.addIssue("feature %d: can't decode: %v", id, err)
+
+			return nil
+		}
+
+		polygons, err := fs.Polygons()
+		if err != nil {
+			r.addIssue("feature %d: can't decode polygons: %v", id, err)
+
+			return nil
+		}
+
+		features[id] = len(polygons)
+
+		cv := cb.Get(insideout.CellKey(id))
+		if cv == nil {
+			r.addIssue("feature %d: missing cells storage", id)
+
+			return nil
+		}
+
+		cs := &insideout.CellsStorage{}
+		if err := cbor.NewDecoder(bytes.NewReader(cv)).Decode(cs); err != nil {
+			r.addIssue("feature %d: can't decode cells storage: %v", id, err)
+
+			return nil
+		}
+
+		if len(cs.CellsIn) != len(polygons) || len(cs.CellsOut) != len(polygons) {
+			r.addIssue("feature %d: %d polygons but %d inside and %d outside covers",
+				id, len(polygons), len(cs.CellsIn), len(cs.CellsOut))
+		}
+
+		return nil
+	})
+}
+
+// checkCells verifies every cells entry references an existing feature polygon.
+func checkCells(tx *bbolt.Tx, r *CheckReport, features map[uint32]int) {
+	_ = tx.Bucket([]byte{insideout.CellPrefix()}).ForEach(func(k, v []byte) error {
+		switch k[0] {
+		case insideout.CellPrefix():
+			if len(k) != 5 {
+				r.addIssue("invalid cells storage key %x", k)
+			} else if _, ok := features[binary.BigEndian.Uint32(k[1:])]; !ok {
+				r.addIssue("cells storage of missing feature %d", binary.BigEndian.Uint32(k[1:]))
+			}
+
+			return nil
+		case insideout.InsidePrefix():
+			r.InsideCellCount++
+		case insideout.OutsidePrefix():
+			r.OutsideCellCount++
+		default:
+			r.addIssue("unknown cells key %x", k)
+
+			return nil
+		}
+
+		if len(k) != 9 || len(v) == 0 || len(v)%6 != 0 {
+			r.addIssue("invalid cells entry %x of %d bytes", k, len(v))
+
+			return nil
+		}
+
+		for _, res := range kv.DecodeValues(v) {
+			count, ok := features[res.ID]
+			if !ok {
+				r.addIssue("cell %c %s references missing feature %d",
+					k[0], s2.CellID(binary.BigEndian.Uint64(k[1:])).ToToken(), res.ID)
+
+				continue
+			}
+
+			if int(res.Pos) >= count {
+				r.addIssue("cell %c %s references feature %d polygon #%d of %d",
+					k[0], s2.CellID(binary.BigEndian.Uint64(k[1:])).ToToken(), res.ID, res.Pos, count)
+			}
+		}
+
+		return nil
+	})
+}
+
+// checkOptionalBuckets verifies the properties and external ids, missing in DBs indexed by older versions.
+func checkOptionalBuckets(tx *bbolt.Tx, r *CheckReport, features map[uint32]int, infos *insideout.IndexInfos) {
+	if pb := tx.Bucket([]byte{insideout.PropertiesPrefix()}); pb != nil {
+		_ = pb.ForEach(func(k, v []byte) error {
+			if len(k) != 5 {
+				r.addIssue("invalid properties key %x", k)
+			} else if _, ok := features[binary.BigEndian.Uint32(k[1:])]; !ok {
+				r.addIssue("properties of missing feature %d", binary.BigEndian.Uint32(k[1:]))
+			}
+
+			return nil
+		})
+
+		if infos != nil && infos.SchemaVersion > 0 {
+			for id := range features {
+				if pb.Get(insideout.PropertiesKey(id)) == nil {
+					r.addIssue("feature %d: missing properties", id)
+				}
+			}
+		}
+	}
+
+	if xb := tx.Bucket([]byte{insideout.ExternalIDPrefix()}); xb != nil {
+		eb := tx.Bucket([]byte{insideout.FeatureExternalIDPrefix()})
+
+		_ = xb.ForEach(func(k, v []byte) error {
+			if len(v) != 4 {
+				r.addIssue("invalid external id %q entry", k[1:])
+
+				return nil
+			}
+
+			id := binary.BigEndian.Uint32(v)
+			if _, ok := features[id]; !ok {
+				r.addIssue("external id %q references missing feature %d", k[1:], id)
+
+				return nil
+			}
+
+			if eb != nil && !bytes.Equal(eb.Get(insideout.FeatureExternalIDKey(id)), k[1:]) {
+				r.addIssue("feature %d: external id %q has no reverse entry", id, k[1:])
+			}
+
+			return nil
+		})
+	}
+}
+
+// checkSamples looks up random points inside sampled features
+// and tests the inside cover cells of these features against their polygons.
+func (s *Storage) checkSamples(r *CheckReport, features map[uint32]int, opts CheckOptions) error {
+	rnd := rand.New(rand.NewSource(opts.Seed)) //nolint:gosec
+
+	ids := make([]uint32, 0, len(features))
+	for id := range features {
+		ids = append(ids, id)
+	}
+
+	// map order is random, sort for reproducible samples
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	rnd.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+	if len(ids) > opts.SampleFeatures {
+		ids = ids[:opts.SampleFeatures]
+	}
+
+	for _, id := range ids {
+		f, err := s.LoadFeature(id)
+		if err != nil {
+			return err
+		}
+
+		cs, err := s.LoadCellStorage(id)
+		if err != nil {
+			return err
+		}
+
+		for pos, p := range f.Polygons {
+			fid := insideout.FeatureIndexResponse{ID: id, Pos: uint16(pos)}
+
+			if pos < len(cs.CellsIn) {
+				for _, c := range cs.CellsIn[pos] {
+					r.SampledInsideCells++
+
+					if !p.ContainsPoint(c.Point()) {
+						r.InsideFalsePositives++
+
+						r.addIssue("feature %d polygon #%d: inside cell %s is not in the polygon", id, pos, c.ToToken())
+					}
+				}
+			}
+
+			indexed, err := s.outsideIndexed(cs, fid)
+			if err != nil {
+				return err
+			}
+
+			if !indexed {
+				r.SkippedPolygons++
+
+				continue
+			}
+
+			for _, ll := range randomPoints(rnd, p, opts.SamplePoints) {
+				resp, err := s.StabDB(ll.Lat.Degrees(), ll.Lng.Degrees(), false)
+				if err != nil {
+					return err
+				}
+
+				r.SampledPoints++
+
+				if !containsResponse(resp.IDsInside, fid) && !containsResponse(resp.IDsMayBeInside, fid) {
+					r.FalseNegatives++
+
+					r.addIssue("feature %d polygon #%d: point %f,%f not found by the index",
+						id, pos, ll.Lat.Degrees(), ll.Lng.Degrees())
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// outsideIndexed reports whether the outside cover of the polygon fid was added to the cells entries.
+func (s *Storage) outsideIndexed(cs *insideout.CellsStorage, fid insideout.FeatureIndexResponse) (bool, error) {
+	if int(fid.Pos) >= len(cs.CellsOut) || len(cs.CellsOut[fid.Pos]) == 0 {
+		return false, nil
+	}
+
+	var indexed bool
+
+	err := s.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket([]byte{insideout.CellPrefix()}).Get(insideout.OutsideKey(cs.CellsOut[fid.Pos][0]))
+		indexed = containsResponse(kv.DecodeValues(v), fid)
+
+		return nil
+	})
+
+	return indexed, err
+}
+
+// randomPoints returns up to n random points inside p.
+func randomPoints(rnd *rand.Rand, p *s2.Polygon, n int) []s2.LatLng {
+	rect := p.RectBound()
+	lls := make([]s2.LatLng, 0, n)
+
+	for i := 0; i < 20*n && len(lls) < n; i++ {
+		ll := s2.LatLng{
+			Lat: s1.Angle(rect.Lat.Lo + rnd.Float64()*rect.Lat.Length()),
+			Lng: s1.Angle(rect.Lng.Lo + rnd.Float64()*rect.Lng.Length()),
+		}.Normalized()
+
+		if p.ContainsPoint(s2.PointFromLatLng(ll)) {
+			lls = append(lls, ll)
+		}
+	}
+
+	return lls
+}
+
+func containsResponse(resps []insideout.FeatureIndexResponse, fid insideout.FeatureIndexResponse) bool {
+	for _, res := range resps {
+		if res == fid {
+			return true
+		}
+	}
+
+	return false
+}
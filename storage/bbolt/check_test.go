@@ -0,0 +1,90 @@
+package bbolt_test
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		// corrupt modifies the DB before the check
+		corrupt    func(tx *bolt.Tx) error
+		wantIssues []string
+	}{
+		{"valid", nil, nil},
+		{
+			"missing feature",
+			func(tx *bolt.Tx) error {
+				return tx.Bucket([]byte{insideout.FeaturePrefix()}).Delete(insideout.FeatureKey(1))
+			},
+			[]string{"cells storage of missing feature 1", "references missing feature 1", "infos feature count 3, found 2"},
+		},
+		{
+			"invalid polygon index",
+			func(tx *bolt.Tx) error {
+				v := make([]byte, 6)
+				binary.BigEndian.PutUint32(v, 0)
+				binary.BigEndian.PutUint16(v[4:], 5)
+
+				return tx.Bucket([]byte{insideout.CellPrefix()}).Put(insideout.InsideKey(s2.CellIDFromToken("47e")), v)
+			},
+			[]string{"references feature 0 polygon #5 of 3", "infos cells count"},
+		},
+		{
+			"missing cells storage",
+			func(tx *bolt.Tx) error {
+				return tx.Bucket([]byte{insideout.CellPrefix()}).Delete(insideout.CellKey(0))
+			},
+			[]string{"feature 0: missing cells storage"},
+		},
+		{
+			"half written",
+			func(tx *bolt.Tx) error {
+				return tx.DeleteBucket(insideout.InfoKey())
+			},
+			[]string{"missing infos bucket"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, clean := setup(t)
+			defer clean()
+
+			if tt.corrupt != nil {
+				require.NoError(t, s.Update(tt.corrupt))
+			}
+
+			path := s.Path()
+			require.NoError(t, s.Close())
+
+			r, err := bbolt.Check(path, bbolt.CheckOptions{SampleFeatures: 10, SamplePoints: 20, Seed: 1})
+			require.NoError(t, err)
+
+			if tt.wantIssues == nil {
+				require.True(t, r.OK(), r.Issues)
+				require.Equal(t, 3, r.FeatureCount)
+				require.NotZero(t, r.SampledPoints)
+				require.NotZero(t, r.SampledInsideCells)
+
+				return
+			}
+
+			require.False(t, r.OK())
+
+			issues := strings.Join(r.Issues, "\n")
+			for _, want := range tt.wantIssues {
+				require.Contains(t, issues, want)
+			}
+		})
+	}
+}
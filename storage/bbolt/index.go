@@ -0,0 +1,184 @@
+package bbolt
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"go.etcd.io/bbolt"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/internal/kv"
+)
+
+// cells entries written per transaction
+const cellsBatchSize = 100000
+
+// IndexOptions tunes the indexing pipeline, zero values use the defaults.
+type IndexOptions = kv.IndexOptions
+
+// SetIndexOptions sets the options used by Index.
+func (s *Storage) SetIndexOptions(opts IndexOptions) {
+	s.indexOptions = opts
+}
+
+// Index indexes the features read from features until io.EOF.
+// Features are covered and encoded by a pool of workers then stored in batches,
+// the cells entries are merged and written in key order once every feature is stored.
+func (s *Storage) Index(features insideout.FeatureIterator, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	logger := log.With(s.logger, "component", "indexer")
+	opts := s.indexOptions.WithDefaults()
+
+	err := s.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucket(insideout.InfoKey()); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket([]byte{insideout.FeaturePrefix()}); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket([]byte{insideout.CellPrefix()}); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket([]byte{insideout.PropertiesPrefix()}); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket([]byte{insideout.ExternalIDPrefix()}); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket([]byte{insideout.FeatureExternalIDPrefix()}); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can't create bucket into DB: %w", err)
+	}
+
+	// the index is built from scratch, the DB is synced once at the end
+	s.NoSync = true
+	defer func() { s.NoSync = false }()
+
+	ps := kv.NewPostings(opts.TempDir, opts.MaxPostings)
+	defer ps.Close()
+
+	count, err := kv.IndexFeatures(logger, features, ps, icoverer, ocoverer, warningCellsCover, opts,
+		func(batch []*kv.EncodedFeature, firstID uint32) error {
+			return s.putBatch(logger, batch, firstID)
+		})
+	if err != nil {
+		return err
+	}
+
+	level.Info(logger).Log("msg", "stored features", "feature_count", count, "spill_count", ps.SpillCount())
+
+	infos := &insideout.IndexInfos{
+		Filename:       fileName,
+		IndexerVersion: version,
+		FeatureCount:   count,
+		NextID:         count,
+	}
+
+	if err := s.writeCells(ps, infos); err != nil {
+		return fmt.Errorf("can't store cells into DB: %w", err)
+	}
+
+	if err := s.writeInfos(infos, icoverer, ocoverer, warningCellsCover); err != nil {
+		return err
+	}
+
+	return s.Sync()
+}
+
+// putBatch stores the features in one transaction, with ids starting at firstID.
+func (s *Storage) putBatch(logger log.Logger, batch []*kv.EncodedFeature, firstID uint32) error {
+	return s.Update(func(tx *bbolt.Tx) error {
+		for i, ef := range batch {
+			id := firstID + uint32(i)
+
+			if ef.ExtID != "" {
+				if ev := tx.Bucket([]byte{insideout.ExternalIDPrefix()}).Get(insideout.ExternalIDKey(ef.ExtID)); ev != nil {
+					level.Warn(logger).Log("msg", "duplicate feature id, only the last one keeps it", "feature_id", ef.ExtID)
+
+					err := tx.Bucket([]byte{insideout.FeatureExternalIDPrefix()}).
+						Delete(insideout.FeatureExternalIDKey(binary.BigEndian.Uint32(ev)))
+					if err != nil {
+						return err
+					}
+				}
+
+				if err := putExternalID(tx, ef.ExtID, id); err != nil {
+					return err
+				}
+			}
+
+			if err := putEncodedFeature(tx, id, ef); err != nil {
+				return err
+			}
+		}
+
+		level.Debug(logger).Log("msg", "stored features batch", "first_id", firstID, "feature_count", len(batch))
+
+		return nil
+	})
+}
+
+// writeCells writes the cells entries in key order, cellsBatchSize entries per transaction,
+// and counts them into infos.
+func (s *Storage) writeCells(ps *kv.Postings, infos *insideout.IndexInfos) error {
+	tx, err := s.Begin(true)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var n int
+
+	err = ps.Merge(func(key, value []byte) error {
+		cb := tx.Bucket([]byte{insideout.CellPrefix()})
+		// keys are appended in order
+		cb.FillPercent = 0.9
+
+		if err := cb.Put(key, value); err != nil {
+			return err
+		}
+
+		if key[0] == insideout.InsidePrefix() {
+			infos.InsideCellCount++
+		} else {
+			infos.OutsideCellCount++
+		}
+
+		n++
+		if n%cellsBatchSize != 0 {
+			return nil
+		}
+
+		err := tx.Commit()
+		tx = nil
+
+		if err != nil {
+			return err
+		}
+
+		tx, err = s.Begin(true)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	tx = nil
+
+	return err
+}
@@ -0,0 +1,125 @@
+package bbolt_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/fxamacker/cbor"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+func TestStorage_IndexSpill(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	// one feature per transaction and the cells entries spilled after every feature
+	ss, sclean := setupOptions(t, bbolt.IndexOptions{Workers: 3, BatchSize: 1, MaxPostings: 1, TempDir: os.TempDir()})
+	defer sclean()
+
+	for _, prefix := range []byte{
+		insideout.FeaturePrefix(),
+		insideout.PropertiesPrefix(),
+		insideout.CellPrefix(),
+		insideout.ExternalIDPrefix(),
+		insideout.FeatureExternalIDPrefix(),
+	} {
+		want := bucketEntries(t, s, prefix)
+		require.NotEmpty(t, want)
+		require.Equal(t, want, bucketEntries(t, ss, prefix), "bucket %c", prefix)
+	}
+
+	require.Equal(t, []uint32{0}, stabIDs(t, ss, 47.39650628189986, -2.9876390969486524))
+}
+
+func TestStorage_IndexInfos(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	infos, err := s.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, insideout.SchemaVersion, infos.SchemaVersion)
+	require.Equal(t, uint32(3), infos.FeatureCount)
+	require.Equal(t, 10, infos.MinCoverLevel)
+	require.Equal(t, insideout.CoverInfos{MinLevel: 10, MaxLevel: 16, MaxCells: 24}, infos.InsideCover)
+	require.Equal(t, insideout.CoverInfos{MinLevel: 10, MaxLevel: 15, MaxCells: 16}, infos.OutsideCover)
+	require.Equal(t, 100, infos.WarningCellsCover)
+	require.NotZero(t, infos.InsideCellCount)
+	requireCellCounts(t, s, infos)
+
+	src := insideout.SourceInfos{Filename: "poly.geojson", SHA256: "abcd"}
+	require.NoError(t, s.AddSource(src))
+
+	infos, err = s.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, []insideout.SourceInfos{src}, infos.Sources)
+}
+
+func TestStorage_SchemaVersion(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	// written by a newer indexer
+	err := s.Update(func(tx *bolt.Tx) error {
+		b := new(bytes.Buffer)
+		infos := &insideout.IndexInfos{SchemaVersion: insideout.SchemaVersion + 1}
+
+		if err := cbor.NewEncoder(b, cbor.CanonicalEncOptions()).Encode(infos); err != nil {
+			return err
+		}
+
+		return tx.Bucket(insideout.InfoKey()).Put(insideout.InfoKey(), b.Bytes())
+	})
+	require.NoError(t, err)
+
+	_, err = s.LoadIndexInfos()
+	require.True(t, errors.Is(err, insideout.ErrSchemaVersion))
+
+	_, err = s.PutFeature("square", squareFeature(3.0, 48.0, "square"), icoverer, ocoverer, 100)
+	require.True(t, errors.Is(err, insideout.ErrSchemaVersion))
+
+	err = s.DeleteFeature("hole")
+	require.True(t, errors.Is(err, insideout.ErrSchemaVersion))
+}
+
+// requireCellCounts checks the infos cells counts against the stored cells entries.
+func requireCellCounts(t *testing.T, s *bbolt.Storage, infos *insideout.IndexInfos) {
+	t.Helper()
+
+	var ic, oc uint64
+
+	for k := range bucketEntries(t, s, insideout.CellPrefix()) {
+		switch k[0] {
+		case insideout.InsidePrefix():
+			ic++
+		case insideout.OutsidePrefix():
+			oc++
+		}
+	}
+
+	require.Equal(t, ic, infos.InsideCellCount)
+	require.Equal(t, oc, infos.OutsideCellCount)
+}
+
+// bucketEntries returns every key value of the bucket prefix.
+func bucketEntries(t *testing.T, s *bbolt.Storage, prefix byte) map[string]string {
+	t.Helper()
+
+	m := make(map[string]string)
+
+	err := s.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte{prefix}).ForEach(func(k, v []byte) error {
+			m[string(k)] = string(v)
+
+			return nil
+		})
+	})
+	require.NoError(t, err)
+
+	return m
+}
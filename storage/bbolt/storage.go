@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"go.etcd.io/bbolt"
 
 	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/internal/kv"
 )
 
 var featureStoragePool = sync.Pool{
@@ -29,6 +31,7 @@ type Storage struct {
 	*bbolt.DB
 	logger        log.Logger
 	minCoverLevel int
+	indexOptions  IndexOptions
 }
 
 var ErrStorage = errors.New("storage error")
@@ -66,6 +69,8 @@ func NewROStorage(path string, logger log.Logger) (*Storage, func() error, error
 
 	infos, err := s.LoadIndexInfos()
 	if err != nil {
+		db.Close()
+
 		return nil, nil, err
 	}
 
@@ -78,6 +83,8 @@ func NewROStorage(path string, logger log.Logger) (*Storage, func() error, error
 func (s *Storage) LoadFeature(id uint32) (*insideout.Feature, error) {
 	fs := &insideout.FeatureStorage{}
 
+	var extID string
+
 	err := s.View(func(tx *bbolt.Tx) error {
 		b := tx.Bucket([]byte{insideout.FeaturePrefix()})
 		k := insideout.FeatureKey(id)
@@ -86,6 +93,8 @@ func (s *Storage) LoadFeature(id uint32) (*insideout.Feature, error) {
 			return OperationStorageError(fmt.Sprintf("feature id not found: %d", id))
 		}
 
+		extID = txExternalID(tx, id)
+
 		dec := cbor.NewDecoder(bytes.NewReader(v))
 
 		return dec.Decode(fs)
@@ -94,24 +103,106 @@ func (s *Storage) LoadFeature(id uint32) (*insideout.Feature, error) {
 		return nil, fmt.Errorf("error loading feature %w", err)
 	}
 
-	loops := make([]*s2.Loop, len(fs.LoopsBytes))
-	for i := 0; i < len(loops); i++ {
-		l := &s2.Loop{}
-		if err = l.Decode(bytes.NewReader(fs.LoopsBytes[i])); err != nil {
-			return nil, err
-		}
-
-		loops[i] = l
+	polygons, err := fs.Polygons()
+	if err != nil {
+		return nil, err
 	}
 
 	f := &insideout.Feature{
-		Loops:      loops,
+		Polygons:   polygons,
 		Properties: fs.Properties,
+		ExternalID: extID,
 	}
 
 	return f, nil
 }
 
+// LoadFeatureProperties loads the properties of one feature without decoding its polygons,
+// features indexed before properties were stored fall back to the full feature.
+func (s *Storage) LoadFeatureProperties(id uint32) (map[string]interface{}, error) {
+	var props map[string]interface{}
+
+	var found bool
+
+	err := s.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte{insideout.PropertiesPrefix()})
+		if b == nil {
+			return nil
+		}
+
+		v := b.Get(insideout.PropertiesKey(id))
+		if v == nil {
+			return nil
+		}
+
+		found = true
+
+		dec := cbor.NewDecoder(bytes.NewReader(v))
+
+		return dec.Decode(&props)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading feature properties %w", err)
+	}
+
+	if !found {
+		f, err := s.LoadFeature(id)
+		if err != nil {
+			return nil, err
+		}
+
+		return f.Properties, nil
+	}
+
+	return props, nil
+}
+
+// LoadExternalID returns the external id of the feature id, empty when it has none.
+func (s *Storage) LoadExternalID(id uint32) (string, error) {
+	var extID string
+
+	err := s.View(func(tx *bbolt.Tx) error {
+		extID = txExternalID(tx, id)
+
+		return nil
+	})
+
+	return extID, err
+}
+
+// LookupExternalID returns the feature id for the external id.
+func (s *Storage) LookupExternalID(extID string) (uint32, error) {
+	var id uint32
+
+	err := s.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte{insideout.ExternalIDPrefix()})
+		if b == nil {
+			return insideout.ErrFeatureNotFound
+		}
+
+		v := b.Get(insideout.ExternalIDKey(extID))
+		if v == nil {
+			return insideout.ErrFeatureNotFound
+		}
+
+		id = binary.BigEndian.Uint32(v)
+
+		return nil
+	})
+
+	return id, err
+}
+
+// txExternalID returns the external id of the feature id, DBs indexed before external ids have none.
+func txExternalID(tx *bbolt.Tx, id uint32) string {
+	b := tx.Bucket([]byte{insideout.FeatureExternalIDPrefix()})
+	if b == nil {
+		return ""
+	}
+
+	return string(b.Get(insideout.FeatureExternalIDKey(id)))
+}
+
 // LoadAllFeatures loads FeatureStorage from DB into idx
 // only useful to fill in memory shapeindex.
 func (s *Storage) LoadAllFeatures(add func(*insideout.FeatureStorage, uint32) error) error {
@@ -171,6 +262,24 @@ func (s *Storage) LoadFeaturesCells(add func([]s2.CellUnion, []s2.CellUnion, uin
 	return err
 }
 
+// LoadCellsEntries calls fn with the inside then the outside cells entries in key order,
+// values are the feature ids and polygon indexes, only valid during the call.
+func (s *Storage) LoadCellsEntries(fn func(key, value []byte) error) error {
+	return s.View(func(tx *bbolt.Tx) error {
+		curs := tx.Bucket([]byte{insideout.CellPrefix()}).Cursor()
+
+		for _, prefix := range []byte{insideout.InsidePrefix(), insideout.OutsidePrefix()} {
+			for k, v := curs.Seek([]byte{prefix}); k != nil && k[0] == prefix; k, v = curs.Next() {
+				if err := fn(k, v); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
 // LoadMapInfos loads map infos from the DB if any.
 func (s *Storage) LoadMapInfos() (*insideout.MapInfos, bool, error) {
 	var mapInfos *insideout.MapInfos
@@ -202,17 +311,14 @@ func (s *Storage) LoadMapInfos() (*insideout.MapInfos, bool, error) {
 
 // LoadIndexInfos loads index infos from the DB.
 func (s *Storage) LoadIndexInfos() (*insideout.IndexInfos, error) {
-	infos := &insideout.IndexInfos{}
+	var infos *insideout.IndexInfos
 
 	err := s.View(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(insideout.InfoKey())
-		value := b.Get(insideout.InfoKey())
-		if value == nil {
-			return OperationStorageError("can't find infos entries, invalid DB")
-		}
-		dec := cbor.NewDecoder(bytes.NewReader(value))
+		var err error
+
+		infos, err = txInfos(tx)
 
-		return dec.Decode(infos)
+		return err
 	})
 
 	return infos, err
@@ -316,223 +422,246 @@ func (s *Storage) StabDB(lat, lng float64, stopOnInsideFound bool) (insideout.In
 	return idxResp, nil
 }
 
-func (s *Storage) Index(fc geojson.FeatureCollection, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
-	warningCellsCover int, fileName, version string) error {
-	var count uint32
+// StabBatchDB returns for each lat lng polygon's ids containing it and polygon's ids that may contain it,
+// responses are in the same order as lls.
+// Points are sorted by cell id so the cells keys are read in one ordered cursor pass.
+func (s *Storage) StabBatchDB(lls []s2.LatLng, stopOnInsideFound bool) ([]insideout.IndexResponse, error) {
+	resps := make([]insideout.IndexResponse, len(lls))
+	cells := make([]s2.CellID, len(lls))
+	order := make([]int, len(lls))
+
+	for i, ll := range lls {
+		cells[i] = s2.CellIDFromLatLng(ll)
+		order[i] = i
+	}
 
-	logger := log.With(s.logger, "component", "indexer")
+	sort.Slice(order, func(i, j int) bool { return cells[order[i]] < cells[order[j]] })
 
-	err := s.Update(func(tx *bbolt.Tx) error {
-		if _, err := tx.CreateBucket(insideout.InfoKey()); err != nil {
-			return err
-		}
-		if _, err := tx.CreateBucket([]byte{insideout.FeaturePrefix()}); err != nil {
-			return err
-		}
-		if _, err := tx.CreateBucket([]byte{insideout.CellPrefix()}); err != nil {
-			return err
+	err := s.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte{insideout.CellPrefix()})
+		icurs, ocurs := b.Cursor(), b.Cursor()
+
+		for start := 0; start < len(order); {
+			// points sharing the same lookup cell share the same keys range
+			cLookup := cells[order[start]].Parent(s.minCoverLevel)
+
+			end := start + 1
+			for end < len(order) && cells[order[end]].Parent(s.minCoverLevel) == cLookup {
+				end++
+			}
+
+			ientries := rangeEntries(icurs, cLookup, insideout.InsideRangeKeys)
+			oentries := rangeEntries(ocurs, cLookup, insideout.OutsideRangeKeys)
+
+			for _, i := range order[start:end] {
+				resps[i] = kv.StabEntries(cells[i], ientries, oentries, stopOnInsideFound)
+			}
+
+			start = end
 		}
 
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("can't create bucket into DB: %w", err)
+		return nil, fmt.Errorf("while iterating over cells keys: %w db: %s", err, s.DB.Path())
 	}
 
-	for _, f := range fc.Features {
-		f := f
-		// cover inside
-		cui, err := insideout.GeoJSONCoverCellUnion(f, icoverer, true)
-		if err != nil {
-			level.Warn(logger).Log("msg", "error covering inside", "error", err, "feature_properties", f.Properties)
+	return resps, nil
+}
 
-			continue
-		}
+// rangeEntries returns the entries stored under c and its children cells, values are only valid during the transaction.
+func rangeEntries(curs *bbolt.Cursor, c s2.CellID, rangeFunc func(s2.CellID) ([]byte, []byte)) []kv.CellEntry {
+	var entries []kv.CellEntry
 
-		// cover outside
-		cuo, err := insideout.GeoJSONCoverCellUnion(f, ocoverer, false)
-		if err != nil {
-			level.Warn(logger).Log("msg", "error covering outside", "error", err, "feature_properties", f.Properties)
+	startKey, stopKey := rangeFunc(c)
 
-			continue
-		}
+	for k, v := curs.Seek(startKey); k != nil && bytes.Compare(k, stopKey) <= 0; k, v = curs.Next() {
+		entries = append(entries, kv.NewCellEntry(k, v))
+	}
 
-		// store interior cover
-		err = s.Update(func(tx *bbolt.Tx) error {
-			for fi, cu := range cui {
-				if warningCellsCover != 0 && len(cu) > warningCellsCover {
-					level.Warn(logger).Log(
-						"msg", fmt.Sprintf("inside cover too big %d cells, not indexing polygon #%d %s", len(cui), fi, f.Properties),
-						"feature_properties", f.Properties,
-					)
+	return entries
+}
 
-					continue
-				}
-				for _, c := range cu {
-					// value is the feature id: current count, the polygon index in a multipolygon: fi
-					v := make([]byte, 6)
-					binary.BigEndian.PutUint32(v, count)
-					binary.BigEndian.PutUint16(v[4:], uint16(fi))
-					// append to existing if any
-					b := tx.Bucket([]byte{insideout.CellPrefix()})
-					ev := b.Get(insideout.InsideKey(c))
-
-					if ev != nil {
-						v = append(v, ev...) //nolint: makezero
-					}
-
-					err = b.Put(insideout.InsideKey(c), v)
-					if err != nil {
-						return err
-					}
-				}
-			}
+// IntersectsDB returns polygon's ids whose inside cover intersects the cell union
+// and polygon's ids whose outside cover only intersects it.
+func (s *Storage) IntersectsDB(cu s2.CellUnion) (insideout.IndexResponse, error) {
+	mi := make(map[insideout.FeatureIndexResponse]struct{})
+	mo := make(map[insideout.FeatureIndexResponse]struct{})
 
-			return nil
-		})
-		if err != nil {
-			return fmt.Errorf("failed set inside cover into DB: %w", err)
+	err := s.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte{insideout.CellPrefix()})
+
+		for _, c := range cu {
+			intersectingCells(b, c, s.minCoverLevel, insideout.InsideKey, insideout.InsideRangeKeys, mi)
+			intersectingCells(b, c, s.minCoverLevel, insideout.OutsideKey, insideout.OutsideRangeKeys, mo)
 		}
 
-		// store outside cover
-		err = s.Update(func(tx *bbolt.Tx) error {
-			for fi, cu := range cuo {
-				if warningCellsCover != 0 && len(cu) > warningCellsCover {
-					level.Warn(logger).Log(
-						"msg", fmt.Sprintf("outisde cover too big %d not indexing polygon #%d %s", len(cui), fi, f.Properties),
-						"feature_properties", f.Properties,
-					)
+		return nil
+	})
+	if err != nil {
+		return insideout.IndexResponse{}, fmt.Errorf("while iterating over cells keys: %w db: %s", err, s.DB.Path())
+	}
 
-					continue
-				}
-				for _, c := range cu {
-					// TODO: filter cells already indexed by inside cover
-
-					// value is the feature id: current count, the polygon index in a multipolygon: fi
-					v := make([]byte, 6)
-					binary.BigEndian.PutUint32(v, count)
-					binary.BigEndian.PutUint16(v[4:], uint16(fi))
-					// append to existing if any
-					b := tx.Bucket([]byte{insideout.CellPrefix()})
-					ev := b.Get(insideout.OutsideKey(c))
-					if ev != nil {
-						v = append(v, ev...) //nolint: makezero
-					}
-
-					err = b.Put(insideout.OutsideKey(c), v)
-					if err != nil {
-						return err
-					}
-				}
-			}
+	return kv.IntersectsResponse(mi, mo), nil
+}
 
-			return nil
-		})
-		if err != nil {
-			return fmt.Errorf("failed set outside cover into DB: %w", err)
-		}
+// intersectingCells adds to m the polygons stored under any cell intersecting c:
+// parent cells down to the min cover level and children cells.
+func intersectingCells(b *bbolt.Bucket, c s2.CellID, minCoverLevel int,
+	keyFunc func(s2.CellID) []byte, rangeFunc func(s2.CellID) ([]byte, []byte),
+	m map[insideout.FeatureIndexResponse]struct{}) {
+	for l := minCoverLevel; l < c.Level(); l++ {
+		kv.AddValues(b.Get(keyFunc(c.Parent(l))), m)
+	}
 
-		// store feature
-		if err := s.writeFeature(f, count, cui, cuo); err != nil {
-			return fmt.Errorf("can't store featrure into DB: %w", err)
-		}
+	startKey, stopKey := rangeFunc(c)
+	curs := b.Cursor()
 
-		level.Debug(s.logger).Log(
-			"msg", "Stored feature",
-		)
+	for k, v := curs.Seek(startKey); k != nil && bytes.Compare(k, stopKey) <= 0; k, v = curs.Next() {
+		kv.AddValues(v, m)
+	}
+}
 
-		count++
+// putEncodedFeature stores the feature, its properties and its cells, not the cells entries.
+func putEncodedFeature(tx *bbolt.Tx, id uint32, ef *kv.EncodedFeature) error {
+	if err := tx.Bucket([]byte{insideout.FeaturePrefix()}).Put(insideout.FeatureKey(id), ef.Feature); err != nil {
+		return err
 	}
 
-	return s.writeInfos(icoverer, ocoverer, count, fileName, version)
+	if err := tx.Bucket([]byte{insideout.PropertiesPrefix()}).Put(insideout.PropertiesKey(id), ef.Properties); err != nil {
+		return err
+	}
+
+	return tx.Bucket([]byte{insideout.CellPrefix()}).Put(insideout.CellKey(id), ef.Cells)
 }
 
-func (s *Storage) writeFeature(f *geojson.Feature, id uint32, cui, cuo []s2.CellUnion) error {
-	// store feature
-	lb, err := insideout.GeoJSONEncodeLoops(f)
+// putFeature stores the feature, its properties, its cells and adds it to the inside and outside cells entries,
+// covers larger than warningCellsCover are not added to the cells entries, new entries are counted into infos.
+func (s *Storage) putFeature(tx *bbolt.Tx, logger log.Logger, f *geojson.Feature, id uint32,
+	cui, cuo []s2.CellUnion, warningCellsCover int, infos *insideout.IndexInfos) error {
+	ef, err := kv.EncodeFeature(logger, f, cui, cuo, warningCellsCover)
 	if err != nil {
-		return fmt.Errorf("can't encode loop: %w", err)
+		return err
+	}
+
+	if err := putEncodedFeature(tx, id, ef); err != nil {
+		return err
 	}
 
-	b := new(bytes.Buffer)
-	enc := cbor.NewEncoder(b, cbor.CanonicalEncOptions())
+	cb := tx.Bucket([]byte{insideout.CellPrefix()})
 
-	// TODO: filter cuo cui[fi].ContainsCellID(c)
-	fs := &insideout.FeatureStorage{Properties: f.Properties, LoopsBytes: lb}
-	if err := enc.Encode(fs); err != nil {
-		return fmt.Errorf("can't encode FeatureStorage: %w", err)
+	n, err := addCells(cb, insideout.InsideKey, ef.CellsIn, id)
+	if err != nil {
+		return fmt.Errorf("failed set inside cover into DB: %w", err)
 	}
 
-	err = s.Update(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket([]byte{insideout.FeaturePrefix()})
-		err := bucket.Put(insideout.FeatureKey(id), b.Bytes())
-		if err != nil {
-			return err
-		}
-		// store cells for tree
-		b = new(bytes.Buffer)
-		enc = cbor.NewEncoder(b, cbor.CanonicalEncOptions())
-		cs := &insideout.CellsStorage{
-			CellsIn:  cui,
-			CellsOut: cuo,
-		}
+	infos.InsideCellCount += uint64(n)
 
-		if err := enc.Encode(cs); err != nil {
-			return fmt.Errorf("can't encode CellsStorage: %w", err)
-		}
+	n, err = addCells(cb, insideout.OutsideKey, ef.CellsOut, id)
+	if err != nil {
+		return fmt.Errorf("failed set outside cover into DB: %w", err)
+	}
 
-		bucket = tx.Bucket([]byte{insideout.CellPrefix()})
-		err = bucket.Put(insideout.CellKey(id), b.Bytes())
-		if err != nil {
-			return err
+	infos.OutsideCellCount += uint64(n)
+
+	level.Debug(s.logger).Log(
+		"msg", "stored FeatureStorage",
+		"inside_loop_id", id,
+	)
+
+	return nil
+}
+
+// addCells adds the feature id and the polygon index to the entries of every cell of cus,
+// it returns the number of new entries.
+func addCells(b *bbolt.Bucket, key func(s2.CellID) []byte, cus []s2.CellUnion, id uint32) (int, error) {
+	var n int
+
+	for fi, cu := range cus {
+		for _, c := range cu {
+			// value is the feature id, the polygon index in a multipolygon: fi
+			v := kv.EncodeValue(id, uint16(fi))
+			// append to existing if any
+			if ev := b.Get(key(c)); ev != nil {
+				v = append(v, ev...) //nolint: makezero
+			} else {
+				n++
+			}
+
+			if err := b.Put(key(c), v); err != nil {
+				return n, err
+			}
 		}
+	}
 
-		level.Debug(s.logger).Log(
-			"msg", "stored FeatureStorage",
-			"loop_count", len(fs.LoopsBytes),
-			"inside_loop_id", id,
-		)
+	return n, nil
+}
 
-		return nil
+// writeInfos stores the infos of a new index.
+func (s *Storage) writeInfos(infos *insideout.IndexInfos, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int) error {
+	infos.IndexTime = time.Now()
+	kv.SetIndexInfos(infos, icoverer, ocoverer, warningCellsCover)
+
+	err := s.Update(func(tx *bbolt.Tx) error {
+		return putInfos(tx, infos)
 	})
 	if err != nil {
-		return fmt.Errorf("failed store feature into DB: %w", err)
+		return fmt.Errorf("failed encoding IndexInfos: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Storage) writeInfos(icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
-	fcount uint32, fileName, version string) error {
-	infoBytes := new(bytes.Buffer)
+// AddSource records a file used to index or update the index.
+func (s *Storage) AddSource(src insideout.SourceInfos) error {
+	return s.Update(func(tx *bbolt.Tx) error {
+		infos, err := txInfos(tx)
+		if err != nil {
+			return err
+		}
 
-	// Finding the lowest cover level
-	minCoverLevel := ocoverer.MinLevel
-	if icoverer.MinLevel < ocoverer.MinLevel {
-		minCoverLevel = icoverer.MinLevel
-	}
+		infos.Sources = append(infos.Sources, src)
 
-	infos := &insideout.IndexInfos{
-		Filename:       fileName,
-		IndexTime:      time.Now(),
-		IndexerVersion: version,
-		FeatureCount:   fcount,
-		MinCoverLevel:  minCoverLevel,
-	}
+		return putInfos(tx, infos)
+	})
+}
+
+func putInfos(tx *bbolt.Tx, infos *insideout.IndexInfos) error {
+	infoBytes := new(bytes.Buffer)
 
 	enc := cbor.NewEncoder(infoBytes, cbor.CanonicalEncOptions())
 	if err := enc.Encode(infos); err != nil {
 		return fmt.Errorf("failed encoding IndexInfos: %w", err)
 	}
 
-	err := s.Update(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(insideout.InfoKey())
+	return tx.Bucket(insideout.InfoKey()).Put(insideout.InfoKey(), infoBytes.Bytes())
+}
 
-		return b.Put(insideout.InfoKey(), infoBytes.Bytes())
-	})
+// putExternalID stores the mapping between the external id and the feature id in both directions.
+func putExternalID(tx *bbolt.Tx, extID string, id uint32) error {
+	xb, err := tx.CreateBucketIfNotExists([]byte{insideout.ExternalIDPrefix()})
 	if err != nil {
-		return fmt.Errorf("failed encoding IndexInfos: %w", err)
+		return err
 	}
 
-	return nil
+	if err := xb.Put(insideout.ExternalIDKey(extID), idBytes(id)); err != nil {
+		return err
+	}
+
+	// DBs indexed before the reverse mapping
+	eb, err := tx.CreateBucketIfNotExists([]byte{insideout.FeatureExternalIDPrefix()})
+	if err != nil {
+		return err
+	}
+
+	return eb.Put(insideout.FeatureExternalIDKey(id), []byte(extID))
+}
+
+// idBytes encodes an internal feature id.
+func idBytes(id uint32) []byte {
+	v := make([]byte, 4)
+	binary.BigEndian.PutUint32(v, id)
+
+	return v
 }
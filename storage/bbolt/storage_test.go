@@ -0,0 +1,51 @@
+package bbolt_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/bbolt"
+	"github.com/akhenakh/insideout/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.TestStore(t, func(t *testing.T) (insideout.Store, func()) {
+		tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+		require.NoError(t, err)
+
+		s, sclose, err := bbolt.NewStorage(tmpFile.Name(), log.NewNopLogger())
+		require.NoError(t, err)
+
+		return s, func() {
+			sclose()
+			os.Remove(tmpFile.Name())
+		}
+	})
+}
+
+func TestNewROStorage(t *testing.T) {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+
+	defer os.Remove(tmpFile.Name())
+
+	// an empty DB without infos
+	_, sclose, err := bbolt.NewStorage(tmpFile.Name(), log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, sclose())
+
+	_, _, err = bbolt.NewROStorage(tmpFile.Name(), log.NewNopLogger())
+	require.Error(t, err)
+
+	// the failed storage released its lock
+	db, err := bolt.Open(tmpFile.Name(), 0600, &bolt.Options{Timeout: time.Second})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
@@ -0,0 +1,275 @@
+package bbolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fxamacker/cbor"
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	"go.etcd.io/bbolt"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/internal/kv"
+)
+
+// PutFeature adds or replaces the feature extID and returns its internal id,
+// a replaced feature keeps its internal id, a new feature gets the next one.
+// Covers must not use a lower level than the index MinCoverLevel.
+func (s *Storage) PutFeature(extID string, f *geojson.Feature, icoverer *s2.RegionCoverer,
+	ocoverer *s2.RegionCoverer, warningCellsCover int) (uint32, error) {
+	if extID == "" {
+		return 0, errors.New("missing external id")
+	}
+
+	logger := log.With(s.logger, "component", "updater")
+
+	cui, cuo, err := kv.CoverFeature(logger, f, icoverer, ocoverer)
+	if err != nil {
+		return 0, fmt.Errorf("can't cover feature %s: %w", extID, err)
+	}
+
+	var id uint32
+
+	err = s.Update(func(tx *bbolt.Tx) error {
+		infos, err := txInfos(tx)
+		if err != nil {
+			return err
+		}
+
+		if icoverer.MinLevel < infos.MinCoverLevel || ocoverer.MinLevel < infos.MinCoverLevel {
+			return fmt.Errorf("cover min level lower than the index min cover level %d", infos.MinCoverLevel)
+		}
+
+		xb, err := externalIDBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		if v := xb.Get(insideout.ExternalIDKey(extID)); v != nil {
+			id = binary.BigEndian.Uint32(v)
+
+			if err := removeFeature(tx, id, infos); err != nil {
+				return err
+			}
+
+			level.Debug(logger).Log("msg", "replacing feature", "feature_id", extID, "id", id)
+		} else {
+			// ids are never reused, clients may have cached deleted ones
+			id, err = nextID(tx, infos)
+			if err != nil {
+				return err
+			}
+
+			if err := putExternalID(tx, extID, id); err != nil {
+				return err
+			}
+
+			infos.FeatureCount++
+
+			level.Debug(logger).Log("msg", "adding feature", "feature_id", extID, "id", id)
+		}
+
+		// DBs indexed before properties were stored
+		if _, err := tx.CreateBucketIfNotExists([]byte{insideout.PropertiesPrefix()}); err != nil {
+			return err
+		}
+
+		if err := s.putFeature(tx, logger, f, id, cui, cuo, warningCellsCover, infos); err != nil {
+			return err
+		}
+
+		infos.UpdateTime = time.Now()
+
+		return putInfos(tx, infos)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("can't put feature %s: %w", extID, err)
+	}
+
+	return id, nil
+}
+
+// DeleteFeature deletes the feature extID, its cells entries, cells storage and properties.
+func (s *Storage) DeleteFeature(extID string) error {
+	err := s.Update(func(tx *bbolt.Tx) error {
+		infos, err := txInfos(tx)
+		if err != nil {
+			return err
+		}
+
+		xb, err := externalIDBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		v := xb.Get(insideout.ExternalIDKey(extID))
+		if v == nil {
+			return insideout.ErrFeatureNotFound
+		}
+
+		id := binary.BigEndian.Uint32(v)
+
+		if err := removeFeature(tx, id, infos); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket([]byte{insideout.FeaturePrefix()}).Delete(insideout.FeatureKey(id)); err != nil {
+			return err
+		}
+
+		if err := xb.Delete(insideout.ExternalIDKey(extID)); err != nil {
+			return err
+		}
+
+		if eb := tx.Bucket([]byte{insideout.FeatureExternalIDPrefix()}); eb != nil {
+			if err := eb.Delete(insideout.FeatureExternalIDKey(id)); err != nil {
+				return err
+			}
+		}
+
+		if infos.FeatureCount > 0 {
+			infos.FeatureCount--
+		}
+
+		infos.UpdateTime = time.Now()
+
+		return putInfos(tx, infos)
+	})
+	if err != nil {
+		return fmt.Errorf("can't delete feature %s: %w", extID, err)
+	}
+
+	return nil
+}
+
+// removeFeature removes the feature id from its cells entries and deletes its cells storage and properties,
+// the feature entry itself is left to be overwritten or deleted by the caller, deleted entries are counted into infos.
+func removeFeature(tx *bbolt.Tx, id uint32, infos *insideout.IndexInfos) error {
+	cb := tx.Bucket([]byte{insideout.CellPrefix()})
+
+	v := cb.Get(insideout.CellKey(id))
+	if v == nil {
+		return OperationStorageError(fmt.Sprintf("cells not found for feature id: %d", id))
+	}
+
+	cs := &insideout.CellsStorage{}
+	if err := cbor.NewDecoder(bytes.NewReader(v)).Decode(cs); err != nil {
+		return fmt.Errorf("can't decode CellsStorage: %w", err)
+	}
+
+	n, err := removeCells(cb, insideout.InsideKey, cs.CellsIn, id)
+	if err != nil {
+		return err
+	}
+
+	infos.InsideCellCount = kv.SubCount(infos.InsideCellCount, n)
+
+	n, err = removeCells(cb, insideout.OutsideKey, cs.CellsOut, id)
+	if err != nil {
+		return err
+	}
+
+	infos.OutsideCellCount = kv.SubCount(infos.OutsideCellCount, n)
+
+	if err := cb.Delete(insideout.CellKey(id)); err != nil {
+		return err
+	}
+
+	if pb := tx.Bucket([]byte{insideout.PropertiesPrefix()}); pb != nil {
+		return pb.Delete(insideout.PropertiesKey(id))
+	}
+
+	return nil
+}
+
+// removeCells removes the feature id from the entries of every cell of cus, emptied entries are deleted,
+// it returns the number of deleted entries.
+func removeCells(b *bbolt.Bucket, key func(s2.CellID) []byte, cus []s2.CellUnion, id uint32) (int, error) {
+	var n int
+
+	for _, cu := range cus {
+		for _, c := range cu {
+			v := b.Get(key(c))
+			if v == nil {
+				continue
+			}
+
+			// v is only valid during the transaction, copying the kept values
+			nv := kv.RemoveValues(v, id)
+
+			if len(nv) == len(v) {
+				continue
+			}
+
+			var err error
+			if len(nv) == 0 {
+				err = b.Delete(key(c))
+				n++
+			} else {
+				err = b.Put(key(c), nv)
+			}
+
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// nextID returns the id of the next added feature and advances infos.NextID,
+// DBs indexed before NextID was kept start after the last stored feature.
+func nextID(tx *bbolt.Tx, infos *insideout.IndexInfos) (uint32, error) {
+	id := infos.NextID
+
+	if id == 0 {
+		k, _ := tx.Bucket([]byte{insideout.FeaturePrefix()}).Cursor().Last()
+		if k != nil {
+			if len(k) != 5 {
+				return 0, OperationStorageError(fmt.Sprintf("invalid feature key %x", k))
+			}
+
+			id = binary.BigEndian.Uint32(k[1:]) + 1
+		}
+	}
+
+	infos.NextID = id + 1
+
+	return id, nil
+}
+
+// externalIDBucket returns the external ids bucket, created for DBs indexed before updates support.
+func externalIDBucket(tx *bbolt.Tx) (*bbolt.Bucket, error) {
+	return tx.CreateBucketIfNotExists([]byte{insideout.ExternalIDPrefix()})
+}
+
+func txInfos(tx *bbolt.Tx) (*insideout.IndexInfos, error) {
+	b := tx.Bucket(insideout.InfoKey())
+	if b == nil {
+		return nil, OperationStorageError("can't find infos bucket, invalid DB")
+	}
+
+	value := b.Get(insideout.InfoKey())
+	if value == nil {
+		return nil, OperationStorageError("can't find infos entries, invalid DB")
+	}
+
+	infos := &insideout.IndexInfos{}
+	if err := cbor.NewDecoder(bytes.NewReader(value)).Decode(infos); err != nil {
+		return nil, fmt.Errorf("failed decoding IndexInfos: %w", err)
+	}
+
+	// an older indexer must not modify a newer DB
+	if err := infos.CheckSchema(); err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
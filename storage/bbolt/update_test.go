@@ -0,0 +1,225 @@
+package bbolt_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/bbolt"
+)
+
+var (
+	icoverer = &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer = &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+)
+
+func TestStorage_PutFeature(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	// add
+	id, err := s.PutFeature("square", squareFeature(3.0, 48.0, "square"), icoverer, ocoverer, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), id)
+	require.Equal(t, []uint32{3}, stabIDs(t, s, 48.05, 3.05))
+
+	id, err = s.LookupExternalID("square")
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), id)
+
+	f, err := s.LoadFeature(3)
+	require.NoError(t, err)
+	require.Equal(t, "square", f.ExternalID)
+
+	// replace keeps the internal id
+	id, err = s.PutFeature("houat", squareFeature(4.0, 48.0, "moved"), icoverer, ocoverer, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), id)
+	require.Empty(t, stabIDs(t, s, 47.39650628189986, -2.9876390969486524))
+	require.Equal(t, []uint32{0}, stabIDs(t, s, 48.05, 4.05))
+	requireNoCells(t, s, 0, 47.39650628189986, -2.9876390969486524)
+
+	props, err := s.LoadFeatureProperties(0)
+	require.NoError(t, err)
+	require.Equal(t, "moved", props["nom"])
+
+	cs, err := s.LoadCellStorage(0)
+	require.NoError(t, err)
+	require.Len(t, cs.CellsIn, 1)
+
+	infos, err := s.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, uint32(4), infos.FeatureCount)
+	require.False(t, infos.UpdateTime.IsZero())
+	requireCellCounts(t, s, infos)
+
+	// covers lower than the index min cover level would be missed by the stab lookups
+	_, err = s.PutFeature("low", squareFeature(5.0, 48.0, "low"),
+		&s2.RegionCoverer{MinLevel: 2, MaxLevel: 16, MaxCells: 24}, ocoverer, 100)
+	require.Error(t, err)
+
+	_, err = s.PutFeature("", squareFeature(5.0, 48.0, "no id"), icoverer, ocoverer, 100)
+	require.Error(t, err)
+}
+
+func TestStorage_DeleteFeature(t *testing.T) {
+	s, clean := setup(t)
+	defer clean()
+
+	require.Equal(t, []uint32{1}, stabIDs(t, s, 48.02, 2.02))
+
+	err := s.DeleteFeature("hole")
+	require.NoError(t, err)
+
+	require.Empty(t, stabIDs(t, s, 48.02, 2.02))
+	requireNoCells(t, s, 1, 48.02, 2.02)
+
+	_, err = s.LoadFeature(1)
+	require.Error(t, err)
+
+	_, err = s.LoadCellStorage(1)
+	require.Error(t, err)
+
+	_, err = s.LookupExternalID("hole")
+	require.True(t, errors.Is(err, insideout.ErrFeatureNotFound))
+
+	extID, err := s.LoadExternalID(1)
+	require.NoError(t, err)
+	require.Empty(t, extID)
+
+	// other features are untouched
+	require.Equal(t, []uint32{0}, stabIDs(t, s, 47.39650628189986, -2.9876390969486524))
+
+	infos, err := s.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), infos.FeatureCount)
+	requireCellCounts(t, s, infos)
+
+	err = s.DeleteFeature("hole")
+	require.True(t, errors.Is(err, insideout.ErrFeatureNotFound))
+
+	// deleted ids are not reused
+	id, err := s.PutFeature("hole", squareFeature(2.0, 48.0, "hole"), icoverer, ocoverer, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), id)
+
+	// even the highest one
+	err = s.DeleteFeature("hole")
+	require.NoError(t, err)
+
+	id, err = s.PutFeature("hole", squareFeature(2.0, 48.0, "hole"), icoverer, ocoverer, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint32(4), id)
+}
+
+// stabIDs returns the sorted feature ids whose cells contain lat lng.
+func stabIDs(t *testing.T, s *bbolt.Storage, lat, lng float64) []uint32 {
+	t.Helper()
+
+	resp, err := s.StabDB(lat, lng, false)
+	require.NoError(t, err)
+
+	m := make(map[uint32]struct{})
+	for _, fid := range append(resp.IDsInside, resp.IDsMayBeInside...) {
+		m[fid.ID] = struct{}{}
+	}
+
+	ids := make([]uint32, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+// requireNoCells checks no cell entry around lat lng references the feature id.
+func requireNoCells(t *testing.T, s *bbolt.Storage, id uint32, lat, lng float64) {
+	t.Helper()
+
+	c := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng)).Parent(icoverer.MinLevel)
+
+	err := s.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte{insideout.CellPrefix()})
+
+		for _, rangeKeys := range []func(s2.CellID) ([]byte, []byte){insideout.InsideRangeKeys, insideout.OutsideRangeKeys} {
+			startKey, stopKey := rangeKeys(c)
+
+			curs := b.Cursor()
+			for k, v := curs.Seek(startKey); k != nil && bytes.Compare(k, stopKey) <= 0; k, v = curs.Next() {
+				require.NotEmpty(t, v)
+
+				for i := 0; i < len(v); i += 6 {
+					require.NotEqual(t, id, binary.BigEndian.Uint32(v[i:]))
+				}
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// squareFeature returns a 0.1 degree square feature at lng lat.
+func squareFeature(lng, lat float64, name string) *geojson.Feature {
+	return &geojson.Feature{
+		Geometry: geom.NewPolygonFlat(geom.XY, []float64{
+			lng, lat, lng + 0.1, lat, lng + 0.1, lat + 0.1, lng, lat + 0.1, lng, lat,
+		}, []int{10}),
+		Properties: map[string]interface{}{"nom": name},
+	}
+}
+
+func setup(t *testing.T) (*bbolt.Storage, func()) {
+	t.Helper()
+
+	return setupOptions(t, bbolt.IndexOptions{})
+}
+
+// setupOptions indexes poly.geojson with opts.
+func setupOptions(t *testing.T, opts bbolt.IndexOptions) (*bbolt.Storage, func()) {
+	t.Helper()
+
+	logger := log.NewLogfmtLogger(os.Stdout)
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+
+	s, sclose, err := bbolt.NewStorage(tmpFile.Name(), logger)
+	require.NoError(t, err)
+
+	file, err := os.Open("../../index/testdata/poly.geojson")
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	fc, err := insideout.DecodeFeatureCollection(file)
+	require.NoError(t, err)
+
+	for i, id := range []string{"houat", "hole", "antimeridian"} {
+		fc.Features[i].ID = id
+	}
+
+	s.SetIndexOptions(opts)
+
+	err = s.Index(insideout.NewFeatureCollectionIterator(fc), icoverer, ocoverer, 100, "poly.geojson", "unittest")
+	require.NoError(t, err)
+
+	return s, func() {
+		sclose()
+		os.Remove(tmpFile.Name())
+	}
+}
@@ -0,0 +1,212 @@
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+const (
+	defaultBatchSize   = 1000
+	defaultMaxPostings = 4 << 20
+)
+
+// IndexOptions tunes the indexing pipeline, zero values use the defaults.
+type IndexOptions struct {
+	// Workers count covering and encoding the features, defaults to the CPU count
+	Workers int
+	// BatchSize features stored per transaction, defaults to 1000
+	BatchSize int
+	// MaxPostings cells entries kept in memory before being spilled to a sorted temporary file,
+	// defaults to 4M entries, around 80MB
+	MaxPostings int
+	// TempDir directory for the spill files, defaults to the system temporary directory
+	TempDir string
+	// Skipped is called in input order with the features that can't be covered and are not indexed,
+	// seq is the position of the feature in the iterator
+	Skipped func(seq int, f *geojson.Feature, err error)
+}
+
+// WithDefaults returns opts with the zero values replaced by the defaults.
+func (opts IndexOptions) WithDefaults() IndexOptions {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+
+	if opts.MaxPostings <= 0 {
+		opts.MaxPostings = defaultMaxPostings
+	}
+
+	return opts
+}
+
+type indexJob struct {
+	seq int
+	f   *geojson.Feature
+}
+
+type indexResult struct {
+	seq int
+	f   *geojson.Feature
+	// ef is nil when the feature can't be covered
+	ef       *EncodedFeature
+	coverErr error
+	err      error
+}
+
+// IndexFeatures covers and encodes the features on opts.Workers goroutines,
+// calls put with batches of opts.BatchSize features in input order, with ids starting at firstID,
+// and collects their cells entries into ps, it returns the count of stored features.
+func IndexFeatures(logger log.Logger, features insideout.FeatureIterator, ps *Postings,
+	icoverer, ocoverer *s2.RegionCoverer, warningCellsCover int, opts IndexOptions,
+	put func(batch []*EncodedFeature, firstID uint32) error) (uint32, error) {
+	jobs := make(chan indexJob, opts.Workers)
+	results := make(chan indexResult, opts.Workers)
+	done := make(chan struct{})
+
+	defer close(done)
+
+	// window limits the features in flight, so results waiting for a slow one stay bounded
+	window := make(chan struct{}, 4*opts.Workers)
+
+	var readErr error
+
+	go func() {
+		defer close(jobs)
+
+		for seq := 0; ; seq++ {
+			select {
+			case window <- struct{}{}:
+			case <-done:
+				return
+			}
+
+			f, err := features.Next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			if err != nil {
+				readErr = fmt.Errorf("can't read feature: %w", err)
+
+				return
+			}
+
+			select {
+			case jobs <- indexJob{seq: seq, f: f}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				res := indexResult{seq: job.seq, f: job.f}
+
+				cui, cuo, err := CoverFeature(logger, job.f, icoverer, ocoverer)
+				if err == nil {
+					res.ef, res.err = EncodeFeature(logger, job.f, cui, cuo, warningCellsCover)
+				} else {
+					res.coverErr = err
+				}
+
+				select {
+				case results <- res:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		count   uint32
+		next    int
+		batch   []*EncodedFeature
+		pending = make(map[int]indexResult)
+	)
+
+	for res := range results {
+		pending[res.seq] = res
+
+		// features are stored in input order
+		for {
+			res, ok := pending[next]
+			if !ok {
+				break
+			}
+
+			delete(pending, next)
+			next++
+			<-window
+
+			if res.err != nil {
+				return 0, fmt.Errorf("can't store feature into DB: %w", res.err)
+			}
+
+			if res.ef == nil {
+				if opts.Skipped != nil {
+					opts.Skipped(res.seq, res.f, res.coverErr)
+				}
+
+				continue
+			}
+
+			if err := ps.Add(insideout.InsideKey, res.ef.CellsIn, count); err != nil {
+				return 0, err
+			}
+
+			if err := ps.Add(insideout.OutsideKey, res.ef.CellsOut, count); err != nil {
+				return 0, err
+			}
+
+			batch = append(batch, res.ef)
+			count++
+
+			if len(batch) == opts.BatchSize {
+				if err := put(batch, count-uint32(len(batch))); err != nil {
+					return 0, fmt.Errorf("can't store feature into DB: %w", err)
+				}
+
+				batch = batch[:0]
+			}
+		}
+	}
+
+	if readErr != nil {
+		return 0, readErr
+	}
+
+	if len(batch) > 0 {
+		if err := put(batch, count-uint32(len(batch))); err != nil {
+			return 0, fmt.Errorf("can't store feature into DB: %w", err)
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,287 @@
+// Package kv contains the storage logic shared by the key value engines:
+// features encoding, cells entries values and the stab lookups over cells entries.
+// Every engine uses the keys from insideout.
+package kv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/fxamacker/cbor"
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+// ValueSize the size of a cells entry value: the feature id and the polygon index uint32 + uint16.
+const ValueSize = 4 + 2
+
+// EncodeValue encodes a cells entry value.
+func EncodeValue(id uint32, pos uint16) []byte {
+	v := make([]byte, ValueSize)
+	binary.BigEndian.PutUint32(v, id)
+	binary.BigEndian.PutUint16(v[4:], pos)
+
+	return v
+}
+
+// DecodeValues reads back the feature ids and polygon indexes uint32 + uint16.
+func DecodeValues(v []byte) []insideout.FeatureIndexResponse {
+	resps := make([]insideout.FeatureIndexResponse, 0, len(v)/ValueSize)
+
+	for i := 0; i+ValueSize <= len(v); i += ValueSize {
+		res := insideout.FeatureIndexResponse{}
+		res.ID = binary.BigEndian.Uint32(v[i : i+4])
+		res.Pos = binary.BigEndian.Uint16(v[i+4:])
+		resps = append(resps, res)
+	}
+
+	return resps
+}
+
+// AddValues reads back the feature ids and polygon indexes into m.
+func AddValues(v []byte, m map[insideout.FeatureIndexResponse]struct{}) {
+	for _, res := range DecodeValues(v) {
+		m[res] = struct{}{}
+	}
+}
+
+// RemoveValues returns v without the values of the feature id.
+func RemoveValues(v []byte, id uint32) []byte {
+	nv := make([]byte, 0, len(v))
+
+	for i := 0; i+ValueSize <= len(v); i += ValueSize {
+		if binary.BigEndian.Uint32(v[i:]) != id {
+			nv = append(nv, v[i:i+ValueSize]...)
+		}
+	}
+
+	return nv
+}
+
+// CellEntry a cell key and its values.
+type CellEntry struct {
+	C s2.CellID
+	V []byte
+}
+
+// NewCellEntry returns the entry of an inside or outside cells key.
+func NewCellEntry(k, v []byte) CellEntry {
+	return CellEntry{C: s2.CellID(binary.BigEndian.Uint64(k[1:])), V: v}
+}
+
+// StabEntries returns the polygons stored under cells containing c.
+func StabEntries(c s2.CellID, ientries, oentries []CellEntry, stopOnInsideFound bool) insideout.IndexResponse {
+	var idxResp insideout.IndexResponse
+
+	mi := make(map[insideout.FeatureIndexResponse]struct{})
+
+	for _, e := range ientries {
+		if !e.C.Contains(c) {
+			continue
+		}
+
+		for _, res := range DecodeValues(e.V) {
+			if stopOnInsideFound {
+				idxResp.IDsInside = append(idxResp.IDsInside, res)
+
+				return idxResp
+			}
+
+			if _, ok := mi[res]; !ok {
+				mi[res] = struct{}{}
+				idxResp.IDsInside = append(idxResp.IDsInside, res)
+			}
+		}
+	}
+
+	mo := make(map[insideout.FeatureIndexResponse]struct{})
+
+	for _, e := range oentries {
+		if !e.C.Contains(c) {
+			continue
+		}
+
+		for _, res := range DecodeValues(e.V) {
+			_, in := mi[res]
+			_, out := mo[res]
+
+			// remove any answer matching inside
+			if !in && !out {
+				mo[res] = struct{}{}
+				idxResp.IDsMayBeInside = append(idxResp.IDsMayBeInside, res)
+			}
+		}
+	}
+
+	return idxResp
+}
+
+// IntersectsResponse returns the inside polygons and the outside ones not inside.
+func IntersectsResponse(mi, mo map[insideout.FeatureIndexResponse]struct{}) insideout.IndexResponse {
+	var idxResp insideout.IndexResponse
+
+	for res := range mi {
+		idxResp.IDsInside = append(idxResp.IDsInside, res)
+	}
+
+	for res := range mo {
+		// remove any answer matching inside
+		if _, ok := mi[res]; !ok {
+			idxResp.IDsMayBeInside = append(idxResp.IDsMayBeInside, res)
+		}
+	}
+
+	return idxResp
+}
+
+// CoverFeature returns the inside and outside covers of f.
+func CoverFeature(logger log.Logger, f *geojson.Feature,
+	icoverer, ocoverer *s2.RegionCoverer) ([]s2.CellUnion, []s2.CellUnion, error) {
+	// cover inside
+	cui, err := insideout.GeoJSONCoverCellUnion(f, icoverer, true)
+	if err != nil {
+		level.Warn(logger).Log("msg", "error covering inside", "error", err, "feature_properties", f.Properties)
+
+		return nil, nil, err
+	}
+
+	// cover outside
+	cuo, err := insideout.GeoJSONCoverCellUnion(f, ocoverer, false)
+	if err != nil {
+		level.Warn(logger).Log("msg", "error covering outside", "error", err, "feature_properties", f.Properties)
+
+		return nil, nil, err
+	}
+
+	return cui, cuo, nil
+}
+
+// skipLargeCovers returns cus with the covers larger than warningCellsCover replaced by empty covers,
+// so polygons keep their indexes.
+func skipLargeCovers(logger log.Logger, f *geojson.Feature, cus []s2.CellUnion,
+	warningCellsCover int, kind string) []s2.CellUnion {
+	if warningCellsCover == 0 {
+		return cus
+	}
+
+	res := make([]s2.CellUnion, len(cus))
+
+	for fi, cu := range cus {
+		if len(cu) > warningCellsCover {
+			level.Warn(logger).Log(
+				"msg", fmt.Sprintf("%s cover too big %d cells, not indexing polygon #%d %s", kind, len(cu), fi, f.Properties),
+				"feature_properties", f.Properties,
+			)
+
+			continue
+		}
+
+		res[fi] = cu
+	}
+
+	return res
+}
+
+// EncodedFeature a feature ready to be stored.
+type EncodedFeature struct {
+	ExtID string
+	// Feature, Properties and Cells are the values of the FeatureKey, PropertiesKey and CellKey entries
+	Feature, Properties, Cells []byte
+	// CellsIn and CellsOut are the covers added to the cells entries
+	CellsIn, CellsOut []s2.CellUnion
+}
+
+// EncodeFeature encodes the feature, its properties and its cells,
+// covers larger than warningCellsCover are not added to the cells entries.
+func EncodeFeature(logger log.Logger, f *geojson.Feature,
+	cui, cuo []s2.CellUnion, warningCellsCover int) (*EncodedFeature, error) {
+	pb, err := insideout.GeoJSONEncodePolygons(f)
+	if err != nil {
+		return nil, fmt.Errorf("can't encode polygon: %w", err)
+	}
+
+	ef := &EncodedFeature{ExtID: f.ID}
+
+	b := new(bytes.Buffer)
+	enc := cbor.NewEncoder(b, cbor.CanonicalEncOptions())
+
+	fs := &insideout.FeatureStorage{Properties: f.Properties, PolygonsBytes: pb}
+	if err := enc.Encode(fs); err != nil {
+		return nil, fmt.Errorf("can't encode FeatureStorage: %w", err)
+	}
+
+	ef.Feature = b.Bytes()
+
+	// properties are also stored alone, to be filtered without decoding polygons
+	b = new(bytes.Buffer)
+	if err := cbor.NewEncoder(b, cbor.CanonicalEncOptions()).Encode(f.Properties); err != nil {
+		return nil, fmt.Errorf("can't encode properties: %w", err)
+	}
+
+	ef.Properties = b.Bytes()
+
+	// store cells for tree
+	b = new(bytes.Buffer)
+	enc = cbor.NewEncoder(b, cbor.CanonicalEncOptions())
+	cs := &insideout.CellsStorage{
+		CellsIn:  cui,
+		CellsOut: cuo,
+	}
+
+	if err := enc.Encode(cs); err != nil {
+		return nil, fmt.Errorf("can't encode CellsStorage: %w", err)
+	}
+
+	ef.Cells = b.Bytes()
+
+	// TODO: filter cuo cui[fi].ContainsCellID(c)
+	ef.CellsIn = skipLargeCovers(logger, f, cui, warningCellsCover, "inside")
+	ef.CellsOut = skipLargeCovers(logger, f, cuo, warningCellsCover, "outside")
+
+	return ef, nil
+}
+
+// Encode encodes v in canonical CBOR.
+func Encode(v interface{}) ([]byte, error) {
+	b := new(bytes.Buffer)
+	if err := cbor.NewEncoder(b, cbor.CanonicalEncOptions()).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// Decode decodes the CBOR data into v.
+func Decode(data []byte, v interface{}) error {
+	return cbor.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// SetIndexInfos fills infos with the parameters of a new index.
+func SetIndexInfos(infos *insideout.IndexInfos, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int) {
+	// Finding the lowest cover level
+	minCoverLevel := ocoverer.MinLevel
+	if icoverer.MinLevel < ocoverer.MinLevel {
+		minCoverLevel = icoverer.MinLevel
+	}
+
+	infos.MinCoverLevel = minCoverLevel
+	infos.SchemaVersion = insideout.SchemaVersion
+	infos.InsideCover = insideout.NewCoverInfos(icoverer)
+	infos.OutsideCover = insideout.NewCoverInfos(ocoverer)
+	infos.WarningCellsCover = warningCellsCover
+}
+
+// SubCount returns c minus n, DBs indexed before cells counting have no count.
+func SubCount(c uint64, n int) uint64 {
+	if uint64(n) > c {
+		return 0
+	}
+
+	return c - uint64(n)
+}
@@ -0,0 +1,236 @@
+package kv
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/golang/geo/s2"
+)
+
+// postingSize encoded size of a posting: the cell key, the feature id and the polygon index.
+const postingSize = 9 + 4 + 2
+
+// posting an entry of a cell key.
+type posting struct {
+	key [9]byte
+	id  uint32
+	pos uint16
+}
+
+// less orders by key, then newest feature and polygon first as in the stored values.
+func (p posting) less(o posting) bool {
+	if c := bytes.Compare(p.key[:], o.key[:]); c != 0 {
+		return c < 0
+	}
+
+	if p.id != o.id {
+		return p.id > o.id
+	}
+
+	return p.pos > o.pos
+}
+
+// Postings collects the cells entries, when more than max are kept in memory
+// they are sorted and spilled to a temporary file in dir.
+type Postings struct {
+	dir    string
+	max    int
+	mem    []posting
+	spills []*os.File
+}
+
+// NewPostings returns Postings spilling to dir after max entries.
+func NewPostings(dir string, max int) *Postings {
+	return &Postings{dir: dir, max: max}
+}
+
+// SpillCount returns the number of spill files.
+func (ps *Postings) SpillCount() int {
+	return len(ps.spills)
+}
+
+// Add adds the feature id and the polygon index to every cell of cus.
+func (ps *Postings) Add(key func(s2.CellID) []byte, cus []s2.CellUnion, id uint32) error {
+	for fi, cu := range cus {
+		for _, c := range cu {
+			p := posting{id: id, pos: uint16(fi)}
+			copy(p.key[:], key(c))
+			ps.mem = append(ps.mem, p)
+		}
+	}
+
+	if len(ps.mem) < ps.max {
+		return nil
+	}
+
+	return ps.spill()
+}
+
+func (ps *Postings) sort() {
+	sort.Slice(ps.mem, func(i, j int) bool {
+		return ps.mem[i].less(ps.mem[j])
+	})
+}
+
+// spill writes the sorted postings in memory to a temporary file.
+func (ps *Postings) spill() error {
+	ps.sort()
+
+	f, err := ioutil.TempFile(ps.dir, "insideout-cells-")
+	if err != nil {
+		return err
+	}
+
+	ps.spills = append(ps.spills, f)
+
+	w := bufio.NewWriter(f)
+
+	var b [postingSize]byte
+
+	for _, p := range ps.mem {
+		copy(b[:], p.key[:])
+		binary.BigEndian.PutUint32(b[9:], p.id)
+		binary.BigEndian.PutUint16(b[13:], p.pos)
+
+		if _, err := w.Write(b[:]); err != nil {
+			return err
+		}
+	}
+
+	ps.mem = ps.mem[:0]
+
+	return w.Flush()
+}
+
+// Merge calls fn for every key in order with its value: the feature ids and polygon indexes uint32 + uint16.
+func (ps *Postings) Merge(fn func(key, value []byte) error) error {
+	ps.sort()
+
+	h := make(runHeap, 0, len(ps.spills)+1)
+	runs := []*run{{mem: ps.mem}}
+
+	for _, f := range ps.spills {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		runs = append(runs, &run{r: bufio.NewReader(f)})
+	}
+
+	for _, r := range runs {
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			h = append(h, r)
+		}
+	}
+
+	heap.Init(&h)
+
+	var key, value []byte
+
+	for h.Len() > 0 {
+		r := h[0]
+		p := r.cur
+
+		if key != nil && !bytes.Equal(key, p.key[:]) {
+			if err := fn(key, value); err != nil {
+				return err
+			}
+
+			key, value = nil, nil
+		}
+
+		if key == nil {
+			key = append([]byte(nil), p.key[:]...)
+		}
+
+		value = append(value, EncodeValue(p.id, p.pos)...)
+
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+
+	if key != nil {
+		return fn(key, value)
+	}
+
+	return nil
+}
+
+// Close removes the spill files.
+func (ps *Postings) Close() {
+	for _, f := range ps.spills {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	ps.spills = nil
+}
+
+// run a sorted sequence of postings, in memory or from a spill file.
+type run struct {
+	mem []posting
+	r   *bufio.Reader
+	cur posting
+}
+
+// next moves to the next posting, false at the end of the run.
+func (r *run) next() (bool, error) {
+	if r.r == nil {
+		if len(r.mem) == 0 {
+			return false, nil
+		}
+
+		r.cur, r.mem = r.mem[0], r.mem[1:]
+
+		return true, nil
+	}
+
+	var b [postingSize]byte
+	if _, err := io.ReadFull(r.r, b[:]); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	copy(r.cur.key[:], b[:9])
+	r.cur.id = binary.BigEndian.Uint32(b[9:])
+	r.cur.pos = binary.BigEndian.Uint16(b[13:])
+
+	return true, nil
+}
+
+type runHeap []*run
+
+func (h runHeap) Len() int            { return len(h) }
+func (h runHeap) Less(i, j int) bool  { return h[i].cur.less(h[j].cur) }
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*run)) }
+
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return r
+}
@@ -0,0 +1,208 @@
+package leveldb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/internal/kv"
+)
+
+// cells entries written per batch
+const cellsBatchSize = 100000
+
+// IndexOptions tunes the indexing pipeline, zero values use the defaults.
+type IndexOptions = kv.IndexOptions
+
+// SetIndexOptions sets the options used by Index.
+func (s *Storage) SetIndexOptions(opts IndexOptions) {
+	s.indexOptions = opts
+}
+
+// Index indexes the features read from features until io.EOF into an empty DB.
+// Features are covered and encoded by a pool of workers then stored in batches,
+// the cells entries are merged and written in key order once every feature is stored.
+func (s *Storage) Index(features insideout.FeatureIterator, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	logger := log.With(s.logger, "component", "indexer")
+	opts := s.indexOptions.WithDefaults()
+
+	it := s.NewIterator(nil, nil)
+	empty := !it.First()
+	it.Release()
+
+	if !empty {
+		return errors.New("can't index into a non empty DB")
+	}
+
+	ps := kv.NewPostings(opts.TempDir, opts.MaxPostings)
+	defer ps.Close()
+
+	count, err := kv.IndexFeatures(logger, features, ps, icoverer, ocoverer, warningCellsCover, opts,
+		func(batch []*kv.EncodedFeature, firstID uint32) error {
+			return s.putBatch(logger, batch, firstID)
+		})
+	if err != nil {
+		return err
+	}
+
+	level.Info(logger).Log("msg", "stored features", "feature_count", count, "spill_count", ps.SpillCount())
+
+	infos := &insideout.IndexInfos{
+		Filename:       fileName,
+		IndexerVersion: version,
+		FeatureCount:   count,
+		NextID:         count,
+		IndexTime:      time.Now(),
+	}
+
+	if err := s.writeCells(ps, infos); err != nil {
+		return fmt.Errorf("can't store cells into DB: %w", err)
+	}
+
+	kv.SetIndexInfos(infos, icoverer, ocoverer, warningCellsCover)
+	s.minCoverLevel = infos.MinCoverLevel
+
+	b, err := kv.Encode(infos)
+	if err != nil {
+		return fmt.Errorf("failed encoding IndexInfos: %w", err)
+	}
+
+	return s.Put(insideout.InfoKey(), b, &opt.WriteOptions{Sync: true})
+}
+
+// putBatch stores the features in one transaction, with ids starting at firstID.
+func (s *Storage) putBatch(logger log.Logger, batch []*kv.EncodedFeature, firstID uint32) error {
+	tr, err := s.OpenTransaction()
+	if err != nil {
+		return err
+	}
+
+	defer tr.Discard()
+
+	for i, ef := range batch {
+		id := firstID + uint32(i)
+
+		if ef.ExtID != "" {
+			ev, err := get(tr, insideout.ExternalIDKey(ef.ExtID))
+			if err != nil {
+				return err
+			}
+
+			if ev != nil {
+				level.Warn(logger).Log("msg", "duplicate feature id, only the last one keeps it", "feature_id", ef.ExtID)
+
+				if err := tr.Delete(insideout.FeatureExternalIDKey(binary.BigEndian.Uint32(ev)), nil); err != nil {
+					return err
+				}
+			}
+
+			if err := putExternalID(tr, ef.ExtID, id); err != nil {
+				return err
+			}
+		}
+
+		if err := putEncodedFeature(tr, id, ef); err != nil {
+			return err
+		}
+	}
+
+	level.Debug(logger).Log("msg", "stored features batch", "first_id", firstID, "feature_count", len(batch))
+
+	return tr.Commit()
+}
+
+// writeCells writes the cells entries in key order, cellsBatchSize entries per batch,
+// and counts them into infos.
+func (s *Storage) writeCells(ps *kv.Postings, infos *insideout.IndexInfos) error {
+	b := new(leveldb.Batch)
+
+	err := ps.Merge(func(key, value []byte) error {
+		b.Put(key, value)
+
+		if key[0] == insideout.InsidePrefix() {
+			infos.InsideCellCount++
+		} else {
+			infos.OutsideCellCount++
+		}
+
+		if b.Len() < cellsBatchSize {
+			return nil
+		}
+
+		err := s.Write(b, nil)
+		b.Reset()
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.Write(b, &opt.WriteOptions{Sync: true})
+}
+
+// AddSource records a file used to index or update the index.
+func (s *Storage) AddSource(src insideout.SourceInfos) error {
+	tr, err := s.OpenTransaction()
+	if err != nil {
+		return err
+	}
+
+	defer tr.Discard()
+
+	infos, err := loadInfos(tr)
+	if err != nil {
+		return err
+	}
+
+	infos.Sources = append(infos.Sources, src)
+
+	if err := putInfos(tr, infos); err != nil {
+		return err
+	}
+
+	return tr.Commit()
+}
+
+// putEncodedFeature stores the feature, its properties and its cells, not the cells entries.
+func putEncodedFeature(tr *leveldb.Transaction, id uint32, ef *kv.EncodedFeature) error {
+	if err := tr.Put(insideout.FeatureKey(id), ef.Feature, nil); err != nil {
+		return err
+	}
+
+	if err := tr.Put(insideout.PropertiesKey(id), ef.Properties, nil); err != nil {
+		return err
+	}
+
+	return tr.Put(insideout.CellKey(id), ef.Cells, nil)
+}
+
+// putExternalID stores the mapping between the external id and the feature id in both directions.
+func putExternalID(tr *leveldb.Transaction, extID string, id uint32) error {
+	v := make([]byte, 4)
+	binary.BigEndian.PutUint32(v, id)
+
+	if err := tr.Put(insideout.ExternalIDKey(extID), v, nil); err != nil {
+		return err
+	}
+
+	return tr.Put(insideout.FeatureExternalIDKey(id), []byte(extID), nil)
+}
+
+func putInfos(tr *leveldb.Transaction, infos *insideout.IndexInfos) error {
+	b, err := kv.Encode(infos)
+	if err != nil {
+		return fmt.Errorf("failed encoding IndexInfos: %w", err)
+	}
+
+	return tr.Put(insideout.InfoKey(), b, nil)
+}
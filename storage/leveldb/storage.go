@@ -0,0 +1,416 @@
+// Package leveldb is a storage using goleveldb, an LSM tree engine,
+// every entry lives in one keyspace prefixed as the insideout keys.
+package leveldb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/internal/kv"
+)
+
+// Storage cold storage.
+type Storage struct {
+	*leveldb.DB
+	logger        log.Logger
+	minCoverLevel int
+	indexOptions  IndexOptions
+}
+
+// reader reads from the DB, a snapshot or a transaction.
+type reader interface {
+	Get(key []byte, ro *opt.ReadOptions) ([]byte, error)
+	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
+}
+
+// NewStorage returns a cold storage using leveldb.
+func NewStorage(path string, logger log.Logger) (*Storage, func() error, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't open database %w", err)
+	}
+
+	return &Storage{
+		DB:     db,
+		logger: logger,
+	}, db.Close, nil
+}
+
+// NewROStorage returns a read only storage using leveldb.
+func NewROStorage(path string, logger log.Logger) (*Storage, func() error, error) {
+	db, err := leveldb.OpenFile(path, &opt.Options{ReadOnly: true, ErrorIfMissing: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open DB for reading at %s: %w", path, err)
+	}
+
+	s := &Storage{
+		DB:     db,
+		logger: logger,
+	}
+
+	infos, err := s.LoadIndexInfos()
+	if err != nil {
+		db.Close()
+
+		return nil, nil, err
+	}
+
+	s.minCoverLevel = infos.MinCoverLevel
+
+	return s, db.Close, nil
+}
+
+// get returns the value of k, nil when not found.
+func get(r reader, k []byte) ([]byte, error) {
+	v, err := r.Get(k, nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return nil, nil
+	}
+
+	return v, err
+}
+
+// LoadFeature loads one feature from the DB.
+func (s *Storage) LoadFeature(id uint32) (*insideout.Feature, error) {
+	v, err := get(s.DB, insideout.FeatureKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("error loading feature %w", err)
+	}
+
+	if v == nil {
+		return nil, fmt.Errorf("error loading feature %w: %d", insideout.ErrFeatureNotFound, id)
+	}
+
+	fs := &insideout.FeatureStorage{}
+	if err := kv.Decode(v, fs); err != nil {
+		return nil, fmt.Errorf("error loading feature %w", err)
+	}
+
+	polygons, err := fs.Polygons()
+	if err != nil {
+		return nil, err
+	}
+
+	extID, err := s.LoadExternalID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &insideout.Feature{
+		Polygons:   polygons,
+		Properties: fs.Properties,
+		ExternalID: extID,
+	}, nil
+}
+
+// LoadFeatureProperties loads the properties of one feature without decoding its polygons.
+func (s *Storage) LoadFeatureProperties(id uint32) (map[string]interface{}, error) {
+	v, err := get(s.DB, insideout.PropertiesKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("error loading feature properties %w", err)
+	}
+
+	if v == nil {
+		return nil, fmt.Errorf("error loading feature properties %w: %d", insideout.ErrFeatureNotFound, id)
+	}
+
+	var props map[string]interface{}
+	if err := kv.Decode(v, &props); err != nil {
+		return nil, fmt.Errorf("error loading feature properties %w", err)
+	}
+
+	return props, nil
+}
+
+// LoadExternalID returns the external id of the feature id, empty when it has none.
+func (s *Storage) LoadExternalID(id uint32) (string, error) {
+	v, err := get(s.DB, insideout.FeatureExternalIDKey(id))
+
+	return string(v), err
+}
+
+// LookupExternalID returns the feature id for the external id.
+func (s *Storage) LookupExternalID(extID string) (uint32, error) {
+	v, err := get(s.DB, insideout.ExternalIDKey(extID))
+	if err != nil {
+		return 0, err
+	}
+
+	if v == nil {
+		return 0, insideout.ErrFeatureNotFound
+	}
+
+	return binary.BigEndian.Uint32(v), nil
+}
+
+// LoadAllFeatures loads FeatureStorage from DB into idx
+// only useful to fill in memory shapeindex.
+func (s *Storage) LoadAllFeatures(add func(*insideout.FeatureStorage, uint32) error) error {
+	it := s.NewIterator(util.BytesPrefix([]byte{insideout.FeaturePrefix()}), nil)
+	defer it.Release()
+
+	for it.Next() {
+		fs := &insideout.FeatureStorage{}
+		if err := kv.Decode(it.Value(), fs); err != nil {
+			return err
+		}
+
+		if err := add(fs, binary.BigEndian.Uint32(it.Key()[1:])); err != nil {
+			return err
+		}
+	}
+
+	return it.Error()
+}
+
+// LoadFeaturesCells loads CellsStorage from DB into idx
+// only useful to fill in memory tree indexes.
+func (s *Storage) LoadFeaturesCells(add func([]s2.CellUnion, []s2.CellUnion, uint32)) error {
+	it := s.NewIterator(util.BytesPrefix([]byte{insideout.CellPrefix()}), nil)
+	defer it.Release()
+
+	for it.Next() {
+		cs := &insideout.CellsStorage{}
+		if err := kv.Decode(it.Value(), cs); err != nil {
+			return err
+		}
+
+		add(cs.CellsIn, cs.CellsOut, binary.BigEndian.Uint32(it.Key()[1:]))
+	}
+
+	return it.Error()
+}
+
+// LoadCellsEntries calls fn with the inside then the outside cells entries in key order,
+// values are the feature ids and polygon indexes, only valid during the call.
+func (s *Storage) LoadCellsEntries(fn func(key, value []byte) error) error {
+	snap, err := s.GetSnapshot()
+	if err != nil {
+		return err
+	}
+
+	defer snap.Release()
+
+	for _, prefix := range []byte{insideout.InsidePrefix(), insideout.OutsidePrefix()} {
+		if err := forEach(snap, prefix, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func forEach(r reader, prefix byte, fn func(key, value []byte) error) error {
+	it := r.NewIterator(util.BytesPrefix([]byte{prefix}), nil)
+	defer it.Release()
+
+	for it.Next() {
+		if err := fn(it.Key(), it.Value()); err != nil {
+			return err
+		}
+	}
+
+	return it.Error()
+}
+
+// LoadMapInfos loads map infos from the DB if any.
+func (s *Storage) LoadMapInfos() (*insideout.MapInfos, bool, error) {
+	v, err := get(s.DB, insideout.MapKey())
+	if err != nil || v == nil {
+		return nil, false, err
+	}
+
+	mapInfos := &insideout.MapInfos{}
+	if err := kv.Decode(v, mapInfos); err != nil {
+		return nil, false, err
+	}
+
+	return mapInfos, true, nil
+}
+
+// LoadIndexInfos loads index infos from the DB.
+func (s *Storage) LoadIndexInfos() (*insideout.IndexInfos, error) {
+	return loadInfos(s.DB)
+}
+
+// LoadCellStorage loads cell storage from.
+func (s *Storage) LoadCellStorage(id uint32) (*insideout.CellsStorage, error) {
+	v, err := get(s.DB, insideout.CellKey(id))
+	if err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return nil, fmt.Errorf("cells not found for feature id %d: %w", id, insideout.ErrFeatureNotFound)
+	}
+
+	cs := &insideout.CellsStorage{}
+
+	return cs, kv.Decode(v, cs)
+}
+
+func (s *Storage) StabDB(lat, lng float64, stopOnInsideFound bool) (insideout.IndexResponse, error) {
+	resps, err := s.StabBatchDB([]s2.LatLng{s2.LatLngFromDegrees(lat, lng)}, stopOnInsideFound)
+	if err != nil {
+		return insideout.IndexResponse{}, err
+	}
+
+	return resps[0], nil
+}
+
+// StabBatchDB returns for each lat lng polygon's ids containing it and polygon's ids that may contain it,
+// responses are in the same order as lls.
+// Points are sorted by cell id so the cells keys are read in key order from one snapshot.
+func (s *Storage) StabBatchDB(lls []s2.LatLng, stopOnInsideFound bool) ([]insideout.IndexResponse, error) {
+	resps := make([]insideout.IndexResponse, len(lls))
+	cells := make([]s2.CellID, len(lls))
+	order := make([]int, len(lls))
+
+	for i, ll := range lls {
+		cells[i] = s2.CellIDFromLatLng(ll)
+		order[i] = i
+	}
+
+	sort.Slice(order, func(i, j int) bool { return cells[order[i]] < cells[order[j]] })
+
+	snap, err := s.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	defer snap.Release()
+
+	for start := 0; start < len(order); {
+		// points sharing the same lookup cell share the same keys range
+		cLookup := cells[order[start]].Parent(s.minCoverLevel)
+
+		end := start + 1
+		for end < len(order) && cells[order[end]].Parent(s.minCoverLevel) == cLookup {
+			end++
+		}
+
+		ientries, err := rangeEntries(snap, cLookup, insideout.InsideRangeKeys)
+		if err != nil {
+			return nil, fmt.Errorf("while iterating over inside keys: %w", err)
+		}
+
+		oentries, err := rangeEntries(snap, cLookup, insideout.OutsideRangeKeys)
+		if err != nil {
+			return nil, fmt.Errorf("while iterating over outside keys: %w", err)
+		}
+
+		for _, i := range order[start:end] {
+			resps[i] = kv.StabEntries(cells[i], ientries, oentries, stopOnInsideFound)
+		}
+
+		start = end
+	}
+
+	return resps, nil
+}
+
+// rangeEntries returns the entries stored under c and its children cells.
+func rangeEntries(r reader, c s2.CellID, rangeFunc func(s2.CellID) ([]byte, []byte)) ([]kv.CellEntry, error) {
+	var entries []kv.CellEntry
+
+	it := r.NewIterator(cellRange(c, rangeFunc), nil)
+	defer it.Release()
+
+	for it.Next() {
+		// iterator buffers are reused
+		entries = append(entries, kv.NewCellEntry(it.Key(), append([]byte(nil), it.Value()...)))
+	}
+
+	return entries, it.Error()
+}
+
+// cellRange returns the range of the keys of c and its children cells, the stop key included.
+func cellRange(c s2.CellID, rangeFunc func(s2.CellID) ([]byte, []byte)) *util.Range {
+	startKey, stopKey := rangeFunc(c)
+
+	return &util.Range{Start: startKey, Limit: append(stopKey, 0)}
+}
+
+// IntersectsDB returns polygon's ids whose inside cover intersects the cell union
+// and polygon's ids whose outside cover only intersects it.
+func (s *Storage) IntersectsDB(cu s2.CellUnion) (insideout.IndexResponse, error) {
+	mi := make(map[insideout.FeatureIndexResponse]struct{})
+	mo := make(map[insideout.FeatureIndexResponse]struct{})
+
+	snap, err := s.GetSnapshot()
+	if err != nil {
+		return insideout.IndexResponse{}, err
+	}
+
+	defer snap.Release()
+
+	for _, c := range cu {
+		if err := intersectingCells(snap, c, s.minCoverLevel, insideout.InsideKey, insideout.InsideRangeKeys, mi); err != nil {
+			return insideout.IndexResponse{}, fmt.Errorf("while iterating over cells keys: %w", err)
+		}
+
+		if err := intersectingCells(snap, c, s.minCoverLevel, insideout.OutsideKey, insideout.OutsideRangeKeys, mo); err != nil {
+			return insideout.IndexResponse{}, fmt.Errorf("while iterating over cells keys: %w", err)
+		}
+	}
+
+	return kv.IntersectsResponse(mi, mo), nil
+}
+
+// intersectingCells adds to m the polygons stored under any cell intersecting c:
+// parent cells down to the min cover level and children cells.
+func intersectingCells(r reader, c s2.CellID, minCoverLevel int,
+	keyFunc func(s2.CellID) []byte, rangeFunc func(s2.CellID) ([]byte, []byte),
+	m map[insideout.FeatureIndexResponse]struct{}) error {
+	for l := minCoverLevel; l < c.Level(); l++ {
+		v, err := get(r, keyFunc(c.Parent(l)))
+		if err != nil {
+			return err
+		}
+
+		kv.AddValues(v, m)
+	}
+
+	it := r.NewIterator(cellRange(c, rangeFunc), nil)
+	defer it.Release()
+
+	for it.Next() {
+		kv.AddValues(it.Value(), m)
+	}
+
+	return it.Error()
+}
+
+// loadInfos returns the index infos, refusing DBs written with a newer schema.
+func loadInfos(r reader) (*insideout.IndexInfos, error) {
+	v, err := get(r, insideout.InfoKey())
+	if err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return nil, errors.New("can't find infos entries, invalid DB")
+	}
+
+	infos := &insideout.IndexInfos{}
+	if err := kv.Decode(v, infos); err != nil {
+		return nil, fmt.Errorf("failed decoding IndexInfos: %w", err)
+	}
+
+	// an older indexer must not modify a newer DB
+	if err := infos.CheckSchema(); err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
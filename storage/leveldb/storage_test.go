@@ -0,0 +1,70 @@
+package leveldb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/leveldb"
+	"github.com/akhenakh/insideout/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.TestStore(t, func(t *testing.T) (insideout.Store, func()) {
+		dir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+		require.NoError(t, err)
+
+		s, sclose, err := leveldb.NewStorage(dir, log.NewNopLogger())
+		require.NoError(t, err)
+
+		return s, func() {
+			sclose()
+			os.RemoveAll(dir)
+		}
+	})
+}
+
+func TestNewROStorage(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	s, sclose, err := leveldb.NewStorage(dir, log.NewNopLogger())
+	require.NoError(t, err)
+
+	file, err := os.Open("../../index/testdata/poly.geojson")
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	icoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+
+	err = s.Index(insideout.NewFeatureDecoder(file), icoverer, ocoverer, 100, "poly.geojson", "unittest")
+	require.NoError(t, err)
+
+	// indexing only creates new DBs
+	err = s.Index(insideout.NewFeatureCollectionIterator(&geojson.FeatureCollection{}), icoverer, ocoverer, 100, "", "")
+	require.Error(t, err)
+	require.NoError(t, sclose())
+
+	ros, rosclose, err := leveldb.NewROStorage(dir, log.NewNopLogger())
+	require.NoError(t, err)
+
+	defer rosclose()
+
+	resp, err := ros.StabDB(47.39650628189986, -2.9876390969486524, false)
+	require.NoError(t, err)
+	require.Equal(t, []insideout.FeatureIndexResponse{{ID: 0, Pos: 1}}, resp.IDsInside)
+
+	_, _, err = leveldb.NewROStorage(filepath.Join(dir, "missing"), log.NewNopLogger())
+	require.Error(t, err)
+}
@@ -0,0 +1,312 @@
+package leveldb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/internal/kv"
+)
+
+// PutFeature adds or replaces the feature extID and returns its internal id,
+// a replaced feature keeps its internal id, a new feature gets the next one.
+// Covers must not use a lower level than the index MinCoverLevel.
+func (s *Storage) PutFeature(extID string, f *geojson.Feature, icoverer *s2.RegionCoverer,
+	ocoverer *s2.RegionCoverer, warningCellsCover int) (uint32, error) {
+	if extID == "" {
+		return 0, errors.New("missing external id")
+	}
+
+	logger := log.With(s.logger, "component", "updater")
+
+	cui, cuo, err := kv.CoverFeature(logger, f, icoverer, ocoverer)
+	if err != nil {
+		return 0, fmt.Errorf("can't cover feature %s: %w", extID, err)
+	}
+
+	id, err := s.putFeature(logger, extID, f, cui, cuo, icoverer, ocoverer, warningCellsCover)
+	if err != nil {
+		return 0, fmt.Errorf("can't put feature %s: %w", extID, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) putFeature(logger log.Logger, extID string, f *geojson.Feature, cui, cuo []s2.CellUnion,
+	icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer, warningCellsCover int) (uint32, error) {
+	tr, err := s.OpenTransaction()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tr.Discard()
+
+	infos, err := loadInfos(tr)
+	if err != nil {
+		return 0, err
+	}
+
+	if icoverer.MinLevel < infos.MinCoverLevel || ocoverer.MinLevel < infos.MinCoverLevel {
+		return 0, fmt.Errorf("cover min level lower than the index min cover level %d", infos.MinCoverLevel)
+	}
+
+	v, err := get(tr, insideout.ExternalIDKey(extID))
+	if err != nil {
+		return 0, err
+	}
+
+	var id uint32
+
+	if v != nil {
+		id = binary.BigEndian.Uint32(v)
+
+		if err := removeFeature(tr, id, infos); err != nil {
+			return 0, err
+		}
+
+		level.Debug(logger).Log("msg", "replacing feature", "feature_id", extID, "id", id)
+	} else {
+		// ids are never reused, clients may have cached deleted ones
+		id, err = nextID(tr, infos)
+		if err != nil {
+			return 0, err
+		}
+
+		if err := putExternalID(tr, extID, id); err != nil {
+			return 0, err
+		}
+
+		infos.FeatureCount++
+
+		level.Debug(logger).Log("msg", "adding feature", "feature_id", extID, "id", id)
+	}
+
+	ef, err := kv.EncodeFeature(logger, f, cui, cuo, warningCellsCover)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := putEncodedFeature(tr, id, ef); err != nil {
+		return 0, err
+	}
+
+	n, err := addCells(tr, insideout.InsideKey, ef.CellsIn, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed set inside cover into DB: %w", err)
+	}
+
+	infos.InsideCellCount += uint64(n)
+
+	n, err = addCells(tr, insideout.OutsideKey, ef.CellsOut, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed set outside cover into DB: %w", err)
+	}
+
+	infos.OutsideCellCount += uint64(n)
+	infos.UpdateTime = time.Now()
+
+	if err := putInfos(tr, infos); err != nil {
+		return 0, err
+	}
+
+	return id, tr.Commit()
+}
+
+// DeleteFeature deletes the feature extID, its cells entries, cells storage and properties.
+func (s *Storage) DeleteFeature(extID string) error {
+	if err := s.deleteFeature(extID); err != nil {
+		return fmt.Errorf("can't delete feature %s: %w", extID, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) deleteFeature(extID string) error {
+	tr, err := s.OpenTransaction()
+	if err != nil {
+		return err
+	}
+
+	defer tr.Discard()
+
+	infos, err := loadInfos(tr)
+	if err != nil {
+		return err
+	}
+
+	v, err := get(tr, insideout.ExternalIDKey(extID))
+	if err != nil {
+		return err
+	}
+
+	if v == nil {
+		return insideout.ErrFeatureNotFound
+	}
+
+	id := binary.BigEndian.Uint32(v)
+
+	if err := removeFeature(tr, id, infos); err != nil {
+		return err
+	}
+
+	for _, k := range [][]byte{
+		insideout.FeatureKey(id),
+		insideout.ExternalIDKey(extID),
+		insideout.FeatureExternalIDKey(id),
+	} {
+		if err := tr.Delete(k, nil); err != nil {
+			return err
+		}
+	}
+
+	if infos.FeatureCount > 0 {
+		infos.FeatureCount--
+	}
+
+	infos.UpdateTime = time.Now()
+
+	if err := putInfos(tr, infos); err != nil {
+		return err
+	}
+
+	return tr.Commit()
+}
+
+// removeFeature removes the feature id from its cells entries and deletes its cells storage and properties,
+// the feature entry itself is left to be overwritten or deleted by the caller, deleted entries are counted into infos.
+func removeFeature(tr *leveldb.Transaction, id uint32, infos *insideout.IndexInfos) error {
+	v, err := get(tr, insideout.CellKey(id))
+	if err != nil {
+		return err
+	}
+
+	if v == nil {
+		return fmt.Errorf("cells not found for feature id: %d", id)
+	}
+
+	cs := &insideout.CellsStorage{}
+	if err := kv.Decode(v, cs); err != nil {
+		return fmt.Errorf("can't decode CellsStorage: %w", err)
+	}
+
+	n, err := removeCells(tr, insideout.InsideKey, cs.CellsIn, id)
+	if err != nil {
+		return err
+	}
+
+	infos.InsideCellCount = kv.SubCount(infos.InsideCellCount, n)
+
+	n, err = removeCells(tr, insideout.OutsideKey, cs.CellsOut, id)
+	if err != nil {
+		return err
+	}
+
+	infos.OutsideCellCount = kv.SubCount(infos.OutsideCellCount, n)
+
+	if err := tr.Delete(insideout.CellKey(id), nil); err != nil {
+		return err
+	}
+
+	return tr.Delete(insideout.PropertiesKey(id), nil)
+}
+
+// addCells adds the feature id and the polygon index to the entries of every cell of cus,
+// it returns the number of new entries.
+func addCells(tr *leveldb.Transaction, key func(s2.CellID) []byte, cus []s2.CellUnion, id uint32) (int, error) {
+	var n int
+
+	for fi, cu := range cus {
+		for _, c := range cu {
+			// value is the feature id, the polygon index in a multipolygon: fi
+			v := kv.EncodeValue(id, uint16(fi))
+
+			ev, err := get(tr, key(c))
+			if err != nil {
+				return n, err
+			}
+
+			// append to existing if any
+			if ev != nil {
+				v = append(v, ev...) //nolint: makezero
+			} else {
+				n++
+			}
+
+			if err := tr.Put(key(c), v, nil); err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// removeCells removes the feature id from the entries of every cell of cus, emptied entries are deleted,
+// it returns the number of deleted entries.
+func removeCells(tr *leveldb.Transaction, key func(s2.CellID) []byte, cus []s2.CellUnion, id uint32) (int, error) {
+	var n int
+
+	for _, cu := range cus {
+		for _, c := range cu {
+			v, err := get(tr, key(c))
+			if err != nil {
+				return n, err
+			}
+
+			nv := kv.RemoveValues(v, id)
+			if len(nv) == len(v) {
+				continue
+			}
+
+			if len(nv) == 0 {
+				err = tr.Delete(key(c), nil)
+				n++
+			} else {
+				err = tr.Put(key(c), nv, nil)
+			}
+
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// nextID returns the id of the next added feature and advances infos.NextID,
+// DBs indexed before NextID was kept start after the last stored feature.
+func nextID(tr *leveldb.Transaction, infos *insideout.IndexInfos) (uint32, error) {
+	id := infos.NextID
+
+	if id == 0 {
+		it := tr.NewIterator(util.BytesPrefix([]byte{insideout.FeaturePrefix()}), nil)
+		defer it.Release()
+
+		if it.Last() {
+			k := it.Key()
+			if len(k) != 5 {
+				return 0, fmt.Errorf("invalid feature key %x", k)
+			}
+
+			id = binary.BigEndian.Uint32(k[1:]) + 1
+		}
+
+		if err := it.Error(); err != nil {
+			return 0, err
+		}
+	}
+
+	infos.NextID = id + 1
+
+	return id, nil
+}
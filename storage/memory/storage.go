@@ -0,0 +1,595 @@
+// Package memory is a storage keeping the whole index in memory, for tests, embedders and ephemeral datasets.
+// The cells entries are kept in slices sorted by cell, it is the reference implementation of the storage engines.
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/internal/kv"
+)
+
+// Storage in memory storage, safe for concurrent use.
+type Storage struct {
+	sync.RWMutex
+	logger       log.Logger
+	indexOptions IndexOptions
+
+	infos    *insideout.IndexInfos
+	mapInfos *insideout.MapInfos
+	features map[uint32]*feature
+	extIDs   map[string]uint32
+	// inside and outside cells entries sorted by cell
+	inside, outside []kv.CellEntry
+}
+
+// feature a stored feature, as encoded by the other engines.
+type feature struct {
+	extID                      string
+	feature, properties, cells []byte
+}
+
+// IndexOptions tunes the indexing pipeline, zero values use the defaults.
+type IndexOptions = kv.IndexOptions
+
+// NewStorage returns an empty in memory storage.
+func NewStorage(logger log.Logger) *Storage {
+	return &Storage{
+		logger:   logger,
+		features: make(map[uint32]*feature),
+		extIDs:   make(map[string]uint32),
+	}
+}
+
+// SetIndexOptions sets the options used by Index, the cells entries are never spilled to disk.
+func (s *Storage) SetIndexOptions(opts IndexOptions) {
+	s.indexOptions = opts
+}
+
+// SetMapInfos sets the map infos returned by LoadMapInfos.
+func (s *Storage) SetMapInfos(mapInfos *insideout.MapInfos) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.mapInfos = mapInfos
+}
+
+// Index indexes the features read from features until io.EOF into an empty storage.
+func (s *Storage) Index(features insideout.FeatureIterator, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	logger := log.With(s.logger, "component", "indexer")
+	opts := s.indexOptions.WithDefaults()
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.infos != nil {
+		return errors.New("can't index into a non empty storage")
+	}
+
+	ps := kv.NewPostings("", math.MaxInt32)
+	defer ps.Close()
+
+	count, err := kv.IndexFeatures(logger, features, ps, icoverer, ocoverer, warningCellsCover, opts,
+		func(batch []*kv.EncodedFeature, firstID uint32) error {
+			for i, ef := range batch {
+				s.putEncodedFeature(logger, firstID+uint32(i), ef)
+			}
+
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+
+	infos := &insideout.IndexInfos{
+		Filename:       fileName,
+		IndexerVersion: version,
+		FeatureCount:   count,
+		NextID:         count,
+		IndexTime:      time.Now(),
+	}
+
+	err = ps.Merge(func(key, value []byte) error {
+		if key[0] == insideout.InsidePrefix() {
+			s.inside = append(s.inside, kv.NewCellEntry(key, value))
+		} else {
+			s.outside = append(s.outside, kv.NewCellEntry(key, value))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	infos.InsideCellCount = uint64(len(s.inside))
+	infos.OutsideCellCount = uint64(len(s.outside))
+	kv.SetIndexInfos(infos, icoverer, ocoverer, warningCellsCover)
+
+	s.infos = infos
+
+	level.Info(logger).Log("msg", "stored features", "feature_count", count)
+
+	return nil
+}
+
+// putEncodedFeature stores the feature, its properties and its cells, not the cells entries.
+func (s *Storage) putEncodedFeature(logger log.Logger, id uint32, ef *kv.EncodedFeature) {
+	if ef.ExtID != "" {
+		if eid, ok := s.extIDs[ef.ExtID]; ok {
+			level.Warn(logger).Log("msg", "duplicate feature id, only the last one keeps it", "feature_id", ef.ExtID)
+
+			// features are never modified, concurrent readers may hold them
+			old := *s.features[eid]
+			old.extID = ""
+			s.features[eid] = &old
+		}
+
+		s.extIDs[ef.ExtID] = id
+	}
+
+	s.features[id] = &feature{extID: ef.ExtID, feature: ef.Feature, properties: ef.Properties, cells: ef.Cells}
+}
+
+// AddSource records a file used to index or update the index.
+func (s *Storage) AddSource(src insideout.SourceInfos) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.infos == nil {
+		return errors.New("storage not indexed")
+	}
+
+	s.infos.Sources = append(s.infos.Sources, src)
+
+	return nil
+}
+
+// LoadFeature loads one feature.
+func (s *Storage) LoadFeature(id uint32) (*insideout.Feature, error) {
+	s.RLock()
+	f, ok := s.features[id]
+	s.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("error loading feature %w: %d", insideout.ErrFeatureNotFound, id)
+	}
+
+	fs := &insideout.FeatureStorage{}
+	if err := kv.Decode(f.feature, fs); err != nil {
+		return nil, fmt.Errorf("error loading feature %w", err)
+	}
+
+	polygons, err := fs.Polygons()
+	if err != nil {
+		return nil, err
+	}
+
+	return &insideout.Feature{
+		Polygons:   polygons,
+		Properties: fs.Properties,
+		ExternalID: f.extID,
+	}, nil
+}
+
+// LoadFeatureProperties loads the properties of one feature without decoding its polygons.
+func (s *Storage) LoadFeatureProperties(id uint32) (map[string]interface{}, error) {
+	s.RLock()
+	f, ok := s.features[id]
+	s.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("error loading feature properties %w: %d", insideout.ErrFeatureNotFound, id)
+	}
+
+	var props map[string]interface{}
+	if err := kv.Decode(f.properties, &props); err != nil {
+		return nil, fmt.Errorf("error loading feature properties %w", err)
+	}
+
+	return props, nil
+}
+
+// LoadExternalID returns the external id of the feature id, empty when it has none.
+func (s *Storage) LoadExternalID(id uint32) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if f, ok := s.features[id]; ok {
+		return f.extID, nil
+	}
+
+	return "", nil
+}
+
+// LookupExternalID returns the feature id for the external id.
+func (s *Storage) LookupExternalID(extID string) (uint32, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	id, ok := s.extIDs[extID]
+	if !ok {
+		return 0, insideout.ErrFeatureNotFound
+	}
+
+	return id, nil
+}
+
+// ids returns the stored feature ids in order.
+func (s *Storage) ids() []uint32 {
+	s.RLock()
+	defer s.RUnlock()
+
+	ids := make([]uint32, 0, len(s.features))
+	for id := range s.features {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+// LoadAllFeatures loads FeatureStorage into idx
+// only useful to fill in memory shapeindex.
+func (s *Storage) LoadAllFeatures(add func(*insideout.FeatureStorage, uint32) error) error {
+	for _, id := range s.ids() {
+		s.RLock()
+		f, ok := s.features[id]
+		s.RUnlock()
+
+		if !ok {
+			continue
+		}
+
+		fs := &insideout.FeatureStorage{}
+		if err := kv.Decode(f.feature, fs); err != nil {
+			return err
+		}
+
+		if err := add(fs, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadFeaturesCells loads CellsStorage into idx
+// only useful to fill in memory tree indexes.
+func (s *Storage) LoadFeaturesCells(add func([]s2.CellUnion, []s2.CellUnion, uint32)) error {
+	for _, id := range s.ids() {
+		cs, err := s.LoadCellStorage(id)
+		if errors.Is(err, insideout.ErrFeatureNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		add(cs.CellsIn, cs.CellsOut, id)
+	}
+
+	return nil
+}
+
+// LoadCellStorage loads cell storage.
+func (s *Storage) LoadCellStorage(id uint32) (*insideout.CellsStorage, error) {
+	s.RLock()
+	f, ok := s.features[id]
+	s.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("cells not found for feature id %d: %w", id, insideout.ErrFeatureNotFound)
+	}
+
+	cs := &insideout.CellsStorage{}
+
+	return cs, kv.Decode(f.cells, cs)
+}
+
+// LoadCellsEntries calls fn with the inside then the outside cells entries in key order,
+// values are the feature ids and polygon indexes, only valid during the call.
+func (s *Storage) LoadCellsEntries(fn func(key, value []byte) error) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, e := range s.inside {
+		if err := fn(insideout.InsideKey(e.C), e.V); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range s.outside {
+		if err := fn(insideout.OutsideKey(e.C), e.V); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadMapInfos returns the map infos if any.
+func (s *Storage) LoadMapInfos() (*insideout.MapInfos, bool, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.mapInfos, s.mapInfos != nil, nil
+}
+
+// LoadIndexInfos returns a copy of the index infos.
+func (s *Storage) LoadIndexInfos() (*insideout.IndexInfos, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if s.infos == nil {
+		return nil, errors.New("storage not indexed")
+	}
+
+	infos := *s.infos
+	infos.Sources = append([]insideout.SourceInfos(nil), s.infos.Sources...)
+
+	return &infos, nil
+}
+
+func (s *Storage) StabDB(lat, lng float64, stopOnInsideFound bool) (insideout.IndexResponse, error) {
+	resps, err := s.StabBatchDB([]s2.LatLng{s2.LatLngFromDegrees(lat, lng)}, stopOnInsideFound)
+	if err != nil {
+		return insideout.IndexResponse{}, err
+	}
+
+	return resps[0], nil
+}
+
+// StabBatchDB returns for each lat lng polygon's ids containing it and polygon's ids that may contain it,
+// responses are in the same order as lls.
+func (s *Storage) StabBatchDB(lls []s2.LatLng, stopOnInsideFound bool) ([]insideout.IndexResponse, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if s.infos == nil {
+		return nil, errors.New("storage not indexed")
+	}
+
+	resps := make([]insideout.IndexResponse, len(lls))
+
+	for i, ll := range lls {
+		c := s2.CellIDFromLatLng(ll)
+		cLookup := c.Parent(s.infos.MinCoverLevel)
+
+		resps[i] = kv.StabEntries(c, rangeEntries(s.inside, cLookup), rangeEntries(s.outside, cLookup), stopOnInsideFound)
+	}
+
+	return resps, nil
+}
+
+// IntersectsDB returns polygon's ids whose inside cover intersects the cell union
+// and polygon's ids whose outside cover only intersects it.
+func (s *Storage) IntersectsDB(cu s2.CellUnion) (insideout.IndexResponse, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if s.infos == nil {
+		return insideout.IndexResponse{}, errors.New("storage not indexed")
+	}
+
+	mi := make(map[insideout.FeatureIndexResponse]struct{})
+	mo := make(map[insideout.FeatureIndexResponse]struct{})
+
+	for _, c := range cu {
+		intersectingCells(s.inside, c, s.infos.MinCoverLevel, mi)
+		intersectingCells(s.outside, c, s.infos.MinCoverLevel, mo)
+	}
+
+	return kv.IntersectsResponse(mi, mo), nil
+}
+
+// intersectingCells adds to m the polygons stored under any cell intersecting c:
+// parent cells down to the min cover level and children cells.
+func intersectingCells(entries []kv.CellEntry, c s2.CellID, minCoverLevel int,
+	m map[insideout.FeatureIndexResponse]struct{}) {
+	for l := minCoverLevel; l < c.Level(); l++ {
+		if i, ok := search(entries, c.Parent(l)); ok {
+			kv.AddValues(entries[i].V, m)
+		}
+	}
+
+	for _, e := range rangeEntries(entries, c) {
+		kv.AddValues(e.V, m)
+	}
+}
+
+// search returns the position of c in entries and whether it is found.
+func search(entries []kv.CellEntry, c s2.CellID) (int, bool) {
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].C >= c })
+
+	return i, i < len(entries) && entries[i].C == c
+}
+
+// rangeEntries returns the entries of c and its children cells.
+func rangeEntries(entries []kv.CellEntry, c s2.CellID) []kv.CellEntry {
+	start, _ := search(entries, c.RangeMin())
+
+	end := start
+	for end < len(entries) && entries[end].C <= c.RangeMax() {
+		end++
+	}
+
+	return entries[start:end]
+}
+
+// PutFeature adds or replaces the feature extID and returns its internal id,
+// a replaced feature keeps its internal id, a new feature gets the next one.
+// Covers must not use a lower level than the index MinCoverLevel.
+func (s *Storage) PutFeature(extID string, f *geojson.Feature, icoverer *s2.RegionCoverer,
+	ocoverer *s2.RegionCoverer, warningCellsCover int) (uint32, error) {
+	if extID == "" {
+		return 0, errors.New("missing external id")
+	}
+
+	logger := log.With(s.logger, "component", "updater")
+
+	cui, cuo, err := kv.CoverFeature(logger, f, icoverer, ocoverer)
+	if err != nil {
+		return 0, fmt.Errorf("can't cover feature %s: %w", extID, err)
+	}
+
+	ef, err := kv.EncodeFeature(logger, f, cui, cuo, warningCellsCover)
+	if err != nil {
+		return 0, fmt.Errorf("can't put feature %s: %w", extID, err)
+	}
+
+	ef.ExtID = extID
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.infos == nil {
+		return 0, errors.New("storage not indexed")
+	}
+
+	if icoverer.MinLevel < s.infos.MinCoverLevel || ocoverer.MinLevel < s.infos.MinCoverLevel {
+		return 0, fmt.Errorf("can't put feature %s: cover min level lower than the index min cover level %d",
+			extID, s.infos.MinCoverLevel)
+	}
+
+	id, ok := s.extIDs[extID]
+	if ok {
+		if err := s.removeFeature(id); err != nil {
+			return 0, fmt.Errorf("can't put feature %s: %w", extID, err)
+		}
+
+		level.Debug(logger).Log("msg", "replacing feature", "feature_id", extID, "id", id)
+	} else {
+		// ids are never reused, clients may have cached deleted ones
+		id = s.infos.NextID
+		s.infos.NextID++
+		s.infos.FeatureCount++
+
+		level.Debug(logger).Log("msg", "adding feature", "feature_id", extID, "id", id)
+	}
+
+	s.putEncodedFeature(logger, id, ef)
+
+	var n int
+	s.inside, n = addCells(s.inside, ef.CellsIn, id)
+	s.infos.InsideCellCount += uint64(n)
+
+	s.outside, n = addCells(s.outside, ef.CellsOut, id)
+	s.infos.OutsideCellCount += uint64(n)
+
+	s.infos.UpdateTime = time.Now()
+
+	return id, nil
+}
+
+// DeleteFeature deletes the feature extID and its cells entries.
+func (s *Storage) DeleteFeature(extID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.infos == nil {
+		return errors.New("storage not indexed")
+	}
+
+	id, ok := s.extIDs[extID]
+	if !ok {
+		return fmt.Errorf("can't delete feature %s: %w", extID, insideout.ErrFeatureNotFound)
+	}
+
+	if err := s.removeFeature(id); err != nil {
+		return fmt.Errorf("can't delete feature %s: %w", extID, err)
+	}
+
+	delete(s.features, id)
+	delete(s.extIDs, extID)
+
+	if s.infos.FeatureCount > 0 {
+		s.infos.FeatureCount--
+	}
+
+	s.infos.UpdateTime = time.Now()
+
+	return nil
+}
+
+// removeFeature removes the feature id from its cells entries.
+func (s *Storage) removeFeature(id uint32) error {
+	cs := &insideout.CellsStorage{}
+	if err := kv.Decode(s.features[id].cells, cs); err != nil {
+		return fmt.Errorf("can't decode CellsStorage: %w", err)
+	}
+
+	var n int
+	s.inside, n = removeCells(s.inside, cs.CellsIn, id)
+	s.infos.InsideCellCount = kv.SubCount(s.infos.InsideCellCount, n)
+
+	s.outside, n = removeCells(s.outside, cs.CellsOut, id)
+	s.infos.OutsideCellCount = kv.SubCount(s.infos.OutsideCellCount, n)
+
+	return nil
+}
+
+// addCells adds the feature id and the polygon index to the entries of every cell of cus,
+// it returns the entries and the number of new entries.
+func addCells(entries []kv.CellEntry, cus []s2.CellUnion, id uint32) ([]kv.CellEntry, int) {
+	var n int
+
+	for fi, cu := range cus {
+		for _, c := range cu {
+			v := kv.EncodeValue(id, uint16(fi))
+
+			i, ok := search(entries, c)
+			if ok {
+				// newest first as in the other engines
+				entries[i].V = append(v, entries[i].V...) //nolint: makezero
+
+				continue
+			}
+
+			entries = append(entries, kv.CellEntry{})
+			copy(entries[i+1:], entries[i:])
+			entries[i] = kv.CellEntry{C: c, V: v}
+			n++
+		}
+	}
+
+	return entries, n
+}
+
+// removeCells removes the feature id from the entries of every cell of cus, emptied entries are deleted,
+// it returns the entries and the number of deleted entries.
+func removeCells(entries []kv.CellEntry, cus []s2.CellUnion, id uint32) ([]kv.CellEntry, int) {
+	var n int
+
+	for _, cu := range cus {
+		for _, c := range cu {
+			i, ok := search(entries, c)
+			if !ok {
+				continue
+			}
+
+			entries[i].V = kv.RemoveValues(entries[i].V, id)
+			if len(entries[i].V) > 0 {
+				continue
+			}
+
+			entries = append(entries[:i], entries[i+1:]...)
+			n++
+		}
+	}
+
+	return entries, n
+}
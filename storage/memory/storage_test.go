@@ -0,0 +1,52 @@
+package memory_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/memory"
+	"github.com/akhenakh/insideout/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.TestStore(t, func(t *testing.T) (insideout.Store, func()) {
+		return memory.NewStorage(log.NewNopLogger()), func() {}
+	})
+}
+
+func TestStorage_IndexSkipped(t *testing.T) {
+	fc := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "id": "point", "geometry": {"type": "Point", "coordinates": [1, 47]}, "properties": {}},
+		{"type": "Feature", "id": "square", "properties": {}, "geometry": {"type": "Polygon",
+			"coordinates": [[[1, 47], [1.1, 47], [1.1, 47.1], [1, 47.1], [1, 47]]]}}
+	]}`
+
+	var skipped []int
+
+	s := memory.NewStorage(log.NewNopLogger())
+	s.SetIndexOptions(memory.IndexOptions{
+		Workers: 2,
+		Skipped: func(seq int, f *geojson.Feature, err error) {
+			require.Equal(t, "point", f.ID)
+			require.Error(t, err)
+
+			skipped = append(skipped, seq)
+		},
+	})
+
+	coverer := &s2.RegionCoverer{MinLevel: 1, MaxLevel: 16, MaxCells: 8}
+
+	err := s.Index(insideout.NewFeatureDecoder(strings.NewReader(fc)), coverer, coverer, 0, "test", "test")
+	require.NoError(t, err)
+	require.Equal(t, []int{0}, skipped)
+
+	infos, err := s.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), infos.FeatureCount)
+}
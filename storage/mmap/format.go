@@ -0,0 +1,102 @@
+// Package mmap is a read only storage using an immutable single file, memory mapped,
+// written from an existing index by Write.
+//
+// The file starts with a header: the magic, the format version and the offset and length of every section,
+// all integers are little endian.
+//
+//	features       per feature: the external id, the CBOR properties, the CBOR cells storage
+//	               and the s2 encoded polygons, each prefixed by its uint32 length
+//	feature table  uint64 offset of every feature by id, 0 for deleted ids
+//	values         cells entries values: feature id uint32 + polygon index uint16, big endian as in the other engines
+//	inside         inside cells entries sorted by cell: cell uint64, values offset uint64, values count uint32
+//	outside        outside cells entries, same as inside
+//	extid strings  external ids
+//	extids         external ids sorted: string offset uint64, string length uint32, feature id uint32
+//	infos          CBOR IndexInfos
+//	map infos      CBOR MapInfos, empty if none
+package mmap
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// FormatVersion version of the file format.
+const FormatVersion = 1
+
+// ErrInvalidFile is returned when opening a file which is not a valid index.
+var ErrInvalidFile = errors.New("invalid index file")
+
+var magic = [8]byte{'i', 'n', 's', 'i', 'd', 'e', 'i', 'x'}
+
+const (
+	secFeatures = iota
+	secFeatureTable
+	secValues
+	secInside
+	secOutside
+	secExtIDStrings
+	secExtIDs
+	secInfos
+	secMapInfos
+	sectionCount
+)
+
+const (
+	headerSize    = 8 + 4 + 4 + sectionCount*16
+	entrySize     = 8 + 8 + 4
+	extIDSize     = 8 + 4 + 4
+	tableSize     = 8
+	valueSize     = 4 + 2
+	lengthSize    = 4
+	maxSectionLen = 1 << 62
+)
+
+type section struct {
+	off, len uint64
+}
+
+type header struct {
+	version  uint32
+	sections [sectionCount]section
+}
+
+func (h *header) encode() []byte {
+	b := make([]byte, headerSize)
+	copy(b, magic[:])
+	binary.LittleEndian.PutUint32(b[8:], h.version)
+
+	for i, sec := range h.sections {
+		binary.LittleEndian.PutUint64(b[16+i*16:], sec.off)
+		binary.LittleEndian.PutUint64(b[16+i*16+8:], sec.len)
+	}
+
+	return b
+}
+
+// decodeHeader reads the header of data and checks every section is inside data.
+func decodeHeader(data []byte) (*header, error) {
+	if len(data) < headerSize || string(data[:8]) != string(magic[:]) {
+		return nil, ErrInvalidFile
+	}
+
+	h := &header{version: binary.LittleEndian.Uint32(data[8:])}
+	if h.version != FormatVersion {
+		return nil, errors.New("unsupported index file version")
+	}
+
+	for i := range h.sections {
+		sec := section{
+			off: binary.LittleEndian.Uint64(data[16+i*16:]),
+			len: binary.LittleEndian.Uint64(data[16+i*16+8:]),
+		}
+
+		if sec.len > maxSectionLen || sec.off > uint64(len(data)) || sec.len > uint64(len(data))-sec.off {
+			return nil, ErrInvalidFile
+		}
+
+		h.sections[i] = sec
+	}
+
+	return h, nil
+}
@@ -0,0 +1,582 @@
+package mmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	log "github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/internal/kv"
+)
+
+// ErrReadOnly is returned by Index, files are written by Write.
+var ErrReadOnly = errors.New("read only storage")
+
+// Storage a read only storage over a memory mapped index file, safe for concurrent use.
+type Storage struct {
+	logger   log.Logger
+	data     []byte
+	h        *header
+	infos    *insideout.IndexInfos
+	mapInfos *insideout.MapInfos
+}
+
+// NewROStorage maps the index file at path.
+func NewROStorage(path string, logger log.Logger) (*Storage, func() error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open DB for reading at %s: %w", path, err)
+	}
+
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if fi.Size() < headerSize || fi.Size() != int64(int(fi.Size())) {
+		return nil, nil, fmt.Errorf("failed to open DB for reading at %s: %w", path, ErrInvalidFile)
+	}
+
+	data, unmap, err := mapFile(f, int(fi.Size()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to map DB at %s: %w", path, err)
+	}
+
+	s, err := newStorage(data, logger)
+	if err != nil {
+		unmap()
+
+		return nil, nil, fmt.Errorf("failed to open DB for reading at %s: %w", path, err)
+	}
+
+	return s, unmap, nil
+}
+
+func newStorage(data []byte, logger log.Logger) (*Storage, error) {
+	h, err := decodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Storage{logger: logger, data: data, h: h}
+
+	for _, sec := range []struct {
+		i    int
+		size uint64
+	}{
+		{secFeatureTable, tableSize},
+		{secInside, entrySize},
+		{secOutside, entrySize},
+		{secExtIDs, extIDSize},
+	} {
+		if h.sections[sec.i].len%sec.size != 0 {
+			return nil, ErrInvalidFile
+		}
+	}
+
+	s.infos = &insideout.IndexInfos{}
+	if err := kv.Decode(s.section(secInfos), s.infos); err != nil {
+		return nil, fmt.Errorf("failed decoding IndexInfos: %w", err)
+	}
+
+	if err := s.infos.CheckSchema(); err != nil {
+		return nil, err
+	}
+
+	if mb := s.section(secMapInfos); len(mb) > 0 {
+		s.mapInfos = &insideout.MapInfos{}
+		if err := kv.Decode(mb, s.mapInfos); err != nil {
+			return nil, fmt.Errorf("failed decoding MapInfos: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// section returns the bytes of the section i.
+func (s *Storage) section(i int) []byte {
+	sec := s.h.sections[i]
+
+	return s.data[sec.off : sec.off+sec.len]
+}
+
+// Index returns ErrReadOnly, use Write to create a file from another storage.
+func (s *Storage) Index(features insideout.FeatureIterator, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	return ErrReadOnly
+}
+
+// featureRecord returns the fields of the feature id: external id, properties, cells and polygons.
+func (s *Storage) featureRecord(id uint32) (*record, error) {
+	table := s.section(secFeatureTable)
+	if uint64(id) >= uint64(len(table)/tableSize) {
+		return nil, insideout.ErrFeatureNotFound
+	}
+
+	off := binary.LittleEndian.Uint64(table[int(id)*tableSize:])
+	if off == 0 {
+		return nil, insideout.ErrFeatureNotFound
+	}
+
+	features := s.h.sections[secFeatures]
+	if off < features.off || off >= features.off+features.len {
+		return nil, ErrInvalidFile
+	}
+
+	return &record{b: s.data[off : features.off+features.len]}, nil
+}
+
+// record reads the length prefixed fields of a feature.
+type record struct {
+	b []byte
+}
+
+func (r *record) next() ([]byte, error) {
+	if len(r.b) < lengthSize {
+		return nil, ErrInvalidFile
+	}
+
+	n := binary.LittleEndian.Uint32(r.b)
+	if uint64(n) > uint64(len(r.b)-lengthSize) {
+		return nil, ErrInvalidFile
+	}
+
+	v := r.b[lengthSize : lengthSize+int(n)]
+	r.b = r.b[lengthSize+int(n):]
+
+	return v, nil
+}
+
+// fields returns the external id, the properties and the cells of the record.
+func (r *record) fields() (extID, props, cells []byte, err error) {
+	if extID, err = r.next(); err != nil {
+		return
+	}
+
+	if props, err = r.next(); err != nil {
+		return
+	}
+
+	cells, err = r.next()
+
+	return
+}
+
+// polygons appends the encoded polygons of the record to pbs.
+func (r *record) polygons(pbs [][]byte) ([][]byte, error) {
+	if len(r.b) < lengthSize {
+		return nil, ErrInvalidFile
+	}
+
+	n := binary.LittleEndian.Uint32(r.b)
+	r.b = r.b[lengthSize:]
+
+	for i := uint32(0); i < n; i++ {
+		pb, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+
+		pbs = append(pbs, pb)
+	}
+
+	return pbs, nil
+}
+
+// LoadFeature loads one feature.
+func (s *Storage) LoadFeature(id uint32) (*insideout.Feature, error) {
+	r, err := s.featureRecord(id)
+	if err != nil {
+		return nil, fmt.Errorf("error loading feature %w: %d", err, id)
+	}
+
+	extID, props, _, err := r.fields()
+	if err != nil {
+		return nil, fmt.Errorf("error loading feature %w: %d", err, id)
+	}
+
+	pbs, err := r.polygons(nil)
+	if err != nil {
+		return nil, fmt.Errorf("error loading feature %w: %d", err, id)
+	}
+
+	f := &insideout.Feature{
+		Polygons:   make([]*s2.Polygon, len(pbs)),
+		ExternalID: string(extID),
+	}
+
+	if err := kv.Decode(props, &f.Properties); err != nil {
+		return nil, fmt.Errorf("error loading feature properties %w", err)
+	}
+
+	for i, pb := range pbs {
+		p := &s2.Polygon{}
+		if err := p.Decode(bytes.NewReader(pb)); err != nil {
+			return nil, fmt.Errorf("can't decode polygon %d: %w", i, err)
+		}
+
+		f.Polygons[i] = p
+	}
+
+	return f, nil
+}
+
+// LoadFeatureProperties loads the properties of one feature without decoding its polygons.
+func (s *Storage) LoadFeatureProperties(id uint32) (map[string]interface{}, error) {
+	r, err := s.featureRecord(id)
+	if err != nil {
+		return nil, fmt.Errorf("error loading feature properties %w: %d", err, id)
+	}
+
+	_, props, _, err := r.fields()
+	if err != nil {
+		return nil, fmt.Errorf("error loading feature properties %w: %d", err, id)
+	}
+
+	var m map[string]interface{}
+	if err := kv.Decode(props, &m); err != nil {
+		return nil, fmt.Errorf("error loading feature properties %w", err)
+	}
+
+	return m, nil
+}
+
+// LoadExternalID returns the external id of the feature id, empty when it has none.
+func (s *Storage) LoadExternalID(id uint32) (string, error) {
+	r, err := s.featureRecord(id)
+	if errors.Is(err, insideout.ErrFeatureNotFound) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	extID, _, _, err := r.fields()
+
+	return string(extID), err
+}
+
+// LookupExternalID returns the feature id for the external id, by a binary search of the sorted external ids.
+func (s *Storage) LookupExternalID(extID string) (uint32, error) {
+	extIDs := s.section(secExtIDs)
+	strs := s.section(secExtIDStrings)
+	n := len(extIDs) / extIDSize
+
+	var err error
+
+	str := func(i int) string {
+		rec := extIDs[i*extIDSize:]
+		off := binary.LittleEndian.Uint64(rec)
+		l := uint64(binary.LittleEndian.Uint32(rec[8:]))
+
+		if off > uint64(len(strs)) || l > uint64(len(strs))-off {
+			err = ErrInvalidFile
+
+			return ""
+		}
+
+		return string(strs[off : off+l])
+	}
+
+	i := sort.Search(n, func(i int) bool { return str(i) >= extID })
+	if err != nil {
+		return 0, err
+	}
+
+	if i == n || str(i) != extID {
+		return 0, insideout.ErrFeatureNotFound
+	}
+
+	return binary.LittleEndian.Uint32(extIDs[i*extIDSize+12:]), nil
+}
+
+// LoadAllFeatures loads FeatureStorage into idx
+// only useful to fill in memory shapeindex,
+// the FeatureStorage and its polygons bytes are only valid during the call.
+func (s *Storage) LoadAllFeatures(add func(*insideout.FeatureStorage, uint32) error) error {
+	fs := &insideout.FeatureStorage{}
+
+	return s.forEachFeature(func(id uint32, r *record) error {
+		_, props, _, err := r.fields()
+		if err != nil {
+			return err
+		}
+
+		fs.Properties = nil
+		if err := kv.Decode(props, &fs.Properties); err != nil {
+			return err
+		}
+
+		if fs.PolygonsBytes, err = r.polygons(fs.PolygonsBytes[:0]); err != nil {
+			return err
+		}
+
+		return add(fs, id)
+	})
+}
+
+// LoadFeaturesCells loads CellsStorage into idx
+// only useful to fill in memory tree indexes.
+func (s *Storage) LoadFeaturesCells(add func([]s2.CellUnion, []s2.CellUnion, uint32)) error {
+	return s.forEachFeature(func(id uint32, r *record) error {
+		_, _, cells, err := r.fields()
+		if err != nil {
+			return err
+		}
+
+		cs := &insideout.CellsStorage{}
+		if err := kv.Decode(cells, cs); err != nil {
+			return err
+		}
+
+		add(cs.CellsIn, cs.CellsOut, id)
+
+		return nil
+	})
+}
+
+// forEachFeature calls fn for every feature in id order.
+func (s *Storage) forEachFeature(fn func(id uint32, r *record) error) error {
+	n := len(s.section(secFeatureTable)) / tableSize
+
+	for id := 0; id < n; id++ {
+		r, err := s.featureRecord(uint32(id))
+		if errors.Is(err, insideout.ErrFeatureNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := fn(uint32(id), r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadCellStorage loads cell storage.
+func (s *Storage) LoadCellStorage(id uint32) (*insideout.CellsStorage, error) {
+	r, err := s.featureRecord(id)
+	if err != nil {
+		return nil, fmt.Errorf("cells not found for feature id %d: %w", id, err)
+	}
+
+	_, _, cells, err := r.fields()
+	if err != nil {
+		return nil, err
+	}
+
+	cs := &insideout.CellsStorage{}
+
+	return cs, kv.Decode(cells, cs)
+}
+
+// LoadIndexInfos returns a copy of the index infos.
+func (s *Storage) LoadIndexInfos() (*insideout.IndexInfos, error) {
+	infos := *s.infos
+	infos.Sources = append([]insideout.SourceInfos(nil), s.infos.Sources...)
+
+	return &infos, nil
+}
+
+// LoadMapInfos returns the map infos if any.
+func (s *Storage) LoadMapInfos() (*insideout.MapInfos, bool, error) {
+	if s.mapInfos == nil {
+		return nil, false, nil
+	}
+
+	mapInfos := *s.mapInfos
+
+	return &mapInfos, true, nil
+}
+
+func (s *Storage) StabDB(lat, lng float64, stopOnInsideFound bool) (insideout.IndexResponse, error) {
+	var resp insideout.IndexResponse
+
+	err := s.stab(s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng)), stopOnInsideFound, &resp)
+
+	return resp, err
+}
+
+// StabBatchDB returns for each lat lng polygon's ids containing it and polygon's ids that may contain it,
+// responses are in the same order as lls.
+func (s *Storage) StabBatchDB(lls []s2.LatLng, stopOnInsideFound bool) ([]insideout.IndexResponse, error) {
+	resps := make([]insideout.IndexResponse, len(lls))
+
+	for i, ll := range lls {
+		if err := s.stab(s2.CellIDFromLatLng(ll), stopOnInsideFound, &resps[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return resps, nil
+}
+
+// stab adds to resp the polygons stored under cells containing c,
+// only the response slices are allocated.
+func (s *Storage) stab(c s2.CellID, stopOnInsideFound bool, resp *insideout.IndexResponse) error {
+	cLookup := c.Parent(s.infos.MinCoverLevel)
+
+	entries := s.section(secInside)
+	for i := s.search(entries, cLookup.RangeMin()); i < len(entries)/entrySize; i++ {
+		e := s2.CellID(binary.LittleEndian.Uint64(entries[i*entrySize:]))
+		if e > cLookup.RangeMax() {
+			break
+		}
+
+		if !e.Contains(c) {
+			continue
+		}
+
+		v, err := s.values(entries, i)
+		if err != nil {
+			return err
+		}
+
+		for j := 0; j < len(v); j += valueSize {
+			res := decodeValue(v[j:])
+			if stopOnInsideFound {
+				resp.IDsInside = append(resp.IDsInside, res)
+
+				return nil
+			}
+
+			if !contains(resp.IDsInside, res) {
+				resp.IDsInside = append(resp.IDsInside, res)
+			}
+		}
+	}
+
+	entries = s.section(secOutside)
+	for i := s.search(entries, cLookup.RangeMin()); i < len(entries)/entrySize; i++ {
+		e := s2.CellID(binary.LittleEndian.Uint64(entries[i*entrySize:]))
+		if e > cLookup.RangeMax() {
+			break
+		}
+
+		if !e.Contains(c) {
+			continue
+		}
+
+		v, err := s.values(entries, i)
+		if err != nil {
+			return err
+		}
+
+		for j := 0; j < len(v); j += valueSize {
+			res := decodeValue(v[j:])
+
+			// remove any answer matching inside
+			if !contains(resp.IDsInside, res) && !contains(resp.IDsMayBeInside, res) {
+				resp.IDsMayBeInside = append(resp.IDsMayBeInside, res)
+			}
+		}
+	}
+
+	return nil
+}
+
+// IntersectsDB returns polygon's ids whose inside cover intersects the cell union
+// and polygon's ids whose outside cover only intersects it.
+func (s *Storage) IntersectsDB(cu s2.CellUnion) (insideout.IndexResponse, error) {
+	mi := make(map[insideout.FeatureIndexResponse]struct{})
+	mo := make(map[insideout.FeatureIndexResponse]struct{})
+
+	for _, c := range cu {
+		if err := s.intersectingCells(s.section(secInside), c, mi); err != nil {
+			return insideout.IndexResponse{}, err
+		}
+
+		if err := s.intersectingCells(s.section(secOutside), c, mo); err != nil {
+			return insideout.IndexResponse{}, err
+		}
+	}
+
+	return kv.IntersectsResponse(mi, mo), nil
+}
+
+// intersectingCells adds to m the polygons stored under any cell intersecting c:
+// parent cells down to the min cover level and children cells.
+func (s *Storage) intersectingCells(entries []byte, c s2.CellID, m map[insideout.FeatureIndexResponse]struct{}) error {
+	n := len(entries) / entrySize
+
+	for l := s.infos.MinCoverLevel; l < c.Level(); l++ {
+		p := c.Parent(l)
+
+		i := s.search(entries, p)
+		if i == n || s2.CellID(binary.LittleEndian.Uint64(entries[i*entrySize:])) != p {
+			continue
+		}
+
+		v, err := s.values(entries, i)
+		if err != nil {
+			return err
+		}
+
+		kv.AddValues(v, m)
+	}
+
+	for i := s.search(entries, c.RangeMin()); i < n; i++ {
+		if s2.CellID(binary.LittleEndian.Uint64(entries[i*entrySize:])) > c.RangeMax() {
+			break
+		}
+
+		v, err := s.values(entries, i)
+		if err != nil {
+			return err
+		}
+
+		kv.AddValues(v, m)
+	}
+
+	return nil
+}
+
+// search returns the position of the first entry not lower than c.
+func (s *Storage) search(entries []byte, c s2.CellID) int {
+	return sort.Search(len(entries)/entrySize, func(i int) bool {
+		return s2.CellID(binary.LittleEndian.Uint64(entries[i*entrySize:])) >= c
+	})
+}
+
+// values returns the values of the entry i.
+func (s *Storage) values(entries []byte, i int) ([]byte, error) {
+	values := s.section(secValues)
+	off := binary.LittleEndian.Uint64(entries[i*entrySize+8:])
+	l := uint64(binary.LittleEndian.Uint32(entries[i*entrySize+16:])) * valueSize
+
+	if off > uint64(len(values)) || l > uint64(len(values))-off {
+		return nil, ErrInvalidFile
+	}
+
+	return values[off : off+l], nil
+}
+
+func decodeValue(v []byte) insideout.FeatureIndexResponse {
+	return insideout.FeatureIndexResponse{
+		ID:  binary.BigEndian.Uint32(v),
+		Pos: binary.BigEndian.Uint16(v[4:]),
+	}
+}
+
+func contains(resps []insideout.FeatureIndexResponse, res insideout.FeatureIndexResponse) bool {
+	for _, r := range resps {
+		if r == res {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,179 @@
+package mmap_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/bbolt"
+	"github.com/akhenakh/insideout/storage/memory"
+	"github.com/akhenakh/insideout/storage/mmap"
+	"github.com/akhenakh/insideout/storage/storagetest"
+)
+
+// converted indexes into a memory storage then writes and maps the file.
+type converted struct {
+	*mmap.Storage
+	path  string
+	close func() error
+}
+
+func (c *converted) Index(features insideout.FeatureIterator, icoverer *s2.RegionCoverer, ocoverer *s2.RegionCoverer,
+	warningCellsCover int, fileName, version string) error {
+	ms := memory.NewStorage(log.NewNopLogger())
+
+	if err := ms.Index(features, icoverer, ocoverer, warningCellsCover, fileName, version); err != nil {
+		return err
+	}
+
+	if err := mmap.Write(c.path, ms, mmap.WriteOptions{}); err != nil {
+		return err
+	}
+
+	var err error
+
+	c.Storage, c.close, err = mmap.NewROStorage(c.path, log.NewNopLogger())
+
+	return err
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.TestStore(t, func(t *testing.T) (insideout.Store, func()) {
+		dir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+		require.NoError(t, err)
+
+		c := &converted{path: filepath.Join(dir, "inside.idx")}
+
+		return c, func() {
+			if c.close != nil {
+				c.close()
+			}
+
+			os.RemoveAll(dir)
+		}
+	})
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	bs, bclose, err := bbolt.NewStorage(filepath.Join(dir, "inside.db"), log.NewNopLogger())
+	require.NoError(t, err)
+
+	defer bclose()
+
+	file, err := os.Open("../../index/testdata/poly.geojson")
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	err = bs.Index(insideout.NewFeatureDecoder(file), &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24},
+		&s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}, 100, "poly.geojson", "unittest")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "inside.idx")
+	require.NoError(t, mmap.Write(path, bs, mmap.WriteOptions{SnapLevel: 30}))
+
+	s, sclose, err := mmap.NewROStorage(path, log.NewNopLogger())
+	require.NoError(t, err)
+
+	defer sclose()
+
+	lls := []s2.LatLng{
+		s2.LatLngFromDegrees(47.39650628189986, -2.9876390969486524),
+		s2.LatLngFromDegrees(47.39444367083928, -2.992874768945723),
+		s2.LatLngFromDegrees(48.02, 2.02),
+		s2.LatLngFromDegrees(48.05, 2.05),
+		s2.LatLngFromDegrees(-16.5, 179.5),
+		s2.LatLngFromDegrees(10, 10),
+	}
+
+	want, err := bs.StabBatchDB(lls, false)
+	require.NoError(t, err)
+
+	got, err := s.StabBatchDB(lls, false)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// snapped vertices use the compressed encoding
+	var bsize, ssize int
+
+	sizes := func(size *int) func(fs *insideout.FeatureStorage, id uint32) error {
+		return func(fs *insideout.FeatureStorage, id uint32) error {
+			for _, pb := range fs.PolygonsBytes {
+				*size += len(pb)
+			}
+
+			return nil
+		}
+	}
+
+	require.NoError(t, bs.LoadAllFeatures(sizes(&bsize)))
+	require.NoError(t, s.LoadAllFeatures(sizes(&ssize)))
+	require.Less(t, ssize, bsize)
+
+	f, err := s.LoadFeature(0)
+	require.NoError(t, err)
+	require.Len(t, f.Polygons, 3)
+	require.True(t, f.Polygons[1].ContainsPoint(s2.PointFromLatLng(lls[0])))
+
+	// not found points don't allocate
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = s.StabDB(10, 10, false)
+	})
+	require.Zero(t, allocs)
+
+	err = s.Index(nil, nil, nil, 0, "", "")
+	require.True(t, errors.Is(err, mmap.ErrReadOnly))
+}
+
+func TestNewROStorage_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "insideout-test-")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	ms := memory.NewStorage(log.NewNopLogger())
+	err = ms.Index(insideout.NewFeatureCollectionIterator(&geojson.FeatureCollection{}), &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24},
+		&s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}, 100, "", "")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "inside.idx")
+	require.NoError(t, mmap.Write(path, ms, mmap.WriteOptions{}))
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not an index", []byte("{\"type\": \"FeatureCollection\", \"features\": []} and more bytes to fill the header..." +
+			"................................................................................................")},
+		{"truncated", b[:len(b)-1]},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(dir, "invalid.idx")
+			require.NoError(t, ioutil.WriteFile(p, tt.data, 0600))
+
+			_, _, err := mmap.NewROStorage(p, log.NewNopLogger())
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package mmap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/internal/kv"
+)
+
+// Source a storage to convert.
+type Source interface {
+	insideout.Store
+	// LoadCellsEntries calls fn with the inside then the outside cells entries in key order.
+	LoadCellsEntries(fn func(key, value []byte) error) error
+}
+
+// WriteOptions options of Write.
+type WriteOptions struct {
+	// SnapLevel snaps the polygons vertices to the center of the cells at this level,
+	// so they are encoded in the s2 compressed encoding, 30 moves vertices less than a centimeter,
+	// 0 keeps the vertices as stored.
+	SnapLevel int
+}
+
+// entry a cells entry in the file.
+type entry struct {
+	c   s2.CellID
+	off uint64
+	n   uint32
+}
+
+type extIDEntry struct {
+	extID string
+	id    uint32
+}
+
+// countWriter counts the bytes written.
+type countWriter struct {
+	w   *bufio.Writer
+	off uint64
+	b   [8]byte
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.off += uint64(n)
+
+	return n, err
+}
+
+func (cw *countWriter) uint32(v uint32) error {
+	binary.LittleEndian.PutUint32(cw.b[:], v)
+	_, err := cw.Write(cw.b[:4])
+
+	return err
+}
+
+func (cw *countWriter) uint64(v uint64) error {
+	binary.LittleEndian.PutUint64(cw.b[:], v)
+	_, err := cw.Write(cw.b[:])
+
+	return err
+}
+
+// field writes b prefixed by its length.
+func (cw *countWriter) field(b []byte) error {
+	if err := cw.uint32(uint32(len(b))); err != nil {
+		return err
+	}
+
+	_, err := cw.Write(b)
+
+	return err
+}
+
+// Write writes the index of src to a new file at path,
+// the file is written to a temporary file in the same directory then renamed.
+func Write(path string, src Source, opts WriteOptions) error {
+	tmpPath := path + ".tmp"
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if f != nil {
+			f.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	h, err := write(f, src, opts)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteAt(h.encode(), 0); err != nil {
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	err = f.Close()
+	f = nil
+
+	if err != nil {
+		os.Remove(tmpPath)
+
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// write writes the sections after an empty header and returns the header.
+func write(w io.Writer, src Source, opts WriteOptions) (*header, error) {
+	infos, err := src.LoadIndexInfos()
+	if err != nil {
+		return nil, err
+	}
+
+	h := &header{version: FormatVersion}
+	cw := &countWriter{w: bufio.NewWriter(w)}
+
+	if _, err := cw.Write(make([]byte, headerSize)); err != nil {
+		return nil, err
+	}
+
+	// features
+	var (
+		table  []uint64
+		extIDs []extIDEntry
+	)
+
+	h.sections[secFeatures].off = cw.off
+
+	err = src.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		for uint32(len(table)) <= id {
+			table = append(table, 0)
+		}
+
+		table[id] = cw.off
+
+		extID, err := src.LoadExternalID(id)
+		if err != nil {
+			return err
+		}
+
+		if extID != "" {
+			extIDs = append(extIDs, extIDEntry{extID: extID, id: id})
+		}
+
+		return writeFeature(cw, src, fs, id, extID, opts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't write features: %w", err)
+	}
+
+	h.sections[secFeatures].len = cw.off - h.sections[secFeatures].off
+
+	h.sections[secFeatureTable].off = cw.off
+
+	for _, off := range table {
+		if err := cw.uint64(off); err != nil {
+			return nil, err
+		}
+	}
+
+	h.sections[secFeatureTable].len = cw.off - h.sections[secFeatureTable].off
+
+	// cells entries values, then the entries
+	var inside, outside []entry
+
+	h.sections[secValues].off = cw.off
+
+	err = src.LoadCellsEntries(func(key, value []byte) error {
+		e := entry{
+			c:   kv.NewCellEntry(key, nil).C,
+			off: cw.off - h.sections[secValues].off,
+			n:   uint32(len(value) / valueSize),
+		}
+
+		if key[0] == insideout.InsidePrefix() {
+			inside = append(inside, e)
+		} else {
+			outside = append(outside, e)
+		}
+
+		_, err := cw.Write(value[:int(e.n)*valueSize])
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't write cells entries: %w", err)
+	}
+
+	h.sections[secValues].len = cw.off - h.sections[secValues].off
+
+	for _, es := range []struct {
+		i       int
+		entries []entry
+	}{{secInside, inside}, {secOutside, outside}} {
+		h.sections[es.i].off = cw.off
+
+		for _, e := range es.entries {
+			if err := writeEntry(cw, e); err != nil {
+				return nil, err
+			}
+		}
+
+		h.sections[es.i].len = cw.off - h.sections[es.i].off
+	}
+
+	// external ids
+	sort.Slice(extIDs, func(i, j int) bool { return extIDs[i].extID < extIDs[j].extID })
+
+	h.sections[secExtIDStrings].off = cw.off
+
+	for _, e := range extIDs {
+		if _, err := io.WriteString(cw, e.extID); err != nil {
+			return nil, err
+		}
+	}
+
+	h.sections[secExtIDStrings].len = cw.off - h.sections[secExtIDStrings].off
+	h.sections[secExtIDs].off = cw.off
+
+	var strOff uint64
+
+	for _, e := range extIDs {
+		if err := cw.uint64(strOff); err != nil {
+			return nil, err
+		}
+
+		if err := cw.uint32(uint32(len(e.extID))); err != nil {
+			return nil, err
+		}
+
+		if err := cw.uint32(e.id); err != nil {
+			return nil, err
+		}
+
+		strOff += uint64(len(e.extID))
+	}
+
+	h.sections[secExtIDs].len = cw.off - h.sections[secExtIDs].off
+
+	// infos
+	b, err := kv.Encode(infos)
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding IndexInfos: %w", err)
+	}
+
+	h.sections[secInfos] = section{off: cw.off, len: uint64(len(b))}
+
+	if _, err := cw.Write(b); err != nil {
+		return nil, err
+	}
+
+	mapInfos, ok, err := src.LoadMapInfos()
+	if err != nil {
+		return nil, err
+	}
+
+	h.sections[secMapInfos].off = cw.off
+
+	if ok {
+		b, err := kv.Encode(mapInfos)
+		if err != nil {
+			return nil, fmt.Errorf("failed encoding MapInfos: %w", err)
+		}
+
+		h.sections[secMapInfos].len = uint64(len(b))
+
+		if _, err := cw.Write(b); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, cw.w.Flush()
+}
+
+func writeEntry(cw *countWriter, e entry) error {
+	if err := cw.uint64(uint64(e.c)); err != nil {
+		return err
+	}
+
+	if err := cw.uint64(e.off); err != nil {
+		return err
+	}
+
+	return cw.uint32(e.n)
+}
+
+// writeFeature writes the feature record: external id, properties, cells and polygons.
+func writeFeature(cw *countWriter, src Source, fs *insideout.FeatureStorage, id uint32, extID string,
+	opts WriteOptions) error {
+	props, err := kv.Encode(fs.Properties)
+	if err != nil {
+		return fmt.Errorf("can't encode properties: %w", err)
+	}
+
+	cs, err := src.LoadCellStorage(id)
+	if err != nil {
+		return err
+	}
+
+	cells, err := kv.Encode(cs)
+	if err != nil {
+		return fmt.Errorf("can't encode CellsStorage: %w", err)
+	}
+
+	pbs := fs.PolygonsBytes
+
+	// DBs indexed before holes support only have loops
+	if opts.SnapLevel > 0 || len(pbs) == 0 {
+		if pbs, err = encodePolygons(fs, opts.SnapLevel); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range [][]byte{[]byte(extID), props, cells} {
+		if err := cw.field(b); err != nil {
+			return err
+		}
+	}
+
+	if err := cw.uint32(uint32(len(pbs))); err != nil {
+		return err
+	}
+
+	for _, pb := range pbs {
+		if err := cw.field(pb); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodePolygons encodes the polygons of fs, with their vertices snapped to level if not 0.
+func encodePolygons(fs *insideout.FeatureStorage, level int) ([][]byte, error) {
+	polygons, err := fs.Polygons()
+	if err != nil {
+		return nil, err
+	}
+
+	pbs := make([][]byte, len(polygons))
+
+	for i, p := range polygons {
+		if level > 0 {
+			p = snapPolygon(p, level)
+		}
+
+		b := new(bytes.Buffer)
+		if err := p.Encode(b); err != nil {
+			return nil, fmt.Errorf("can't encode polygon %d: %w", i, err)
+		}
+
+		pbs[i] = b.Bytes()
+	}
+
+	return pbs, nil
+}
+
+// snapPolygon returns p with its vertices moved to the center of their cell at level.
+func snapPolygon(p *s2.Polygon, level int) *s2.Polygon {
+	loops := make([]*s2.Loop, p.NumLoops())
+
+	for i, l := range p.Loops() {
+		vs := make([]s2.Point, l.NumVertices())
+		for j, v := range l.Vertices() {
+			vs[j] = s2.CellFromPoint(v).ID().Parent(level).Point()
+		}
+
+		loops[i] = s2.LoopFromPoints(vs)
+		// holes are found by nesting normalized loops, as when indexing
+		loops[i].Normalize()
+	}
+
+	return s2.PolygonFromLoops(loops)
+}
@@ -0,0 +1,64 @@
+// Package storage opens the insideout storage engines by name.
+package storage
+
+import (
+	"fmt"
+
+	log "github.com/go-kit/kit/log"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/bbolt"
+	"github.com/akhenakh/insideout/storage/internal/kv"
+	"github.com/akhenakh/insideout/storage/leveldb"
+	"github.com/akhenakh/insideout/storage/mmap"
+)
+
+// Engines names.
+const (
+	BBolt   = "bbolt"
+	LevelDB = "leveldb"
+	// Mmap is read only, files are converted from another engine
+	Mmap = "mmap"
+)
+
+// Engines lists the available engines.
+var Engines = []string{BBolt, LevelDB, Mmap}
+
+// IndexStore a writable storage: indexing, updating and recording its sources.
+type IndexStore interface {
+	insideout.Store
+	insideout.Updater
+	AddSource(src insideout.SourceInfos) error
+	SetIndexOptions(opts IndexOptions)
+}
+
+// IndexOptions tunes the indexing pipeline, zero values use the defaults.
+type IndexOptions = kv.IndexOptions
+
+// NewStorage opens the DB at path with engine for indexing or updating.
+func NewStorage(engine, path string, logger log.Logger) (IndexStore, func() error, error) {
+	switch engine {
+	case BBolt:
+		return bbolt.NewStorage(path, logger)
+	case LevelDB:
+		return leveldb.NewStorage(path, logger)
+	case Mmap:
+		return nil, nil, fmt.Errorf("storage engine %s is read only", engine)
+	default:
+		return nil, nil, fmt.Errorf("unknown storage engine %q", engine)
+	}
+}
+
+// NewROStorage opens the DB at path with engine for reading.
+func NewROStorage(engine, path string, logger log.Logger) (insideout.Store, func() error, error) {
+	switch engine {
+	case BBolt:
+		return bbolt.NewROStorage(path, logger)
+	case LevelDB:
+		return leveldb.NewROStorage(path, logger)
+	case Mmap:
+		return mmap.NewROStorage(path, logger)
+	default:
+		return nil, nil, fmt.Errorf("unknown storage engine %q", engine)
+	}
+}
@@ -0,0 +1,369 @@
+// Package storagetest implements tests for storage engines, every engine must pass TestStore.
+package storagetest
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+	"github.com/akhenakh/insideout/storage/memory"
+)
+
+// features a polygon with a hole, a multipolygon and a feature without id.
+const features = `{"type": "FeatureCollection", "features": [
+{"type": "Feature", "id": "hole", "properties": {"name": "hole"}, "geometry": {"type": "Polygon", "coordinates": [
+	[[2.0, 48.0], [2.1, 48.0], [2.1, 48.1], [2.0, 48.1], [2.0, 48.0]],
+	[[2.04, 48.04], [2.04, 48.06], [2.06, 48.06], [2.06, 48.04], [2.04, 48.04]]
+]}},
+{"type": "Feature", "id": "multi", "properties": {"name": "multi"}, "geometry": {"type": "MultiPolygon", "coordinates": [
+	[[[3.0, 48.0], [3.1, 48.0], [3.1, 48.1], [3.0, 48.1], [3.0, 48.0]]],
+	[[[3.2, 48.0], [3.3, 48.0], [3.3, 48.1], [3.2, 48.1], [3.2, 48.0]]]
+]}},
+{"type": "Feature", "properties": {"name": "noid"}, "geometry": {"type": "Polygon", "coordinates": [
+	[[4.0, 48.0], [4.1, 48.0], [4.1, 48.1], [4.0, 48.1], [4.0, 48.0]]
+]}}
+]}`
+
+var (
+	icoverer = &s2.RegionCoverer{MinLevel: 10, MaxLevel: 16, MaxCells: 24}
+	ocoverer = &s2.RegionCoverer{MinLevel: 10, MaxLevel: 15, MaxCells: 16}
+)
+
+// TestStore indexes the test features into the empty store returned by newStore and checks every Store method,
+// lookups are compared with the memory storage, Updater methods are checked when the store implements it.
+// newStore returns the store and a function to close and remove it.
+func TestStore(t *testing.T, newStore func(t *testing.T) (insideout.Store, func())) {
+	t.Helper()
+
+	s, clean := newStore(t)
+	defer clean()
+
+	err := s.Index(insideout.NewFeatureDecoder(strings.NewReader(features)), icoverer, ocoverer, 100, "test.geojson", "unittest")
+	require.NoError(t, err)
+
+	t.Run("Infos", func(t *testing.T) { testInfos(t, s) })
+	t.Run("Features", func(t *testing.T) { testFeatures(t, s) })
+	t.Run("ExternalIDs", func(t *testing.T) { testExternalIDs(t, s) })
+	t.Run("Stab", func(t *testing.T) { testStab(t, s) })
+	t.Run("Intersects", func(t *testing.T) { testIntersects(t, s) })
+	t.Run("Reference", func(t *testing.T) { testReference(t, s) })
+
+	if u, ok := s.(insideout.Updater); ok {
+		t.Run("Update", func(t *testing.T) { testUpdate(t, s, u) })
+	}
+}
+
+func testInfos(t *testing.T, s insideout.Store) {
+	infos, err := s.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, "test.geojson", infos.Filename)
+	require.Equal(t, "unittest", infos.IndexerVersion)
+	require.Equal(t, uint32(3), infos.FeatureCount)
+	require.Equal(t, insideout.SchemaVersion, infos.SchemaVersion)
+	require.Equal(t, 10, infos.MinCoverLevel)
+	require.Equal(t, insideout.NewCoverInfos(icoverer), infos.InsideCover)
+	require.Equal(t, insideout.NewCoverInfos(ocoverer), infos.OutsideCover)
+	require.Equal(t, 100, infos.WarningCellsCover)
+	require.NotZero(t, infos.InsideCellCount)
+	require.NotZero(t, infos.OutsideCellCount)
+	require.False(t, infos.IndexTime.IsZero())
+
+	_, ok, err := s.LoadMapInfos()
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func testFeatures(t *testing.T, s insideout.Store) {
+	f, err := s.LoadFeature(0)
+	require.NoError(t, err)
+	require.Len(t, f.Polygons, 1)
+	require.Equal(t, 2, f.Polygons[0].NumLoops())
+	require.Equal(t, "hole", f.Properties["name"])
+
+	f, err = s.LoadFeature(1)
+	require.NoError(t, err)
+	require.Len(t, f.Polygons, 2)
+
+	_, err = s.LoadFeature(3)
+	require.Error(t, err)
+
+	props, err := s.LoadFeatureProperties(2)
+	require.NoError(t, err)
+	require.Equal(t, "noid", props["name"])
+
+	cs, err := s.LoadCellStorage(1)
+	require.NoError(t, err)
+	require.Len(t, cs.CellsIn, 2)
+	require.Len(t, cs.CellsOut, 2)
+
+	var ids []uint32
+
+	err = s.LoadAllFeatures(func(fs *insideout.FeatureStorage, id uint32) error {
+		ids = append(ids, id)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uint32{0, 1, 2}, ids)
+
+	ids = nil
+
+	err = s.LoadFeaturesCells(func(cui, cuo []s2.CellUnion, id uint32) {
+		require.NotEmpty(t, cui)
+		require.NotEmpty(t, cuo)
+
+		ids = append(ids, id)
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uint32{0, 1, 2}, ids)
+}
+
+func testExternalIDs(t *testing.T, s insideout.Store) {
+	id, err := s.LookupExternalID("multi")
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), id)
+
+	_, err = s.LookupExternalID("unknown")
+	require.True(t, errors.Is(err, insideout.ErrFeatureNotFound))
+
+	extID, err := s.LoadExternalID(0)
+	require.NoError(t, err)
+	require.Equal(t, "hole", extID)
+
+	extID, err = s.LoadExternalID(2)
+	require.NoError(t, err)
+	require.Empty(t, extID)
+
+	f, err := s.LoadFeature(1)
+	require.NoError(t, err)
+	require.Equal(t, "multi", f.ExternalID)
+}
+
+func testStab(t *testing.T, s insideout.Store) {
+	tests := []struct {
+		name     string
+		lat, lng float64
+		// polygons containing or that may contain the point
+		want []insideout.FeatureIndexResponse
+		// mayBeOnly the point is only in the outside cover
+		mayBeOnly bool
+	}{
+		{"polygon", 48.02, 2.02, []insideout.FeatureIndexResponse{{ID: 0}}, false},
+		{"hole", 48.05, 2.05, []insideout.FeatureIndexResponse{{ID: 0}}, true},
+		{"first polygon", 48.05, 3.05, []insideout.FeatureIndexResponse{{ID: 1}}, false},
+		{"second polygon", 48.05, 3.25, []insideout.FeatureIndexResponse{{ID: 1, Pos: 1}}, false},
+		{"no id", 48.05, 4.05, []insideout.FeatureIndexResponse{{ID: 2}}, false},
+		{"nothing", 10, 10, nil, false},
+	}
+
+	lls := make([]s2.LatLng, len(tests))
+
+	for i, tt := range tests {
+		lls[i] = s2.LatLngFromDegrees(tt.lat, tt.lng)
+	}
+
+	resps, err := s.StabBatchDB(lls, false)
+	require.NoError(t, err)
+	require.Len(t, resps, len(tests))
+
+	for i, tt := range tests {
+		tt := tt
+		batchResp := resps[i]
+
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.StabDB(tt.lat, tt.lng, false)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, sorted(allIDs(resp)))
+
+			if tt.mayBeOnly {
+				require.Empty(t, resp.IDsInside)
+			}
+
+			require.Equal(t, sorted(resp.IDsInside), sorted(batchResp.IDsInside))
+			require.Equal(t, sorted(resp.IDsMayBeInside), sorted(batchResp.IDsMayBeInside))
+
+			if len(resp.IDsInside) > 0 {
+				resp, err := s.StabDB(tt.lat, tt.lng, true)
+				require.NoError(t, err)
+				require.Len(t, resp.IDsInside, 1)
+			}
+		})
+	}
+}
+
+func testIntersects(t *testing.T, s insideout.Store) {
+	// a cell larger than the min cover level around both polygons of multi and a cell inside the first one
+	c := s2.CellIDFromLatLng(s2.LatLngFromDegrees(48.05, 3.15)).Parent(7)
+	resp, err := s.IntersectsDB(s2.CellUnion{c})
+	require.NoError(t, err)
+	require.Contains(t, allIDs(resp), insideout.FeatureIndexResponse{ID: 1})
+	require.Contains(t, allIDs(resp), insideout.FeatureIndexResponse{ID: 1, Pos: 1})
+
+	c = s2.CellIDFromLatLng(s2.LatLngFromDegrees(48.05, 3.05)).Parent(18)
+	resp, err = s.IntersectsDB(s2.CellUnion{c})
+	require.NoError(t, err)
+	require.Equal(t, []insideout.FeatureIndexResponse{{ID: 1}}, sorted(resp.IDsInside))
+
+	c = s2.CellIDFromLatLng(s2.LatLngFromDegrees(10, 10)).Parent(12)
+	resp, err = s.IntersectsDB(s2.CellUnion{c})
+	require.NoError(t, err)
+	require.Empty(t, allIDs(resp))
+}
+
+// testReference compares the lookups on a grid of points and cells around the features with the memory storage.
+func testReference(t *testing.T, s insideout.Store) {
+	ref := memory.NewStorage(log.NewNopLogger())
+
+	err := ref.Index(insideout.NewFeatureDecoder(strings.NewReader(features)), icoverer, ocoverer, 100, "test.geojson", "unittest")
+	require.NoError(t, err)
+
+	var lls []s2.LatLng
+
+	for lat := 47.99; lat < 48.11; lat += 0.005 {
+		for lng := 1.99; lng < 4.11; lng += 0.005 {
+			lls = append(lls, s2.LatLngFromDegrees(lat, lng))
+		}
+	}
+
+	resps, err := s.StabBatchDB(lls, false)
+	require.NoError(t, err)
+
+	want, err := ref.StabBatchDB(lls, false)
+	require.NoError(t, err)
+
+	for i, ll := range lls {
+		require.Equal(t, sorted(want[i].IDsInside), sorted(resps[i].IDsInside), "inside %v", ll)
+		require.Equal(t, sorted(want[i].IDsMayBeInside), sorted(resps[i].IDsMayBeInside), "may be inside %v", ll)
+
+		if i%20 != 0 {
+			continue
+		}
+
+		for _, l := range []int{8, 12, 16} {
+			cu := s2.CellUnion{s2.CellIDFromLatLng(ll).Parent(l)}
+
+			resp, err := s.IntersectsDB(cu)
+			require.NoError(t, err)
+
+			wresp, err := ref.IntersectsDB(cu)
+			require.NoError(t, err)
+
+			require.Equal(t, sorted(wresp.IDsInside), sorted(resp.IDsInside), "inside %v level %d", ll, l)
+			require.Equal(t, sorted(wresp.IDsMayBeInside), sorted(resp.IDsMayBeInside), "may be inside %v level %d", ll, l)
+		}
+	}
+}
+
+func testUpdate(t *testing.T, s insideout.Store, u insideout.Updater) {
+	// add
+	id, err := u.PutFeature("square", squareFeature(5.0, 48.0, "square"), icoverer, ocoverer, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), id)
+	require.Equal(t, []uint32{3}, stabIDs(t, s, 48.05, 5.05))
+
+	// replace keeps the internal id
+	id, err = u.PutFeature("hole", squareFeature(6.0, 48.0, "moved"), icoverer, ocoverer, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), id)
+	require.Empty(t, stabIDs(t, s, 48.01, 2.01))
+	require.Equal(t, []uint32{0}, stabIDs(t, s, 48.05, 6.05))
+
+	props, err := s.LoadFeatureProperties(0)
+	require.NoError(t, err)
+	require.Equal(t, "moved", props["name"])
+
+	// delete
+	require.NoError(t, u.DeleteFeature("multi"))
+	require.Empty(t, stabIDs(t, s, 48.05, 3.05))
+
+	_, err = s.LookupExternalID("multi")
+	require.True(t, errors.Is(err, insideout.ErrFeatureNotFound))
+
+	_, err = s.LoadFeature(1)
+	require.Error(t, err)
+
+	err = u.DeleteFeature("multi")
+	require.True(t, errors.Is(err, insideout.ErrFeatureNotFound))
+
+	infos, err := s.LoadIndexInfos()
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), infos.FeatureCount)
+	require.False(t, infos.UpdateTime.IsZero())
+
+	// deleted ids are not reused
+	id, err = u.PutFeature("multi", squareFeature(3.0, 48.0, "multi"), icoverer, ocoverer, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint32(4), id)
+
+	// even the highest one
+	require.NoError(t, u.DeleteFeature("multi"))
+
+	id, err = u.PutFeature("multi", squareFeature(3.0, 48.0, "multi"), icoverer, ocoverer, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint32(5), id)
+
+	_, err = u.PutFeature("", squareFeature(7.0, 48.0, "no id"), icoverer, ocoverer, 100)
+	require.Error(t, err)
+}
+
+// stabIDs returns the sorted feature ids whose cells contain lat lng.
+func stabIDs(t *testing.T, s insideout.Store, lat, lng float64) []uint32 {
+	t.Helper()
+
+	resp, err := s.StabDB(lat, lng, false)
+	require.NoError(t, err)
+
+	m := make(map[uint32]struct{})
+	for _, fid := range allIDs(resp) {
+		m[fid.ID] = struct{}{}
+	}
+
+	ids := make([]uint32, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+func allIDs(resp insideout.IndexResponse) []insideout.FeatureIndexResponse {
+	return append(append([]insideout.FeatureIndexResponse(nil), resp.IDsInside...), resp.IDsMayBeInside...)
+}
+
+// sorted returns the responses sorted by id and polygon index, nil when empty.
+func sorted(fids []insideout.FeatureIndexResponse) []insideout.FeatureIndexResponse {
+	if len(fids) == 0 {
+		return nil
+	}
+
+	res := append([]insideout.FeatureIndexResponse(nil), fids...)
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].ID != res[j].ID {
+			return res[i].ID < res[j].ID
+		}
+
+		return res[i].Pos < res[j].Pos
+	})
+
+	return res
+}
+
+// squareFeature returns a 0.1 degree square feature at lng lat.
+func squareFeature(lng, lat float64, name string) *geojson.Feature {
+	return &geojson.Feature{
+		Geometry: geom.NewPolygonFlat(geom.XY, []float64{
+			lng, lat, lng + 0.1, lat, lng + 0.1, lat + 0.1, lng, lat + 0.1, lng, lat,
+		}, []int{10}),
+		Properties: map[string]interface{}{"name": name},
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"log"
+	"math"
+	"strconv"
 	"strings"
 
+	"github.com/golang/geo/s1"
 	"github.com/golang/geo/s2"
 	spb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/pkg/errors"
@@ -18,6 +22,9 @@ const (
 	outsidePrefix byte = 'O'
 	featurePrefix byte = 'F'
 	cellPrefix    byte = 'C'
+	propsPrefix   byte = 'P'
+	extIDPrefix   byte = 'X'
+	fextIDPrefix  byte = 'E'
 	infoKey       byte = 'i'
 	mapKey        byte = 'm'
 	// reserved T & t for tiles
@@ -40,8 +47,7 @@ func GeoJSONCoverCellUnion(f *geojson.Feature, coverer *s2.RegionCoverer, interi
 
 	switch rg := f.Geometry.(type) {
 	case *geom.Polygon:
-		// only supports outer ring
-		cup, err := coverPolygon(rg.FlatCoords(), coverer, interior)
+		cup, err := coverPolygon(rg.FlatCoords(), rg.Ends(), coverer, interior)
 		if err != nil {
 			return nil, fmt.Errorf("can't cover polygon: %w", err)
 		}
@@ -51,7 +57,7 @@ func GeoJSONCoverCellUnion(f *geojson.Feature, coverer *s2.RegionCoverer, interi
 		for i := 0; i < rg.NumPolygons(); i++ {
 			p := rg.Polygon(i)
 
-			cup, err := coverPolygon(p.FlatCoords(), coverer, interior)
+			cup, err := coverPolygon(p.FlatCoords(), p.Ends(), coverer, interior)
 			if err != nil {
 				return nil, fmt.Errorf("can't cover multi polygon %d: %w", i, err)
 			}
@@ -66,8 +72,8 @@ func GeoJSONCoverCellUnion(f *geojson.Feature, coverer *s2.RegionCoverer, interi
 	return cu, nil
 }
 
-// GeoJSONEncodeLoops encodes all MultiPolygons and Polygons as loops []byte
-func GeoJSONEncodeLoops(f *geojson.Feature) ([][]byte, error) {
+// GeoJSONEncodePolygons encodes all MultiPolygons and Polygons as s2 polygons []byte, holes included
+func GeoJSONEncodePolygons(f *geojson.Feature) ([][]byte, error) {
 	if f.Geometry == nil {
 		return nil, errors.New("invalid geometry")
 	}
@@ -76,29 +82,23 @@ func GeoJSONEncodeLoops(f *geojson.Feature) ([][]byte, error) {
 
 	switch rg := f.Geometry.(type) {
 	case *geom.Polygon:
-		// only supports outer ring
-		lb := new(bytes.Buffer)
-		l := LoopFromCoordinates(rg.FlatCoords())
-
-		err := l.Encode(lb)
+		pb, err := encodePolygon(rg.FlatCoords(), rg.Ends())
 		if err != nil {
 			return nil, fmt.Errorf("can't encode polygon: %w", err)
 		}
 
-		b = append(b, lb.Bytes())
+		b = append(b, pb)
 
 	case *geom.MultiPolygon:
 		for i := 0; i < rg.NumPolygons(); i++ {
-			lb := new(bytes.Buffer)
 			p := rg.Polygon(i)
-			l := LoopFromCoordinates(p.FlatCoords())
 
-			err := l.Encode(lb)
+			pb, err := encodePolygon(p.FlatCoords(), p.Ends())
 			if err != nil {
-				return nil, fmt.Errorf("can't encode polygon: %w", err)
+				return nil, fmt.Errorf("can't encode multi polygon %d: %w", i, err)
 			}
 
-			b = append(b, lb.Bytes())
+			b = append(b, pb)
 		}
 
 	default:
@@ -108,26 +108,120 @@ func GeoJSONEncodeLoops(f *geojson.Feature) ([][]byte, error) {
 	return b, nil
 }
 
-// coverPolygon returns an s2 cover from a list of lng, lat forming a closed polygon
-func coverPolygon(c []float64, coverer *s2.RegionCoverer, interior bool) (s2.CellUnion, error) {
-	if len(c) < 6 {
-		return nil, errors.New("invalid polygons not enough coordinates for a closed polygon")
+// encodePolygon encodes a list of lng, lat rings as an s2 Polygon
+func encodePolygon(c []float64, ends []int) ([]byte, error) {
+	p, err := PolygonFromCoordinates(c, ends)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(c)%2 != 0 {
-		return nil, errors.New("invalid polygons odd coordinates number")
+	b := new(bytes.Buffer)
+	if err := p.Encode(b); err != nil {
+		return nil, err
 	}
 
-	l := LoopFromCoordinates(c)
-	if l.IsEmpty() || l.IsFull() || l.ContainsOrigin() {
-		return nil, errors.New("invalid polygons")
+	return b.Bytes(), nil
+}
+
+// coverPolygon returns an s2 cover from a list of lng, lat rings forming a closed polygon
+// holes are excluded from the cover
+func coverPolygon(c []float64, ends []int, coverer *s2.RegionCoverer, interior bool) (s2.CellUnion, error) {
+	p, err := PolygonFromCoordinates(c, ends)
+	if err != nil {
+		return nil, err
 	}
 
 	if interior {
-		return coverer.InteriorCovering(l), nil
+		return coverer.InteriorCovering(p), nil
 	}
 
-	return coverer.Covering(l), nil
+	return coverer.Covering(p), nil
+}
+
+// PolygonFromCoordinates creates an s2 Polygon from a list of lng lat split into rings by ends,
+// as found in GeoJSON the first ring is the outer ring, the next ones are the holes,
+// a clockwise outer ring is reversed
+func PolygonFromCoordinates(c []float64, ends []int) (*s2.Polygon, error) {
+	if len(ends) == 0 {
+		return nil, errors.New("invalid polygons no ring")
+	}
+
+	loops := make([]*s2.Loop, len(ends))
+	start := 0
+
+	for i, end := range ends {
+		if end < start || end > len(c) {
+			return nil, errors.New("invalid polygons ends out of range")
+		}
+
+		rc := c[start:end]
+		start = end
+
+		if len(rc) < 6 {
+			return nil, errors.New("invalid polygons not enough coordinates for a closed polygon")
+		}
+
+		if len(rc)%2 != 0 {
+			return nil, errors.New("invalid polygons odd coordinates number")
+		}
+
+		l := LoopFromCoordinates(rc)
+
+		if i == 0 {
+			if l.IsEmpty() || l.IsFull() {
+				return nil, errors.New("invalid polygons")
+			}
+
+			if !l.IsNormalized() {
+				// the winding order of a ring crossing the antimeridian depends on how
+				// the producer unwrapped it, the smallest loop is the intended one
+				if !CrossesAntimeridian(rc) {
+					log.Printf("warning: clockwise outer ring of %d vertices reversed", len(rc)/2)
+				}
+
+				l.Normalize()
+			}
+		} else {
+			// holes are clockwise in GeoJSON, s2 finds holes by nesting normalized loops
+			l.Normalize()
+		}
+
+		loops[i] = l
+	}
+
+	return s2.PolygonFromLoops(loops), nil
+}
+
+// bboxStep is the max longitude degrees between two vertices along a bbox latitude edge,
+// s2 edges are geodesics not following parallels.
+const bboxStep = 1.0
+
+// PolygonFromBBox creates an s2 Polygon from a lat lng bounding box,
+// minLng greater than maxLng means the bbox is crossing the antimeridian
+func PolygonFromBBox(minLat, minLng, maxLat, maxLng float64) (*s2.Polygon, error) {
+	if minLat >= maxLat || minLng == maxLng {
+		return nil, errors.New("invalid bbox")
+	}
+
+	if maxLng < minLng {
+		maxLng += 360
+	}
+
+	steps := int(math.Ceil((maxLng - minLng) / bboxStep))
+	c := make([]float64, 0, (steps+1)*4+2)
+
+	// counter clockwise, south edge going east, north edge going west
+	for i := 0; i <= steps; i++ {
+		c = append(c, wrapLng(minLng+(maxLng-minLng)*float64(i)/float64(steps)), minLat)
+	}
+
+	for i := steps; i >= 0; i-- {
+		c = append(c, wrapLng(minLng+(maxLng-minLng)*float64(i)/float64(steps)), maxLat)
+	}
+
+	c = append(c, c[0], c[1])
+
+	return PolygonFromCoordinates(c, []int{len(c)})
 }
 
 // LoopFromCoordinates creates a LoopFence from a list of lng lat
@@ -136,15 +230,23 @@ func LoopFromCoordinates(c []float64) *s2.Loop {
 		return nil
 	}
 
-	points := make([]s2.Point, len(c)/2)
+	points := make([]s2.Point, 0, len(c)/2)
 
 	for i := 0; i < len(c); i += 2 {
-		points[i/2] = s2.PointFromLatLng(s2.LatLngFromDegrees(c[i+1], c[i]))
+		p := s2.PointFromLatLng(s2.LatLngFromDegrees(c[i+1], c[i]))
+
+		// rings cut at the antimeridian go through the poles at -180 and 180,
+		// the same point for s2
+		if len(points) > 0 && points[len(points)-1] == p {
+			continue
+		}
+
+		points = append(points, p)
 	}
 
-	if points[0] == points[len(points)-1] {
+	if len(points) > 1 && points[0] == points[len(points)-1] {
 		// remove last item if same as 1st
-		points = append(points[:len(points)-1], points[len(points)-1+1:]...)
+		points = points[:len(points)-1]
 	}
 
 	loop := s2.LoopFromPoints(points)
@@ -152,8 +254,69 @@ func LoopFromCoordinates(c []float64) *s2.Loop {
 	return loop
 }
 
-// CoordinatesFromLoops returns []float64 as lng lat adding 1st as last suitable for GeoJSON
-func CoordinatesFromLoops(l *s2.Loop) []float64 {
+// CoordinatesFromPolygon returns []float64 as lng lat and the rings ends of each polygon suitable for GeoJSON,
+// outer rings are counter clockwise, holes are clockwise.
+// Polygons crossing the antimeridian are cut into several polygons as described in RFC 7946,
+// otherwise a single polygon is returned.
+func CoordinatesFromPolygon(p *s2.Polygon) ([]float64, [][]int) {
+	// group holes with their outer ring
+	var groups [][][]float64
+
+	group := make(map[int]int, p.NumLoops())
+
+	for i, l := range p.Loops() {
+		lc := loopCoordinates(l)
+
+		if !l.IsHole() {
+			group[i] = len(groups)
+			groups = append(groups, [][]float64{lc})
+
+			continue
+		}
+
+		for j, k := 0, len(lc)-2; j < k; j, k = j+2, k-2 {
+			lc[j], lc[j+1], lc[k], lc[k+1] = lc[k], lc[k+1], lc[j], lc[j+1]
+		}
+
+		parent, _ := p.Parent(i)
+		gi := group[parent]
+		groups[gi] = append(groups[gi], lc)
+	}
+
+	var coords []float64
+
+	endss := make([][]int, 0, len(groups))
+
+	for _, rings := range groups {
+		for _, polygon := range CutAntimeridian(rings) {
+			ends := make([]int, 0, len(polygon))
+
+			for _, r := range polygon {
+				coords = append(coords, r...)
+				ends = append(ends, len(coords))
+			}
+
+			endss = append(endss, ends)
+		}
+	}
+
+	return coords, endss
+}
+
+// CoordinatesFromLoops returns the rings as []float64 lng lat adding 1st as last suitable for GeoJSON,
+// a loop crossing the antimeridian is cut into several rings as described in RFC 7946.
+func CoordinatesFromLoops(l *s2.Loop) [][]float64 {
+	var rings [][]float64
+
+	for _, polygon := range CutAntimeridian([][]float64{loopCoordinates(l)}) {
+		rings = append(rings, polygon[0])
+	}
+
+	return rings
+}
+
+// loopCoordinates returns the loop vertices as []float64 lng lat adding 1st as last.
+func loopCoordinates(l *s2.Loop) []float64 {
 	points := l.Vertices()
 	coords := make([]float64, len(points)*2+2)
 
@@ -169,6 +332,19 @@ func CoordinatesFromLoops(l *s2.Loop) []float64 {
 	return coords
 }
 
+// EarthRadius is the mean radius of the Earth in meters.
+const EarthRadius = 6371010.0
+
+// AngleFromMeters returns the angle on the Earth surface for a distance in meters.
+func AngleFromMeters(m float64) s1.Angle {
+	return s1.Angle(m / EarthRadius)
+}
+
+// MetersFromAngle returns the distance in meters on the Earth surface for an angle.
+func MetersFromAngle(a s1.Angle) float64 {
+	return a.Radians() * EarthRadius
+}
+
 func InsideKey(c s2.CellID) []byte {
 	k := make([]byte, 1+8)
 	k[0] = insidePrefix
@@ -229,6 +405,33 @@ func CellKey(id uint32) []byte {
 	return k
 }
 
+// PropertiesKey returns the key for the properties of the feature id
+func PropertiesKey(id uint32) []byte {
+	k := make([]byte, 1+4)
+	k[0] = propsPrefix
+	binary.BigEndian.PutUint32(k[1:], id)
+
+	return k
+}
+
+// ExternalIDKey returns the key for the external id mapping of a feature
+func ExternalIDKey(extID string) []byte {
+	k := make([]byte, 1+len(extID))
+	k[0] = extIDPrefix
+	copy(k[1:], extID)
+
+	return k
+}
+
+// FeatureExternalIDKey returns the key for the external id of the feature id
+func FeatureExternalIDKey(id uint32) []byte {
+	k := make([]byte, 1+4)
+	k[0] = fextIDPrefix
+	binary.BigEndian.PutUint32(k[1:], id)
+
+	return k
+}
+
 // InfoKey returns the key for the info entry
 func InfoKey() []byte {
 	return []byte{infoKey}
@@ -244,33 +447,123 @@ func CellPrefix() byte {
 	return cellPrefix
 }
 
+// InsidePrefix returns the key prefix for inside cells entry
+func InsidePrefix() byte {
+	return insidePrefix
+}
+
+// OutsidePrefix returns the key prefix for outside cells entry
+func OutsidePrefix() byte {
+	return outsidePrefix
+}
+
 // FeaturePrefix returns the key prefix for features entry
 func FeaturePrefix() byte {
 	return featurePrefix
 }
 
-// PropertiesToValues converts feature's properties to protobuf Value
-func PropertiesToValues(f *Feature) (map[string]*spb.Value, error) {
+// PropertiesPrefix returns the key prefix for properties entry
+func PropertiesPrefix() byte {
+	return propsPrefix
+}
+
+// ExternalIDPrefix returns the key prefix for external ids entry
+func ExternalIDPrefix() byte {
+	return extIDPrefix
+}
+
+// FeatureExternalIDPrefix returns the key prefix for features external id entry
+func FeatureExternalIDPrefix() byte {
+	return fextIDPrefix
+}
+
+// PropertiesToValues converts feature's properties selected by mask to protobuf Value,
+// a nil mask selects every property, null and unsupported properties are skipped.
+func PropertiesToValues(f *Feature, mask FieldMask) map[string]*spb.Value {
 	m := make(map[string]*spb.Value)
 
 	for k, vi := range f.Properties {
-		switch tv := vi.(type) {
-		case bool:
-			m[k] = &spb.Value{Kind: &spb.Value_BoolValue{BoolValue: tv}}
-		case int:
-			m[k] = &spb.Value{Kind: &spb.Value_NumberValue{NumberValue: float64(tv)}}
-		case string:
-			m[k] = &spb.Value{Kind: &spb.Value_StringValue{StringValue: tv}}
-		case float64:
-			m[k] = &spb.Value{Kind: &spb.Value_NumberValue{NumberValue: tv}}
-		case nil:
-			// pass
-		default:
-			return nil, fmt.Errorf("GeoJSON property %s unsupported type %T", k, tv)
+		if !mask.Match(k) {
+			continue
+		}
+
+		if v, ok := propertyToValue(vi); ok && vi != nil {
+			m[k] = v
+		}
+	}
+
+	return m
+}
+
+// maxSafeInteger is the largest integer a float64 represents exactly.
+const maxSafeInteger = 1<<53 - 1
+
+// propertyToValue converts a property as decoded from GeoJSON or CBOR to protobuf Value,
+// integers that don't fit a float64 are returned as strings to preserve their precision.
+func propertyToValue(vi interface{}) (*spb.Value, bool) {
+	switch tv := vi.(type) {
+	case bool:
+		return &spb.Value{Kind: &spb.Value_BoolValue{BoolValue: tv}}, true
+	case string:
+		return &spb.Value{Kind: &spb.Value_StringValue{StringValue: tv}}, true
+	case float64:
+		return &spb.Value{Kind: &spb.Value_NumberValue{NumberValue: tv}}, true
+	case float32:
+		return &spb.Value{Kind: &spb.Value_NumberValue{NumberValue: float64(tv)}}, true
+	case int:
+		return intToValue(int64(tv)), true
+	case int64:
+		return intToValue(tv), true
+	case uint64:
+		if tv > maxSafeInteger {
+			return &spb.Value{Kind: &spb.Value_StringValue{StringValue: strconv.FormatUint(tv, 10)}}, true
+		}
+
+		return &spb.Value{Kind: &spb.Value_NumberValue{NumberValue: float64(tv)}}, true
+	case nil:
+		return &spb.Value{Kind: &spb.Value_NullValue{}}, true
+	case []interface{}:
+		l := &spb.ListValue{Values: make([]*spb.Value, 0, len(tv))}
+
+		for _, e := range tv {
+			if v, ok := propertyToValue(e); ok {
+				l.Values = append(l.Values, v)
+			}
+		}
+
+		return &spb.Value{Kind: &spb.Value_ListValue{ListValue: l}}, true
+	case map[string]interface{}:
+		st := &spb.Struct{Fields: make(map[string]*spb.Value, len(tv))}
+
+		for k, e := range tv {
+			if v, ok := propertyToValue(e); ok {
+				st.Fields[k] = v
+			}
 		}
+
+		return &spb.Value{Kind: &spb.Value_StructValue{StructValue: st}}, true
+	case map[interface{}]interface{}:
+		// CBOR decodes nested maps with interface keys
+		st := &spb.Struct{Fields: make(map[string]*spb.Value, len(tv))}
+
+		for k, e := range tv {
+			if v, ok := propertyToValue(e); ok {
+				st.Fields[fmt.Sprint(k)] = v
+			}
+		}
+
+		return &spb.Value{Kind: &spb.Value_StructValue{StructValue: st}}, true
+	default:
+		return nil, false
 	}
+}
 
-	return m, nil
+func intToValue(i int64) *spb.Value {
+	if i > maxSafeInteger || i < -maxSafeInteger {
+		return &spb.Value{Kind: &spb.Value_StringValue{StringValue: strconv.FormatInt(i, 10)}}
+	}
+
+	return &spb.Value{Kind: &spb.Value_NumberValue{NumberValue: float64(i)}}
 }
 
 // ValueToProperties converts a protobuf Value map to its JSON serializable map equivalent
@@ -278,14 +571,7 @@ func ValueToProperties(src map[string]*spb.Value) map[string]interface{} {
 	res := make(map[string]interface{})
 
 	for k, v := range src {
-		switch x := v.Kind.(type) {
-		case *spb.Value_NumberValue:
-			res[k] = x.NumberValue
-		case *spb.Value_StringValue:
-			res[k] = x.StringValue
-		case *spb.Value_BoolValue:
-			res[k] = x.BoolValue
-		}
+		res[k] = v.AsInterface()
 	}
 
 	return res
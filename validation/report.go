@@ -0,0 +1,88 @@
+package validation
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+// Report is the machine readable report of a validation run.
+type Report struct {
+	FeatureCount int `json:"feature_count"`
+	FixedCount   int `json:"fixed_count"`
+	DroppedCount int `json:"dropped_count"`
+	SkippedCount int `json:"skipped_count"`
+
+	// Features only lists the features with issues
+	Features []FeatureReport `json:"features"`
+}
+
+// FeatureReport issues found for one feature.
+type FeatureReport struct {
+	// Index position of the feature in the source
+	Index      int                    `json:"index"`
+	ID         string                 `json:"id,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	Dropped    bool                   `json:"dropped"`
+	Issues     []Issue                `json:"issues"`
+}
+
+// Add records the outcome of Repair for the feature f found at index in the source.
+func (r *Report) Add(index int, f *geojson.Feature, dropped bool, issues []Issue) {
+	r.FeatureCount++
+
+	if len(issues) == 0 {
+		return
+	}
+
+	if dropped {
+		r.DroppedCount++
+	} else {
+		r.FixedCount++
+	}
+
+	r.Features = append(r.Features, FeatureReport{
+		Index:      index,
+		ID:         f.ID,
+		Properties: f.Properties,
+		Dropped:    dropped,
+		Issues:     issues,
+	})
+}
+
+// AddSkipped records the feature f found at index in the source that could not be indexed because of err.
+func (r *Report) AddSkipped(index int, f *geojson.Feature, err error) {
+	r.SkippedCount++
+
+	issue := Issue{Type: CoverFailed, Action: NotIndexed, Message: err.Error()}
+
+	// features are kept in source order, a repaired feature already has an entry
+	i := sort.Search(len(r.Features), func(i int) bool {
+		return r.Features[i].Index >= index
+	})
+
+	if i < len(r.Features) && r.Features[i].Index == index {
+		r.Features[i].Issues = append(r.Features[i].Issues, issue)
+
+		return
+	}
+
+	r.Features = append(r.Features, FeatureReport{})
+	copy(r.Features[i+1:], r.Features[i:])
+	r.Features[i] = FeatureReport{
+		Index:      index,
+		ID:         f.ID,
+		Properties: f.Properties,
+		Issues:     []Issue{issue},
+	}
+}
+
+// Write writes the report as JSON.
+func (r *Report) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+}
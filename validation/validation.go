@@ -0,0 +1,458 @@
+// Package validation validates and repairs features geometries before indexation.
+package validation
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/s2"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout"
+)
+
+// IssueType is the kind of problem found in a geometry.
+type IssueType string
+
+// Action is what has been done to resolve an issue.
+type Action string
+
+const (
+	UnclosedRing        IssueType = "unclosed_ring"
+	DuplicateVertices   IssueType = "duplicate_vertices"
+	Spike               IssueType = "spike"
+	WrongOrientation    IssueType = "wrong_orientation"
+	DegenerateRing      IssueType = "degenerate_ring"
+	SelfIntersection    IssueType = "self_intersection"
+	InvalidPolygon      IssueType = "invalid_polygon"
+	UnsupportedGeometry IssueType = "unsupported_geometry"
+	CoverFailed         IssueType = "cover_failed"
+
+	// Fixed the ring has been repaired
+	Fixed Action = "fixed"
+	// RingDropped the ring has been removed, only used for holes with no area
+	RingDropped Action = "ring_dropped"
+	// PolygonDropped the whole polygon has been removed
+	PolygonDropped Action = "polygon_dropped"
+	// Kept the issue can't be fixed but the ring is still usable and indexed as is
+	Kept Action = "kept"
+	// NotIndexed the feature can't be covered and is left out of the index
+	NotIndexed Action = "not_indexed"
+)
+
+// Issue a problem found in a feature's geometry.
+type Issue struct {
+	Type IssueType `json:"type"`
+	// Polygon index of the polygon in a multipolygon
+	Polygon int `json:"polygon"`
+	// Ring index of the ring in the polygon, 0 is the outer ring
+	Ring    int    `json:"ring"`
+	Action  Action `json:"action"`
+	Message string `json:"message,omitempty"`
+}
+
+// Repair validates the geometry of f and fixes what can be fixed,
+// it returns a repaired copy of f, nil if nothing valid remains, and the issues found.
+func Repair(f *geojson.Feature) (*geojson.Feature, []Issue) {
+	var polygons []*geom.Polygon
+
+	switch g := f.Geometry.(type) {
+	case *geom.Polygon:
+		polygons = append(polygons, g)
+	case *geom.MultiPolygon:
+		for i := 0; i < g.NumPolygons(); i++ {
+			polygons = append(polygons, g.Polygon(i))
+		}
+	default:
+		return nil, []Issue{{
+			Type:    UnsupportedGeometry,
+			Action:  PolygonDropped,
+			Message: fmt.Sprintf("unsupported geometry type %T", f.Geometry),
+		}}
+	}
+
+	var issues []Issue
+
+	mp := geom.NewMultiPolygon(geom.XY)
+
+	for pi, p := range polygons {
+		rp, pissues := repairPolygon(p)
+		for i := range pissues {
+			pissues[i].Polygon = pi
+		}
+
+		issues = append(issues, pissues...)
+
+		if rp != nil {
+			if err := mp.Push(rp); err != nil {
+				issues = append(issues, Issue{Type: InvalidPolygon, Polygon: pi, Action: PolygonDropped, Message: err.Error()})
+			}
+		}
+	}
+
+	if mp.NumPolygons() == 0 {
+		return nil, issues
+	}
+
+	rf := &geojson.Feature{
+		ID:         f.ID,
+		BBox:       f.BBox,
+		Properties: f.Properties,
+		Geometry:   mp,
+	}
+
+	// keep the original geometry type
+	if _, ok := f.Geometry.(*geom.Polygon); ok {
+		rf.Geometry = mp.Polygon(0)
+	}
+
+	return rf, issues
+}
+
+// repairPolygon returns the repaired polygon or nil if it should be dropped.
+func repairPolygon(p *geom.Polygon) (*geom.Polygon, []Issue) {
+	var issues []Issue
+
+	rp := geom.NewPolygon(geom.XY)
+
+	for ri := 0; ri < p.NumLinearRings(); ri++ {
+		r := p.LinearRing(ri)
+		hole := ri > 0
+
+		coords, rissues, ok := repairRing(r.FlatCoords(), r.Stride(), hole)
+		for i := range rissues {
+			rissues[i].Ring = ri
+		}
+
+		issues = append(issues, rissues...)
+
+		if !ok {
+			if hole {
+				continue
+			}
+
+			return nil, issues
+		}
+
+		if err := rp.Push(geom.NewLinearRingFlat(geom.XY, coords)); err != nil {
+			return nil, append(issues, Issue{Type: InvalidPolygon, Ring: ri, Action: PolygonDropped, Message: err.Error()})
+		}
+	}
+
+	if rp.NumLinearRings() == 0 {
+		return nil, issues
+	}
+
+	// validates rings against each others, eg holes outside the outer ring
+	s2p, err := insideout.PolygonFromCoordinates(rp.FlatCoords(), rp.Ends())
+	if err == nil {
+		err = s2p.Validate()
+	}
+
+	if err != nil {
+		return nil, append(issues, Issue{Type: InvalidPolygon, Action: PolygonDropped, Message: err.Error()})
+	}
+
+	return rp, issues
+}
+
+// repairRing fixes the ring if possible, returns the lng lat closed coordinates,
+// the issues found and false if the ring is not usable.
+func repairRing(flat []float64, stride int, hole bool) ([]float64, []Issue, bool) {
+	var issues []Issue
+
+	// drops when unfixable: degenerate holes are removed, anything else drops the polygon
+	dropAction := PolygonDropped
+
+	if len(flat) == 0 || stride < 2 {
+		return nil, []Issue{{Type: DegenerateRing, Action: dropAction, Message: "empty ring"}}, false
+	}
+
+	pts := make([][2]float64, 0, len(flat)/stride)
+	for i := 0; i+1 < len(flat); i += stride {
+		pts = append(pts, [2]float64{flat[i], flat[i+1]})
+	}
+
+	if len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+		pts = pts[:len(pts)-1]
+	} else {
+		issues = append(issues, Issue{Type: UnclosedRing, Action: Fixed})
+	}
+
+	// rings crossing the antimeridian are processed with continuous longitudes
+	flatPts := make([]float64, 0, len(pts)*2)
+	for _, pt := range pts {
+		flatPts = append(flatPts, pt[0], pt[1])
+	}
+
+	unwrapped, offset := insideout.UnwrapLongitudes(flatPts)
+	if offset != 0 {
+		// rings going around a pole have no meaning in a plane, they are left to s2
+		return polarRing(pts, issues)
+	}
+
+	for i := range pts {
+		pts[i][0] = unwrapped[i*2]
+	}
+
+	var dups, spikes int
+
+	pts, dups, spikes = removeDuplicatesAndSpikes(pts)
+	if dups > 0 {
+		issues = append(issues, Issue{
+			Type:    DuplicateVertices,
+			Action:  Fixed,
+			Message: fmt.Sprintf("%d duplicate vertices removed", dups),
+		})
+	}
+
+	if spikes > 0 {
+		issues = append(issues, Issue{
+			Type:    Spike,
+			Action:  Fixed,
+			Message: fmt.Sprintf("%d spikes removed", spikes),
+		})
+	}
+
+	if hole {
+		dropAction = RingDropped
+	}
+
+	area := planarArea(pts)
+	if len(pts) < 3 || area == 0 {
+		return nil, append(issues, Issue{
+			Type:    DegenerateRing,
+			Action:  dropAction,
+			Message: fmt.Sprintf("ring has no area, %d distinct vertices", len(pts)),
+		}), false
+	}
+
+	fixed, removed, err := fixSelfIntersections(pts)
+	switch {
+	case err != nil:
+		// s2 copes with some self intersections using the even odd rule,
+		// keeping the ring is better than dropping the polygon
+		issues = append(issues, Issue{
+			Type:    SelfIntersection,
+			Action:  Kept,
+			Message: err.Error(),
+		})
+	case removed > 0:
+		pts = fixed
+		area = planarArea(pts)
+
+		issues = append(issues, Issue{
+			Type:    SelfIntersection,
+			Action:  Fixed,
+			Message: fmt.Sprintf("%d vertices removed", removed),
+		})
+	}
+
+	// outer rings are counter clockwise, holes clockwise
+	if (area > 0) == hole {
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+
+		issues = append(issues, Issue{Type: WrongOrientation, Action: Fixed})
+	}
+
+	return closedCoordinates(pts), issues, true
+}
+
+// polarRing only removes the duplicate vertices of a ring going around a pole.
+func polarRing(pts [][2]float64, issues []Issue) ([]float64, []Issue, bool) {
+	dedup := make([][2]float64, 0, len(pts))
+
+	for i, pt := range pts {
+		if i > 0 && pt == dedup[len(dedup)-1] {
+			continue
+		}
+
+		dedup = append(dedup, pt)
+	}
+
+	if dups := len(pts) - len(dedup); dups > 0 {
+		issues = append(issues, Issue{
+			Type:    DuplicateVertices,
+			Action:  Fixed,
+			Message: fmt.Sprintf("%d duplicate vertices removed", dups),
+		})
+	}
+
+	return closedCoordinates(dedup), issues, true
+}
+
+// closedCoordinates returns the flat closed ring with longitudes back in [-180, 180].
+func closedCoordinates(pts [][2]float64) []float64 {
+	coords := make([]float64, 0, len(pts)*2+2)
+
+	for _, pt := range pts {
+		lng := pt[0]
+
+		switch {
+		case lng > 180:
+			lng -= 360
+		case lng < -180:
+			lng += 360
+		}
+
+		coords = append(coords, lng, pt[1])
+	}
+
+	return append(coords, coords[0], coords[1])
+}
+
+// removeDuplicatesAndSpikes removes consecutive duplicate vertices
+// and spikes, vertices where the ring goes back on itself,
+// it returns the cleaned ring and the number of duplicates and spikes removed.
+func removeDuplicatesAndSpikes(pts [][2]float64) ([][2]float64, int, int) {
+	var dups, spikes int
+
+	for changed := true; changed && len(pts) > 2; {
+		changed = false
+
+		for i := 0; i < len(pts) && len(pts) > 1; i++ {
+			if pts[i] == pts[(i+1)%len(pts)] {
+				pts = append(pts[:i], pts[i+1:]...)
+				dups++
+				changed = true
+				i--
+			}
+		}
+
+		for i := 0; i < len(pts) && len(pts) > 2; i++ {
+			prev := pts[(i+len(pts)-1)%len(pts)]
+			next := pts[(i+1)%len(pts)]
+
+			if isSpike(prev, pts[i], next) {
+				pts = append(pts[:i], pts[i+1:]...)
+				spikes++
+				changed = true
+				i--
+			}
+		}
+	}
+
+	return pts, dups, spikes
+}
+
+// isSpike reports whether the path a b c goes back on itself at b.
+func isSpike(a, b, c [2]float64) bool {
+	if a == c {
+		return true
+	}
+
+	abx, aby := b[0]-a[0], b[1]-a[1]
+	bcx, bcy := c[0]-b[0], c[1]-b[1]
+
+	// collinear and opposite directions
+	return abx*bcy-aby*bcx == 0 && abx*bcx+aby*bcy < 0
+}
+
+// planarArea returns the signed area of the ring computed in lng lat space,
+// positive when counter clockwise.
+func planarArea(pts [][2]float64) float64 {
+	var a float64
+
+	for i := range pts {
+		j := (i + 1) % len(pts)
+		a += pts[i][0]*pts[j][1] - pts[j][0]*pts[i][1]
+	}
+
+	return a / 2
+}
+
+// maxSelfIntersectionFixes is the maximum count of vertices removed to fix self intersections,
+// more means the ring is too broken to be repaired without altering its shape.
+const maxSelfIntersectionFixes = 8
+
+// fixSelfIntersections removes vertices involved in crossing edges, choosing the one that changes
+// the area the least, it returns the fixed ring and the number of vertices removed,
+// or an error if the ring can't be fixed.
+func fixSelfIntersections(pts [][2]float64) ([][2]float64, int, error) {
+	fixed := pts
+
+	for removed := 0; ; removed++ {
+		i, j, err := findSelfIntersection(fixed)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if i < 0 {
+			return fixed, removed, nil
+		}
+
+		if removed == maxSelfIntersectionFixes {
+			return nil, 0, fmt.Errorf("edge %d crosses edge %d, too many crossings to be fixed", i, j)
+		}
+
+		area := planarArea(fixed)
+
+		var best [][2]float64
+
+		bestDelta := math.Inf(1)
+
+		for _, k := range []int{i, (i + 1) % len(fixed), j, (j + 1) % len(fixed)} {
+			candidate := make([][2]float64, 0, len(fixed)-1)
+			candidate = append(candidate, fixed[:k]...)
+			candidate = append(candidate, fixed[k+1:]...)
+
+			if len(candidate) < 3 {
+				continue
+			}
+
+			if delta := math.Abs(planarArea(candidate) - area); delta < bestDelta {
+				best, bestDelta = candidate, delta
+			}
+		}
+
+		if best == nil {
+			return nil, 0, fmt.Errorf("edge %d crosses edge %d", i, j)
+		}
+
+		fixed = best
+	}
+}
+
+// findSelfIntersection returns the first pair of crossing edges or -1,
+// it returns an error if the ring touches itself on a vertex.
+func findSelfIntersection(pts [][2]float64) (int, int, error) {
+	seen := make(map[[2]float64]int, len(pts))
+
+	for i, pt := range pts {
+		if j, ok := seen[pt]; ok {
+			return 0, 0, fmt.Errorf("ring touches itself at vertices %d and %d", j, i)
+		}
+
+		seen[pt] = i
+	}
+
+	l := loopFromPoints(pts)
+
+	idx := s2.NewShapeIndex()
+	idx.Add(l)
+
+	q := s2.NewCrossingEdgeQuery(idx)
+
+	for i := 0; i < l.NumEdges(); i++ {
+		e := l.Edge(i)
+
+		crossings := q.Crossings(e.V0, e.V1, l, s2.CrossingTypeInterior)
+		if len(crossings) > 0 {
+			return i, crossings[0], nil
+		}
+	}
+
+	return -1, -1, nil
+}
+
+func loopFromPoints(pts [][2]float64) *s2.Loop {
+	points := make([]s2.Point, len(pts))
+	for i, pt := range pts {
+		points[i] = s2.PointFromLatLng(s2.LatLngFromDegrees(pt[1], pt[0]))
+	}
+
+	return s2.LoopFromPoints(points)
+}
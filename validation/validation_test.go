@@ -0,0 +1,238 @@
+package validation_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/insideout/validation"
+)
+
+func TestRepair(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		geometry   geom.T
+		wantCoords []float64
+		wantEnds   []int
+		wantIssues []validation.IssueType
+		wantAction []validation.Action
+	}{
+		{
+			"valid polygon",
+			geom.NewPolygonFlat(geom.XY, []float64{0, 0, 1, 0, 1, 1, 0, 1, 0, 0}, []int{10}),
+			[]float64{0, 0, 1, 0, 1, 1, 0, 1, 0, 0},
+			[]int{10},
+			nil,
+			nil,
+		},
+		{
+			"clockwise outer ring",
+			geom.NewPolygonFlat(geom.XY, []float64{0, 0, 0, 1, 1, 1, 1, 0, 0, 0}, []int{10}),
+			[]float64{1, 0, 1, 1, 0, 1, 0, 0, 1, 0},
+			[]int{10},
+			[]validation.IssueType{validation.WrongOrientation},
+			[]validation.Action{validation.Fixed},
+		},
+		{
+			"duplicate vertices and unclosed ring",
+			geom.NewPolygonFlat(geom.XY, []float64{0, 0, 1, 0, 1, 0, 1, 1, 0, 1}, []int{10}),
+			[]float64{0, 0, 1, 0, 1, 1, 0, 1, 0, 0},
+			[]int{10},
+			[]validation.IssueType{validation.UnclosedRing, validation.DuplicateVertices},
+			[]validation.Action{validation.Fixed, validation.Fixed},
+		},
+		{
+			"spike",
+			geom.NewPolygonFlat(geom.XY, []float64{0, 0, 1, 0, 2, 0, 1, 0, 1, 1, 0, 1, 0, 0}, []int{14}),
+			[]float64{0, 0, 1, 0, 1, 1, 0, 1, 0, 0},
+			[]int{10},
+			[]validation.IssueType{validation.DuplicateVertices, validation.Spike},
+			[]validation.Action{validation.Fixed, validation.Fixed},
+		},
+		{
+			"degenerate hole",
+			geom.NewPolygonFlat(geom.XY, []float64{
+				0, 0, 1, 0, 1, 1, 0, 1, 0, 0,
+				0.2, 0.2, 0.4, 0.4, 0.6, 0.6, 0.2, 0.2,
+			}, []int{10, 18}),
+			[]float64{0, 0, 1, 0, 1, 1, 0, 1, 0, 0},
+			[]int{10},
+			[]validation.IssueType{validation.Spike, validation.DegenerateRing},
+			[]validation.Action{validation.Fixed, validation.RingDropped},
+		},
+		{
+			"counter clockwise hole",
+			geom.NewPolygonFlat(geom.XY, []float64{
+				0, 0, 1, 0, 1, 1, 0, 1, 0, 0,
+				0.2, 0.2, 0.4, 0.2, 0.4, 0.4, 0.2, 0.4, 0.2, 0.2,
+			}, []int{10, 20}),
+			[]float64{
+				0, 0, 1, 0, 1, 1, 0, 1, 0, 0,
+				0.2, 0.4, 0.4, 0.4, 0.4, 0.2, 0.2, 0.2, 0.2, 0.4,
+			},
+			[]int{10, 20},
+			[]validation.IssueType{validation.WrongOrientation},
+			[]validation.Action{validation.Fixed},
+		},
+		{
+			"self intersection",
+			geom.NewPolygonFlat(geom.XY, []float64{0, 0, 3, 0, 0, 1, 2, 2, 0, 0}, []int{10}),
+			[]float64{0, 0, 3, 0, 0, 1, 0, 0},
+			[]int{8},
+			[]validation.IssueType{validation.SelfIntersection},
+			[]validation.Action{validation.Fixed},
+		},
+		{
+			"ring touching itself",
+			geom.NewPolygonFlat(geom.XY, []float64{0, 0, 2, 0, 1, 1, 2, 2, 0, 2, 1, 1, 0, 0}, []int{14}),
+			[]float64{0, 0, 2, 0, 1, 1, 2, 2, 0, 2, 1, 1, 0, 0},
+			[]int{14},
+			[]validation.IssueType{validation.SelfIntersection},
+			[]validation.Action{validation.Kept},
+		},
+		{
+			"clockwise ring crossing the antimeridian",
+			geom.NewPolygonFlat(geom.XY, []float64{-179, -17, 179, -17, 179, -16, -179, -16, -179, -17}, []int{10}),
+			[]float64{-179, -16, 179, -16, 179, -17, -179, -17, -179, -16},
+			[]int{10},
+			[]validation.IssueType{validation.WrongOrientation},
+			[]validation.Action{validation.Fixed},
+		},
+		{
+			"multipolygon with a degenerate polygon",
+			geom.NewMultiPolygonFlat(geom.XY, []float64{
+				0, 0, 1, 1, 2, 2, 0, 0,
+				2, 2, 3, 2, 3, 3, 2, 3, 2, 2,
+			}, [][]int{{8}, {18}}),
+			[]float64{2, 2, 3, 2, 3, 3, 2, 3, 2, 2},
+			[]int{10},
+			[]validation.IssueType{validation.Spike, validation.DegenerateRing},
+			[]validation.Action{validation.Fixed, validation.PolygonDropped},
+		},
+		{
+			"unsupported geometry",
+			geom.NewPointFlat(geom.XY, []float64{1, 1}),
+			nil,
+			nil,
+			[]validation.IssueType{validation.UnsupportedGeometry},
+			[]validation.Action{validation.PolygonDropped},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, issues := validation.Repair(&geojson.Feature{Geometry: tt.geometry})
+
+			var gotIssues []validation.IssueType
+
+			var gotActions []validation.Action
+
+			for _, issue := range issues {
+				gotIssues = append(gotIssues, issue.Type)
+				gotActions = append(gotActions, issue.Action)
+			}
+
+			if !cmp.Equal(gotIssues, tt.wantIssues) || !cmp.Equal(gotActions, tt.wantAction) {
+				t.Fatalf("Repair() issues = %+v, want %v %v", issues, tt.wantIssues, tt.wantAction)
+			}
+
+			if tt.wantCoords == nil {
+				require.Nil(t, f)
+
+				return
+			}
+
+			require.NotNil(t, f)
+
+			var coords []float64
+
+			var ends []int
+
+			switch g := f.Geometry.(type) {
+			case *geom.Polygon:
+				coords, ends = g.FlatCoords(), g.Ends()
+			case *geom.MultiPolygon:
+				require.Equal(t, 1, g.NumPolygons())
+				coords, ends = g.Polygon(0).FlatCoords(), g.Polygon(0).Ends()
+			}
+
+			if !cmp.Equal(coords, tt.wantCoords) || !cmp.Equal(ends, tt.wantEnds) {
+				t.Fatalf("Repair() got = %v %v, want %v %v", coords, ends, tt.wantCoords, tt.wantEnds)
+			}
+		})
+	}
+}
+
+func TestRepairCountries(t *testing.T) {
+	t.Parallel()
+
+	file, err := os.Open("../testdata/ne_110m_admin_0_countries.geojson")
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	var fc geojson.FeatureCollection
+
+	err = json.NewDecoder(file).Decode(&fc)
+	require.NoError(t, err)
+
+	report := &validation.Report{}
+
+	for i, f := range fc.Features {
+		rf, issues := validation.Repair(f)
+		report.Add(i, f, rf == nil, issues)
+	}
+
+	require.Equal(t, len(fc.Features), report.FeatureCount)
+	require.Zero(t, report.DroppedCount, "%+v", report.Features)
+}
+
+func TestReport_AddSkipped(t *testing.T) {
+	t.Parallel()
+
+	report := &validation.Report{}
+
+	for i := 0; i < 4; i++ {
+		var issues []validation.Issue
+		if i == 2 {
+			issues = []validation.Issue{{Type: validation.Spike, Action: validation.Fixed}}
+		}
+
+		report.Add(i, &geojson.Feature{ID: strconv.Itoa(i)}, false, issues)
+	}
+
+	report.AddSkipped(1, &geojson.Feature{ID: "1"}, errors.New("invalid loop"))
+	report.AddSkipped(2, &geojson.Feature{ID: "2"}, errors.New("invalid loop"))
+	report.AddSkipped(3, &geojson.Feature{ID: "3"}, errors.New("invalid loop"))
+
+	require.Equal(t, 3, report.SkippedCount)
+	require.Equal(t, 1, report.FixedCount)
+
+	var (
+		indexes []int
+		issues  []int
+	)
+
+	for _, fr := range report.Features {
+		indexes = append(indexes, fr.Index)
+		issues = append(issues, len(fr.Issues))
+	}
+
+	require.Equal(t, []int{1, 2, 3}, indexes)
+	require.Equal(t, []int{1, 2, 1}, issues)
+	require.Equal(t, validation.CoverFailed, report.Features[0].Issues[0].Type)
+	require.Equal(t, "invalid loop", report.Features[0].Issues[0].Message)
+}